@@ -0,0 +1,151 @@
+// Command bookit-server runs the BookIt API locally over plain HTTP, serving
+// the same routes as the Orchestrator and Auth Lambdas.
+//
+// By default everything is kept in memory and /auth signs in any username,
+// returning tokens the server verifies itself, so the Svelte client works
+// against it with PUBLIC_API_BASE_URL=http://localhost:8080. For quick curl
+// testing, requests can also name their user with the X-Dev-User header.
+//
+// Usage:
+//
+//	go run ./cmd/bookit-server [-addr :8080] [-store memory|dynamo] [-seed-books books.json] [-admins alice]
+//	    [-metadata openLibrary,googleBooks|fixture] [-metadata-fixture books.json]
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/FriedGlue/BookIt/api/pkg/cache"
+	"github.com/FriedGlue/BookIt/api/pkg/handlers"
+	"github.com/FriedGlue/BookIt/api/pkg/localserver"
+	"github.com/FriedGlue/BookIt/api/pkg/metadata"
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/FriedGlue/BookIt/api/pkg/routes"
+	"github.com/FriedGlue/BookIt/api/pkg/search"
+	"github.com/FriedGlue/BookIt/api/pkg/shared"
+	"github.com/FriedGlue/BookIt/api/pkg/store"
+)
+
+func main() {
+	addr := flag.String("addr", envOr("BOOKIT_ADDR", ":8080"), "address to listen on")
+	storeKind := flag.String("store", envOr("BOOKIT_STORE", "memory"), `where data is kept: "memory" or "dynamo" (uses the *_TABLE_NAME env vars)`)
+	secret := flag.String("jwt-secret", envOr("BOOKIT_DEV_JWT_SECRET", "bookit-local-dev-secret"), "secret used to sign local tokens")
+	devHeader := flag.Bool("dev-header", true, "accept the X-Dev-User header in place of a token")
+	admins := flag.String("admins", os.Getenv("BOOKIT_ADMINS"), "comma-separated usernames allowed to call the /admin routes and edit series")
+	seedBooks := flag.String("seed-books", "", "JSON file with an array of books to load into the book store")
+	metadataCfg := metadata.ConfigFromEnv()
+	providers := flag.String("metadata", strings.Join(metadataCfg.Providers, ","), `metadata providers in priority order, e.g. "openLibrary,googleBooks" or "fixture"`)
+	flag.StringVar(&metadataCfg.FixturePath, "metadata-fixture", metadataCfg.FixturePath, "JSON file with an array of books for the fixture metadata provider")
+	flag.Parse()
+
+	var stores handlers.Stores
+	metadataCache := cache.NewTiered(cache.NewLRU(1000))
+	switch *storeKind {
+	case "memory":
+		books := store.NewMemoryBookStore()
+		index := search.NewIndex(store.NewMemorySearchIndexStore(), books)
+		authors := store.NewMemoryAuthorStore()
+		authorIndex := search.NewAuthorIndex(store.NewMemorySearchIndexStore(), authors)
+		stores = handlers.Stores{
+			Profiles:     store.NewMemoryProfileStore(),
+			Books:        search.NewIndexedBookStore(books, index),
+			Works:        store.NewMemoryWorkStore(),
+			Authors:      search.NewIndexedAuthorStore(authors, authorIndex),
+			Series:       store.NewMemorySeriesStore(),
+			ReadingLog:   store.NewMemoryReadingLogStore(),
+			Search:       index,
+			AuthorSearch: authorIndex,
+		}
+	case "dynamo":
+		svc := shared.DynamoDBClient()
+		books := store.NewDynamoBookStore(svc,
+			os.Getenv("BOOKS_TABLE_NAME"),
+			os.Getenv("ISBN_INDEX_NAME"),
+			os.Getenv("OPEN_LIBRARY_INDEX_NAME"),
+			os.Getenv("WORK_INDEX_NAME"),
+		)
+		index := search.NewIndex(store.NewDynamoSearchIndexStore(svc, os.Getenv("SEARCH_INDEX_TABLE_NAME")), books)
+		authors := store.NewDynamoAuthorStore(svc, os.Getenv("AUTHORS_TABLE_NAME"))
+		authorIndex := search.NewAuthorIndex(store.NewDynamoSearchIndexStore(svc, os.Getenv("AUTHOR_SEARCH_INDEX_TABLE_NAME")), authors)
+		stores = handlers.Stores{
+			Profiles:     store.NewDynamoProfileStore(svc, os.Getenv("PROFILES_TABLE_NAME")),
+			Books:        search.NewIndexedBookStore(books, index),
+			Works:        store.NewDynamoWorkStore(svc, os.Getenv("WORKS_TABLE_NAME")),
+			Authors:      search.NewIndexedAuthorStore(authors, authorIndex),
+			Series:       store.NewDynamoSeriesStore(svc, os.Getenv("SERIES_TABLE_NAME")),
+			ReadingLog:   store.NewDynamoReadingLogStore(svc, os.Getenv("READING_LOG_TABLE_NAME")),
+			Search:       index,
+			AuthorSearch: authorIndex,
+		}
+		if table := os.Getenv("METADATA_CACHE_TABLE_NAME"); table != "" {
+			metadataCache = cache.NewTiered(cache.NewLRU(1000), cache.NewDynamo(svc, table))
+		}
+	default:
+		log.Fatalf("Unknown -store %q, expected memory or dynamo", *storeKind)
+	}
+	handlers.Configure(stores)
+
+	metadataCfg.Providers = strings.FieldsFunc(*providers, func(r rune) bool { return r == ',' || r == ' ' })
+	metadataCfg.Cache = metadataCache
+	provider, err := metadata.New(metadataCfg)
+	if err != nil {
+		log.Fatalf("Error configuring metadata providers: %v", err)
+	}
+	handlers.ConfigureMetadata(provider)
+
+	if *seedBooks != "" {
+		if err := loadBooks(stores.Books, *seedBooks); err != nil {
+			log.Fatalf("Error seeding books: %v", err)
+		}
+	}
+
+	tokens := localserver.NewDevTokens(*secret)
+	devAuth := localserver.NewDevAuth(tokens, stores.Profiles)
+	server := localserver.New(localserver.Config{
+		Orchestrator:   routes.Orchestrator,
+		Auth:           devAuth.Route,
+		Tokens:         tokens,
+		DevAuth:        devAuth,
+		AllowDevHeader: *devHeader,
+		Admins:         strings.FieldsFunc(*admins, func(r rune) bool { return r == ',' || r == ' ' }),
+		AdminGroup:     envOr("ADMIN_GROUP", "admin"),
+	})
+
+	for _, route := range append(append(devAuth.Routes(), routes.OpenAPIRoutes()...), routes.OrchestratorRoutes()...) {
+		log.Printf("  %-7s %s\n", route.Method, route.Pattern)
+	}
+	log.Printf("BookIt API listening on %s (store: %s, metadata: %s)\n", *addr, *storeKind, provider.Name())
+	log.Fatal(http.ListenAndServe(*addr, server))
+}
+
+// loadBooks puts every book in the JSON array stored at path into books.
+func loadBooks(books store.BookStore, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var seed []models.BookData
+	if err := json.Unmarshal(data, &seed); err != nil {
+		return err
+	}
+	for i := range seed {
+		if err := books.Put(&seed[i]); err != nil {
+			return err
+		}
+	}
+	log.Printf("Seeded %d books from %s\n", len(seed), path)
+	return nil
+}
+
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
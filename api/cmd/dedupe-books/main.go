@@ -0,0 +1,82 @@
+// Command dedupe-books finds books stored more than once in the Books table
+// and, with -merge, merges each group into its most complete book, pointing
+// every list, currently reading, reading log and series reference at it. Without
+// -merge it only reports the groups.
+//
+// Groups found only by a similar title and a shared author may be different
+// editions, so they are merged only with -fuzzy. It is safe to run more than
+// once: a merge that fails part way is completed by the next run.
+//
+// Usage:
+//
+//	go run ./cmd/dedupe-books -books BookDataTable-dev -profiles ProfilesTable-dev \
+//	    -reading-log ReadingLogTable-dev -search-index SearchIndexTable-dev -series SeriesTable-dev [-merge] [-fuzzy]
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/FriedGlue/BookIt/api/pkg/dedupe"
+	"github.com/FriedGlue/BookIt/api/pkg/search"
+	"github.com/FriedGlue/BookIt/api/pkg/shared"
+	"github.com/FriedGlue/BookIt/api/pkg/store"
+)
+
+func main() {
+	booksTable := flag.String("books", os.Getenv("BOOKS_TABLE_NAME"), "name of the Books table")
+	profilesTable := flag.String("profiles", os.Getenv("PROFILES_TABLE_NAME"), "name of the Profiles table")
+	readingLogTable := flag.String("reading-log", os.Getenv("READING_LOG_TABLE_NAME"), "name of the ReadingLog table")
+	indexTable := flag.String("search-index", os.Getenv("SEARCH_INDEX_TABLE_NAME"), "name of the SearchIndex table")
+	seriesTable := flag.String("series", os.Getenv("SERIES_TABLE_NAME"), "name of the Series table")
+	merge := flag.Bool("merge", false, "merge the duplicates instead of only reporting them")
+	fuzzy := flag.Bool("fuzzy", false, "also merge groups found only by title and author")
+	flag.Parse()
+
+	if *booksTable == "" || *profilesTable == "" || *readingLogTable == "" || *indexTable == "" || *seriesTable == "" {
+		log.Fatal("-books, -profiles, -reading-log, -search-index and -series (or the matching *_TABLE_NAME variables) are required")
+	}
+
+	svc := shared.DynamoDBClient()
+	books := store.NewDynamoBookStore(svc, *booksTable, "", "", "")
+	index := search.NewIndex(store.NewDynamoSearchIndexStore(svc, *indexTable), books)
+	merger := dedupe.Merger{
+		Books:      search.NewIndexedBookStore(books, index),
+		Profiles:   store.NewDynamoProfileStore(svc, *profilesTable),
+		ReadingLog: store.NewDynamoReadingLogStore(svc, *readingLogTable),
+		Series:     store.NewDynamoSeriesStore(svc, *seriesTable),
+	}
+
+	all, err := books.List()
+	if err != nil {
+		log.Fatalf("Error listing books: %v", err)
+	}
+	groups := dedupe.Find(all)
+	log.Printf("Found %d groups of duplicates among %d books\n", len(groups), len(all))
+
+	var merged, skipped, failed int
+	for _, group := range groups {
+		log.Printf("Keep %s %q, duplicates %v (%v)\n",
+			group.CanonicalBookID, group.Books[0].Title, group.DuplicateBookIDs, group.Reasons)
+		if !*merge {
+			continue
+		}
+		if !group.Exact() && !*fuzzy {
+			skipped++
+			continue
+		}
+		if _, err := merger.Merge(group.CanonicalBookID, group.DuplicateBookIDs); err != nil {
+			log.Printf("Error merging into %s: %v\n", group.CanonicalBookID, err)
+			failed++
+			continue
+		}
+		merged++
+	}
+
+	log.Printf("Merged %d groups (%d skipped as title and author matches only, %d failed, merge: %t)\n",
+		merged, skipped, failed, *merge)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,115 @@
+// Command migrate-reading-log moves reading log entries that are still stored
+// on profile items into the ReadingLog table and then clears them from the
+// profile. It is safe to run more than once: migrated entries keep a
+// deterministic ID, so rerunning overwrites them instead of duplicating them.
+//
+// Usage:
+//
+//	go run ./cmd/migrate-reading-log -profiles ProfilesTable-dev -reading-log ReadingLogTable-dev [-dry-run]
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/FriedGlue/BookIt/api/pkg/shared"
+	"github.com/FriedGlue/BookIt/api/pkg/store"
+)
+
+func main() {
+	profilesTable := flag.String("profiles", os.Getenv("PROFILES_TABLE_NAME"), "name of the Profiles table")
+	readingLogTable := flag.String("reading-log", os.Getenv("READING_LOG_TABLE_NAME"), "name of the ReadingLog table")
+	dryRun := flag.Bool("dry-run", false, "report what would be migrated without writing anything")
+	flag.Parse()
+
+	if *profilesTable == "" || *readingLogTable == "" {
+		log.Fatal("both -profiles and -reading-log (or PROFILES_TABLE_NAME and READING_LOG_TABLE_NAME) are required")
+	}
+
+	svc := shared.DynamoDBClient()
+	profiles := store.NewDynamoProfileStore(svc, *profilesTable)
+	readingLog := store.NewDynamoReadingLogStore(svc, *readingLogTable)
+
+	userIDs, err := profiles.ListIDs()
+	if err != nil {
+		log.Fatalf("Error listing profiles: %v", err)
+	}
+	log.Printf("Found %d profiles\n", len(userIDs))
+
+	var migratedProfiles, migratedEntries, failed int
+	for _, userID := range userIDs {
+		n, err := migrateProfile(profiles, readingLog, userID, *dryRun)
+		if err != nil {
+			log.Printf("Error migrating profile %s: %v\n", userID, err)
+			failed++
+			continue
+		}
+		if n > 0 {
+			migratedProfiles++
+			migratedEntries += n
+		}
+	}
+
+	log.Printf("Migrated %d entries from %d profiles (%d failed, dry run: %t)\n",
+		migratedEntries, migratedProfiles, failed, *dryRun)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// migrateProfile copies one profile's embedded reading log into the
+// ReadingLog table and clears it from the profile. It returns the number of
+// entries migrated.
+func migrateProfile(profiles store.ProfileStore, readingLog store.ReadingLogStore, userID string, dryRun bool) (int, error) {
+	profile, err := profiles.Get(userID)
+	if err != nil {
+		return 0, err
+	}
+	if len(profile.ReadingLog) == 0 {
+		return 0, nil
+	}
+	if dryRun {
+		log.Printf("Would migrate %d entries for user %s\n", len(profile.ReadingLog), userID)
+		return len(profile.ReadingLog), nil
+	}
+
+	for i, entry := range profile.ReadingLog {
+		entry.Id = migratedEntryID(entry, i)
+		if err := readingLog.Append(userID, &entry); err != nil {
+			return 0, err
+		}
+	}
+
+	// Only clear the profile once every entry has been copied, so a failed
+	// run can simply be retried.
+	_, err = profiles.Update(userID, func(p *models.Profile) error {
+		p.ReadingLog = nil
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	log.Printf("Migrated %d entries for user %s\n", len(profile.ReadingLog), userID)
+	return len(profile.ReadingLog), nil
+}
+
+// migratedEntryID derives a stable ReadingLog ID for an old entry from its
+// date and its old ID, falling back to its position for entries without one.
+func migratedEntryID(entry models.ReadingLogItem, index int) string {
+	date, err := time.Parse(time.RFC3339, entry.Date)
+	if err != nil {
+		log.Printf("Entry %q has an unparseable date %q, filing it at the epoch\n", entry.Id, entry.Date)
+		date = time.Unix(0, 0)
+	}
+
+	suffix := entry.Id
+	if suffix == "" {
+		suffix = "legacy-" + strconv.Itoa(index)
+	}
+	return models.ReadingLogID(date, suffix)
+}
@@ -3,18 +3,20 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"os"
 
 	"github.com/FriedGlue/BookIt/api/pkg/models"
 	"github.com/FriedGlue/BookIt/api/pkg/shared"
+	"github.com/FriedGlue/BookIt/api/pkg/store"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 )
 
+// profiles is where new profiles are written; it is set up in main.
+var profiles store.ProfileStore
+
 type UserEvent struct {
 	Action   string `json:"action"`
 	Username string `json:"username"`
@@ -37,36 +39,15 @@ func handleRequest(ctx context.Context, snsEvent events.SNSEvent) error {
 		}
 
 		// Create a new profile
-		profile := models.Profile{
-			ID: userEvent.Sub,
-			ProfileInformation: models.ProfileInformation{
-				Username: userEvent.Username,
-			},
-			CurrentlyReading: []models.CurrentlyReadingItem{},
-			Lists: models.UserLists{
-				ToBeRead:    []models.ToBeReadItem{},
-				Read:        []models.ReadItem{},
-				CustomLists: make(map[string][]models.CustomListItem),
-			},
-			ReadingLog: []models.ReadingLogItem{},
-			Challenges: []models.ReadingChallenge{},
-		}
+		profile := models.NewProfile(userEvent.Sub, userEvent.Username)
 
-		// Marshal the profile to DynamoDB format
-		item, err := dynamodbattribute.MarshalMap(profile)
-		if err != nil {
-			log.Printf("Error marshalling profile: %v", err)
+		err := profiles.Create(&profile)
+		if errors.Is(err, store.ErrAlreadyExists) {
+			// A redelivered event, or the user saved their profile first
+			log.Printf("Profile for user %s already exists", userEvent.Username)
 			continue
 		}
-
-		// Save to DynamoDB
-		svc := shared.DynamoDBClient()
-		input := &dynamodb.PutItemInput{
-			TableName: aws.String(os.Getenv("PROFILES_TABLE_NAME")),
-			Item:      item,
-		}
-
-		if _, err := svc.PutItem(input); err != nil {
+		if err != nil {
 			log.Printf("Error saving profile: %v", err)
 			continue
 		}
@@ -78,5 +59,6 @@ func handleRequest(ctx context.Context, snsEvent events.SNSEvent) error {
 }
 
 func main() {
+	profiles = store.NewDynamoProfileStore(shared.DynamoDBClient(), os.Getenv("PROFILES_TABLE_NAME"))
 	lambda.Start(handleRequest)
 }
@@ -0,0 +1,103 @@
+// Command reindex-books rebuilds the search index entries of every book in
+// the Books table, and the index from authors to their books kept in the
+// Books table itself. Run it once after creating the SearchIndex table, and
+// again if a failed index update left search results out of date. It is safe
+// to run more than once: each book's entries are replaced, not added to.
+// With -authors and -author-search-index it rebuilds the author name index
+// the same way, and with -series the index from works to the series they are
+// in, which GET /series/next reads.
+//
+// Usage:
+//
+//	go run ./cmd/reindex-books -books BookDataTable-dev -search-index SearchIndexTable-dev \
+//	    [-authors AuthorsTable-dev -author-search-index AuthorSearchIndexTable-dev] [-series SeriesTable-dev]
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/FriedGlue/BookIt/api/pkg/search"
+	"github.com/FriedGlue/BookIt/api/pkg/shared"
+	"github.com/FriedGlue/BookIt/api/pkg/store"
+)
+
+func main() {
+	booksTable := flag.String("books", os.Getenv("BOOKS_TABLE_NAME"), "name of the Books table")
+	indexTable := flag.String("search-index", os.Getenv("SEARCH_INDEX_TABLE_NAME"), "name of the SearchIndex table")
+	authorsTable := flag.String("authors", "", "name of the Authors table, to reindex authors too")
+	authorIndexTable := flag.String("author-search-index", "", "name of the AuthorSearchIndex table")
+	seriesTable := flag.String("series", "", "name of the Series table, to reindex series too")
+	flag.Parse()
+
+	if *booksTable == "" || *indexTable == "" {
+		log.Fatal("both -books and -search-index (or BOOKS_TABLE_NAME and SEARCH_INDEX_TABLE_NAME) are required")
+	}
+	if (*authorsTable == "") != (*authorIndexTable == "") {
+		log.Fatal("-authors and -author-search-index go together")
+	}
+
+	svc := shared.DynamoDBClient()
+	books := store.NewDynamoBookStore(svc, *booksTable, "", "", "")
+	index := search.NewIndex(store.NewDynamoSearchIndexStore(svc, *indexTable), books)
+
+	all, err := books.List()
+	if err != nil {
+		log.Fatalf("Error listing books: %v", err)
+	}
+	log.Printf("Found %d books\n", len(all))
+
+	var indexed, failed int
+	for _, book := range all {
+		if err := index.Add(book); err != nil {
+			log.Printf("Error indexing book %s: %v\n", book.BookID, err)
+			failed++
+			continue
+		}
+		indexed++
+	}
+
+	log.Printf("Indexed %d books (%d failed)\n", indexed, failed)
+
+	byAuthor, err := books.ReindexAuthors()
+	if err != nil {
+		log.Printf("Error indexing books by author: %v\n", err)
+		failed++
+	}
+	log.Printf("Indexed %d books by author\n", byAuthor)
+
+	if *authorsTable != "" {
+		authors := store.NewDynamoAuthorStore(svc, *authorsTable)
+		authorIndex := search.NewAuthorIndex(store.NewDynamoSearchIndexStore(svc, *authorIndexTable), authors)
+		allAuthors, err := authors.List()
+		if err != nil {
+			log.Fatalf("Error listing authors: %v", err)
+		}
+
+		var indexedAuthors, failedAuthors int
+		for _, author := range allAuthors {
+			if err := authorIndex.Add(author); err != nil {
+				log.Printf("Error indexing author %s: %v\n", author.AuthorID, err)
+				failedAuthors++
+				continue
+			}
+			indexedAuthors++
+		}
+		log.Printf("Indexed %d authors (%d failed)\n", indexedAuthors, failedAuthors)
+		failed += failedAuthors
+	}
+
+	if *seriesTable != "" {
+		indexedSeries, err := store.NewDynamoSeriesStore(svc, *seriesTable).Reindex()
+		if err != nil {
+			log.Printf("Error indexing series: %v\n", err)
+			failed++
+		}
+		log.Printf("Indexed %d series\n", indexedSeries)
+	}
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
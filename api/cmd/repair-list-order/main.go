@@ -0,0 +1,86 @@
+// Command repair-list-order puts the lists of every profile in display order
+// and renumbers them, so each item's Order is its index. Profiles written
+// before list order was kept consistent can hold duplicate, negative or
+// missing Orders. It also moves custom lists still kept in the old
+// name-keyed map onto shelves. It is safe to run more than once: profiles
+// already in order are left alone.
+//
+// Usage:
+//
+//	go run ./cmd/repair-list-order -profiles ProfilesTable-dev [-dry-run]
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/FriedGlue/BookIt/api/pkg/shared"
+	"github.com/FriedGlue/BookIt/api/pkg/store"
+)
+
+func main() {
+	profilesTable := flag.String("profiles", os.Getenv("PROFILES_TABLE_NAME"), "name of the Profiles table")
+	dryRun := flag.Bool("dry-run", false, "report which profiles would be repaired without writing anything")
+	flag.Parse()
+
+	if *profilesTable == "" {
+		log.Fatal("-profiles (or PROFILES_TABLE_NAME) is required")
+	}
+
+	profiles := store.NewDynamoProfileStore(shared.DynamoDBClient(), *profilesTable)
+
+	userIDs, err := profiles.ListIDs()
+	if err != nil {
+		log.Fatalf("Error listing profiles: %v", err)
+	}
+	log.Printf("Found %d profiles\n", len(userIDs))
+
+	var repaired, failed int
+	for _, userID := range userIDs {
+		changed, err := repairProfile(profiles, userID, *dryRun)
+		if err != nil {
+			log.Printf("Error repairing profile %s: %v\n", userID, err)
+			failed++
+			continue
+		}
+		if changed {
+			repaired++
+		}
+	}
+
+	log.Printf("Repaired %d profiles (%d failed, dry run: %t)\n", repaired, failed, *dryRun)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// repairProfile normalizes one profile's lists and reports whether they
+// needed it.
+func repairProfile(profiles store.ProfileStore, userID string, dryRun bool) (bool, error) {
+	profile, err := profiles.Get(userID)
+	if err != nil {
+		return false, err
+	}
+	if !profile.Lists.Normalize() {
+		return false, nil
+	}
+	if dryRun {
+		log.Printf("Would repair the lists of user %s\n", userID)
+		return true, nil
+	}
+
+	// The profile may have changed since it was read, so normalize the
+	// stored one within the conditional update.
+	_, err = profiles.Update(userID, func(p *models.Profile) error {
+		p.Lists.Normalize()
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	log.Printf("Repaired the lists of user %s\n", userID)
+	return true, nil
+}
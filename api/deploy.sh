@@ -6,14 +6,17 @@ STAGE=${1:-dev}
 # Additional check for production stage
 if [ "$STAGE" == "prod" ]; then
     echo "🚨 PRODUCTION DEPLOYMENT WARNING 🚨"
-    read -p "Have you reviewed and updated CORS headers? (yes/no): " cors_confirmation
+    read -p "Is ALLOWED_ORIGINS (currently '${ALLOWED_ORIGINS}') set for CORS? (yes/no): " cors_confirmation
 
     if [ "$cors_confirmation" != "yes" ]; then
-        echo "Deployment aborted. Please update CORS headers before proceeding."
+        echo "Deployment aborted. Please set ALLOWED_ORIGINS before proceeding."
         exit 1
     fi
 fi
 
+# Comma-separated origins allowed to call the API from a browser.
+ALLOWED_ORIGINS=${ALLOWED_ORIGINS:-http://localhost:5173}
+
 FULL_STACK_NAME="BookIt-${STAGE}"
 
 # The GOOS and GOARCH are set for AWS Lambda's Linux environment.
@@ -54,4 +57,4 @@ for SRC_DIR in "${SRC_DIRS[@]}"; do
 done
 
 # Deploy using AWS SAM with the stage parameter
-sam deploy --stack-name="$FULL_STACK_NAME" --parameter-overrides StageName="$STAGE"
+sam deploy --stack-name="$FULL_STACK_NAME" --parameter-overrides StageName="$STAGE" AllowedOrigins="$ALLOWED_ORIGINS"
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -10,6 +11,7 @@ import (
 	"github.com/FriedGlue/BookIt/api/pkg/shared"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
 	"github.com/aws/aws-sdk-go/service/sns"
@@ -49,10 +51,10 @@ type SignUpPayload struct {
 func HandleSignUp(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
 	var payload SignUpPayload
 	if err := json.Unmarshal([]byte(request.Body), &payload); err != nil {
-		return shared.ErrorResponse(400, "Invalid JSON: "+err.Error())
+		return shared.Error(shared.CodeInvalidJSON, "Invalid JSON: "+err.Error())
 	}
 	if payload.Username == "" || payload.Password == "" || payload.Email == "" {
-		return shared.ErrorResponse(400, "username, password, and email are required")
+		return shared.Error(shared.CodeMissingParameter, "username, password, and email are required")
 	}
 
 	cip := newCognitoClient()
@@ -70,13 +72,10 @@ func HandleSignUp(request events.APIGatewayProxyRequest) events.APIGatewayProxyR
 
 	_, err := cip.SignUp(input)
 	if err != nil {
-		return shared.ErrorResponse(500, fmt.Sprintf("SignUp error: %v", err))
+		return cognitoErrorResponse("SignUp", err)
 	}
 
-	return events.APIGatewayProxyResponse{
-		StatusCode: 200,
-		Body:       fmt.Sprintf("User '%s' sign-up initiated. Check your email for a confirmation code.", payload.Username),
-	}
+	return shared.MessageResponse(200, fmt.Sprintf("User '%s' sign-up initiated. Check your email for a confirmation code.", payload.Username))
 }
 
 // ----------------- Confirm Sign Up Logic -----------------
@@ -85,16 +84,19 @@ func HandleSignUp(request events.APIGatewayProxyRequest) events.APIGatewayProxyR
 // Request body (JSON): { "username":"...", "code":"123456" }
 //
 
+// ConfirmSignUpPayload represents the expected JSON structure for confirmation requests
+type ConfirmSignUpPayload struct {
+	Username string `json:"username"`
+	Code     string `json:"code"`
+}
+
 func HandleConfirmSignUp(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
-	var payload struct {
-		Username string `json:"username"`
-		Code     string `json:"code"`
-	}
+	var payload ConfirmSignUpPayload
 	if err := json.Unmarshal([]byte(request.Body), &payload); err != nil {
-		return shared.ErrorResponse(400, "Invalid JSON: "+err.Error())
+		return shared.Error(shared.CodeInvalidJSON, "Invalid JSON: "+err.Error())
 	}
 	if payload.Username == "" || payload.Code == "" {
-		return shared.ErrorResponse(400, "username and code are required")
+		return shared.Error(shared.CodeMissingParameter, "username and code are required")
 	}
 
 	cip := newCognitoClient()
@@ -106,7 +108,7 @@ func HandleConfirmSignUp(request events.APIGatewayProxyRequest) events.APIGatewa
 
 	_, err := cip.ConfirmSignUp(input)
 	if err != nil {
-		return shared.ErrorResponse(500, fmt.Sprintf("ConfirmSignUp error: %v", err))
+		return cognitoErrorResponse("ConfirmSignUp", err)
 	}
 
 	// Get the user's sub from Cognito
@@ -116,7 +118,7 @@ func HandleConfirmSignUp(request events.APIGatewayProxyRequest) events.APIGatewa
 	})
 	if err != nil {
 		log.Printf("Error getting user info: %v", err)
-		return shared.ErrorResponse(500, fmt.Sprintf("Error getting user info: %v", err))
+		return shared.Error(shared.CodeExternalService, "Error getting user info")
 	}
 
 	var sub string
@@ -129,7 +131,7 @@ func HandleConfirmSignUp(request events.APIGatewayProxyRequest) events.APIGatewa
 
 	if sub == "" {
 		log.Printf("User sub not found")
-		return shared.ErrorResponse(500, "User sub not found")
+		return shared.Error(shared.CodeInternal, "User sub not found")
 	}
 
 	// Send a message to the service bus to create a new user profile
@@ -155,10 +157,7 @@ func HandleConfirmSignUp(request events.APIGatewayProxyRequest) events.APIGatewa
 		}
 	}
 
-	return events.APIGatewayProxyResponse{
-		StatusCode: 200,
-		Body:       fmt.Sprintf("User '%s' confirmed successfully.", payload.Username),
-	}
+	return shared.MessageResponse(200, fmt.Sprintf("User '%s' confirmed successfully.", payload.Username))
 }
 
 // ----------------- Resend Confirmation Code Logic -----------------
@@ -172,10 +171,10 @@ func HandleResendConfirmationCode(request events.APIGatewayProxyRequest) events.
 		Username string `json:"username"`
 	}
 	if err := json.Unmarshal([]byte(request.Body), &payload); err != nil {
-		return shared.ErrorResponse(400, "Invalid JSON: "+err.Error())
+		return shared.Error(shared.CodeInvalidJSON, "Invalid JSON: "+err.Error())
 	}
 	if payload.Username == "" {
-		return shared.ErrorResponse(400, "username is required")
+		return shared.Error(shared.CodeMissingParameter, "username is required")
 	}
 
 	cip := newCognitoClient()
@@ -186,13 +185,10 @@ func HandleResendConfirmationCode(request events.APIGatewayProxyRequest) events.
 
 	_, err := cip.ResendConfirmationCode(input)
 	if err != nil {
-		return shared.ErrorResponse(500, fmt.Sprintf("ResendConfirmationCode error: %v", err))
+		return cognitoErrorResponse("ResendConfirmationCode", err)
 	}
 
-	return events.APIGatewayProxyResponse{
-		StatusCode: 200,
-		Body:       fmt.Sprintf("Confirmation code resent to '%s'.", payload.Username),
-	}
+	return shared.MessageResponse(200, fmt.Sprintf("Confirmation code resent to '%s'.", payload.Username))
 }
 
 // ----------------- Sign In Logic -----------------
@@ -201,16 +197,26 @@ func HandleResendConfirmationCode(request events.APIGatewayProxyRequest) events.
 // Request body (JSON): { "username":"...", "password":"..." }
 //
 
+// SignInPayload represents the expected JSON structure for sign-in requests
+type SignInPayload struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Tokens is returned by sign-in and refresh. Refresh leaves RefreshToken empty.
+type Tokens struct {
+	IdToken      string `json:"IdToken"`
+	AccessToken  string `json:"AccessToken"`
+	RefreshToken string `json:"RefreshToken,omitempty"`
+}
+
 func HandleSignIn(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
-	var payload struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
-	}
+	var payload SignInPayload
 	if err := json.Unmarshal([]byte(request.Body), &payload); err != nil {
-		return shared.ErrorResponse(400, "Invalid JSON: "+err.Error())
+		return shared.Error(shared.CodeInvalidJSON, "Invalid JSON: "+err.Error())
 	}
 	if payload.Username == "" || payload.Password == "" {
-		return shared.ErrorResponse(400, "username and password are required")
+		return shared.Error(shared.CodeMissingParameter, "username and password are required")
 	}
 
 	cip := newCognitoClient()
@@ -225,31 +231,24 @@ func HandleSignIn(request events.APIGatewayProxyRequest) events.APIGatewayProxyR
 
 	output, err := cip.InitiateAuth(input)
 	if err != nil {
-		return shared.ErrorResponse(401, fmt.Sprintf("SignIn error: %v", err))
+		return cognitoErrorResponse("SignIn", err)
 	}
 
 	if output.AuthenticationResult == nil {
-		return shared.ErrorResponse(401, "No authentication result returned.")
+		return shared.Error(shared.CodeAuthFailed, "No authentication result returned.")
 	}
 
 	// Build a JSON response with the tokens
-	tokens := map[string]string{
-		"IdToken":      aws.StringValue(output.AuthenticationResult.IdToken),
-		"AccessToken":  aws.StringValue(output.AuthenticationResult.AccessToken),
-		"RefreshToken": aws.StringValue(output.AuthenticationResult.RefreshToken),
+	tokens := Tokens{
+		IdToken:      aws.StringValue(output.AuthenticationResult.IdToken),
+		AccessToken:  aws.StringValue(output.AuthenticationResult.AccessToken),
+		RefreshToken: aws.StringValue(output.AuthenticationResult.RefreshToken),
 	}
 
-	body, _ := json.Marshal(tokens)
-
 	// Create response with refresh token cookie
-	response := events.APIGatewayProxyResponse{
-		StatusCode: 200,
-		Body:       string(body),
-		Headers: map[string]string{
-			"Set-Cookie": fmt.Sprintf("refreshToken=%s; Path=/; Max-Age=2592000; HttpOnly; Secure; SameSite=none",
-				aws.StringValue(output.AuthenticationResult.RefreshToken)),
-		},
-	}
+	response := shared.SuccessResponse(200, tokens)
+	response.Headers["Set-Cookie"] = fmt.Sprintf("refreshToken=%s; Path=/; Max-Age=2592000; HttpOnly; Secure; SameSite=none",
+		aws.StringValue(output.AuthenticationResult.RefreshToken))
 
 	return response
 }
@@ -261,13 +260,9 @@ func HandleSignIn(request events.APIGatewayProxyRequest) events.APIGatewayProxyR
 
 func HandleSignOut(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
 	// Return response that clears the refresh token cookie
-	return events.APIGatewayProxyResponse{
-		StatusCode: 200,
-		Headers: map[string]string{
-			"Set-Cookie": "refreshToken=; Path=/; Expires=Thu, 01 Jan 1970 00:00:01 GMT; HttpOnly; Secure; SameSite=none",
-		},
-		Body: "Signed out successfully",
-	}
+	response := shared.MessageResponse(200, "Signed out successfully")
+	response.Headers["Set-Cookie"] = "refreshToken=; Path=/; Expires=Thu, 01 Jan 1970 00:00:01 GMT; HttpOnly; Secure; SameSite=none"
+	return response
 }
 
 // ----------------- Refresh Token Logic -----------------
@@ -280,13 +275,13 @@ func HandleRefresh(request events.APIGatewayProxyRequest) events.APIGatewayProxy
 	// Get refresh token from Authorization header
 	authHeader := request.Headers["Authorization"]
 	if authHeader == "" {
-		return shared.ErrorResponse(401, "No Authorization header provided")
+		return shared.Error(shared.CodeUnauthorized, "No Authorization header provided")
 	}
 
 	// Extract token from "Bearer <token>"
 	parts := strings.Split(authHeader, " ")
 	if len(parts) != 2 || parts[0] != "Bearer" {
-		return shared.ErrorResponse(401, "Invalid Authorization header format")
+		return shared.Error(shared.CodeUnauthorized, "Invalid Authorization header format")
 	}
 	refreshToken := parts[1]
 
@@ -301,22 +296,49 @@ func HandleRefresh(request events.APIGatewayProxyRequest) events.APIGatewayProxy
 
 	output, err := cip.InitiateAuth(input)
 	if err != nil {
-		return shared.ErrorResponse(401, fmt.Sprintf("Refresh token error: %v", err))
+		return cognitoErrorResponse("Refresh token", err)
 	}
 
 	if output.AuthenticationResult == nil {
-		return shared.ErrorResponse(401, "No authentication result returned")
+		return shared.Error(shared.CodeAuthFailed, "No authentication result returned")
 	}
 
 	// Build a JSON response with the new tokens
-	tokens := map[string]string{
-		"IdToken":     aws.StringValue(output.AuthenticationResult.IdToken),
-		"AccessToken": aws.StringValue(output.AuthenticationResult.AccessToken),
+	tokens := Tokens{
+		IdToken:     aws.StringValue(output.AuthenticationResult.IdToken),
+		AccessToken: aws.StringValue(output.AuthenticationResult.AccessToken),
 	}
 
-	body, _ := json.Marshal(tokens)
-	return events.APIGatewayProxyResponse{
-		StatusCode: 200,
-		Body:       string(body),
+	return shared.SuccessResponse(200, tokens)
+}
+
+// cognitoErrorResponse maps a Cognito error onto an API error code so the
+// client can tell a taken username from a wrong password without parsing
+// the message.
+func cognitoErrorResponse(action string, err error) events.APIGatewayProxyResponse {
+	log.Printf("%s error: %v", action, err)
+
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		return shared.Error(shared.CodeExternalService, action+" failed")
+	}
+
+	switch aerr.Code() {
+	case cognitoidentityprovider.ErrCodeUsernameExistsException:
+		return shared.Error(shared.CodeUsernameTaken, aerr.Message())
+	case cognitoidentityprovider.ErrCodeInvalidPasswordException:
+		return shared.Error(shared.CodeInvalidPassword, aerr.Message())
+	case cognitoidentityprovider.ErrCodeCodeMismatchException,
+		cognitoidentityprovider.ErrCodeExpiredCodeException:
+		return shared.Error(shared.CodeInvalidConfirmationCode, aerr.Message())
+	case cognitoidentityprovider.ErrCodeUserNotConfirmedException:
+		return shared.Error(shared.CodeUserNotConfirmed, aerr.Message())
+	case cognitoidentityprovider.ErrCodeNotAuthorizedException,
+		cognitoidentityprovider.ErrCodeUserNotFoundException:
+		return shared.Error(shared.CodeAuthFailed, aerr.Message())
+	case cognitoidentityprovider.ErrCodeInvalidParameterException:
+		return shared.Error(shared.CodeInvalidParameter, aerr.Message())
+	default:
+		return shared.Error(shared.CodeExternalService, action+" failed")
 	}
 }
@@ -0,0 +1,119 @@
+// Package cache stores byte values under string keys for a limited time. An
+// in-process LRU keeps entries for the life of a warm Lambda, a DynamoDB
+// table shares them between Lambdas, and Tiered puts the two together.
+package cache
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Cache is one tier of cached values. Implementations are safe for
+// concurrent use.
+type Cache interface {
+	// Name identifies the tier in logs and stats, e.g. "memory".
+	Name() string
+
+	// Get returns the value stored under key, with ok false if there is
+	// none or it has expired.
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+
+	// Set stores value under key for ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// TierStats counts the lookups answered and missed by one tier.
+type TierStats struct {
+	Name   string `json:"name"`
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+	Errors uint64 `json:"errors"`
+}
+
+// Tiered looks a key up in each tier in turn, fastest first, and copies a
+// value found in a slower tier into the faster ones. Errors from a tier are
+// logged and treated as misses: a cache that is down only makes requests
+// slower.
+type Tiered struct {
+	tiers []Cache
+	stats []tierCounters
+}
+
+type tierCounters struct {
+	hits, misses, errors atomic.Uint64
+}
+
+// NewTiered returns a cache over tiers, fastest first.
+func NewTiered(tiers ...Cache) *Tiered {
+	return &Tiered{tiers: tiers, stats: make([]tierCounters, len(tiers))}
+}
+
+// Name lists the tiers, fastest first.
+func (t *Tiered) Name() string {
+	names := make([]string, len(t.tiers))
+	for i, tier := range t.tiers {
+		names[i] = tier.Name()
+	}
+	return strings.Join(names, "+")
+}
+
+// Get returns the value from the fastest tier that has it.
+func (t *Tiered) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	for i, tier := range t.tiers {
+		value, ok, err := tier.Get(ctx, key)
+		switch {
+		case err != nil:
+			t.stats[i].errors.Add(1)
+			log.Printf("Cache %s: get %s: %v", tier.Name(), key, err)
+		case ok:
+			t.stats[i].hits.Add(1)
+			t.backfill(ctx, i, key, value)
+			return value, true, nil
+		default:
+			t.stats[i].misses.Add(1)
+		}
+	}
+	return nil, false, nil
+}
+
+// backfill copies a value found in tier found into the faster tiers. The
+// slower tier does not say how long the value has left, so the copies are
+// only kept for backfillTTL.
+func (t *Tiered) backfill(ctx context.Context, found int, key string, value []byte) {
+	for i := 0; i < found; i++ {
+		if err := t.tiers[i].Set(ctx, key, value, backfillTTL); err != nil {
+			log.Printf("Cache %s: backfill %s: %v", t.tiers[i].Name(), key, err)
+		}
+	}
+}
+
+// backfillTTL is how long a value copied into a faster tier is kept there.
+const backfillTTL = 5 * time.Minute
+
+// Set stores value in every tier.
+func (t *Tiered) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	for i, tier := range t.tiers {
+		if err := tier.Set(ctx, key, value, ttl); err != nil {
+			t.stats[i].errors.Add(1)
+			log.Printf("Cache %s: set %s: %v", tier.Name(), key, err)
+		}
+	}
+	return nil
+}
+
+// Stats returns the hit and miss counts of each tier since startup.
+func (t *Tiered) Stats() []TierStats {
+	stats := make([]TierStats, len(t.tiers))
+	for i, tier := range t.tiers {
+		stats[i] = TierStats{
+			Name:   tier.Name(),
+			Hits:   t.stats[i].hits.Load(),
+			Misses: t.stats[i].misses.Load(),
+			Errors: t.stats[i].errors.Load(),
+		}
+	}
+	return stats
+}
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// brokenCache fails every call.
+type brokenCache struct{}
+
+func (brokenCache) Name() string { return "broken" }
+
+func (brokenCache) Get(context.Context, string) ([]byte, bool, error) {
+	return nil, false, errors.New("unavailable")
+}
+
+func (brokenCache) Set(context.Context, string, []byte, time.Duration) error {
+	return errors.New("unavailable")
+}
+
+func TestTiered(t *testing.T) {
+	ctx := context.Background()
+	fast, slow := NewLRU(10), NewLRU(10)
+	tiered := NewTiered(fast, slow)
+
+	if _, ok, err := tiered.Get(ctx, "a"); ok || err != nil {
+		t.Fatalf("Get on empty cache = %v, %v", ok, err)
+	}
+
+	slow.Set(ctx, "a", []byte("1"), time.Hour)
+	if value, ok, _ := tiered.Get(ctx, "a"); !ok || string(value) != "1" {
+		t.Fatalf("Get from slow tier = %q, %v", value, ok)
+	}
+	if value, ok, _ := fast.Get(ctx, "a"); !ok || string(value) != "1" {
+		t.Errorf("value from slow tier was not backfilled into the fast one")
+	}
+	if _, ok, _ := tiered.Get(ctx, "a"); !ok {
+		t.Fatal("backfilled value missing")
+	}
+
+	tiered.Set(ctx, "b", []byte("2"), time.Hour)
+	for _, tier := range []*LRU{fast, slow} {
+		if value, ok, _ := tier.Get(ctx, "b"); !ok || string(value) != "2" {
+			t.Errorf("Set did not reach every tier")
+		}
+	}
+
+	want := []TierStats{
+		{Name: "memory", Hits: 1, Misses: 2},
+		{Name: "memory", Hits: 1, Misses: 1},
+	}
+	if got := tiered.Stats(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestTieredTreatsErrorsAsMisses(t *testing.T) {
+	ctx := context.Background()
+	memory := NewLRU(10)
+	tiered := NewTiered(memory, brokenCache{})
+
+	if _, ok, err := tiered.Get(ctx, "a"); ok || err != nil {
+		t.Errorf("Get with broken tier = %v, %v; want a miss", ok, err)
+	}
+	if err := tiered.Set(ctx, "a", []byte("1"), time.Hour); err != nil {
+		t.Errorf("Set with broken tier: %v", err)
+	}
+	if _, ok, _ := tiered.Get(ctx, "a"); !ok {
+		t.Error("working tier did not keep the value")
+	}
+
+	want := []TierStats{
+		{Name: "memory", Hits: 1, Misses: 1},
+		{Name: "broken", Errors: 2},
+	}
+	if got := tiered.Stats(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	lru := NewLRU(2)
+	lru.now = func() time.Time { return now }
+
+	lru.Set(ctx, "a", []byte("1"), time.Minute)
+	lru.Set(ctx, "b", []byte("2"), time.Hour)
+	lru.Get(ctx, "a")
+	lru.Set(ctx, "c", []byte("3"), time.Hour)
+
+	if _, ok, _ := lru.Get(ctx, "b"); ok {
+		t.Error("least recently used entry was not evicted")
+	}
+	if _, ok, _ := lru.Get(ctx, "a"); !ok {
+		t.Error("recently used entry was evicted")
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok, _ := lru.Get(ctx, "a"); ok {
+		t.Error("expired entry was returned")
+	}
+	if lru.Len() != 1 {
+		t.Errorf("Len() = %d after expiry, want 1", lru.Len())
+	}
+}
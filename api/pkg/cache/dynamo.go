@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// Dynamo is a cache tier kept in a DynamoDB table whose partition key is
+// cacheKey. Entries carry their expiry in expiresAt (epoch seconds), which
+// should be the table's TTL attribute so DynamoDB deletes them; until it
+// does, Get ignores expired entries itself.
+type Dynamo struct {
+	svc   dynamodbiface.DynamoDBAPI
+	table string
+	now   func() time.Time
+}
+
+// NewDynamo returns a cache tier that reads and writes table.
+func NewDynamo(svc dynamodbiface.DynamoDBAPI, table string) *Dynamo {
+	return &Dynamo{svc: svc, table: table, now: time.Now}
+}
+
+// Name returns "dynamo".
+func (c *Dynamo) Name() string { return "dynamo" }
+
+func (c *Dynamo) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	result, err := c.svc.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(c.table),
+		Key: map[string]*dynamodb.AttributeValue{
+			"cacheKey": {S: aws.String(key)},
+		},
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if result.Item == nil || result.Item["value"] == nil || result.Item["expiresAt"] == nil {
+		return nil, false, nil
+	}
+
+	expiresAt, err := strconv.ParseInt(aws.StringValue(result.Item["expiresAt"].N), 10, 64)
+	if err != nil || c.now().Unix() >= expiresAt {
+		return nil, false, nil
+	}
+	return result.Item["value"].B, true, nil
+}
+
+func (c *Dynamo) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	expiresAt := c.now().Add(ttl).Unix()
+	_, err := c.svc.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(c.table),
+		Item: map[string]*dynamodb.AttributeValue{
+			"cacheKey":  {S: aws.String(key)},
+			"value":     {B: value},
+			"expiresAt": {N: aws.String(strconv.FormatInt(expiresAt, 10))},
+		},
+	})
+	return err
+}
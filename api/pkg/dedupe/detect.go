@@ -0,0 +1,310 @@
+// Package dedupe finds books stored more than once in the Books table and
+// merges them into one. CreateBook and SaveExternalBook each mint a new book
+// ID, so the same work can end up under several IDs, each referenced from
+// some users' lists, currently reading and reading log.
+//
+// Find groups the duplicates and proposes which book to keep; Merger folds
+// the others into it and rewrites every reference to them.
+package dedupe
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/FriedGlue/BookIt/api/pkg/isbn"
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/FriedGlue/BookIt/api/pkg/search"
+)
+
+// Reason says why books were grouped as duplicates.
+type Reason string
+
+const (
+	// ReasonISBN groups books with the same ISBN, in either form.
+	ReasonISBN Reason = "isbn"
+
+	// ReasonOpenLibraryID groups books without an ISBN saved from the same
+	// Open Library work. Books with ISBNs are editions of the work and are
+	// kept apart.
+	ReasonOpenLibraryID Reason = "openLibraryId"
+
+	// ReasonTitleAuthor groups books whose titles are the same or nearly so
+	// and which share an author, unless both have ISBNs. It is a guess, so
+	// these groups deserve a look before merging.
+	ReasonTitleAuthor Reason = "titleAuthor"
+)
+
+// Two normalized titles by the same author count as the same if one can be
+// turned into the other with one edit, or one per editsPerRune runes of the
+// longer. Titles shorter than minFuzzyTitle runes must match exactly, which
+// allows a typo in "Hobbit" but keeps "Emma" and "Emmy" apart.
+const (
+	editsPerRune  = 10
+	minFuzzyTitle = 5
+)
+
+// Group is a set of books that appear to be the same work.
+type Group struct {
+	// CanonicalBookID is the proposed book to keep: the one with the most
+	// complete metadata.
+	CanonicalBookID string `json:"canonicalBookId"`
+
+	// DuplicateBookIDs are the other books of the group.
+	DuplicateBookIDs []string `json:"duplicateBookIds"`
+
+	// Reasons lists every reason that linked books of the group.
+	Reasons []Reason `json:"reasons"`
+
+	// Books are the books of the group, the canonical one first.
+	Books []models.BookData `json:"books"`
+}
+
+// Exact reports whether the group was formed by identifiers alone, without
+// the title and author guess.
+func (g Group) Exact() bool {
+	for _, reason := range g.Reasons {
+		if reason == ReasonTitleAuthor {
+			return false
+		}
+	}
+	return true
+}
+
+// Find returns the groups of duplicates among books, largest first. Books
+// are linked when they share an ISBN or an Open Library ID, or have nearly
+// the same title and share an author; links are transitive. Books with
+// different ISBNs are editions, so only an ISBN links two books that both
+// have one.
+func Find(books []models.BookData) []Group {
+	sets := newDisjointSets(len(books))
+	reasons := make(map[int]map[Reason]bool) // keyed by the link's first book
+
+	link := func(a, b int, reason Reason) {
+		if reasons[a] == nil {
+			reasons[a] = make(map[Reason]bool)
+		}
+		reasons[a][reason] = true
+		sets.union(a, b)
+	}
+
+	linkByKey := func(reason Reason, key func(models.BookData) string) {
+		first := make(map[string]int)
+		for i, book := range books {
+			k := key(book)
+			if k == "" {
+				continue
+			}
+			if j, ok := first[k]; ok {
+				link(j, i, reason)
+			} else {
+				first[k] = i
+			}
+		}
+	}
+	linkByKey(ReasonISBN, isbnKey)
+	linkByKey(ReasonOpenLibraryID, func(b models.BookData) string {
+		if isbnKey(b) != "" {
+			return ""
+		}
+		return strings.TrimSpace(b.OpenLibraryId)
+	})
+
+	// Only books sharing an author surname are compared by title, which
+	// keeps the pairwise comparison small.
+	byAuthor := make(map[string][]int)
+	titles := make([]string, len(books))
+	hasISBN := make([]bool, len(books))
+	for i, book := range books {
+		hasISBN[i] = isbnKey(book) != ""
+		titles[i] = titleKey(book.Title)
+		if titles[i] == "" {
+			continue
+		}
+		for _, surname := range surnames(book.Authors) {
+			byAuthor[surname] = append(byAuthor[surname], i)
+		}
+	}
+	for _, candidates := range byAuthor {
+		for x := 0; x < len(candidates); x++ {
+			for y := x + 1; y < len(candidates); y++ {
+				a, b := candidates[x], candidates[y]
+				if hasISBN[a] && hasISBN[b] {
+					continue
+				}
+				if sets.find(a) != sets.find(b) && sameTitle(titles[a], titles[b]) {
+					link(a, b, ReasonTitleAuthor)
+				}
+			}
+		}
+	}
+
+	members := make(map[int][]int)
+	for i := range books {
+		root := sets.find(i)
+		members[root] = append(members[root], i)
+	}
+
+	var groups []Group
+	for _, indexes := range members {
+		if len(indexes) < 2 {
+			continue
+		}
+		group := Group{}
+		found := make(map[Reason]bool)
+		for _, i := range indexes {
+			group.Books = append(group.Books, books[i])
+			for reason := range reasons[i] {
+				found[reason] = true
+			}
+		}
+		for _, reason := range []Reason{ReasonISBN, ReasonOpenLibraryID, ReasonTitleAuthor} {
+			if found[reason] {
+				group.Reasons = append(group.Reasons, reason)
+			}
+		}
+
+		sort.SliceStable(group.Books, func(i, j int) bool {
+			a, b := group.Books[i], group.Books[j]
+			if completeness(a) != completeness(b) {
+				return completeness(a) > completeness(b)
+			}
+			return a.BookID < b.BookID
+		})
+		group.CanonicalBookID = group.Books[0].BookID
+		for _, book := range group.Books[1:] {
+			group.DuplicateBookIDs = append(group.DuplicateBookIDs, book.BookID)
+		}
+		groups = append(groups, group)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if len(groups[i].Books) != len(groups[j].Books) {
+			return len(groups[i].Books) > len(groups[j].Books)
+		}
+		return groups[i].CanonicalBookID < groups[j].CanonicalBookID
+	})
+	return groups
+}
+
+// isbnKey returns the book's ISBN-13, or its ISBN as stored if it does not
+// validate.
+func isbnKey(book models.BookData) string {
+	for _, number := range []string{book.ISBN13, book.ISBN10} {
+		if isbn13, err := isbn.Parse(number); err == nil {
+			return isbn13
+		}
+	}
+	if number := isbn.Normalize(book.ISBN13); number != "" {
+		return number
+	}
+	return isbn.Normalize(book.ISBN10)
+}
+
+// titleKey normalizes a title for comparison: the subtitle and a leading
+// article are dropped, and case, accents and punctuation are ignored.
+func titleKey(title string) string {
+	if main, _, found := strings.Cut(title, ":"); found && strings.TrimSpace(main) != "" {
+		title = main
+	}
+	words := search.Tokenize(title)
+	if len(words) > 1 && (words[0] == "the" || words[0] == "a" || words[0] == "an") {
+		words = words[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// surnames returns the last word of each author's name, normalized.
+func surnames(authors []string) []string {
+	var result []string
+	for _, author := range authors {
+		if words := search.Tokenize(author); len(words) > 0 {
+			result = append(result, words[len(words)-1])
+		}
+	}
+	return result
+}
+
+// completeness scores how much of a book's metadata is filled in.
+func completeness(book models.BookData) int {
+	score := 0
+	for _, present := range []bool{
+		book.ISBN13 != "", book.ISBN10 != "", book.OpenLibraryId != "",
+		book.Description != "", book.CoverImageURL != "", book.PageCount > 0,
+		len(book.Authors) > 0, len(book.Tags) > 0,
+	} {
+		if present {
+			score++
+		}
+	}
+	return score
+}
+
+// sameTitle reports whether two normalized titles are the same but for a
+// few typos.
+func sameTitle(a, b string) bool {
+	if a == b {
+		return true
+	}
+	ra, rb := []rune(a), []rune(b)
+	if min(len(ra), len(rb)) < minFuzzyTitle {
+		return false
+	}
+	allowed := max(1, max(len(ra), len(rb))/editsPerRune)
+	if abs(len(ra)-len(rb)) > allowed {
+		return false
+	}
+	return editDistance(ra, rb) <= allowed
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// disjointSets is a union-find over the integers 0..n-1.
+type disjointSets struct {
+	parent []int
+}
+
+func newDisjointSets(n int) *disjointSets {
+	parent := make([]int, n)
+	for i := range parent {
+		parent[i] = i
+	}
+	return &disjointSets{parent: parent}
+}
+
+func (s *disjointSets) find(i int) int {
+	for s.parent[i] != i {
+		s.parent[i] = s.parent[s.parent[i]]
+		i = s.parent[i]
+	}
+	return i
+}
+
+func (s *disjointSets) union(a, b int) {
+	s.parent[s.find(b)] = s.find(a)
+}
@@ -0,0 +1,433 @@
+package dedupe
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/FriedGlue/BookIt/api/pkg/store"
+)
+
+// ErrInvalidMerge is returned by Merge when asked to merge no books, or a
+// book into itself.
+var ErrInvalidMerge = errors.New("invalid merge")
+
+// Merger merges duplicate books into a canonical one. Books should be an
+// IndexedBookStore, so merged books leave the search index too.
+type Merger struct {
+	Books      store.BookStore
+	Profiles   store.ProfileStore
+	ReadingLog store.ReadingLogStore
+	Series     store.SeriesStore
+}
+
+// Result reports what a merge changed.
+type Result struct {
+	CanonicalBookID string `json:"canonicalBookId"`
+
+	// MergedBookIDs are the duplicates that were deleted.
+	MergedBookIDs []string `json:"mergedBookIds"`
+
+	// MissingBookIDs are duplicates that no longer existed, typically
+	// because an earlier run merged them. References to them are still
+	// rewritten.
+	MissingBookIDs []string `json:"missingBookIds,omitempty"`
+
+	ProfilesUpdated          int `json:"profilesUpdated"`
+	ReadingLogEntriesUpdated int `json:"readingLogEntriesUpdated"`
+	SeriesEntriesUpdated     int `json:"seriesEntriesUpdated"`
+}
+
+// Merge folds the duplicates into the canonical book: metadata the
+// canonical book lacks is copied from them, every list, currently reading,
+// reading log and series reference to them is pointed at it, and they are
+// deleted.
+//
+// The duplicates are deleted last, so a merge that fails part way leaves
+// them in place and can simply be run again.
+func (m *Merger) Merge(canonicalID string, duplicateIDs []string) (*Result, error) {
+	duplicates := make(map[string]bool, len(duplicateIDs))
+	for _, id := range duplicateIDs {
+		if id == canonicalID {
+			return nil, fmt.Errorf("%w: book %s cannot be merged into itself", ErrInvalidMerge, id)
+		}
+		duplicates[id] = true
+	}
+	if len(duplicates) == 0 {
+		return nil, fmt.Errorf("%w: no duplicates given", ErrInvalidMerge)
+	}
+
+	canonical, err := m.Books.Get(canonicalID)
+	if err != nil {
+		return nil, fmt.Errorf("loading canonical book %s: %w", canonicalID, err)
+	}
+
+	result := &Result{CanonicalBookID: canonicalID, MergedBookIDs: []string{}}
+	var found []models.BookData
+	for _, id := range duplicateIDs {
+		book, err := m.Books.Get(id)
+		if errors.Is(err, store.ErrNotFound) {
+			result.MissingBookIDs = append(result.MissingBookIDs, id)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("loading duplicate book %s: %w", id, err)
+		}
+		found = append(found, *book)
+	}
+
+	if len(found) > 0 {
+		canonical, err = m.Books.Update(canonicalID, func(book *models.BookData) error {
+			for _, duplicate := range found {
+				fillMissing(book, duplicate)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("updating canonical book %s: %w", canonicalID, err)
+		}
+	}
+
+	userIDs, err := m.Profiles.ListIDs()
+	if err != nil {
+		return nil, fmt.Errorf("listing profiles: %w", err)
+	}
+	for _, userID := range userIDs {
+		updated, err := m.rewriteProfile(userID, canonical, duplicates)
+		if err != nil {
+			return nil, fmt.Errorf("rewriting profile %s: %w", userID, err)
+		}
+		if updated {
+			result.ProfilesUpdated++
+		}
+
+		entries, err := m.rewriteReadingLog(userID, canonical, duplicates)
+		if err != nil {
+			return nil, fmt.Errorf("rewriting reading log of %s: %w", userID, err)
+		}
+		result.ReadingLogEntriesUpdated += entries
+	}
+
+	entries, err := m.rewriteSeries(canonical, duplicates, found)
+	if err != nil {
+		return nil, fmt.Errorf("rewriting series: %w", err)
+	}
+	result.SeriesEntriesUpdated = entries
+
+	for _, book := range found {
+		if err := m.Books.Delete(book.BookID); err != nil {
+			return nil, fmt.Errorf("deleting duplicate book %s: %w", book.BookID, err)
+		}
+		result.MergedBookIDs = append(result.MergedBookIDs, book.BookID)
+	}
+
+	log.Printf("Merged books %v into %s: %d profiles, %d reading log entries and %d series entries updated\n",
+		result.MergedBookIDs, canonicalID, result.ProfilesUpdated, result.ReadingLogEntriesUpdated, result.SeriesEntriesUpdated)
+	return result, nil
+}
+
+// rewriteProfile points the profile's references to duplicates at the
+// canonical book. It reports whether the profile had any.
+func (m *Merger) rewriteProfile(userID string, canonical *models.BookData, duplicates map[string]bool) (bool, error) {
+	profile, err := m.Profiles.Get(userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return false, nil // deleted since it was listed
+	}
+	if err != nil {
+		return false, err
+	}
+	profile.Lists.Normalize() // moves custom lists not yet on shelves
+	if !references(profile, duplicates) {
+		return false, nil
+	}
+
+	_, err = m.Profiles.Update(userID, func(p *models.Profile) error {
+		// Dropped entries would leave gaps in the lists' Orders
+		p.Lists.Normalize()
+		rewriteProfile(p, canonical, duplicates)
+		p.Lists.Renumber()
+		return nil
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// rewriteReadingLog points the user's reading log entries for duplicates at
+// the canonical book and returns how many it changed.
+func (m *Merger) rewriteReadingLog(userID string, canonical *models.BookData, duplicates map[string]bool) (int, error) {
+	entries, err := m.ReadingLog.Query(userID, time.Time{}, time.Time{})
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for _, entry := range entries {
+		if !duplicates[entry.BookID] {
+			continue
+		}
+		_, err := m.ReadingLog.Update(userID, entry.Id, func(item *models.ReadingLogItem) error {
+			refreshReadingLogItem(item, canonical)
+			return nil
+		})
+		if errors.Is(err, store.ErrNotFound) {
+			continue // deleted meanwhile
+		}
+		if err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+// rewriteSeries points the series entries for duplicates at the canonical
+// book and returns how many it changed. Entries name a work and a saved
+// edition of it; a duplicate's work is replaced only when it was the
+// duplicate's own book ID, which stands in for the work of books without
+// one. A series left with two entries for the canonical work keeps the
+// first in reading order.
+func (m *Merger) rewriteSeries(canonical *models.BookData, duplicates map[string]bool, found []models.BookData) (int, error) {
+	// Duplicates already deleted by an earlier run are only known by ID
+	workIDs := make([]string, 0, len(duplicates)+len(found))
+	for id := range duplicates {
+		workIDs = append(workIDs, id)
+	}
+	for _, book := range found {
+		workIDs = append(workIDs, book.WorkKey())
+	}
+	affected, err := m.Series.QueryByWorkIDs(workIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	canonicalWork := canonical.WorkKey()
+	updated := 0
+	for _, series := range affected {
+		changed := 0
+		entries := make([]models.SeriesEntry, 0, len(series.Entries))
+		seen := make(map[string]bool, len(series.Entries))
+		for _, entry := range series.Entries {
+			if duplicates[entry.BookID] || duplicates[entry.WorkID] {
+				entry.BookID = canonical.BookID
+				if entry.WorkID == "" || duplicates[entry.WorkID] {
+					entry.WorkID = canonicalWork
+				}
+				changed++
+			}
+			if entry.WorkID != "" && seen[entry.WorkID] {
+				continue
+			}
+			seen[entry.WorkID] = true
+			entries = append(entries, entry)
+		}
+		if changed == 0 {
+			continue
+		}
+		series.Entries = entries
+		if err := m.Series.Put(&series); err != nil {
+			return updated, fmt.Errorf("saving series %s: %w", series.SeriesID, err)
+		}
+		updated += changed
+	}
+	return updated, nil
+}
+
+// references reports whether the profile mentions any of the books.
+func references(profile *models.Profile, bookIDs map[string]bool) bool {
+	for _, item := range profile.CurrentlyReading {
+		if bookIDs[item.Book.BookID] {
+			return true
+		}
+	}
+	for _, item := range profile.Lists.ToBeRead {
+		if bookIDs[item.BookID] {
+			return true
+		}
+	}
+	for _, item := range profile.Lists.Read {
+		if bookIDs[item.BookID] {
+			return true
+		}
+	}
+	for _, shelf := range profile.Lists.Shelves {
+		for _, item := range shelf.Items {
+			if bookIDs[item.BookID] {
+				return true
+			}
+		}
+	}
+	for _, item := range profile.ReadingLog {
+		if bookIDs[item.BookID] {
+			return true
+		}
+	}
+	return false
+}
+
+// rewriteProfile replaces references to duplicates with the canonical book,
+// along with the title, authors and cover copied into each entry. A list that
+// ends up holding the canonical book twice keeps its first entry, completed
+// with the rating and review of the other for the read list; currently
+// reading keeps the entry furthest along.
+func rewriteProfile(profile *models.Profile, canonical *models.BookData, duplicates map[string]bool) {
+	id := canonical.BookID
+
+	reading := make([]models.CurrentlyReadingItem, 0, len(profile.CurrentlyReading))
+	kept := -1
+	for _, item := range profile.CurrentlyReading {
+		if duplicates[item.Book.BookID] {
+			refresh(entryFields{&item.Book.BookID, &item.Book.Title, &item.Book.Authors, &item.Book.Thumbnail}, canonical)
+			if canonical.ISBN13 != "" {
+				item.Book.ISBN = canonical.ISBN13
+			}
+		}
+		if item.Book.BookID != id {
+			reading = append(reading, item)
+			continue
+		}
+		if kept < 0 {
+			kept = len(reading)
+			reading = append(reading, item)
+		} else if item.Book.Progress.LastPageRead > reading[kept].Book.Progress.LastPageRead {
+			reading[kept] = item
+		}
+	}
+	profile.CurrentlyReading = reading
+
+	profile.Lists.ToBeRead = mergeEntries(profile.Lists.ToBeRead, duplicates, canonical,
+		func(item *models.ToBeReadItem) entryFields {
+			return entryFields{&item.BookID, &item.Title, &item.Authors, &item.Thumbnail}
+		}, nil)
+	profile.Lists.Read = mergeEntries(profile.Lists.Read, duplicates, canonical,
+		func(item *models.ReadItem) entryFields {
+			return entryFields{&item.BookID, &item.Title, &item.Authors, &item.Thumbnail}
+		},
+		func(kept *models.ReadItem, dropped models.ReadItem) {
+			if kept.Rating == 0 {
+				kept.Rating = dropped.Rating
+			}
+			if kept.Review == "" {
+				kept.Review = dropped.Review
+			}
+			if kept.CompletedDate == "" {
+				kept.CompletedDate = dropped.CompletedDate
+			}
+		})
+	for i := range profile.Lists.Shelves {
+		profile.Lists.Shelves[i].Items = mergeEntries(profile.Lists.Shelves[i].Items, duplicates, canonical,
+			func(item *models.CustomListItem) entryFields {
+				return entryFields{&item.BookID, &item.Title, &item.Authors, &item.Thumbnail}
+			}, nil)
+	}
+
+	for i := range profile.ReadingLog {
+		if duplicates[profile.ReadingLog[i].BookID] {
+			refreshReadingLogItem(&profile.ReadingLog[i], canonical)
+		}
+	}
+}
+
+// entryFields points at the fields a list entry copies from its book.
+type entryFields struct {
+	bookID    *string
+	title     *string
+	authors   *[]string
+	thumbnail *string
+}
+
+// refresh points an entry at book.
+func refresh(entry entryFields, book *models.BookData) {
+	*entry.bookID = book.BookID
+	if book.Title != "" {
+		*entry.title = book.Title
+	}
+	if len(book.Authors) > 0 {
+		*entry.authors = book.Authors
+	}
+	if book.CoverImageURL != "" {
+		*entry.thumbnail = book.CoverImageURL
+	}
+}
+
+// refreshReadingLogItem points a reading log entry at book.
+func refreshReadingLogItem(item *models.ReadingLogItem, book *models.BookData) {
+	item.BookID = book.BookID
+	if book.Title != "" {
+		item.Title = book.Title
+	}
+	if book.CoverImageURL != "" {
+		item.BookThumbnail = book.CoverImageURL
+	}
+}
+
+// mergeEntries points a list's entries for duplicates at canonical and drops
+// all but the first entry for it, passing the dropped ones to combine if it
+// is not nil.
+func mergeEntries[T any](items []T, duplicates map[string]bool, canonical *models.BookData, fields func(*T) entryFields, combine func(kept *T, dropped T)) []T {
+	if items == nil {
+		return nil
+	}
+	result := make([]T, 0, len(items))
+	kept := -1
+	for _, item := range items {
+		entry := fields(&item)
+		if duplicates[*entry.bookID] {
+			refresh(entry, canonical)
+		}
+		if *entry.bookID != canonical.BookID {
+			result = append(result, item)
+			continue
+		}
+		if kept < 0 {
+			kept = len(result)
+			result = append(result, item)
+		} else if combine != nil {
+			combine(&result[kept], item)
+		}
+	}
+	return result
+}
+
+// fillMissing copies into book the metadata it lacks from duplicate.
+func fillMissing(book *models.BookData, duplicate models.BookData) {
+	fill := func(field *string, value string) {
+		if *field == "" {
+			*field = value
+		}
+	}
+	fill(&book.ISBN10, duplicate.ISBN10)
+	fill(&book.ISBN13, duplicate.ISBN13)
+	fill(&book.Title, duplicate.Title)
+	fill(&book.TitleLowercase, duplicate.TitleLowercase)
+	fill(&book.CoverImageURL, duplicate.CoverImageURL)
+	fill(&book.OpenLibraryId, duplicate.OpenLibraryId)
+	fill(&book.Description, duplicate.Description)
+	fill(&book.WorkID, duplicate.WorkID)
+	fill(&book.Publisher, duplicate.Publisher)
+	fill(&book.PublishDate, duplicate.PublishDate)
+	fill(&book.Format, duplicate.Format)
+	if len(book.Authors) == 0 {
+		book.Authors = duplicate.Authors
+	}
+	if len(book.AuthorIDs) == 0 {
+		book.AuthorIDs = duplicate.AuthorIDs
+	}
+	if book.PageCount == 0 {
+		book.PageCount = duplicate.PageCount
+	}
+
+	seen := make(map[string]bool, len(book.Tags))
+	for _, tag := range book.Tags {
+		seen[tag] = true
+	}
+	for _, tag := range duplicate.Tags {
+		if !seen[tag] {
+			seen[tag] = true
+			book.Tags = append(book.Tags, tag)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package dedupe
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/FriedGlue/BookIt/api/pkg/store"
+)
+
+// newMerger returns a Merger over memory stores holding the canonical book
+// "c" and its duplicate "d". "gone" is a duplicate an earlier run deleted.
+func newMerger(t *testing.T) *Merger {
+	t.Helper()
+	m := &Merger{
+		Books:      store.NewMemoryBookStore(),
+		Profiles:   store.NewMemoryProfileStore(),
+		ReadingLog: store.NewMemoryReadingLogStore(),
+		Series:     store.NewMemorySeriesStore(),
+	}
+	books := []models.BookData{
+		{BookID: "c", WorkID: "w1", Title: "Dune", Authors: []string{"Frank Herbert"}, ISBN13: "9780441013593", Tags: []string{"classic"}},
+		{BookID: "d", WorkID: "w1", Title: "Dune (Paperback)", CoverImageURL: "https://covers/d.jpg", PageCount: 412, Tags: []string{"scifi", "classic"}},
+		{BookID: "x", WorkID: "w2", Title: "Other"},
+	}
+	for i := range books {
+		if err := m.Books.Put(&books[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+func TestMergeProfiles(t *testing.T) {
+	tests := []struct {
+		name        string
+		lists       models.UserLists
+		reading     []models.CurrentlyReadingItem
+		wantUpdated int
+		wantTBR     []string
+		wantRead    []models.ReadItem
+		wantShelf   []string
+		wantReading []models.CurrentlyReadingItem
+	}{
+		{
+			name:        "to be read",
+			lists:       models.UserLists{ToBeRead: []models.ToBeReadItem{{BookID: "x", Order: 0}, {BookID: "d", Order: 1}}},
+			wantUpdated: 1,
+			wantTBR:     []string{"x", "c"},
+		},
+		{
+			name:        "already deleted duplicate",
+			lists:       models.UserLists{ToBeRead: []models.ToBeReadItem{{BookID: "gone"}}},
+			wantUpdated: 1,
+			wantTBR:     []string{"c"},
+		},
+		{
+			name: "read twice keeps the first entry",
+			lists: models.UserLists{Read: []models.ReadItem{
+				{BookID: "c", Order: 0, CompletedDate: "2025-01-01T00:00:00Z"},
+				{BookID: "x", Order: 1},
+				{BookID: "d", Order: 2, Rating: 5, Review: "great"},
+			}},
+			wantUpdated: 1,
+			wantRead: []models.ReadItem{
+				{BookID: "c", Order: 0, CompletedDate: "2025-01-01T00:00:00Z", Rating: 5, Review: "great"},
+				{BookID: "x", Order: 1},
+			},
+		},
+		{
+			name: "shelf",
+			lists: models.UserLists{Shelves: []models.Shelf{{ShelfID: "s1", Name: "Holiday", Items: []models.CustomListItem{
+				{BookID: "d", Order: 0}, {BookID: "x", Order: 1}, {BookID: "c", Order: 2},
+			}}}},
+			wantUpdated: 1,
+			wantShelf:   []string{"c", "x"},
+		},
+		{
+			name: "currently reading keeps the furthest along",
+			reading: []models.CurrentlyReadingItem{
+				{Book: models.Book{BookID: "c", Progress: models.ReadingProgress{LastPageRead: 10}}},
+				{Book: models.Book{BookID: "d", Progress: models.ReadingProgress{LastPageRead: 50}}},
+			},
+			wantUpdated: 1,
+			wantReading: []models.CurrentlyReadingItem{
+				{Book: models.Book{BookID: "c", ISBN: "9780441013593", Title: "Dune", Authors: []string{"Frank Herbert"},
+					Thumbnail: "https://covers/d.jpg", Progress: models.ReadingProgress{LastPageRead: 50}}},
+			},
+		},
+		{
+			name:    "no references",
+			lists:   models.UserLists{ToBeRead: []models.ToBeReadItem{{BookID: "x"}}},
+			wantTBR: []string{"x"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMerger(t)
+			profile := &models.Profile{ID: "user", Lists: tt.lists, CurrentlyReading: tt.reading}
+			if err := m.Profiles.Put(profile); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := m.Merge("c", []string{"d", "gone"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.ProfilesUpdated != tt.wantUpdated {
+				t.Errorf("ProfilesUpdated = %d, want %d", result.ProfilesUpdated, tt.wantUpdated)
+			}
+
+			got, err := m.Profiles.Get("user")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var tbr []string
+			for i, item := range got.Lists.ToBeRead {
+				tbr = append(tbr, item.BookID)
+				if item.Order != i {
+					t.Errorf("to be read %s has order %d at index %d", item.BookID, item.Order, i)
+				}
+			}
+			if !slices.Equal(tbr, tt.wantTBR) {
+				t.Errorf("to be read = %v, want %v", tbr, tt.wantTBR)
+			}
+			if len(got.Lists.Read) != len(tt.wantRead) {
+				t.Fatalf("read = %+v, want %+v", got.Lists.Read, tt.wantRead)
+			}
+			for i, want := range tt.wantRead {
+				item := got.Lists.Read[i]
+				if item.BookID != want.BookID || item.Order != want.Order || item.Rating != want.Rating ||
+					item.Review != want.Review || item.CompletedDate != want.CompletedDate {
+					t.Errorf("read[%d] = %+v, want %+v", i, item, want)
+				}
+			}
+			if tt.wantShelf != nil {
+				var ids []string
+				for _, item := range got.Lists.Shelf("s1").Items {
+					ids = append(ids, item.BookID)
+				}
+				if !slices.Equal(ids, tt.wantShelf) {
+					t.Errorf("shelf = %v, want %v", ids, tt.wantShelf)
+				}
+			}
+			if len(got.CurrentlyReading) != len(tt.wantReading) {
+				t.Fatalf("currently reading = %+v, want %+v", got.CurrentlyReading, tt.wantReading)
+			}
+			for i, want := range tt.wantReading {
+				book := got.CurrentlyReading[i].Book
+				if book.BookID != want.Book.BookID || book.ISBN != want.Book.ISBN || book.Title != want.Book.Title ||
+					book.Thumbnail != want.Book.Thumbnail || book.Progress != want.Book.Progress ||
+					!slices.Equal(book.Authors, want.Book.Authors) {
+					t.Errorf("currently reading[%d] = %+v, want %+v", i, book, want.Book)
+				}
+			}
+		})
+	}
+}
+
+func TestMergeBooksReadingLogAndSeries(t *testing.T) {
+	m := newMerger(t)
+	for _, entry := range []models.ReadingLogItem{
+		{BookID: "d", Title: "Dune (Paperback)", Date: "2025-01-01T00:00:00Z"},
+		{BookID: "x", Title: "Other", Date: "2025-01-02T00:00:00Z"},
+		{BookID: "gone", Title: "Dune", Date: "2025-01-03T00:00:00Z"},
+	} {
+		if err := m.ReadingLog.Append("user", &entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Profiles.Put(&models.Profile{ID: "user"}); err != nil {
+		t.Fatal(err)
+	}
+	series := &models.Series{SeriesID: "dune", Name: "Dune", Entries: []models.SeriesEntry{
+		{Position: 1, WorkID: "w1", BookID: "c", Title: "Dune"},
+		{Position: 1, WorkID: "gone", BookID: "gone", Title: "Dune"},
+		{Position: 2, WorkID: "w2", BookID: "x", Title: "Dune Messiah"},
+	}}
+	if err := m.Series.Put(series); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := m.Merge("c", []string{"d", "gone"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(result.MergedBookIDs, []string{"d"}) || !slices.Equal(result.MissingBookIDs, []string{"gone"}) {
+		t.Errorf("merged %v, missing %v, want [d] and [gone]", result.MergedBookIDs, result.MissingBookIDs)
+	}
+	if result.ReadingLogEntriesUpdated != 2 || result.SeriesEntriesUpdated != 1 {
+		t.Errorf("updated %d reading log and %d series entries, want 2 and 1",
+			result.ReadingLogEntriesUpdated, result.SeriesEntriesUpdated)
+	}
+
+	if _, err := m.Books.Get("d"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("duplicate was not deleted: %v", err)
+	}
+	canonical, err := m.Books.Get("c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if canonical.Title != "Dune" || canonical.CoverImageURL != "https://covers/d.jpg" || canonical.PageCount != 412 ||
+		!slices.Equal(canonical.Tags, []string{"classic", "scifi"}) {
+		t.Errorf("canonical book = %+v, want its own title and the duplicate's cover, pages and tags", canonical)
+	}
+
+	entries, err := m.ReadingLog.Query("user", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry.BookID == "d" || entry.BookID == "gone" {
+			t.Errorf("reading log entry %s still names %s", entry.Id, entry.BookID)
+		}
+		if entry.BookID == "c" && (entry.Title != "Dune" || entry.BookThumbnail != "https://covers/d.jpg") {
+			t.Errorf("reading log entry %s = %+v, want the canonical title and cover", entry.Id, entry)
+		}
+	}
+
+	got, err := m.Series.Get("dune")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.SeriesEntry{
+		{Position: 1, WorkID: "w1", BookID: "c", Title: "Dune"},
+		{Position: 2, WorkID: "w2", BookID: "x", Title: "Dune Messiah"},
+	}
+	if !slices.Equal(got.Entries, want) {
+		t.Errorf("series entries = %+v, want %+v", got.Entries, want)
+	}
+
+	// A second run finds nothing left to merge
+	result, err = m.Merge("c", []string{"d", "gone"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.MergedBookIDs) != 0 || result.ReadingLogEntriesUpdated != 0 || result.SeriesEntriesUpdated != 0 {
+		t.Errorf("second merge = %+v, want no changes", result)
+	}
+}
+
+func TestMergeErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		canonical  string
+		duplicates []string
+		want       error
+	}{
+		{"no duplicates", "c", nil, ErrInvalidMerge},
+		{"into itself", "c", []string{"d", "c"}, ErrInvalidMerge},
+		{"missing canonical book", "missing", []string{"d"}, store.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMerger(t)
+			if _, err := m.Merge(tt.canonical, tt.duplicates); !errors.Is(err, tt.want) {
+				t.Errorf("Merge(%q, %v) error = %v, want %v", tt.canonical, tt.duplicates, err, tt.want)
+			}
+			if _, err := m.Books.Get("d"); err != nil {
+				t.Errorf("a failed merge deleted the duplicate: %v", err)
+			}
+		})
+	}
+}
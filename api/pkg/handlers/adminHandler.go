@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/FriedGlue/BookIt/api/pkg/dedupe"
+	"github.com/FriedGlue/BookIt/api/pkg/metadata"
+	"github.com/FriedGlue/BookIt/api/pkg/shared"
+	"github.com/FriedGlue/BookIt/api/pkg/store"
+	"github.com/FriedGlue/BookIt/api/pkg/validation"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// MergeBooksRequest is the body of POST /admin/books/merge.
+type MergeBooksRequest struct {
+	CanonicalBookID  string   `json:"canonicalBookId" validate:"required"`
+	DuplicateBookIDs []string `json:"duplicateBookIds" validate:"required,min=1,max=50"`
+}
+
+func (r MergeBooksRequest) Validate() []validation.FieldError {
+	for _, id := range r.DuplicateBookIDs {
+		if id == "" {
+			return []validation.FieldError{{Field: "duplicateBookIds", Message: "must not contain empty IDs"}}
+		}
+		if id == r.CanonicalBookID {
+			return []validation.FieldError{{Field: "duplicateBookIds", Message: "must not contain canonicalBookId"}}
+		}
+	}
+	return nil
+}
+
+// FindDuplicateBooks lists the groups of books that appear to be stored more
+// than once, each with the book proposed to keep.
+func FindDuplicateBooks(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	books, err := stores.Books.List()
+	if err != nil {
+		return internalErrorResponse("Error listing books", err)
+	}
+
+	groups := dedupe.Find(books)
+	log.Printf("Found %d groups of duplicate books among %d books\n", len(groups), len(books))
+	return shared.ListResponse(200, groups)
+}
+
+// MergeBooks merges duplicate books into a canonical one and points every
+// profile, reading log and series reference to them at it.
+func MergeBooks(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	var input MergeBooksRequest
+	if apiErr := validation.Decode(request.Body, &input); apiErr != nil {
+		return shared.ErrorResponse(apiErr)
+	}
+
+	log.Printf("User %s merging books %v into %s\n", shared.UserID(request), input.DuplicateBookIDs, input.CanonicalBookID)
+	merger := dedupe.Merger{Books: stores.Books, Profiles: stores.Profiles, ReadingLog: stores.ReadingLog, Series: stores.Series}
+	result, err := merger.Merge(input.CanonicalBookID, input.DuplicateBookIDs)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return shared.Error(shared.CodeBookNotFound, fmt.Sprintf("No book found with ID: %s", input.CanonicalBookID))
+	case errors.Is(err, dedupe.ErrInvalidMerge):
+		return shared.Error(shared.CodeInvalidParameter, err.Error())
+	case err != nil:
+		return storeErrorResponse(err, shared.CodeBookNotFound, "Book not found")
+	}
+	return shared.SuccessResponse(200, result)
+}
+
+// GetCacheStats reports the metadata cache hits and misses of each provider
+// and cache tier. Every Lambda instance counts its own calls since it
+// started.
+func GetCacheStats(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	stats := metadata.Stats(metadataProvider)
+	log.Printf("Metadata cache stats: %+v\n", stats)
+	return shared.SuccessResponse(200, stats)
+}
@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/FriedGlue/BookIt/api/pkg/metadata"
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/FriedGlue/BookIt/api/pkg/pagination"
+	"github.com/FriedGlue/BookIt/api/pkg/search"
+	"github.com/FriedGlue/BookIt/api/pkg/shared"
+	"github.com/FriedGlue/BookIt/api/pkg/store"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// maxBibliography bounds the works asked of the metadata providers for an
+// author's bibliography.
+const maxBibliography = 100
+
+// Reading statuses of a bibliography entry, from the caller's lists.
+const (
+	statusToBeRead         = "toBeRead"
+	statusRead             = "read"
+	statusCurrentlyReading = "currentlyReading"
+)
+
+// statusRank orders the statuses of a work found in several lists: being
+// read beats read, which beats to be read.
+var statusRank = map[string]int{statusToBeRead: 1, statusRead: 2, statusCurrentlyReading: 3}
+
+// AuthorResponse is the body of GET /authors/{authorId}.
+type AuthorResponse struct {
+	Author       models.Author       `json:"author"`
+	Bibliography []BibliographyEntry `json:"bibliography"`
+}
+
+// BibliographyEntry is one work of an author, with where the caller keeps
+// it.
+type BibliographyEntry struct {
+	WorkID        string   `json:"workId"`
+	Title         string   `json:"title"`
+	CoverImageURL string   `json:"coverImageUrl,omitempty"`
+	BookIDs       []string `json:"bookIds,omitempty"` // saved editions
+	Status        string   `json:"status,omitempty"`  // currentlyReading, read or toBeRead
+	Rating        int      `json:"rating,omitempty"`  // from the read list
+	Shelves       []string `json:"shelves,omitempty"` // custom lists holding it
+}
+
+// GetAuthor returns an author and their bibliography: the works the
+// metadata providers list for them and the saved books linked to them,
+// each marked with its status in the caller's lists. Authors that are not
+// saved yet are looked up and saved.
+func GetAuthor(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	authorId := request.PathParameters["authorId"]
+	if authorId == "" {
+		return shared.Error(shared.CodeMissingParameter, "Missing path parameter: authorId")
+	}
+	ctx := shared.RequestContext(request)
+
+	author, err := loadAuthor(ctx, authorId)
+	switch {
+	case errors.Is(err, store.ErrNotFound), errors.Is(err, metadata.ErrNotFound):
+		return shared.Error(shared.CodeAuthorNotFound, fmt.Sprintf("No author found with ID: %s", authorId))
+	case err != nil:
+		return metadataErrorResponse(err, "author "+authorId)
+	}
+
+	books, err := stores.Books.QueryByAuthorID(authorId)
+	if err != nil {
+		return internalErrorResponse("Error loading the author's books", err)
+	}
+	works, err := metadataProvider.LookupAuthorWorks(ctx, authorId, maxBibliography)
+	if err != nil && !errors.Is(err, metadata.ErrNotFound) {
+		// The saved books still make a bibliography
+		log.Printf("Error fetching works by %s: %v", authorId, err)
+	}
+	bibliography := buildBibliography(works, books)
+
+	profile, err := stores.Profiles.Get(shared.UserID(request))
+	switch {
+	case err == nil:
+		profile.Lists.Normalize() // moves custom lists not yet on shelves
+		markBibliography(bibliography, profile, author)
+	case !errors.Is(err, store.ErrNotFound):
+		return internalErrorResponse("Error loading profile", err)
+	}
+
+	return shared.SuccessResponse(200, AuthorResponse{Author: *author, Bibliography: bibliography})
+}
+
+// SearchAuthors finds saved authors by their name or alternate names.
+func SearchAuthors(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	q := request.QueryStringParameters["q"]
+	if q == "" {
+		return shared.Error(shared.CodeMissingParameter, "Missing query parameter: q")
+	}
+
+	pageReq, apiErr := pageRequest(request, search.DefaultLimit, search.MaxLimit)
+	if apiErr != nil {
+		return shared.ErrorResponse(apiErr)
+	}
+	page, err := stores.AuthorSearch.Search(shared.RequestContext(request), q, pageReq)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		return shared.Error(shared.CodeInvalidParameter, "Invalid cursor")
+	}
+	if err != nil {
+		return internalErrorResponse("Error searching authors", err)
+	}
+	return shared.PageResponse(200, page.Authors, pagination.Encode(page.Next))
+}
+
+// linkAuthors saves the authors a book is linked to that are not saved yet,
+// and names them on the book when the catalogue only gave their IDs. A
+// failed lookup is logged and skipped, as the book is worth saving without
+// it.
+func linkAuthors(ctx context.Context, book *models.BookData) {
+	var names []string
+	for _, authorID := range book.AuthorIDs {
+		author, err := loadAuthor(ctx, authorID)
+		if err != nil {
+			log.Printf("Error loading author %s: %v", authorID, err)
+			continue
+		}
+		names = append(names, author.Name)
+	}
+	if len(book.Authors) == 0 {
+		book.Authors = names
+	}
+}
+
+// loadAuthor returns a saved author, or looks them up in the metadata
+// providers and saves them.
+func loadAuthor(ctx context.Context, authorID string) (*models.Author, error) {
+	author, err := stores.Authors.Get(authorID)
+	if !errors.Is(err, store.ErrNotFound) {
+		return author, err
+	}
+	author, err = metadataProvider.LookupAuthor(ctx, authorID)
+	if err != nil {
+		return nil, err
+	}
+	if err := stores.Authors.Put(author); err != nil {
+		return nil, err
+	}
+	return author, nil
+}
+
+// buildBibliography lists the catalogue's works in its order, followed by
+// works only known from saved books, with each saved book under its work.
+func buildBibliography(works []metadata.SearchResult, books []models.BookData) []BibliographyEntry {
+	bibliography := []BibliographyEntry{}
+	byWork := make(map[string]int)
+	for _, work := range works {
+		if _, seen := byWork[work.WorkID]; seen {
+			continue
+		}
+		byWork[work.WorkID] = len(bibliography)
+		bibliography = append(bibliography, BibliographyEntry{
+			WorkID:        work.WorkID,
+			Title:         work.Title,
+			CoverImageURL: work.Thumbnail,
+		})
+	}
+
+	sort.Slice(books, func(i, j int) bool { return books[i].BookID < books[j].BookID })
+	for _, book := range books {
+		i, ok := byWork[book.WorkKey()]
+		if !ok {
+			i = len(bibliography)
+			byWork[book.WorkKey()] = i
+			bibliography = append(bibliography, BibliographyEntry{WorkID: book.WorkKey(), Title: book.Title})
+		}
+		entry := &bibliography[i]
+		entry.BookIDs = append(entry.BookIDs, book.BookID)
+		if entry.CoverImageURL == "" {
+			entry.CoverImageURL = book.CoverImageURL
+		}
+	}
+	return bibliography
+}
+
+// shelfMark is where the caller keeps a book.
+type shelfMark struct {
+	status  string
+	rating  int
+	shelves []string
+}
+
+func (m *shelfMark) merge(other *shelfMark) {
+	if other == nil {
+		return
+	}
+	if statusRank[other.status] > statusRank[m.status] {
+		m.status = other.status
+	}
+	if other.rating > 0 {
+		m.rating = other.rating
+	}
+	for _, shelf := range other.shelves {
+		if !slices.Contains(m.shelves, shelf) {
+			m.shelves = append(m.shelves, shelf)
+		}
+	}
+}
+
+// markBibliography sets the status of each entry from the profile's lists.
+// List entries are matched to works by book ID, or by title when they are
+// by the author, as books added by ISBN are not saved.
+func markBibliography(bibliography []BibliographyEntry, profile *models.Profile, author *models.Author) {
+	byBook := make(map[string]*shelfMark)
+	byTitle := make(map[string]*shelfMark)
+	mark := func(bookID, title string, authors []string, apply func(*shelfMark)) {
+		marks := []*shelfMark{}
+		if bookID != "" {
+			if byBook[bookID] == nil {
+				byBook[bookID] = &shelfMark{}
+			}
+			marks = append(marks, byBook[bookID])
+		}
+		if key := normalizedTitle(title); key != "" && writtenBy(authors, author) {
+			if byTitle[key] == nil {
+				byTitle[key] = &shelfMark{}
+			}
+			marks = append(marks, byTitle[key])
+		}
+		for _, m := range marks {
+			apply(m)
+		}
+	}
+	setStatus := func(status string) func(*shelfMark) {
+		return func(m *shelfMark) { m.merge(&shelfMark{status: status}) }
+	}
+
+	for _, item := range profile.CurrentlyReading {
+		mark(item.Book.BookID, item.Book.Title, item.Book.Authors, setStatus(statusCurrentlyReading))
+	}
+	for _, item := range profile.Lists.Read {
+		rating := item.Rating
+		mark(item.BookID, item.Title, item.Authors, func(m *shelfMark) {
+			m.merge(&shelfMark{status: statusRead, rating: rating})
+		})
+	}
+	for _, item := range profile.Lists.ToBeRead {
+		mark(item.BookID, item.Title, item.Authors, setStatus(statusToBeRead))
+	}
+	shelves := slices.Clone(profile.Lists.Shelves)
+	sort.SliceStable(shelves, func(i, j int) bool { return shelves[i].Name < shelves[j].Name })
+	for _, shelf := range shelves {
+		for _, item := range shelf.Items {
+			mark(item.BookID, item.Title, item.Authors, func(m *shelfMark) {
+				m.merge(&shelfMark{shelves: []string{shelf.Name}})
+			})
+		}
+	}
+
+	for i := range bibliography {
+		entry := &bibliography[i]
+		found := &shelfMark{}
+		for _, bookID := range entry.BookIDs {
+			found.merge(byBook[bookID])
+		}
+		found.merge(byTitle[normalizedTitle(entry.Title)])
+		entry.Status, entry.Rating, entry.Shelves = found.status, found.rating, found.shelves
+	}
+}
+
+// writtenBy reports whether one of the names is the author's name or one of
+// their alternate names.
+func writtenBy(names []string, author *models.Author) bool {
+	for _, name := range names {
+		key := normalizedName(name)
+		if key == "" {
+			continue
+		}
+		if key == normalizedName(author.Name) {
+			return true
+		}
+		for _, alternate := range author.AlternateNames {
+			if key == normalizedName(alternate) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// normalizedName ignores case, accents and punctuation, so "J.R.R. Tolkien"
+// and "J. R. R. Tolkien" are the same name.
+func normalizedName(name string) string {
+	return strings.Join(search.Tokenize(name), " ")
+}
+
+// normalizedTitle ignores case, accents and punctuation, and the subtitle.
+func normalizedTitle(title string) string {
+	if main, _, found := strings.Cut(title, ":"); found && strings.TrimSpace(main) != "" {
+		title = main
+	}
+	return strings.Join(search.Tokenize(title), " ")
+}
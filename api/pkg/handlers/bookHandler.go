@@ -1,346 +1,218 @@
 package handlers
 
 import (
-	"encoding/json"
 	"fmt"
-	"net/http"
-	"os"
-	"strings"
 
+	"github.com/FriedGlue/BookIt/api/pkg/isbn"
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/FriedGlue/BookIt/api/pkg/pagination"
 	"github.com/FriedGlue/BookIt/api/pkg/shared"
+	"github.com/FriedGlue/BookIt/api/pkg/validation"
 	"github.com/aws/aws-lambda-go/events"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
-	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
 	"github.com/google/uuid"
 )
 
-// For this example, we'll call our DynamoDB table "Books"
-// with a partition key named "isbn" (string).
-
-var (
-	PROFILES_TABLE_NAME     = os.Getenv("PROFILES_TABLE_NAME")     // e.g. "UserProfiles"
-	BOOKS_TABLE_NAME        = os.Getenv("BOOKS_TABLE_NAME")        // e.g. "UserProfiles"
-	OPEN_LIBRARY_INDEX_NAME = os.Getenv("OPEN_LIBRARY_INDEX_NAME") // e.g. "OpenLibraryIndex"
-	ISBN_INDEX_NAME         = os.Getenv("ISBN_INDEX_NAME")         // e.g. "ISBNIndex"
-)
-
-// Book represents a single book record in DynamoDB.
-type BookData struct {
-	BookID         string   `json:"bookId"`
-	ISBN10         string   `json:"isbn10,omitempty"`
-	ISBN13         string   `json:"isbn13,omitempty"`
-	Title          string   `json:"title,omitempty"`
-	TitleLowercase string   `json:"titleLowercase,omitempty"`
-	Authors        []string `json:"authors,omitempty"`
-	PageCount      int      `json:"pageCount,omitempty"`
-	CoverImageURL  string   `json:"coverImageUrl,omitempty"`
-	Tags           []string `json:"tags,omitempty"`
-	OpenLibraryId  string   `json:"openLibraryId,omitempty"`
-	Description    string   `json:"description,omitempty"`
-}
-
-// ===============================
-//  Open Library Fetch Logic
-// ===============================
-
-// OLBookData shapes the "api/books?bibkeys=..." response from Open Library.
-type OLBookData struct {
-	Title         string      `json:"title"`
-	Authors       []OLAuthor  `json:"authors"`
-	NumberOfPages int         `json:"number_of_pages"`
-	PublishDate   string      `json:"publish_date"`
-	Cover         OLCover     `json:"cover"`
-	Subjects      []OLSubject `json:"subjects"`
-}
-
-type OLAuthor struct {
-	Name string `json:"name"`
-}
-
-type OLCover struct {
-	Small  string `json:"small"`
-	Medium string `json:"medium"`
-	Large  string `json:"large"`
-}
-
-type OLSubject struct {
-	Name string `json:"name"`
-}
-
-// fetchBookFromOpenLibrary fetches metadata by ISBN via Open Library.
-func FetchBookFromOpenLibrary(isbn string) (BookData, error) {
-	// Example: https://openlibrary.org/api/books?bibkeys=ISBN:<isbn>&format=json&jscmd=data
-	url := fmt.Sprintf("https://openlibrary.org/api/books?bibkeys=ISBN:%s&format=json&jscmd=data", isbn)
-
-	resp, err := http.Get(url)
-	if err != nil {
-		return BookData{}, fmt.Errorf("HTTP request error: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return BookData{}, fmt.Errorf("Open Library returned status code %d", resp.StatusCode)
-	}
-
-	var responseMap map[string]OLBookData
-	if err := json.NewDecoder(resp.Body).Decode(&responseMap); err != nil {
-		return BookData{}, fmt.Errorf("JSON decode error: %v", err)
-	}
-
-	key := fmt.Sprintf("ISBN:%s", isbn)
-	olData, exists := responseMap[key]
-	if !exists {
-		return BookData{}, fmt.Errorf("No data found for ISBN %s", isbn)
-	}
-
-	// Convert from OLBookData -> our Book struct
-	var authors []string
-	for _, a := range olData.Authors {
-		authors = append(authors, a.Name)
-	}
-
-	// Attempt to pick the largest cover image
-	coverURL := olData.Cover.Large
-	if coverURL == "" {
-		coverURL = olData.Cover.Medium
-	}
-	if coverURL == "" {
-		coverURL = olData.Cover.Small
-	}
-
-	var subjects []string
-	for _, s := range olData.Subjects {
-		subjects = append(subjects, s.Name)
-	}
-
-	// Generate a unique BookID using UUID
-	bookId := uuid.New().String()
-
-	b := BookData{
-		BookID:         bookId,
-		ISBN13:         isbn,
-		Title:          olData.Title,
-		TitleLowercase: strings.ToLower(olData.Title),
-		Authors:        authors,
-		PageCount:      olData.NumberOfPages,
-		CoverImageURL:  coverURL,
-		Tags:           subjects,
-	}
-	return b, nil
-}
-
 // ===============================
 //  CRUD Handlers (API Gateway)
 // ===============================
 
 // 1. GET /books/{bookId?}
 //   - If a bookId is provided in pathParameters["bookId"], retrieve that specific book.
-//   - If no bookId is provided, do a scan or handle accordingly (not recommended at large scale).
+//   - If no bookId is provided, list the books a page at a time (?limit= and ?cursor=).
 func GetBooks(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
 	bookId, hasBookId := request.PathParameters["bookId"]
-	svc := shared.DynamoDBClient()
 
 	if hasBookId && bookId != "" {
 		// Retrieve a single book by primary key
-		getInput := &dynamodb.GetItemInput{
-			TableName: aws.String(BOOKS_TABLE_NAME),
-			Key: map[string]*dynamodb.AttributeValue{
-				"bookId": {S: aws.String(bookId)},
-			},
-		}
-		result, err := svc.GetItem(getInput)
+		book, err := stores.Books.Get(bookId)
 		if err != nil {
-			return shared.ErrorResponse(500, "Error retrieving book: "+err.Error())
-		}
-		if result.Item == nil {
-			return shared.ErrorResponse(404, "Book not found")
+			return storeErrorResponse(err, shared.CodeBookNotFound, "Book not found")
 		}
 
-		var book BookData
-		err = dynamodbattribute.UnmarshalMap(result.Item, &book)
-		if err != nil {
-			return shared.ErrorResponse(500, "Error unmarshalling book: "+err.Error())
-		}
-
-		bytes, _ := json.Marshal(book)
-		return events.APIGatewayProxyResponse{
-			StatusCode: 200,
-			Body:       string(bytes),
-		}
+		return shared.SuccessResponse(200, book)
 	}
 
-	// No bookId => we might do a full table scan or return an error
-	// WARNING: Doing a scan on a large table can be expensive
-	scanInput := &dynamodb.ScanInput{
-		TableName: aws.String(BOOKS_TABLE_NAME),
+	// No bookId => list one page of books in the table's scan order
+	page, apiErr := pageRequest(request, pagination.DefaultLimit, pagination.MaxLimit)
+	if apiErr != nil {
+		return shared.ErrorResponse(apiErr)
 	}
-	scanRes, err := svc.Scan(scanInput)
+	books, next, err := stores.Books.ListPage(page)
 	if err != nil {
-		return shared.ErrorResponse(500, "Scan error: "+err.Error())
-	}
-	if len(scanRes.Items) == 0 {
-		return shared.ErrorResponse(404, "No books found")
+		return internalErrorResponse("Error listing books", err)
 	}
+	return shared.PageResponse(200, books, pagination.Encode(next))
+}
 
-	var books []BookData
-	err = dynamodbattribute.UnmarshalListOfMaps(scanRes.Items, &books)
-	if err != nil {
-		return shared.ErrorResponse(500, "Error unmarshalling items: "+err.Error())
-	}
+// CreateBookRequest is the body of POST /books.
+type CreateBookRequest struct {
+	ISBN string `json:"isbn" validate:"required,isbn"` // ISBN-10 or ISBN-13, hyphens allowed
+	// Optionally, you could allow manual override of some fields
+}
 
-	bytes, _ := json.Marshal(books)
-	return events.APIGatewayProxyResponse{
-		StatusCode: 200,
-		Body:       string(bytes),
+// UpdateBookRequest is the body of PUT /books/{bookId}. It matches the
+// updatable fields in BookData; only the fields present are changed.
+type UpdateBookRequest struct {
+	ISBN10        *string   `json:"isbn10,omitempty" validate:"isbn"`
+	ISBN13        *string   `json:"isbn13,omitempty" validate:"isbn"`
+	Title         *string   `json:"title,omitempty"`
+	Authors       *[]string `json:"authors,omitempty"`
+	PageCount     *int      `json:"pageCount,omitempty"`
+	CoverImageURL *string   `json:"coverImageUrl,omitempty"`
+	Tags          *[]string `json:"tags,omitempty"`
+}
+
+// Validate checks that isbn10 and isbn13, when both are given, are the same
+// book.
+func (r UpdateBookRequest) Validate() []validation.FieldError {
+	if r.ISBN10 == nil || r.ISBN13 == nil || *r.ISBN10 == "" || *r.ISBN13 == "" {
+		return nil
+	}
+	if !isbn.Equal(*r.ISBN10, *r.ISBN13) {
+		return []validation.FieldError{{Field: "isbn10", Message: "must be the same book as isbn13"}}
 	}
+	return nil
 }
 
 // 2. POST /books
-//   - The request body can include a JSON with an `isbn` field to fetch from Open Library,
+//   - The request body can include a JSON with an `isbn` field to look up with the metadata providers,
 //     or a full Book object to store directly.
 func CreateBook(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
-	var input struct {
-		ISBN string `json:"isbn"`
-		// Optionally, you could allow manual override of some fields
-	}
-
-	if err := json.Unmarshal([]byte(request.Body), &input); err != nil {
-		return shared.ErrorResponse(400, "Invalid JSON request: "+err.Error())
-	}
-	if strings.TrimSpace(input.ISBN) == "" {
-		return shared.ErrorResponse(400, "ISBN is required in POST body")
-	}
+	var input CreateBookRequest
 
-	// 1) Fetch data from Open Library
-	book, err := FetchBookFromOpenLibrary(input.ISBN)
-	if err != nil {
-		return shared.ErrorResponse(500, "Failed to fetch from Open Library: "+err.Error())
+	if apiErr := validation.Decode(request.Body, &input); apiErr != nil {
+		return shared.ErrorResponse(apiErr)
 	}
+	isbn13, _ := isbn.Parse(input.ISBN) // already validated
 
-	// 2) Store in DynamoDB
-	av, err := dynamodbattribute.MarshalMap(book)
+	// 1) Fetch data from the metadata providers
+	book, err := metadataProvider.LookupByISBN(shared.RequestContext(request), isbn13)
 	if err != nil {
-		return shared.ErrorResponse(500, "Error marshalling book data: "+err.Error())
+		return metadataErrorResponse(err, "ISBN "+input.ISBN)
 	}
-
-	svc := shared.DynamoDBClient()
-	putInput := &dynamodb.PutItemInput{
-		TableName: aws.String(BOOKS_TABLE_NAME),
-		Item:      av,
+	book.BookID = uuid.New().String()
+	if book.ISBN13 == "" && book.ISBN10 == "" {
+		book.ISBN13 = isbn13
 	}
-	_, err = svc.PutItem(putInput)
-	if err != nil {
-		return shared.ErrorResponse(500, "DynamoDB PutItem error: "+err.Error())
+	normalizeISBNs(book)
+	linkAuthors(shared.RequestContext(request), book)
+	if _, err := ensureWork(book); err != nil {
+		return internalErrorResponse("Error saving work", err)
 	}
 
-	return events.APIGatewayProxyResponse{
-		StatusCode: 200,
-		Body:       fmt.Sprintf("Book with ISBN %s created successfully", book.ISBN13),
+	// 2) Store in DynamoDB
+	if err := stores.Books.Put(book); err != nil {
+		return internalErrorResponse("Error saving book", err)
 	}
+	linkSeries(book)
+
+	return shared.MessageResponse(200, fmt.Sprintf("Book with ISBN %s created successfully", isbn13))
 }
 
 // 3. PUT /books/{bookId}
-//   - The request body can contain partial updates.
-//   - We'll demonstrate using a DynamoDB UpdateItem with an expression builder.
+//   - The request body can contain partial updates; only the fields present are changed.
 func UpdateBook(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
 	bookId, hasBookId := request.PathParameters["bookId"]
 	if !hasBookId || bookId == "" {
-		return shared.ErrorResponse(400, "Missing path parameter: bookId")
+		return shared.Error(shared.CodeMissingParameter, "Missing path parameter: bookId")
 	}
 
-	// This struct matches the updatable fields in BookData
-	var updates struct {
-		ISBN10        *string   `json:"isbn10,omitempty"`
-		ISBN13        *string   `json:"isbn13,omitempty"`
-		Title         *string   `json:"title,omitempty"`
-		Authors       *[]string `json:"authors,omitempty"`
-		PageCount     *int      `json:"pageCount,omitempty"`
-		CoverImageURL *string   `json:"coverImageUrl,omitempty"`
-		Tags          *[]string `json:"tags,omitempty"`
-	}
+	var updates UpdateBookRequest
 
-	if err := json.Unmarshal([]byte(request.Body), &updates); err != nil {
-		return shared.ErrorResponse(400, "Invalid JSON request: "+err.Error())
-	}
-
-	// Convert partial updates to map
-	updateMap, err := dynamodbattribute.MarshalMap(updates)
-	if err != nil {
-		return shared.ErrorResponse(500, "Error marshalling update data: "+err.Error())
+	if apiErr := validation.Decode(request.Body, &updates); apiErr != nil {
+		return shared.ErrorResponse(apiErr)
 	}
 
-	// Build update expression
-	updateBuilder := expression.UpdateBuilder{}
-	for key, val := range updateMap {
-		if val.NULL == nil {
-			// Special handling for title to also update titleLowercase
-			if key == "title" {
-				updateBuilder = updateBuilder.Set(expression.Name(key), expression.Value(val))
-				// Add titleLowercase field update
-				if val.S != nil {
-					updateBuilder = updateBuilder.Set(
-						expression.Name("titleLowercase"),
-						expression.Value(strings.ToLower(*val.S)),
-					)
-				}
-			} else {
-				updateBuilder = updateBuilder.Set(expression.Name(key), expression.Value(val))
+	_, err := stores.Books.Update(bookId, func(book *models.BookData) error {
+		// A new ISBN in one form replaces the other form too, so the two
+		// never name different books.
+		switch {
+		case updates.ISBN13 != nil:
+			book.ISBN13 = *updates.ISBN13
+			book.ISBN10 = ""
+			if updates.ISBN10 != nil {
+				book.ISBN10 = *updates.ISBN10
 			}
+		case updates.ISBN10 != nil:
+			book.ISBN10 = *updates.ISBN10
+			book.ISBN13 = ""
 		}
-	}
-
-	expr, err := expression.NewBuilder().WithUpdate(updateBuilder).Build()
+		normalizeISBNs(book)
+		if updates.Title != nil {
+			book.Title = *updates.Title
+		}
+		if updates.Authors != nil {
+			book.Authors = *updates.Authors
+		}
+		if updates.PageCount != nil {
+			book.PageCount = *updates.PageCount
+		}
+		if updates.CoverImageURL != nil {
+			book.CoverImageURL = *updates.CoverImageURL
+		}
+		if updates.Tags != nil {
+			book.Tags = *updates.Tags
+		}
+		return nil
+	})
 	if err != nil {
-		return shared.ErrorResponse(500, "Error building expression: "+err.Error())
+		return storeErrorResponse(err, shared.CodeBookNotFound, "Book not found")
 	}
 
-	svc := shared.DynamoDBClient()
-	input := &dynamodb.UpdateItemInput{
-		TableName: aws.String(BOOKS_TABLE_NAME),
-		Key: map[string]*dynamodb.AttributeValue{
-			"bookId": {S: aws.String(bookId)},
-		},
-		ExpressionAttributeNames:  expr.Names(),
-		ExpressionAttributeValues: expr.Values(),
-		UpdateExpression:          expr.Update(),
-		ReturnValues:              aws.String("UPDATED_NEW"),
+	return shared.MessageResponse(200, fmt.Sprintf("Book with ID %s updated successfully", bookId))
+}
+
+// 4. DELETE /books?isbn={isbn}
+func DeleteBook(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	number, hasISBN := request.QueryStringParameters["isbn"]
+	if !hasISBN || number == "" {
+		return shared.Error(shared.CodeMissingParameter, "Missing query string parameter: isbn")
+	}
+	if _, err := isbn.Parse(number); err != nil {
+		return shared.Error(shared.CodeInvalidParameter, fmt.Sprintf("Invalid ISBN: %s", number))
 	}
 
-	_, err = svc.UpdateItem(input)
+	books, err := searchByISBN(number)
 	if err != nil {
-		return shared.ErrorResponse(500, "Error updating book: "+err.Error())
+		return internalErrorResponse("Error deleting book", err)
+	}
+	if len(books) == 0 {
+		return shared.Error(shared.CodeBookNotFound, "Book not found")
 	}
 
-	return events.APIGatewayProxyResponse{
-		StatusCode: 200,
-		Body:       fmt.Sprintf("Book with ID %s updated successfully", bookId),
+	for _, book := range books {
+		if err := stores.Books.Delete(book.BookID); err != nil {
+			return internalErrorResponse("Error deleting book", err)
+		}
 	}
+
+	return shared.MessageResponse(200, fmt.Sprintf("Book with ISBN %s deleted successfully", number))
 }
 
-// 4. DELETE /books/{isbn}
-func DeleteBook(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
-	isbn, hasISBN := request.QueryStringParameters["isbn"]
-	if !hasISBN || isbn == "" {
-		return shared.ErrorResponse(400, "Missing query string parameter: isbn")
-	}
+// normalizeISBNs writes the book's ISBNs without punctuation and fills in
+// whichever of the ISBN-10 and ISBN-13 is missing, so the book can be found
+// by either. A number that does not validate is kept as given, since
+// catalogues occasionally record one wrongly.
+func normalizeISBNs(book *models.BookData) {
+	book.ISBN10 = isbn.Normalize(book.ISBN10)
+	book.ISBN13 = isbn.Normalize(book.ISBN13)
 
-	svc := shared.DynamoDBClient()
-	input := &dynamodb.DeleteItemInput{
-		TableName: aws.String(BOOKS_TABLE_NAME),
-		Key: map[string]*dynamodb.AttributeValue{
-			"isbn": {S: aws.String(isbn)},
-		},
+	// Catalogues sometimes put one form in the other's field.
+	if len(book.ISBN13) == 10 && book.ISBN10 == "" {
+		book.ISBN10, book.ISBN13 = book.ISBN13, ""
 	}
-
-	_, err := svc.DeleteItem(input)
-	if err != nil {
-		return shared.ErrorResponse(500, "Error deleting book: "+err.Error())
+	if len(book.ISBN10) == 13 && book.ISBN13 == "" {
+		book.ISBN10, book.ISBN13 = "", book.ISBN10
 	}
 
-	return events.APIGatewayProxyResponse{
-		StatusCode: 200,
-		Body:       fmt.Sprintf("Book with ISBN %s deleted successfully", isbn),
+	if book.ISBN13 == "" {
+		if isbn13, err := isbn.To13(book.ISBN10); err == nil {
+			book.ISBN13 = isbn13
+		}
+	}
+	if book.ISBN10 == "" {
+		if isbn10, err := isbn.To10(book.ISBN13); err == nil {
+			book.ISBN10 = isbn10
+		}
 	}
 }
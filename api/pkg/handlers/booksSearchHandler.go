@@ -1,23 +1,24 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"regexp"
 	"strings"
 
+	"github.com/FriedGlue/BookIt/api/pkg/isbn"
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/FriedGlue/BookIt/api/pkg/pagination"
+	"github.com/FriedGlue/BookIt/api/pkg/search"
 	"github.com/FriedGlue/BookIt/api/pkg/shared"
+	"github.com/FriedGlue/BookIt/api/pkg/store"
 	"github.com/aws/aws-lambda-go/events"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
-	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
 )
 
 func SearchBooks(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
 	// Grab query params, e.g. ?isbn=XXX or ?q=someTitle
-	isbn := request.QueryStringParameters["isbn"]
+	isbnValue := request.QueryStringParameters["isbn"]
 	q := request.QueryStringParameters["q"]
 	bookId := request.QueryStringParameters["bookId"]
 	openLibraryId := request.QueryStringParameters["openLibraryId"]
@@ -27,13 +28,11 @@ func SearchBooks(request events.APIGatewayProxyRequest) events.APIGatewayProxyRe
 	// authorParam := request.QueryStringParameters["author"]
 	// etc.
 
-	if isbn == "" && q == "" && bookId == "" && openLibraryId == "" {
-		return shared.ErrorResponse(400, "Please provide at least one search parameter (?isbn= or ?q= or ?bookId= or ?openLibraryId=).")
+	if isbnValue == "" && q == "" && bookId == "" && openLibraryId == "" {
+		return shared.Error(shared.CodeMissingParameter, "Please provide at least one search parameter (?isbn= or ?q= or ?bookId= or ?openLibraryId=).")
 	}
 
-	svc := shared.DynamoDBClient()
-
-	var books []BookData
+	var books []models.BookData
 	var err error
 
 	// Check if openLibraryId is actually a UUID format
@@ -55,207 +54,92 @@ func SearchBooks(request events.APIGatewayProxyRequest) events.APIGatewayProxyRe
 	}
 
 	if bookId != "" {
-		books, err = searchByBookId(svc, bookId)
+		books, err = searchByBookId(bookId)
 		if err != nil {
-			log.Printf("Error searching by bookId: %v\n", err)
-			return shared.ErrorResponse(500, fmt.Sprintf("Error searching by bookId: %v", err))
+			return internalErrorResponse("Error searching by bookId", err)
 		}
 		if len(books) == 0 {
-			return shared.ErrorResponse(404, fmt.Sprintf("No book found with ID: %s", bookId))
+			return shared.Error(shared.CodeBookNotFound, fmt.Sprintf("No book found with ID: %s", bookId))
+		}
+	} else if isbnValue != "" {
+		if _, err := isbn.Parse(isbnValue); err != nil {
+			return shared.Error(shared.CodeInvalidParameter, fmt.Sprintf("Invalid ISBN: %s", isbnValue))
 		}
-	} else if isbn != "" {
-		books, err = searchByISBN(svc, isbn)
+		books, err = searchByISBN(isbnValue)
 		if err != nil {
-			log.Printf("Error searching by ISBN: %v\n", err)
-			return shared.ErrorResponse(500, fmt.Sprintf("Error searching by ISBN: %v", err))
+			return internalErrorResponse("Error searching by ISBN", err)
 		}
 	} else if openLibraryId != "" {
-		books, err = searchByOpenLibraryId(svc, openLibraryId)
+		books, err = searchByOpenLibraryId(openLibraryId)
 		if err != nil {
-			log.Printf("Error searching by openLibraryId: %v\n", err)
-			return shared.ErrorResponse(500, fmt.Sprintf("Error searching by openLibraryId: %v", err))
+			return internalErrorResponse("Error searching by openLibraryId", err)
 		}
 	} else {
-		// 2) If a general query (q) is provided, do a partial match on 'titleLowercase' or do a scan:
-		if q != "" {
-			// For small scale, do a scan.
-			// Example: 'contains(titleLowercase, :qLower)'
-			books, err = searchByPartialTitle(svc, q)
-			if err != nil {
-				log.Printf("Error searching by partial title: %v\n", err)
-				return shared.ErrorResponse(500, fmt.Sprintf("Error searching by partial title: %v", err))
-			}
+		// A general query (q) goes to the search index, a page at a time
+		pageReq, apiErr := pageRequest(request, search.DefaultLimit, search.MaxLimit)
+		if apiErr != nil {
+			return shared.ErrorResponse(apiErr)
+		}
+		page, err := stores.Search.Search(shared.RequestContext(request), q, pageReq)
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			return shared.Error(shared.CodeInvalidParameter, "Invalid cursor")
+		}
+		if err != nil {
+			return internalErrorResponse("Error searching books", err)
 		}
+		return shared.PageResponse(200, page.Books, pagination.Encode(page.Next))
 	}
 
-	// Convert to JSON
-	responseBytes, _ := json.Marshal(books)
-
-	return events.APIGatewayProxyResponse{
-		StatusCode: 200,
-		Body:       string(responseBytes),
-		Headers: map[string]string{
-			"Content-Type": "application/json",
-		},
-	}
+	return shared.ListResponse(200, books)
 }
 
-// Exact ISBN lookup using GSI
-func searchByISBN(svc *dynamodb.DynamoDB, isbnValue string) ([]BookData, error) {
-	input := &dynamodb.QueryInput{
-		TableName:              aws.String(BOOKS_TABLE_NAME),
-		IndexName:              aws.String(ISBN_INDEX_NAME), // "ISBNIndex"
-		KeyConditionExpression: aws.String("isbn13 = :isbnVal"),
-		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
-			":isbnVal": {S: aws.String(isbnValue)},
-		},
+// Exact ISBN lookup using GSI. Books are saved under their canonical ISBN-13,
+// so an ISBN-10 or a hyphenated number finds the same record; books saved
+// before ISBNs were normalized are still found by the number as given.
+func searchByISBN(isbnValue string) ([]models.BookData, error) {
+	candidates := []string{isbn.Normalize(isbnValue), isbnValue}
+	if isbn13, err := isbn.Parse(isbnValue); err == nil {
+		candidates = append([]string{isbn13}, candidates...)
 	}
 
-	result, err := svc.Query(input)
-	if err != nil {
-		return nil, err
-	}
-
-	var books []BookData
-	if err := dynamodbattribute.UnmarshalListOfMaps(result.Items, &books); err != nil {
-		return nil, err
+	seen := make(map[string]bool)
+	for _, candidate := range candidates {
+		if candidate == "" || seen[candidate] {
+			continue
+		}
+		seen[candidate] = true
+		books, err := stores.Books.QueryByISBN(candidate)
+		if err != nil || len(books) > 0 {
+			return books, err
+		}
 	}
-
-	return books, nil
+	return nil, nil
 }
 
 // Exact bookId lookup using primary key
-func searchByBookId(svc *dynamodb.DynamoDB, bookId string) ([]BookData, error) {
-	input := &dynamodb.GetItemInput{
-		TableName: aws.String(BOOKS_TABLE_NAME),
-		Key: map[string]*dynamodb.AttributeValue{
-			"bookId": {S: aws.String(bookId)},
-		},
+func searchByBookId(bookId string) ([]models.BookData, error) {
+	book, err := stores.Books.Get(bookId)
+	if errors.Is(err, store.ErrNotFound) {
+		return []models.BookData{}, nil // Return empty slice instead of error
 	}
-
-	result, err := svc.GetItem(input)
 	if err != nil {
 		return nil, err
 	}
-
-	// Check if the item was found
-	if result.Item == nil || len(result.Item) == 0 {
-		return []BookData{}, nil // Return empty slice instead of error
-	}
-
-	var book BookData
-	if err := dynamodbattribute.UnmarshalMap(result.Item, &book); err != nil {
-		return nil, err
-	}
-
-	return []BookData{book}, nil
+	return []models.BookData{*book}, nil
 }
 
 // Search for books by OpenLibraryId
-func searchByOpenLibraryId(svc *dynamodb.DynamoDB, openLibraryId string) ([]BookData, error) {
+func searchByOpenLibraryId(openLibraryId string) ([]models.BookData, error) {
 	log.Printf("Searching for book with OpenLibrary ID: %s", openLibraryId)
 
-	// Try two approaches:
-	// 1. First, look for the OpenLibraryId in dedicated field
-	// 2. As fallback, check for tag with OpenLibrary prefix
-
-	// Build filter expression for scan: "openLibraryId = :olid OR contains(tags, :tagPrefix)"
-	olTagPrefix := "OpenLibrary:" + openLibraryId
-
-	// First try with the dedicated field
-	filt := expression.Equal(expression.Name("openLibraryId"), expression.Value(openLibraryId))
-
-	expr, err := expression.NewBuilder().WithFilter(filt).Build()
-	if err != nil {
-		return nil, err
-	}
-
-	scanInput := &dynamodb.ScanInput{
-		TableName:                 aws.String(BOOKS_TABLE_NAME),
-		FilterExpression:          expr.Filter(),
-		ExpressionAttributeNames:  expr.Names(),
-		ExpressionAttributeValues: expr.Values(),
-	}
-
-	result, err := svc.Scan(scanInput)
-	if err != nil {
-		return nil, err
-	}
-
-	var books []BookData
-	if err := dynamodbattribute.UnmarshalListOfMaps(result.Items, &books); err != nil {
-		return nil, err
-	}
-
-	// If we found books, return them
-	if len(books) > 0 {
-		log.Printf("Found book with OpenLibrary ID %s using openLibraryId field", openLibraryId)
-		return []BookData{books[0]}, nil
-	}
-
-	// Otherwise, try the tag-based approach as fallback
-	log.Printf("No book found with openLibraryId field, trying tag-based search for: %s", olTagPrefix)
-
-	// Build filter to search in tags array
-	tagFilt := expression.Contains(expression.Name("tags"), olTagPrefix)
-
-	tagExpr, err := expression.NewBuilder().WithFilter(tagFilt).Build()
+	books, err := stores.Books.QueryByOpenLibraryID(openLibraryId)
 	if err != nil {
 		return nil, err
 	}
-
-	tagScanInput := &dynamodb.ScanInput{
-		TableName:                 aws.String(BOOKS_TABLE_NAME),
-		FilterExpression:          tagExpr.Filter(),
-		ExpressionAttributeNames:  tagExpr.Names(),
-		ExpressionAttributeValues: tagExpr.Values(),
-	}
-
-	tagResult, err := svc.Scan(tagScanInput)
-	if err != nil {
-		return nil, err
-	}
-
-	var tagBooks []BookData
-	if err := dynamodbattribute.UnmarshalListOfMaps(tagResult.Items, &tagBooks); err != nil {
-		return nil, err
-	}
-
-	if len(tagBooks) == 0 {
+	if len(books) == 0 {
 		return nil, fmt.Errorf("no book found with OpenLibrary ID: %s", openLibraryId)
 	}
 
-	log.Printf("Found book with OpenLibrary ID %s using tags search", openLibraryId)
-	return []BookData{tagBooks[0]}, nil
-}
-
-// Partial match on 'titleLowercase' with "contains(...)"
-func searchByPartialTitle(svc *dynamodb.DynamoDB, query string) ([]BookData, error) {
-	lowerQuery := strings.ToLower(query)
-	// Build filter expression: "contains(titleLowercase, :q)"
-	filt := expression.Contains(expression.Name("titleLowercase"), lowerQuery)
-
-	expr, err := expression.NewBuilder().WithFilter(filt).Build()
-	if err != nil {
-		return nil, err
-	}
-
-	input := &dynamodb.ScanInput{
-		TableName:                 aws.String(BOOKS_TABLE_NAME),
-		FilterExpression:          expr.Filter(),
-		ExpressionAttributeNames:  expr.Names(),
-		ExpressionAttributeValues: expr.Values(),
-	}
-
-	result, err := svc.Scan(input)
-	if err != nil {
-		return nil, err
-	}
-
-	var books []BookData
-	if err := dynamodbattribute.UnmarshalListOfMaps(result.Items, &books); err != nil {
-		return nil, err
-	}
-
-	return books, nil
+	log.Printf("Found book with OpenLibrary ID %s", openLibraryId)
+	return []models.BookData{books[0]}, nil
 }
@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/FriedGlue/BookIt/api/pkg/shared"
+	"github.com/FriedGlue/BookIt/api/pkg/store"
+	"github.com/FriedGlue/BookIt/api/pkg/validation"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// BulkListRequest is the body of POST /list/bulk. The operations all go
+// into one profile write, so there can be at most 500, and the reading log
+// entries of those starting or stopping a book go into the same write, so
+// at most store.MaxLogEntriesPerUpdate of them can.
+type BulkListRequest struct {
+	Operations []BulkListOperation `json:"operations" validate:"required,max=500"`
+}
+
+func (r BulkListRequest) Validate() []validation.FieldError {
+	var errs []validation.FieldError
+	logged := 0
+	for i, op := range r.Operations {
+		for _, err := range validation.Struct(op) {
+			err.Field = fmt.Sprintf("operations[%d].%s", i, err.Field)
+			errs = append(errs, err)
+		}
+		if op.logged() {
+			logged++
+		}
+	}
+	if logged > store.MaxLogEntriesPerUpdate {
+		errs = append(errs, validation.FieldError{Field: "operations", Message: fmt.Sprintf("must have at most %d that start or stop reading a book", store.MaxLogEntriesPerUpdate)})
+	}
+	return errs
+}
+
+// BulkListOperation is one operation of a bulk request:
+//
+//	add     puts the saved book bookId on listType, unless it is already there
+//	remove  takes it off listType, which may be currentlyReading
+//	move    moves it from fromList to toList, like POST /list/move
+//	tag     puts it on each of shelves it is not on yet, keeping it where it is
+type BulkListOperation struct {
+	Op       string   `json:"op" validate:"required,oneof=add remove move tag"`
+	BookID   string   `json:"bookId" validate:"required"`
+	ListType string   `json:"listType,omitempty"`                      // add and remove
+	FromList string   `json:"fromList,omitempty"`                      // move
+	ToList   string   `json:"toList,omitempty"`                        // move
+	Shelves  []string `json:"shelves,omitempty" validate:"max=20"`     // tag
+	Rating   int      `json:"rating,omitempty" validate:"min=1,max=5"` // only when adding or moving to the read list
+	Review   string   `json:"review,omitempty"`                        // only when adding or moving to the read list
+}
+
+func (o BulkListOperation) Validate() []validation.FieldError {
+	var errs []validation.FieldError
+	switch o.Op {
+	case "add", "remove":
+		if o.ListType == "" {
+			errs = append(errs, validation.FieldError{Field: "listType", Message: "is required for " + o.Op})
+		} else if o.Op == "add" && o.ListType == "currentlyReading" {
+			errs = append(errs, validation.FieldError{Field: "listType", Message: "must not be currentlyReading for add; move the book there instead"})
+		}
+	case "move":
+		if o.FromList == "" || o.ToList == "" {
+			errs = append(errs, validation.FieldError{Field: "toList", Message: "fromList and toList are required for move"})
+		} else if o.FromList == o.ToList {
+			errs = append(errs, validation.FieldError{Field: "toList", Message: "must differ from fromList"})
+		}
+	case "tag":
+		if len(o.Shelves) == 0 {
+			errs = append(errs, validation.FieldError{Field: "shelves", Message: "is required for tag"})
+		}
+		for _, shelf := range o.Shelves {
+			if strings.TrimSpace(shelf) == "" || isBuiltInList(shelf) {
+				errs = append(errs, validation.FieldError{Field: "shelves", Message: "must only name custom shelves"})
+				break
+			}
+		}
+	}
+
+	toRead := (o.Op == "add" && o.ListType == "read") || (o.Op == "move" && o.ToList == "read")
+	if o.Rating != 0 && !toRead {
+		errs = append(errs, validation.FieldError{Field: "rating", Message: "may only be given when adding or moving to read"})
+	}
+	if o.Review != "" && !toRead {
+		errs = append(errs, validation.FieldError{Field: "review", Message: "may only be given when adding or moving to read"})
+	}
+	return errs
+}
+
+// logged reports whether the operation makes a reading log entry when it
+// applies, which only moving a book into or out of currently reading does.
+func (o BulkListOperation) logged() bool {
+	switch o.Op {
+	case "remove":
+		return o.ListType == "currentlyReading"
+	case "move":
+		return o.FromList == "currentlyReading" || o.ToList == "currentlyReading"
+	}
+	return false
+}
+
+// BulkListResponse reports the outcome of each operation, in request order.
+type BulkListResponse struct {
+	Applied int              `json:"applied"`
+	Failed  int              `json:"failed"`
+	Results []BulkListResult `json:"results"`
+}
+
+// BulkListResult is the outcome of one operation. A failed operation
+// changes nothing, and the operations after it still apply.
+type BulkListResult struct {
+	Index int              `json:"index"`
+	OK    bool             `json:"ok"`
+	Error *shared.APIError `json:"error,omitempty"`
+}
+
+// BulkUpdateLists applies a batch of add, remove, move and tag operations
+// to the caller's lists in one profile write, in order, so a multi-select
+// action on hundreds of books costs one write instead of hundreds. Each
+// operation succeeds or fails on its own.
+func BulkUpdateLists(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	log.Println("BulkUpdateLists invoked")
+	userId := shared.UserID(request)
+
+	var bulkReq BulkListRequest
+	if apiErr := validation.Decode(request.Body, &bulkReq); apiErr != nil {
+		return shared.ErrorResponse(apiErr)
+	}
+
+	books, err := bulkBooks(bulkReq.Operations)
+	if err != nil {
+		return internalErrorResponse("Error loading books", err)
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	var response BulkListResponse
+	_, _, err = updateListsWithLog(userId, func(profile *models.Profile) ([]models.ReadingLogItem, error) {
+		// The update runs again after a conflicting write, so start over
+		response = BulkListResponse{Results: make([]BulkListResult, 0, len(bulkReq.Operations))}
+		var logEntries []models.ReadingLogItem
+
+		for i, op := range bulkReq.Operations {
+			logEntry, err := applyBulkOperation(profile, op, books, now)
+			result := BulkListResult{Index: i, OK: err == nil}
+			if err != nil {
+				var apiErr *shared.APIError
+				if !errors.As(err, &apiErr) {
+					return nil, err
+				}
+				result.Error = apiErr
+				response.Failed++
+			} else {
+				response.Applied++
+			}
+			if logEntry != nil {
+				logEntries = append(logEntries, *logEntry)
+			}
+			response.Results = append(response.Results, result)
+		}
+		if len(logEntries) == 0 {
+			return nil, nil
+		}
+		return logEntries, refreshChallenges(profile, logEntries...)
+	})
+	if err != nil {
+		return storeErrorResponse(err, shared.CodeProfileNotFound, "Profile not found")
+	}
+
+	log.Printf("Bulk list update for user %s: %d applied, %d failed\n", userId, response.Applied, response.Failed)
+	return shared.SuccessResponse(200, response)
+}
+
+// bulkBooks loads the saved books that adding, tagging and starting to read
+// need, keyed by ID.
+func bulkBooks(operations []BulkListOperation) (map[string]*models.BookData, error) {
+	var ids []string
+	for _, op := range operations {
+		if op.Op == "add" || op.Op == "tag" || (op.Op == "move" && op.ToList == "currentlyReading") {
+			if !slices.Contains(ids, op.BookID) {
+				ids = append(ids, op.BookID)
+			}
+		}
+	}
+	books := make(map[string]*models.BookData, len(ids))
+	if len(ids) == 0 {
+		return books, nil
+	}
+	found, err := stores.Books.GetMany(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range found {
+		books[found[i].BookID] = &found[i]
+	}
+	return books, nil
+}
+
+// applyBulkOperation applies one operation to the profile, leaving it
+// untouched if the operation fails, and returns the reading log entry it
+// calls for, if any.
+func applyBulkOperation(profile *models.Profile, op BulkListOperation, books map[string]*models.BookData, now string) (*models.ReadingLogItem, error) {
+	bookNotFound := shared.NewError(shared.CodeBookNotFound, fmt.Sprintf("No book found with ID: %s", op.BookID))
+	switch op.Op {
+	case "add":
+		book := books[op.BookID]
+		if book == nil {
+			return nil, bookNotFound
+		}
+		return nil, addToList(profile, op.ListType, book, op.Rating, op.Review, now)
+	case "remove":
+		entry, err := takeFromList(profile, op.ListType, op.BookID)
+		if err != nil {
+			return nil, err
+		}
+		if op.ListType == "currentlyReading" {
+			return entry.readingLogItem(now, entry.progress.LastPageRead, "Book Removed"), nil
+		}
+		return nil, nil
+	case "move":
+		return moveEntry(profile, op.FromList, op.ToList, op.BookID, books[op.BookID], op.Rating, op.Review, now)
+	case "tag":
+		book := books[op.BookID]
+		if book == nil {
+			return nil, bookNotFound
+		}
+		// Check every shelf first, so a smart shelf among them changes nothing
+		for _, shelf := range op.Shelves {
+			var apiErr *shared.APIError
+			if err := checkPutInList(profile, shelf, op.BookID); err != nil && !(errors.As(err, &apiErr) && apiErr.Code == shared.CodeBookAlreadyInList) {
+				return nil, err
+			}
+		}
+		for _, shelf := range op.Shelves {
+			// Only the book already being there is left to fail on
+			if checkPutInList(profile, shelf, op.BookID) != nil {
+				continue
+			}
+			if err := addToList(profile, shelf, book, 0, "", now); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	}
+	return nil, shared.NewError(shared.CodeInvalidParameter, fmt.Sprintf("Unknown operation %s", op.Op))
+}
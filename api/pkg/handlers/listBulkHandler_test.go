@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/FriedGlue/BookIt/api/pkg/shared"
+	"github.com/FriedGlue/BookIt/api/pkg/store"
+	"github.com/FriedGlue/BookIt/api/pkg/validation"
+)
+
+func TestBulkUpdateLists(t *testing.T) {
+	tests := []struct {
+		name        string
+		ops         []BulkListOperation
+		wantCode    shared.ErrorCode   // of the whole request
+		wantErrors  []shared.ErrorCode // of each operation, "" when it applied
+		wantTBR     []string
+		wantRead    []string
+		wantReading []string
+		wantShelf   []string // Holiday
+	}{
+		{
+			name:       "add",
+			ops:        []BulkListOperation{{Op: "add", BookID: "b", ListType: "toBeRead"}, {Op: "add", BookID: "c", ListType: "read", Rating: 5}},
+			wantErrors: []shared.ErrorCode{"", ""},
+			wantTBR:    []string{"a", "b"}, wantRead: []string{"c"}, wantReading: []string{}, wantShelf: []string{"a"},
+		},
+		{
+			name:       "add twice",
+			ops:        []BulkListOperation{{Op: "add", BookID: "b", ListType: "toBeRead"}, {Op: "add", BookID: "b", ListType: "toBeRead"}},
+			wantErrors: []shared.ErrorCode{"", shared.CodeBookAlreadyInList},
+			wantTBR:    []string{"a", "b"}, wantRead: []string{}, wantReading: []string{}, wantShelf: []string{"a"},
+		},
+		{
+			name:       "add a book already on the list",
+			ops:        []BulkListOperation{{Op: "add", BookID: "a", ListType: "Holiday"}, {Op: "add", BookID: "b", ListType: "Holiday"}},
+			wantErrors: []shared.ErrorCode{shared.CodeBookAlreadyInList, ""},
+			wantTBR:    []string{"a"}, wantRead: []string{}, wantReading: []string{}, wantShelf: []string{"a", "b"},
+		},
+		{
+			name:       "add an unsaved book",
+			ops:        []BulkListOperation{{Op: "add", BookID: "unsaved", ListType: "toBeRead"}},
+			wantErrors: []shared.ErrorCode{shared.CodeBookNotFound},
+			wantTBR:    []string{"a"}, wantRead: []string{}, wantReading: []string{}, wantShelf: []string{"a"},
+		},
+		{
+			name: "remove then move the same book",
+			ops: []BulkListOperation{
+				{Op: "remove", BookID: "a", ListType: "toBeRead"},
+				{Op: "move", BookID: "a", FromList: "toBeRead", ToList: "read"},
+				{Op: "remove", BookID: "a", ListType: "Holiday"},
+			},
+			wantErrors: []shared.ErrorCode{"", shared.CodeListItemNotFound, ""},
+			wantTBR:    []string{}, wantRead: []string{}, wantReading: []string{}, wantShelf: []string{},
+		},
+		{
+			name:       "move to currently reading",
+			ops:        []BulkListOperation{{Op: "move", BookID: "a", FromList: "toBeRead", ToList: "currentlyReading"}},
+			wantErrors: []shared.ErrorCode{""},
+			wantTBR:    []string{}, wantRead: []string{}, wantReading: []string{"a"}, wantShelf: []string{"a"},
+		},
+		{
+			name:       "tag onto shelves already holding the book",
+			ops:        []BulkListOperation{{Op: "tag", BookID: "a", Shelves: []string{"Holiday", "Beach"}}},
+			wantErrors: []shared.ErrorCode{""},
+			wantTBR:    []string{"a"}, wantRead: []string{}, wantReading: []string{}, wantShelf: []string{"a"},
+		},
+		{
+			name:       "tag onto a smart shelf",
+			ops:        []BulkListOperation{{Op: "tag", BookID: "b", Shelves: []string{"Holiday", "Smart"}}},
+			wantErrors: []shared.ErrorCode{shared.CodeInvalidParameter},
+			wantTBR:    []string{"a"}, wantRead: []string{}, wantReading: []string{}, wantShelf: []string{"a"},
+		},
+		{
+			name:     "add to currently reading",
+			ops:      []BulkListOperation{{Op: "add", BookID: "b", ListType: "currentlyReading"}},
+			wantCode: shared.CodeValidationFailed,
+		},
+		{
+			name:     "too many reading changes",
+			ops:      slices.Repeat([]BulkListOperation{{Op: "remove", BookID: "a", ListType: "currentlyReading"}}, store.MaxLogEntriesPerUpdate+1),
+			wantCode: shared.CodeValidationFailed,
+		},
+		{
+			name:     "tag onto a built-in list",
+			ops:      []BulkListOperation{{Op: "tag", BookID: "b", Shelves: []string{"read"}}},
+			wantCode: shared.CodeValidationFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configureTestStores(t, models.Profile{Lists: models.UserLists{
+				ToBeRead: []models.ToBeReadItem{{BookID: "a"}},
+				Shelves: []models.Shelf{
+					{ShelfID: "s1", Name: "Holiday", Items: []models.CustomListItem{{BookID: "a"}}},
+					{ShelfID: "s2", Name: "Smart", Rules: &models.ShelfRules{Match: models.MatchAll,
+						Conditions: []models.ShelfRule{{Field: models.RatingField, Op: models.AtLeast, Number: 4}}}, Items: []models.CustomListItem{}},
+				},
+			}},
+				models.BookData{BookID: "a", Title: "A"},
+				models.BookData{BookID: "b", Title: "B"},
+				models.BookData{BookID: "c", Title: "C"},
+			)
+
+			response := BulkUpdateLists(testRequest(t, BulkListRequest{Operations: tt.ops}))
+			if code := errorCode(t, response); code != tt.wantCode {
+				t.Fatalf("BulkUpdateLists returned %s (%s), want %q", code, response.Body, tt.wantCode)
+			}
+			if tt.wantCode != "" {
+				return
+			}
+
+			var body BulkListResponse
+			if err := json.Unmarshal([]byte(response.Body), &body); err != nil {
+				t.Fatal(err)
+			}
+			var codes []shared.ErrorCode
+			applied := 0
+			for i, result := range body.Results {
+				if result.Index != i {
+					t.Errorf("result %d has index %d", i, result.Index)
+				}
+				if result.Error != nil {
+					codes = append(codes, result.Error.Code)
+				} else {
+					codes = append(codes, "")
+					applied++
+				}
+			}
+			if !slices.Equal(codes, tt.wantErrors) {
+				t.Errorf("operation errors = %v, want %v", codes, tt.wantErrors)
+			}
+			if body.Applied != applied || body.Failed != len(codes)-applied {
+				t.Errorf("applied %d and failed %d, want %d and %d", body.Applied, body.Failed, applied, len(codes)-applied)
+			}
+
+			profile := testProfile(t)
+			if got := toBeReadIDs(t, profile); !slices.Equal(got, tt.wantTBR) {
+				t.Errorf("to be read = %v, want %v", got, tt.wantTBR)
+			}
+			if got := readIDs(t, profile); !slices.Equal(got, tt.wantRead) {
+				t.Errorf("read = %v, want %v", got, tt.wantRead)
+			}
+			if got := readingIDs(profile); !slices.Equal(got, tt.wantReading) {
+				t.Errorf("currently reading = %v, want %v", got, tt.wantReading)
+			}
+			if got := shelfIDs(t, profile, "Holiday"); !slices.Equal(got, tt.wantShelf) {
+				t.Errorf("Holiday = %v, want %v", got, tt.wantShelf)
+			}
+		})
+	}
+}
+
+func TestBulkListOperationValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		op   BulkListOperation
+		want []string // fields with errors
+	}{
+		{name: "add", op: BulkListOperation{Op: "add", BookID: "a", ListType: "read", Rating: 4, Review: "Good"}},
+		{name: "add without a list", op: BulkListOperation{Op: "add", BookID: "a"}, want: []string{"listType"}},
+		{
+			name: "add to currently reading with a rating and review",
+			op:   BulkListOperation{Op: "add", BookID: "a", ListType: "currentlyReading", Rating: 4, Review: "Good"},
+			want: []string{"listType", "rating", "review"},
+		},
+		{name: "move to the same list", op: BulkListOperation{Op: "move", BookID: "a", FromList: "read", ToList: "read"}, want: []string{"toList"}},
+		{
+			name: "tag without a book onto built-in lists",
+			op:   BulkListOperation{Op: "tag", Shelves: []string{"read", "toBeRead"}, Rating: 6},
+			want: []string{"bookId", "rating", "shelves", "rating"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fields []string
+			for _, err := range validation.Struct(tt.op) {
+				fields = append(fields, err.Field)
+			}
+			if !slices.Equal(fields, tt.want) {
+				t.Errorf("errors on %v, want %v", fields, tt.want)
+			}
+		})
+	}
+}
@@ -1,641 +1,863 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/FriedGlue/BookIt/api/pkg/pagination"
 	"github.com/FriedGlue/BookIt/api/pkg/shared"
+	"github.com/FriedGlue/BookIt/api/pkg/store"
+	"github.com/FriedGlue/BookIt/api/pkg/validation"
 	"github.com/aws/aws-lambda-go/events"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 )
 
 // Request structs
 type AddToListRequest struct {
-	ListType  string `json:"listType"` // "toBeRead", "read", or custom list name
-	BookID    string `json:"bookId"`
-	Rating    int    `json:"rating,omitempty"`    // Only for read list
-	Review    string `json:"review,omitempty"`    // Only for read list
-	Thumbnail string `json:"thumbnail,omitempty"` // For toBeRead and custom lists
+	ListType  string `json:"listType" validate:"required"` // "toBeRead", "read", or a shelf ID or name
+	BookID    string `json:"bookId" validate:"required"`
+	Rating    int    `json:"rating,omitempty" validate:"min=1,max=5"` // Only for read list
+	Review    string `json:"review,omitempty"`                        // Only for read list
+	Thumbnail string `json:"thumbnail,omitempty"`                     // For toBeRead and custom lists
 }
 
 type UpdateListItemRequest struct {
-	ListType string `json:"listType"`
-	BookID   string `json:"bookId"`
-	Rating   int    `json:"rating,omitempty"`
+	ListType string `json:"listType" validate:"required"`
+	BookID   string `json:"bookId" validate:"required"`
+	Rating   *int   `json:"rating,omitempty" validate:"min=1,max=5"` // left as it is when not sent
 	Review   string `json:"review,omitempty"`
-	Order    int    `json:"order,omitempty"`
+	Order    *int   `json:"order,omitempty"` // moves the book to this index, like PUT /list/order
 }
 
-// GetList retrieves specific lists (toBeRead, read, or custom) from the Profile, or all lists if no type is provided
+func (r UpdateListItemRequest) Validate() []validation.FieldError {
+	if r.Order != nil && *r.Order < 0 {
+		return []validation.FieldError{{Field: "order", Message: "must be at least 0"}}
+	}
+	return nil
+}
+
+// ReorderListRequest is the body of PUT /list/order. It either moves one
+// book to a new index, with bookId and position, or gives the whole list's
+// new order in bookIds.
+type ReorderListRequest struct {
+	ListType string   `json:"listType" validate:"required"` // "toBeRead", "read", or a shelf ID or name
+	BookID   string   `json:"bookId,omitempty"`
+	Position *int     `json:"position,omitempty"` // past the end moves the book to the end
+	BookIDs  []string `json:"bookIds,omitempty" validate:"max=5000"`
+}
+
+func (r ReorderListRequest) Validate() []validation.FieldError {
+	switch {
+	case r.BookIDs != nil && (r.BookID != "" || r.Position != nil):
+		return []validation.FieldError{{Field: "bookIds", Message: "must not be given with bookId and position"}}
+	case r.BookIDs != nil:
+		return nil
+	case r.BookID == "":
+		return []validation.FieldError{{Field: "bookId", Message: "bookId and position, or bookIds, are required"}}
+	case r.Position == nil:
+		return []validation.FieldError{{Field: "position", Message: "is required with bookId"}}
+	case *r.Position < 0:
+		return []validation.FieldError{{Field: "position", Message: "must be at least 0"}}
+	}
+	return nil
+}
+
+// MoveListItemRequest is the body of POST /list/move. The lists are
+// "toBeRead", "read", "currentlyReading" or a shelf ID or name.
+type MoveListItemRequest struct {
+	FromList string `json:"fromList" validate:"required"`
+	ToList   string `json:"toList" validate:"required"`
+	BookID   string `json:"bookId" validate:"required"`
+	Rating   int    `json:"rating,omitempty" validate:"min=1,max=5"` // only when moving to the read list
+	Review   string `json:"review,omitempty"`                        // only when moving to the read list
+}
+
+func (r MoveListItemRequest) Validate() []validation.FieldError {
+	if r.FromList != "" && r.FromList == r.ToList {
+		return []validation.FieldError{{Field: "toList", Message: "must differ from fromList"}}
+	}
+	if (r.Rating != 0 || r.Review != "") && r.ToList != "read" {
+		return []validation.FieldError{{Field: "toList", Message: "must be read to give a rating or review"}}
+	}
+	return nil
+}
+
+// AllListsResponse is returned by GET /list when no listType is given. Each
+// list holds its first page, with custom lists keyed by shelf name, and
+// Shelves describes the shelves; NextCursors gives, for each list with more,
+// the cursor to fetch the rest with ?listType=.
+type AllListsResponse struct {
+	ToBeRead    []models.ToBeReadItem              `json:"toBeRead"`
+	Read        []models.ReadItem                  `json:"read"`
+	Custom      map[string][]models.CustomListItem `json:"customLists"`
+	Shelves     []ShelfResponse                    `json:"shelves"`
+	NextCursors map[string]string                  `json:"nextCursors,omitempty"`
+}
+
+// GetList retrieves specific lists (toBeRead, read, or custom) from the Profile, or all lists if no type is provided.
+// Lists come a page at a time in their display order, which for a shelf is its default sort.
 func GetList(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
 	log.Println("GetList invoked")
-	userId, err := shared.GetUserIDFromToken(request)
-	if err != nil {
-		log.Printf("Error extracting userId: %v\n", err)
-		return shared.ErrorResponse(401, err.Error())
-	}
+	userId := shared.UserID(request)
 
 	listType := request.QueryStringParameters["listType"]
-
-	svc := shared.DynamoDBClient()
-	input := &dynamodb.GetItemInput{
-		TableName: aws.String(PROFILES_TABLE_NAME),
-		Key: map[string]*dynamodb.AttributeValue{
-			"_id": {S: aws.String(userId)},
-		},
+	page, apiErr := pageRequest(request, pagination.DefaultLimit, pagination.MaxLimit)
+	if apiErr != nil {
+		return shared.ErrorResponse(apiErr)
 	}
 
-	result, err := svc.GetItem(input)
+	profile, err := stores.Profiles.Get(userId)
 	if err != nil {
-		log.Printf("DynamoDB GetItem error: %v\n", err)
-		return shared.ErrorResponse(500, fmt.Sprintf("DynamoDB GetItem error: %v", err))
+		return storeErrorResponse(err, shared.CodeProfileNotFound, "Profile not found")
 	}
-	if result.Item == nil {
-		return shared.ErrorResponse(404, "Profile not found")
+	// Profiles not yet repaired may have duplicate or missing Orders, and
+	// custom lists not yet moved to shelves
+	profile.Lists.Normalize()
+	if err := fillSmartShelves(userId, profile); err != nil {
+		return internalErrorResponse("Error evaluating smart shelves", err)
 	}
 
-	var profile models.Profile
-	if err := dynamodbattribute.UnmarshalMap(result.Item, &profile); err != nil {
-		log.Printf("Error unmarshalling profile: %v\n", err)
-		return shared.ErrorResponse(500, "Error unmarshalling profile: "+err.Error())
-	}
-
-	var responseBody []byte
-	if listType == "" {
-		// If no listType is provided, return all lists
-		allLists := struct {
-			ToBeRead []models.ToBeReadItem              `json:"toBeRead"`
-			Read     []models.ReadItem                  `json:"read"`
-			Custom   map[string][]models.CustomListItem `json:"customLists"`
-		}{
-			ToBeRead: profile.Lists.ToBeRead,
-			Read:     profile.Lists.Read,
-			Custom:   profile.Lists.CustomLists,
+	switch listType {
+	case "":
+		// If no listType is provided, return the first page of every list
+		allLists := AllListsResponse{
+			Custom:      make(map[string][]models.CustomListItem),
+			Shelves:     make([]ShelfResponse, 0, len(profile.Lists.Shelves)),
+			NextCursors: make(map[string]string),
 		}
-		responseBody, err = json.Marshal(allLists)
-	} else {
-		switch listType {
-		case "toBeRead":
-			responseBody, err = json.Marshal(profile.Lists.ToBeRead)
-		case "read":
-			responseBody, err = json.Marshal(profile.Lists.Read)
-		default:
-			if customList, exists := profile.Lists.CustomLists[listType]; exists {
-				responseBody, err = json.Marshal(customList)
-			} else {
-				return shared.ErrorResponse(404, "List not found")
-			}
+		var next string
+		allLists.ToBeRead, next = pagination.SliceAfterID(profile.Lists.ToBeRead, page, toBeReadKey, toBeReadID)
+		addNextCursor(allLists.NextCursors, "toBeRead", next)
+		allLists.Read, next = pagination.SliceAfterID(profile.Lists.Read, page, readKey, readID)
+		addNextCursor(allLists.NextCursors, "read", next)
+		for _, shelf := range profile.Lists.Shelves {
+			allLists.Shelves = append(allLists.Shelves, newShelfResponse(shelf))
+			allLists.Custom[shelf.Name], next = pagination.SliceAfterID(shelf.Items, page, shelfKey(shelf.DefaultSort), customListID)
+			addNextCursor(allLists.NextCursors, shelf.Name, next)
+		}
+		return shared.SuccessResponse(200, allLists)
+	case "toBeRead":
+		items, next := pagination.SliceAfterID(profile.Lists.ToBeRead, page, toBeReadKey, toBeReadID)
+		return shared.PageResponse(200, items, pagination.Encode(next))
+	case "read":
+		items, next := pagination.SliceAfterID(profile.Lists.Read, page, readKey, readID)
+		return shared.PageResponse(200, items, pagination.Encode(next))
+	default:
+		shelf := profile.Lists.Shelf(listType)
+		if shelf == nil {
+			return shared.Error(shared.CodeListNotFound, "List not found")
 		}
+		items, next := pagination.SliceAfterID(shelf.Items, page, shelfKey(shelf.DefaultSort), customListID)
+		return shared.PageResponse(200, items, pagination.Encode(next))
 	}
+}
 
-	if err != nil {
-		log.Printf("Error marshalling response: %v\n", err)
-		return shared.ErrorResponse(500, "Error marshalling response")
-	}
+// List items are paged in display order, with ties broken by book ID. The
+// order changes as books are moved and removed, so cursors resume after the
+// last book returned, found by its ID.
+func toBeReadKey(item models.ToBeReadItem) string {
+	return pagination.OrderKey(item.Order, item.BookID)
+}
+
+func readKey(item models.ReadItem) string {
+	return pagination.OrderKey(item.Order, item.BookID)
+}
 
-	return events.APIGatewayProxyResponse{
-		StatusCode: 200,
-		Body:       string(responseBody),
+func customListKey(item models.CustomListItem) string {
+	return pagination.OrderKey(item.Order, item.BookID)
+}
+
+func toBeReadID(item models.ToBeReadItem) string { return item.BookID }
+
+func readID(item models.ReadItem) string { return item.BookID }
+
+func customListID(item models.CustomListItem) string { return item.BookID }
+
+func addNextCursor(cursors map[string]string, listType, next string) {
+	if next != "" {
+		cursors[listType] = pagination.Encode(next)
 	}
 }
 
 // AddToList adds a book to a specific list (toBeRead, read, or custom)
 func AddToList(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
 	log.Println("AddToList invoked")
-	userId, err := shared.GetUserIDFromToken(request)
-	if err != nil {
-		log.Printf("Error extracting userId: %v\n", err)
-		return shared.ErrorResponse(401, err.Error())
-	}
+	userId := shared.UserID(request)
 
 	var addReq AddToListRequest
-	if err := json.Unmarshal([]byte(request.Body), &addReq); err != nil {
-		log.Printf("Invalid JSON: %v\n", err)
-		return shared.ErrorResponse(400, "Invalid JSON: "+err.Error())
+	if apiErr := validation.Decode(request.Body, &addReq); apiErr != nil {
+		return shared.ErrorResponse(apiErr)
 	}
 
 	// First get the book details from books table
-	svc := shared.DynamoDBClient()
-	bookInput := &dynamodb.GetItemInput{
-		TableName: aws.String(BOOKS_TABLE_NAME),
-		Key: map[string]*dynamodb.AttributeValue{
-			"bookId": {S: aws.String(addReq.BookID)},
-		},
-	}
-
-	bookResult, err := svc.GetItem(bookInput)
+	bookDetails, err := stores.Books.Get(addReq.BookID)
 	if err != nil {
-		log.Printf("DynamoDB GetItem error for book: %v\n", err)
-		return shared.ErrorResponse(500, fmt.Sprintf("DynamoDB GetItem error: %v", err))
-	}
-	if bookResult.Item == nil {
-		log.Println("Book not found")
-		return shared.ErrorResponse(404, "Book not found")
+		return storeErrorResponse(err, shared.CodeBookNotFound, "Book not found")
 	}
 
-	var bookDetails BookData
-	if err := dynamodbattribute.UnmarshalMap(bookResult.Item, &bookDetails); err != nil {
-		log.Printf("Error unmarshalling book details: %v\n", err)
-		return shared.ErrorResponse(500, "Error unmarshalling book details: "+err.Error())
-	}
-
-	// Get user profile
-	profileInput := &dynamodb.GetItemInput{
-		TableName: aws.String(PROFILES_TABLE_NAME),
-		Key: map[string]*dynamodb.AttributeValue{
-			"_id": {S: aws.String(userId)},
-		},
-	}
+	currentTime := time.Now().Format(time.RFC3339)
 
-	result, err := svc.GetItem(profileInput)
+	_, err = updateLists(userId, func(profile *models.Profile) error {
+		return addToList(profile, addReq.ListType, bookDetails, addReq.Rating, addReq.Review, currentTime)
+	})
 	if err != nil {
-		log.Printf("DynamoDB GetItem error: %v\n", err)
-		return shared.ErrorResponse(500, fmt.Sprintf("DynamoDB GetItem error: %v", err))
-	}
-	if result.Item == nil {
-		return shared.ErrorResponse(404, "Profile not found")
+		return storeErrorResponse(err, shared.CodeProfileNotFound, "Profile not found")
 	}
 
-	var profile models.Profile
-	if err := dynamodbattribute.UnmarshalMap(result.Item, &profile); err != nil {
-		log.Printf("Error unmarshalling profile: %v\n", err)
-		return shared.ErrorResponse(500, "Error unmarshalling profile: "+err.Error())
-	}
-
-	currentTime := time.Now().Format(time.RFC3339)
+	return shared.MessageResponse(201, "Book added to list successfully")
+}
 
-	switch addReq.ListType {
-	case "toBeRead":
-		item := models.ToBeReadItem{
-			BookID:    bookDetails.BookID,
-			Thumbnail: bookDetails.CoverImageURL,
-			AddedDate: currentTime,
-			Title:     bookDetails.Title,
-			Authors:   bookDetails.Authors,
-			Order:     len(profile.Lists.ToBeRead),
-		}
-		profile.Lists.ToBeRead = append(profile.Lists.ToBeRead, item)
-	case "read":
-		item := models.ReadItem{
-			BookID:        bookDetails.BookID,
-			CompletedDate: currentTime,
-			Thumbnail:     bookDetails.CoverImageURL,
-			Rating:        addReq.Rating,
-			Review:        addReq.Review,
-			Title:         bookDetails.Title,
-			Authors:       bookDetails.Authors,
-			Order:         len(profile.Lists.Read),
-		}
-		profile.Lists.Read = append(profile.Lists.Read, item)
-	default:
-		item := models.CustomListItem{
-			BookID:    bookDetails.BookID,
-			Thumbnail: bookDetails.CoverImageURL,
-			AddedDate: currentTime,
-			Title:     bookDetails.Title,
-			Authors:   bookDetails.Authors,
-			Order:     len(profile.Lists.CustomLists[addReq.ListType]),
+// MoveListItem moves a book from one list to another in a single profile
+// write, keeping the date it was added, its thumbnail, title and authors.
+// Moving a book into currently reading starts it and moving it out stops
+// it, with the same reading log entries as the currently reading
+// endpoints.
+func MoveListItem(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	log.Println("MoveListItem invoked")
+	userId := shared.UserID(request)
+
+	var moveReq MoveListItemRequest
+	if apiErr := validation.Decode(request.Body, &moveReq); apiErr != nil {
+		return shared.ErrorResponse(apiErr)
+	}
+
+	// Currently reading needs the page count, which only the book has
+	var bookDetails *models.BookData
+	if moveReq.ToList == "currentlyReading" {
+		book, err := stores.Books.Get(moveReq.BookID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return internalErrorResponse("Error loading book", err)
 		}
-		if profile.Lists.CustomLists == nil {
-			profile.Lists.CustomLists = make(map[string][]models.CustomListItem)
-		}
-		profile.Lists.CustomLists[addReq.ListType] = append(profile.Lists.CustomLists[addReq.ListType], item)
-	}
-
-	updatedProfile, err := dynamodbattribute.MarshalMap(profile)
-	if err != nil {
-		log.Printf("Error marshalling updated profile: %v\n", err)
-		return shared.ErrorResponse(500, "Error marshalling updated profile: "+err.Error())
-	}
-
-	putInput := &dynamodb.PutItemInput{
-		TableName: aws.String(PROFILES_TABLE_NAME),
-		Item:      updatedProfile,
+		bookDetails = book
 	}
 
-	_, err = svc.PutItem(putInput)
+	now := time.Now().Format(time.RFC3339)
+	_, _, err := updateListsWithLog(userId, func(profile *models.Profile) ([]models.ReadingLogItem, error) {
+		logEntry, err := moveEntry(profile, moveReq.FromList, moveReq.ToList, moveReq.BookID, bookDetails, moveReq.Rating, moveReq.Review, now)
+		if err != nil || logEntry == nil {
+			return nil, err
+		}
+		return []models.ReadingLogItem{*logEntry}, refreshChallenges(profile, *logEntry)
+	})
 	if err != nil {
-		log.Printf("DynamoDB PutItem error: %v\n", err)
-		return shared.ErrorResponse(500, fmt.Sprintf("DynamoDB PutItem error: %v", err))
+		return storeErrorResponse(err, shared.CodeProfileNotFound, "Profile not found")
 	}
 
-	return events.APIGatewayProxyResponse{
-		StatusCode: 201,
-		Body:       "Book added to list successfully",
-	}
+	log.Printf("Book %s moved from %s to %s for user %s\n", moveReq.BookID, moveReq.FromList, moveReq.ToList, userId)
+	return shared.MessageResponse(200, fmt.Sprintf("Book moved from %s to %s", moveReq.FromList, moveReq.ToList))
 }
 
 // UpdateListItem updates an item in a specific list
 func UpdateListItem(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
 	log.Println("UpdateListItem invoked")
-	userId, err := shared.GetUserIDFromToken(request)
-	if err != nil {
-		log.Printf("Error extracting userId: %v\n", err)
-		return shared.ErrorResponse(401, err.Error())
-	}
+	userId := shared.UserID(request)
 
 	var updateReq UpdateListItemRequest
-	if err := json.Unmarshal([]byte(request.Body), &updateReq); err != nil {
-		log.Printf("Invalid JSON: %v\n", err)
-		return shared.ErrorResponse(400, "Invalid JSON: "+err.Error())
-	}
-
-	svc := shared.DynamoDBClient()
-	input := &dynamodb.GetItemInput{
-		TableName: aws.String(PROFILES_TABLE_NAME),
-		Key: map[string]*dynamodb.AttributeValue{
-			"_id": {S: aws.String(userId)},
-		},
-	}
-
-	result, err := svc.GetItem(input)
-	if err != nil {
-		log.Printf("DynamoDB GetItem error: %v\n", err)
-		return shared.ErrorResponse(500, fmt.Sprintf("DynamoDB GetItem error: %v", err))
-	}
-	if result.Item == nil {
-		return shared.ErrorResponse(404, "Profile not found")
-	}
-
-	var profile models.Profile
-	if err := dynamodbattribute.UnmarshalMap(result.Item, &profile); err != nil {
-		log.Printf("Error unmarshalling profile: %v\n", err)
-		return shared.ErrorResponse(500, "Error unmarshalling profile: "+err.Error())
+	if apiErr := validation.Decode(request.Body, &updateReq); apiErr != nil {
+		return shared.ErrorResponse(apiErr)
 	}
 
-	found := false
-	switch updateReq.ListType {
-	case "toBeRead":
-		for i := range profile.Lists.ToBeRead {
-			if profile.Lists.ToBeRead[i].BookID == updateReq.BookID {
-				if updateReq.Order >= 0 {
-					profile.Lists.ToBeRead[i].Order = updateReq.Order
-				}
-				found = true
-				break
-			}
-		}
-	case "read":
-		for i := range profile.Lists.Read {
-			if profile.Lists.Read[i].BookID == updateReq.BookID {
-				if updateReq.Rating >= 0 {
-					profile.Lists.Read[i].Rating = updateReq.Rating
-				}
-				if updateReq.Review != "" {
-					profile.Lists.Read[i].Review = updateReq.Review
-				}
-				if updateReq.Order >= 0 {
-					profile.Lists.Read[i].Order = updateReq.Order
+	_, err := updateLists(userId, func(profile *models.Profile) error {
+		found := false
+		switch updateReq.ListType {
+		case "toBeRead":
+			for i := range profile.Lists.ToBeRead {
+				if profile.Lists.ToBeRead[i].BookID == updateReq.BookID {
+					found = true
+					break
 				}
-				found = true
-				break
 			}
-		}
-	default:
-		if customList, exists := profile.Lists.CustomLists[updateReq.ListType]; exists {
-			for i := range customList {
-				if customList[i].BookID == updateReq.BookID {
-					if updateReq.Order >= 0 {
-						customList[i].Order = updateReq.Order
+		case "read":
+			for i := range profile.Lists.Read {
+				if profile.Lists.Read[i].BookID == updateReq.BookID {
+					if updateReq.Rating != nil {
+						profile.Lists.Read[i].Rating = *updateReq.Rating
+					}
+					if updateReq.Review != "" {
+						profile.Lists.Read[i].Review = updateReq.Review
 					}
-					profile.Lists.CustomLists[updateReq.ListType] = customList
 					found = true
 					break
 				}
 			}
+		default:
+			if shelf := profile.Lists.Shelf(updateReq.ListType); shelf != nil {
+				for i := range shelf.Items {
+					if shelf.Items[i].BookID == updateReq.BookID {
+						found = true
+						break
+					}
+				}
+			}
 		}
-	}
 
-	if !found {
-		return shared.ErrorResponse(404, "Book not found in the specified list")
-	}
-
-	updatedProfile, err := dynamodbattribute.MarshalMap(profile)
+		if !found {
+			return shared.NewError(shared.CodeListItemNotFound, "Book not found in the specified list")
+		}
+		if updateReq.Order != nil {
+			return moveInList(profile, updateReq.ListType, updateReq.BookID, *updateReq.Order)
+		}
+		return nil
+	})
 	if err != nil {
-		log.Printf("Error marshalling updated profile: %v\n", err)
-		return shared.ErrorResponse(500, "Error marshalling updated profile: "+err.Error())
+		return storeErrorResponse(err, shared.CodeProfileNotFound, "Profile not found")
 	}
 
-	putInput := &dynamodb.PutItemInput{
-		TableName: aws.String(PROFILES_TABLE_NAME),
-		Item:      updatedProfile,
+	return shared.MessageResponse(200, "List item updated successfully")
+}
+
+// ReorderList moves one book of a list to a new index, or puts the whole
+// list in a given order, and renumbers the list so each book's Order is its
+// index.
+func ReorderList(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	log.Println("ReorderList invoked")
+	userId := shared.UserID(request)
+
+	var reorderReq ReorderListRequest
+	if apiErr := validation.Decode(request.Body, &reorderReq); apiErr != nil {
+		return shared.ErrorResponse(apiErr)
 	}
 
-	_, err = svc.PutItem(putInput)
+	profile, err := updateLists(userId, func(profile *models.Profile) error {
+		if reorderReq.BookIDs != nil {
+			return setListOrder(profile, reorderReq.ListType, reorderReq.BookIDs)
+		}
+		return moveInList(profile, reorderReq.ListType, reorderReq.BookID, *reorderReq.Position)
+	})
 	if err != nil {
-		log.Printf("DynamoDB PutItem error: %v\n", err)
-		return shared.ErrorResponse(500, fmt.Sprintf("DynamoDB PutItem error: %v", err))
+		return storeErrorResponse(err, shared.CodeProfileNotFound, "Profile not found")
 	}
 
-	return events.APIGatewayProxyResponse{
-		StatusCode: 200,
-		Body:       "List item updated successfully",
+	switch reorderReq.ListType {
+	case "toBeRead":
+		return shared.ListResponse(200, profile.Lists.ToBeRead)
+	case "read":
+		return shared.ListResponse(200, profile.Lists.Read)
+	default:
+		return shared.ListResponse(200, profile.Lists.Shelf(reorderReq.ListType).Items)
 	}
 }
 
-// DeleteList deletes a custom list from a user's profile
-func DeleteList(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
-	log.Println("DeleteList invoked")
-	userId, err := shared.GetUserIDFromToken(request)
-	if err != nil {
-		log.Printf("Error extracting userId: %v\n", err)
-		return shared.ErrorResponse(401, err.Error())
-	}
+// RemoveFromList removes a book from a specific list
+func DeleteListItem(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	log.Println("RemoveFromList invoked")
+	userId := shared.UserID(request)
 
-	listName := request.QueryStringParameters["listName"]
-	if listName == "" {
-		return shared.ErrorResponse(400, "listName parameter is required")
+	listType := request.QueryStringParameters["listType"]
+	bookId := request.QueryStringParameters["bookId"]
+	if listType == "" || bookId == "" {
+		return shared.Error(shared.CodeMissingParameter, "listType and bookId parameters are required")
 	}
 
-	svc := shared.DynamoDBClient()
-	input := &dynamodb.GetItemInput{
-		TableName: aws.String(PROFILES_TABLE_NAME),
-		Key: map[string]*dynamodb.AttributeValue{
-			"_id": {S: aws.String(userId)},
-		},
-	}
+	_, err := updateLists(userId, func(profile *models.Profile) error {
+		found := false
+		switch listType {
+		case "toBeRead":
+			for i, item := range profile.Lists.ToBeRead {
+				if item.BookID == bookId {
+					profile.Lists.ToBeRead = append(profile.Lists.ToBeRead[:i], profile.Lists.ToBeRead[i+1:]...)
+					found = true
+					break
+				}
+			}
+		case "read":
+			for i, item := range profile.Lists.Read {
+				if item.BookID == bookId {
+					profile.Lists.Read = append(profile.Lists.Read[:i], profile.Lists.Read[i+1:]...)
+					found = true
+					break
+				}
+			}
+		default:
+			if shelf := profile.Lists.Shelf(listType); shelf != nil {
+				for i, item := range shelf.Items {
+					if item.BookID == bookId {
+						shelf.Items = append(shelf.Items[:i], shelf.Items[i+1:]...)
+						found = true
+						break
+					}
+				}
+			}
+		}
 
-	result, err := svc.GetItem(input)
+		if !found {
+			return shared.NewError(shared.CodeListItemNotFound, "Book not found in the specified list")
+		}
+		return nil
+	})
 	if err != nil {
-		log.Printf("DynamoDB GetItem error: %v\n", err)
-		return shared.ErrorResponse(500, fmt.Sprintf("DynamoDB GetItem error: %v", err))
-	}
-	if result.Item == nil {
-		return shared.ErrorResponse(404, "Profile not found")
-	}
-
-	var profile models.Profile
-	if err := dynamodbattribute.UnmarshalMap(result.Item, &profile); err != nil {
-		log.Printf("Error unmarshalling profile: %v\n", err)
-		return shared.ErrorResponse(500, "Error unmarshalling profile: "+err.Error())
+		return storeErrorResponse(err, shared.CodeProfileNotFound, "Profile not found")
 	}
 
-	if _, exists := profile.Lists.CustomLists[listName]; !exists {
-		return shared.ErrorResponse(404, "List not found")
-	}
+	return shared.MessageResponse(200, "Book removed from list successfully")
+}
 
-	delete(profile.Lists.CustomLists, listName)
+// CreateCustomBookshelf creates an empty shelf with the default settings.
+// POST /shelves creates one with a description, icon and settings.
+func CreateCustomBookshelf(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	log.Println("CreateCustomBookshelf invoked")
+	userId := shared.UserID(request)
 
-	updatedProfile, err := dynamodbattribute.MarshalMap(profile)
+	listName := strings.TrimSpace(request.QueryStringParameters["listName"])
+	if listName == "" {
+		return shared.Error(shared.CodeMissingParameter, "listName parameter is required")
+	}
+	_, err := updateLists(userId, func(profile *models.Profile) error {
+		if profile.Lists.Shelf(listName) != nil {
+			return shared.NewError(shared.CodeListAlreadyExists, "Custom bookshelf already exists")
+		}
+		if err := checkNewShelfRef(listName); err != nil {
+			return err
+		}
+		profile.Lists.Shelves = append(profile.Lists.Shelves, models.NewShelf(listName, time.Now().Format(time.RFC3339)))
+		return nil
+	})
 	if err != nil {
-		log.Printf("Error marshalling updated profile: %v\n", err)
-		return shared.ErrorResponse(500, "Error marshalling updated profile: "+err.Error())
+		return storeErrorResponse(err, shared.CodeProfileNotFound, "Profile not found")
 	}
 
-	putInput := &dynamodb.PutItemInput{
-		TableName: aws.String(PROFILES_TABLE_NAME),
-		Item:      updatedProfile,
+	return shared.MessageResponse(200, "Bookshelf created successfully")
+}
+
+func DeleteCustomBookshelf(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	log.Println("DeleteCustomBookshelf invoked")
+	userId := shared.UserID(request)
+
+	listName := request.QueryStringParameters["listName"]
+	if listName == "" {
+		return shared.Error(shared.CodeMissingParameter, "listName parameter is required")
 	}
 
-	_, err = svc.PutItem(putInput)
+	_, err := updateLists(userId, func(profile *models.Profile) error {
+		// Check if the list exists before trying to delete it
+		shelf := profile.Lists.Shelf(listName)
+		if shelf == nil {
+			return shared.NewError(shared.CodeListNotFound, "Custom bookshelf not found")
+		}
+
+		// Delete the shelf along with its books
+		profile.Lists.RemoveShelf(shelf.ShelfID)
+		return nil
+	})
 	if err != nil {
-		log.Printf("DynamoDB PutItem error: %v\n", err)
-		return shared.ErrorResponse(500, fmt.Sprintf("DynamoDB PutItem error: %v", err))
+		return storeErrorResponse(err, shared.CodeProfileNotFound, "Profile not found")
 	}
 
-	return events.APIGatewayProxyResponse{
-		StatusCode: 200,
-		Body:       "List deleted successfully",
-	}
+	return shared.MessageResponse(200, "Bookshelf deleted successfully")
 }
 
-// RemoveFromList removes a book from a specific list
-func DeleteListItem(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
-	log.Println("RemoveFromList invoked")
-	userId, err := shared.GetUserIDFromToken(request)
-	if err != nil {
-		log.Printf("Error extracting userId: %v\n", err)
-		return shared.ErrorResponse(401, err.Error())
+// addToList appends a saved book to the named list, creating a shelf that
+// does not exist yet, and fails like putInList when the book is already
+// there or the shelf is smart. Rating and review only apply to the read list.
+func addToList(profile *models.Profile, listType string, book *models.BookData, rating int, review, now string) error {
+	if err := checkPutInList(profile, listType, book.BookID); err != nil {
+		return err
 	}
-
-	listType := request.QueryStringParameters["listType"]
-	bookId := request.QueryStringParameters["bookId"]
-	if listType == "" || bookId == "" {
-		return shared.ErrorResponse(400, "listType and bookId parameters are required")
+	switch listType {
+	case "toBeRead":
+		item := models.ToBeReadItem{
+			BookID:    book.BookID,
+			Thumbnail: book.CoverImageURL,
+			AddedDate: now,
+			Title:     book.Title,
+			Authors:   book.Authors,
+			Order:     len(profile.Lists.ToBeRead),
+		}
+		profile.Lists.ToBeRead = append(profile.Lists.ToBeRead, item)
+	case "read":
+		item := models.ReadItem{
+			BookID:        book.BookID,
+			CompletedDate: now,
+			Thumbnail:     book.CoverImageURL,
+			Rating:        rating,
+			Review:        review,
+			Title:         book.Title,
+			Authors:       book.Authors,
+			Order:         len(profile.Lists.Read),
+		}
+		profile.Lists.Read = append(profile.Lists.Read, item)
+	default:
+		shelf, err := findOrCreateShelf(profile, listType, now)
+		if err != nil {
+			return err
+		}
+		item := models.CustomListItem{
+			BookID:    book.BookID,
+			Thumbnail: book.CoverImageURL,
+			AddedDate: now,
+			Title:     book.Title,
+			Authors:   book.Authors,
+			Order:     len(shelf.Items),
+		}
+		shelf.Items = append(shelf.Items, item)
 	}
+	return nil
+}
 
-	svc := shared.DynamoDBClient()
-	input := &dynamodb.GetItemInput{
-		TableName: aws.String(PROFILES_TABLE_NAME),
-		Key: map[string]*dynamodb.AttributeValue{
-			"_id": {S: aws.String(userId)},
-		},
+// moveEntry moves a book between two of the profile's lists and returns the
+// reading log entry the move calls for, if any. book, which may be nil, is
+// only needed to start reading. The profile is left untouched if the move
+// fails.
+func moveEntry(profile *models.Profile, fromList, toList, bookID string, book *models.BookData, rating int, review, now string) (*models.ReadingLogItem, error) {
+	if err := checkPutInList(profile, toList, bookID); err != nil {
+		return nil, err
 	}
-
-	result, err := svc.GetItem(input)
+	entry, err := takeFromList(profile, fromList, bookID)
 	if err != nil {
-		log.Printf("DynamoDB GetItem error: %v\n", err)
-		return shared.ErrorResponse(500, fmt.Sprintf("DynamoDB GetItem error: %v", err))
+		return nil, err
 	}
-	if result.Item == nil {
-		return shared.ErrorResponse(404, "Profile not found")
+
+	var logEntry *models.ReadingLogItem
+	switch {
+	case toList == "currentlyReading":
+		entry.startReading(book, now)
+		logEntry = entry.readingLogItem(now, 0, "Book Started")
+	case fromList == "currentlyReading" && toList == "read":
+		logEntry = entry.readingLogItem(now, entry.totalPages, "Book Finished")
+	case fromList == "currentlyReading":
+		logEntry = entry.readingLogItem(now, entry.progress.LastPageRead, "Book Removed")
+	}
+	if toList == "read" {
+		entry.completedDate = now
+		entry.rating, entry.review = rating, review
+	}
+	if entry.addedDate == "" {
+		entry.addedDate = now
 	}
 
-	var profile models.Profile
-	if err := dynamodbattribute.UnmarshalMap(result.Item, &profile); err != nil {
-		log.Printf("Error unmarshalling profile: %v\n", err)
-		return shared.ErrorResponse(500, "Error unmarshalling profile: "+err.Error())
+	if err := putInList(profile, toList, entry); err != nil {
+		return nil, err
 	}
+	return logEntry, nil
+}
 
-	// Initialize lists if they don't exist
+// listEntry is a book on one of a user's lists, with the fields of every
+// kind of list, so it can move between them.
+type listEntry struct {
+	bookID    string
+	isbn      string
+	title     string
+	authors   []string
+	thumbnail string
+
+	// addedDate is when the book went on a to be read or custom list, or
+	// was started for currently reading.
+	addedDate string
+
+	completedDate string
+	rating        int
+	review        string
+
+	totalPages int
+	progress   models.ReadingProgress
+}
 
-	found := false
-	switch listType {
+// takeFromList removes a book from the named list and returns its entry.
+func takeFromList(profile *models.Profile, listName, bookID string) (listEntry, error) {
+	notFound := shared.NewError(shared.CodeListItemNotFound, fmt.Sprintf("Book not found in %s list", listName))
+	switch listName {
+	case "currentlyReading":
+		for i, item := range profile.CurrentlyReading {
+			if item.Book.BookID == bookID {
+				profile.CurrentlyReading = append(profile.CurrentlyReading[:i], profile.CurrentlyReading[i+1:]...)
+				return listEntry{
+					bookID: item.Book.BookID, isbn: item.Book.ISBN, title: item.Book.Title,
+					authors: item.Book.Authors, thumbnail: item.Book.Thumbnail, addedDate: item.StartedDate,
+					totalPages: item.Book.TotalPages, progress: item.Book.Progress,
+				}, nil
+			}
+		}
 	case "toBeRead":
 		for i, item := range profile.Lists.ToBeRead {
-			if item.BookID == bookId {
+			if item.BookID == bookID {
 				profile.Lists.ToBeRead = append(profile.Lists.ToBeRead[:i], profile.Lists.ToBeRead[i+1:]...)
-				found = true
-				break
+				return listEntry{
+					bookID: item.BookID, title: item.Title, authors: item.Authors,
+					thumbnail: item.Thumbnail, addedDate: item.AddedDate,
+				}, nil
 			}
 		}
 	case "read":
 		for i, item := range profile.Lists.Read {
-			if item.BookID == bookId {
+			if item.BookID == bookID {
 				profile.Lists.Read = append(profile.Lists.Read[:i], profile.Lists.Read[i+1:]...)
-				found = true
-				break
+				return listEntry{
+					bookID: item.BookID, title: item.Title, authors: item.Authors, thumbnail: item.Thumbnail,
+					completedDate: item.CompletedDate, rating: item.Rating, review: item.Review,
+				}, nil
 			}
 		}
 	default:
-		if customList, exists := profile.Lists.CustomLists[listType]; exists {
-			for i, item := range customList {
-				if item.BookID == bookId {
-					profile.Lists.CustomLists[listType] = append(customList[:i], customList[i+1:]...)
-					found = true
-					break
-				}
+		shelf := profile.Lists.Shelf(listName)
+		if shelf == nil {
+			return listEntry{}, shared.NewError(shared.CodeListNotFound, "List not found")
+		}
+		if shelf.Smart() {
+			return listEntry{}, smartShelfError(shelf)
+		}
+		for i, item := range shelf.Items {
+			if item.BookID == bookID {
+				shelf.Items = append(shelf.Items[:i], shelf.Items[i+1:]...)
+				return listEntry{
+					bookID: item.BookID, title: item.Title, authors: item.Authors,
+					thumbnail: item.Thumbnail, addedDate: item.AddedDate,
+				}, nil
 			}
 		}
 	}
-
-	if !found {
-		return shared.ErrorResponse(404, "Book not found in the specified list")
-	}
-
-	updatedProfile, err := dynamodbattribute.MarshalMap(profile)
-	if err != nil {
-		log.Printf("Error marshalling updated profile: %v\n", err)
-		return shared.ErrorResponse(500, "Error marshalling updated profile: "+err.Error())
-	}
-
-	putInput := &dynamodb.PutItemInput{
-		TableName: aws.String(PROFILES_TABLE_NAME),
-		Item:      updatedProfile,
-	}
-
-	_, err = svc.PutItem(putInput)
-	if err != nil {
-		log.Printf("DynamoDB PutItem error: %v\n", err)
-		return shared.ErrorResponse(500, fmt.Sprintf("DynamoDB PutItem error: %v", err))
-	}
-
-	return events.APIGatewayProxyResponse{
-		StatusCode: 200,
-		Body:       "Book removed from list successfully",
-	}
+	return listEntry{}, notFound
 }
 
-func CreateCustomBookshelf(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
-	log.Println("CreateCustomBookshelf invoked")
-	userId, err := shared.GetUserIDFromToken(request)
-	if err != nil {
-		log.Printf("Error extracting userId: %v\n", err)
-		return shared.ErrorResponse(401, err.Error())
-	}
-
-	listName := request.QueryStringParameters["listName"]
-	if listName == "" {
-		return shared.ErrorResponse(400, "listName parameter is required")
-	}
-
-	svc := shared.DynamoDBClient()
-	input := &dynamodb.GetItemInput{
-		TableName: aws.String(PROFILES_TABLE_NAME),
-		Key: map[string]*dynamodb.AttributeValue{
-			"_id": {S: aws.String(userId)},
-		},
-	}
-
-	result, err := svc.GetItem(input)
-	if err != nil {
-		log.Printf("DynamoDB GetItem error: %v\n", err)
-		return shared.ErrorResponse(500, fmt.Sprintf("DynamoDB GetItem error: %v", err))
-	}
+// putInList appends an entry to the end of the named list. Like AddToList,
+// it creates a shelf that does not exist yet. The read list may hold
+// a book once for each time it was read; the other lists hold it once.
+func putInList(profile *models.Profile, listName string, entry listEntry) error {
+	if err := checkPutInList(profile, listName, entry.bookID); err != nil {
+		return err
+	}
+
+	switch listName {
+	case "currentlyReading":
+		profile.CurrentlyReading = append(profile.CurrentlyReading, models.CurrentlyReadingItem{
+			Book: models.Book{
+				BookID:     entry.bookID,
+				ISBN:       entry.isbn,
+				Title:      entry.title,
+				Authors:    entry.authors,
+				Thumbnail:  entry.thumbnail,
+				TotalPages: entry.totalPages,
+				Progress:   entry.progress,
+			},
+			StartedDate: entry.addedDate,
+		})
+	case "toBeRead":
+		profile.Lists.ToBeRead = append(profile.Lists.ToBeRead, models.ToBeReadItem{
+			BookID:    entry.bookID,
+			Thumbnail: entry.thumbnail,
+			AddedDate: entry.addedDate,
+			Title:     entry.title,
+			Authors:   entry.authors,
+			Order:     len(profile.Lists.ToBeRead),
+		})
+	case "read":
+		profile.Lists.Read = append(profile.Lists.Read, models.ReadItem{
+			BookID:        entry.bookID,
+			Thumbnail:     entry.thumbnail,
+			CompletedDate: entry.completedDate,
+			Rating:        entry.rating,
+			Review:        entry.review,
+			Title:         entry.title,
+			Authors:       entry.authors,
+			Order:         len(profile.Lists.Read),
+		})
+	default:
+		shelf, err := findOrCreateShelf(profile, listName, time.Now().Format(time.RFC3339))
+		if err != nil {
+			return err
+		}
+		shelf.Items = append(shelf.Items, models.CustomListItem{
+			BookID:    entry.bookID,
+			Thumbnail: entry.thumbnail,
+			AddedDate: entry.addedDate,
+			Title:     entry.title,
+			Authors:   entry.authors,
+			Order:     len(shelf.Items),
+		})
+	}
+	return nil
+}
 
-	if result.Item == nil {
-		return shared.ErrorResponse(404, "Profile not found")
+// checkPutInList returns the error putInList would fail with.
+func checkPutInList(profile *models.Profile, listName, bookID string) error {
+	var present bool
+	switch listName {
+	case "currentlyReading":
+		present = slices.ContainsFunc(profile.CurrentlyReading, func(item models.CurrentlyReadingItem) bool { return item.Book.BookID == bookID })
+	case "toBeRead":
+		present = slices.ContainsFunc(profile.Lists.ToBeRead, func(item models.ToBeReadItem) bool { return item.BookID == bookID })
+	case "read":
+	default:
+		shelf := profile.Lists.Shelf(listName)
+		if shelf == nil {
+			return checkNewShelfRef(listName)
+		}
+		if shelf.Smart() {
+			return smartShelfError(shelf)
+		}
+		present = slices.ContainsFunc(shelf.Items, func(item models.CustomListItem) bool { return item.BookID == bookID })
 	}
-
-	var profile models.Profile
-	if err := dynamodbattribute.UnmarshalMap(result.Item, &profile); err != nil {
-		log.Printf("Error unmarshalling profile: %v\n", err)
-		return shared.ErrorResponse(500, "Error unmarshalling profile: "+err.Error())
+	if present {
+		return shared.NewError(shared.CodeBookAlreadyInList, fmt.Sprintf("Book already in %s list", listName))
 	}
+	return nil
+}
 
-	// Initialize CustomLists map if it doesn't exist
-	if profile.Lists.CustomLists == nil {
-		profile.Lists.CustomLists = make(map[string][]models.CustomListItem)
+// startReading resets the entry to the start of the book, taking the page
+// count, which the lists do not keep, and the latest title and cover from
+// book when it is saved.
+func (e *listEntry) startReading(book *models.BookData, now string) {
+	if book != nil {
+		e.isbn = book.ISBN13
+		e.totalPages = book.PageCount
+		if book.Title != "" {
+			e.title, e.authors = book.Title, book.Authors
+		}
+		if book.CoverImageURL != "" {
+			e.thumbnail = book.CoverImageURL
+		}
 	}
-
-	if _, exists := profile.Lists.CustomLists[listName]; exists {
-		return shared.ErrorResponse(400, "Custom bookshelf already exists")
+	// Set a default page count if it's zero
+	if e.totalPages == 0 {
+		log.Printf("TotalPages for book is 0, setting default value of 300\n")
+		e.totalPages = 300
 	}
+	e.addedDate = now
+	e.progress = models.ReadingProgress{LastUpdated: now}
+}
 
-	profile.Lists.CustomLists[listName] = []models.CustomListItem{}
-
-	updatedProfile, err := dynamodbattribute.MarshalMap(profile)
-	if err != nil {
-		log.Printf("Error marshalling updated profile: %v\n", err)
-		return shared.ErrorResponse(500, "Error marshalling updated profile: "+err.Error())
+// readingLogItem returns the reading log entry recording a change to the
+// entry.
+func (e *listEntry) readingLogItem(now string, pagesRead int, notes string) *models.ReadingLogItem {
+	return &models.ReadingLogItem{
+		Date:          now,
+		BookID:        e.bookID,
+		Title:         e.title,
+		BookThumbnail: e.thumbnail,
+		PagesRead:     pagesRead,
+		Notes:         notes,
 	}
+}
 
-	putInput := &dynamodb.PutItemInput{
-		TableName: aws.String(PROFILES_TABLE_NAME),
-		Item:      updatedProfile,
-	}
+// updateLists updates the user's profile like stores.Profiles.Update, with
+// the lists in display order for mutate to work on by index. The lists are
+// renumbered afterwards, so every Order is its item's index.
+func updateLists(userId string, mutate func(*models.Profile) error) (*models.Profile, error) {
+	return stores.Profiles.Update(userId, func(profile *models.Profile) error {
+		profile.Lists.Normalize()
+		if err := mutate(profile); err != nil {
+			return err
+		}
+		profile.Lists.Renumber()
+		return nil
+	})
+}
 
-	_, err = svc.PutItem(putInput)
-	if err != nil {
-		log.Printf("DynamoDB PutItem error: %v\n", err)
-		return shared.ErrorResponse(500, fmt.Sprintf("DynamoDB PutItem error: %v", err))
-	}
+// updateListsWithLog is updateLists for changes the reading log records,
+// appending the entries mutate returns like updateWithLog.
+func updateListsWithLog(userId string, mutate func(*models.Profile) ([]models.ReadingLogItem, error)) (*models.Profile, []models.ReadingLogItem, error) {
+	return updateWithLog(userId, func(profile *models.Profile) ([]models.ReadingLogItem, error) {
+		profile.Lists.Normalize()
+		entries, err := mutate(profile)
+		if err != nil {
+			return nil, err
+		}
+		profile.Lists.Renumber()
+		return entries, nil
+	})
+}
 
-	return events.APIGatewayProxyResponse{
-		StatusCode: 200,
-		Body:       "Bookshelf created successfully",
+// listBookIDs returns the book IDs of the named list in order.
+func listBookIDs(profile *models.Profile, listType string) ([]string, error) {
+	var ids []string
+	switch listType {
+	case "toBeRead":
+		for _, item := range profile.Lists.ToBeRead {
+			ids = append(ids, item.BookID)
+		}
+	case "read":
+		for _, item := range profile.Lists.Read {
+			ids = append(ids, item.BookID)
+		}
+	default:
+		shelf := profile.Lists.Shelf(listType)
+		if shelf == nil {
+			return nil, shared.NewError(shared.CodeListNotFound, "List not found")
+		}
+		if shelf.Smart() {
+			return nil, smartShelfError(shelf)
+		}
+		for _, item := range shelf.Items {
+			ids = append(ids, item.BookID)
+		}
 	}
+	return ids, nil
 }
 
-func DeleteCustomBookshelf(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
-	log.Println("DeleteCustomBookshelf invoked")
-	userId, err := shared.GetUserIDFromToken(request)
+// moveInList moves a book of the named list to position, or to the end if
+// position is past it.
+func moveInList(profile *models.Profile, listType, bookID string, position int) error {
+	ids, err := listBookIDs(profile, listType)
 	if err != nil {
-		log.Printf("Error extracting userId: %v\n", err)
-		return shared.ErrorResponse(401, err.Error())
+		return err
 	}
-
-	listName := request.QueryStringParameters["listName"]
-	if listName == "" {
-		return shared.ErrorResponse(400, "listName parameter is required")
+	from := slices.Index(ids, bookID)
+	if from < 0 {
+		return shared.NewError(shared.CodeListItemNotFound, "Book not found in the specified list")
 	}
 
-	svc := shared.DynamoDBClient()
-	input := &dynamodb.GetItemInput{
-		TableName: aws.String(PROFILES_TABLE_NAME),
-		Key: map[string]*dynamodb.AttributeValue{
-			"_id": {S: aws.String(userId)},
-		},
+	order := make([]int, 0, len(ids))
+	for i := range ids {
+		if i != from {
+			order = append(order, i)
+		}
 	}
+	order = slices.Insert(order, min(position, len(order)), from)
+	permuteList(profile, listType, order)
+	return nil
+}
 
-	result, err := svc.GetItem(input)
+// setListOrder puts the named list in the order of bookIDs, which must hold
+// every book of the list exactly as often as the list does.
+func setListOrder(profile *models.Profile, listType string, bookIDs []string) error {
+	ids, err := listBookIDs(profile, listType)
 	if err != nil {
-		log.Printf("DynamoDB GetItem error: %v\n", err)
-		return shared.ErrorResponse(500, fmt.Sprintf("DynamoDB GetItem error: %v", err))
+		return err
 	}
-
-	if result.Item == nil {
-		return shared.ErrorResponse(404, "Profile not found")
-	}
-
-	var profile models.Profile
-	if err := dynamodbattribute.UnmarshalMap(result.Item, &profile); err != nil {
-		log.Printf("Error unmarshalling profile: %v\n", err)
-		return shared.ErrorResponse(500, "Error unmarshalling profile: "+err.Error())
+	mismatch := shared.NewError(shared.CodeInvalidParameter, "bookIds must list every book of the list exactly once")
+	if len(bookIDs) != len(ids) {
+		return mismatch
 	}
 
-	// Check if the list exists before trying to delete it
-	if _, exists := profile.Lists.CustomLists[listName]; !exists {
-		return shared.ErrorResponse(404, "Custom bookshelf not found")
-	}
-
-	// Delete the custom list
-	delete(profile.Lists.CustomLists, listName)
-
-	updatedProfile, err := dynamodbattribute.MarshalMap(profile)
-	if err != nil {
-		log.Printf("Error marshalling updated profile: %v\n", err)
-		return shared.ErrorResponse(500, "Error marshalling updated profile: "+err.Error())
+	// The read list may hold a book more than once; its entries keep their
+	// relative order.
+	positions := make(map[string][]int, len(ids))
+	for i, id := range ids {
+		positions[id] = append(positions[id], i)
 	}
-
-	putInput := &dynamodb.PutItemInput{
-		TableName: aws.String(PROFILES_TABLE_NAME),
-		Item:      updatedProfile,
+	order := make([]int, 0, len(ids))
+	for _, id := range bookIDs {
+		if len(positions[id]) == 0 {
+			return mismatch
+		}
+		order = append(order, positions[id][0])
+		positions[id] = positions[id][1:]
 	}
+	permuteList(profile, listType, order)
+	return nil
+}
 
-	_, err = svc.PutItem(putInput)
-	if err != nil {
-		log.Printf("DynamoDB PutItem error: %v\n", err)
-		return shared.ErrorResponse(500, fmt.Sprintf("DynamoDB PutItem error: %v", err))
+// permuteList rearranges the named list so that its item i is the one that
+// was at order[i].
+func permuteList(profile *models.Profile, listType string, order []int) {
+	switch listType {
+	case "toBeRead":
+		profile.Lists.ToBeRead = permute(profile.Lists.ToBeRead, order)
+	case "read":
+		profile.Lists.Read = permute(profile.Lists.Read, order)
+	default:
+		shelf := profile.Lists.Shelf(listType)
+		shelf.Items = permute(shelf.Items, order)
 	}
+}
 
-	return events.APIGatewayProxyResponse{
-		StatusCode: 200,
-		Body:       "Bookshelf deleted successfully",
+func permute[T any](items []T, order []int) []T {
+	result := make([]T, len(order))
+	for i, from := range order {
+		result[i] = items[from]
 	}
+	return result
 }
@@ -0,0 +1,452 @@
+package handlers
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/FriedGlue/BookIt/api/pkg/shared"
+	"github.com/FriedGlue/BookIt/api/pkg/store"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const testUser = "user"
+
+// configureTestStores points the handlers at memory stores holding books
+// and a profile for testUser with the given lists.
+func configureTestStores(t *testing.T, profile models.Profile, books ...models.BookData) {
+	t.Helper()
+	Configure(Stores{
+		Profiles:   store.NewMemoryProfileStore(),
+		Books:      store.NewMemoryBookStore(),
+		Works:      store.NewMemoryWorkStore(),
+		Authors:    store.NewMemoryAuthorStore(),
+		Series:     store.NewMemorySeriesStore(),
+		ReadingLog: store.NewMemoryReadingLogStore(),
+	})
+	profile.ID = testUser
+	if err := stores.Profiles.Put(&profile); err != nil {
+		t.Fatal(err)
+	}
+	for i := range books {
+		if err := stores.Books.Put(&books[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// testRequest returns a request from testUser with body marshalled to JSON.
+func testRequest(t *testing.T, body any) events.APIGatewayProxyRequest {
+	t.Helper()
+	raw, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return events.APIGatewayProxyRequest{
+		Body: string(raw),
+		RequestContext: events.APIGatewayProxyRequestContext{
+			Authorizer: map[string]interface{}{"claims": map[string]interface{}{"sub": testUser}},
+		},
+	}
+}
+
+// errorCode returns the code of an error response, or "" for a success.
+func errorCode(t *testing.T, response events.APIGatewayProxyResponse) shared.ErrorCode {
+	t.Helper()
+	if response.StatusCode < 400 {
+		return ""
+	}
+	var body struct {
+		Error shared.APIError `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(response.Body), &body); err != nil {
+		t.Fatalf("error response %d is not JSON: %s", response.StatusCode, response.Body)
+	}
+	if want := body.Error.Code.Status(); response.StatusCode != want {
+		t.Errorf("%s returned with status %d, want %d", body.Error.Code, response.StatusCode, want)
+	}
+	return body.Error.Code
+}
+
+// testProfile loads testUser's profile.
+func testProfile(t *testing.T) *models.Profile {
+	t.Helper()
+	profile, err := stores.Profiles.Get(testUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return profile
+}
+
+// bookIDs returns the book IDs of items, checking each Order is its index.
+func bookIDs[T any](t *testing.T, items []T, field func(T) (string, int)) []string {
+	t.Helper()
+	ids := []string{}
+	for i, item := range items {
+		id, order := field(item)
+		if order != i {
+			t.Errorf("%s has order %d at index %d", id, order, i)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func toBeReadIDs(t *testing.T, profile *models.Profile) []string {
+	return bookIDs(t, profile.Lists.ToBeRead, func(item models.ToBeReadItem) (string, int) { return item.BookID, item.Order })
+}
+
+func readIDs(t *testing.T, profile *models.Profile) []string {
+	return bookIDs(t, profile.Lists.Read, func(item models.ReadItem) (string, int) { return item.BookID, item.Order })
+}
+
+func shelfIDs(t *testing.T, profile *models.Profile, name string) []string {
+	shelf := profile.Lists.Shelf(name)
+	if shelf == nil {
+		return nil
+	}
+	return bookIDs(t, shelf.Items, func(item models.CustomListItem) (string, int) { return item.BookID, item.Order })
+}
+
+func readingIDs(profile *models.Profile) []string {
+	ids := []string{}
+	for _, item := range profile.CurrentlyReading {
+		ids = append(ids, item.Book.BookID)
+	}
+	return ids
+}
+
+func TestReorderList(t *testing.T) {
+	position := func(p int) *int { return &p }
+	tests := []struct {
+		name     string
+		req      ReorderListRequest
+		wantCode shared.ErrorCode
+		want     []string
+	}{
+		{"to the front", ReorderListRequest{ListType: "toBeRead", BookID: "d", Position: position(0)}, "", []string{"d", "a", "b", "c"}},
+		{"to the middle", ReorderListRequest{ListType: "toBeRead", BookID: "a", Position: position(2)}, "", []string{"b", "c", "a", "d"}},
+		{"past the end", ReorderListRequest{ListType: "toBeRead", BookID: "a", Position: position(10)}, "", []string{"b", "c", "d", "a"}},
+		{"same place", ReorderListRequest{ListType: "toBeRead", BookID: "b", Position: position(1)}, "", []string{"a", "b", "c", "d"}},
+		{"whole list", ReorderListRequest{ListType: "toBeRead", BookIDs: []string{"c", "a", "d", "b"}}, "", []string{"c", "a", "d", "b"}},
+		{"shelf by name", ReorderListRequest{ListType: "Holiday", BookID: "y", Position: position(0)}, "", []string{"y", "x"}},
+		{"read list with a book read twice", ReorderListRequest{ListType: "read", BookIDs: []string{"r2", "r1", "r1"}}, "", []string{"r2", "r1", "r1"}},
+		{"whole list missing a book", ReorderListRequest{ListType: "toBeRead", BookIDs: []string{"a", "b", "c"}}, shared.CodeInvalidParameter, nil},
+		{"whole list with a book twice", ReorderListRequest{ListType: "toBeRead", BookIDs: []string{"a", "a", "b", "c"}}, shared.CodeInvalidParameter, nil},
+		{"book not on the list", ReorderListRequest{ListType: "toBeRead", BookID: "x", Position: position(0)}, shared.CodeListItemNotFound, nil},
+		{"unknown shelf", ReorderListRequest{ListType: "Nowhere", BookID: "x", Position: position(0)}, shared.CodeListNotFound, nil},
+		{"negative position", ReorderListRequest{ListType: "toBeRead", BookID: "a", Position: position(-1)}, shared.CodeValidationFailed, nil},
+		{"bookIds with bookId", ReorderListRequest{ListType: "toBeRead", BookID: "a", BookIDs: []string{"a"}}, shared.CodeValidationFailed, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configureTestStores(t, models.Profile{Lists: models.UserLists{
+				ToBeRead: []models.ToBeReadItem{{BookID: "a", Order: 0}, {BookID: "b", Order: 1}, {BookID: "c", Order: 2}, {BookID: "d", Order: 3}},
+				Read:     []models.ReadItem{{BookID: "r1", Order: 0}, {BookID: "r1", Order: 1}, {BookID: "r2", Order: 2}},
+				Shelves: []models.Shelf{{ShelfID: "s1", Name: "Holiday", Items: []models.CustomListItem{
+					{BookID: "x", Order: 0}, {BookID: "y", Order: 1},
+				}}},
+			}})
+
+			response := ReorderList(testRequest(t, tt.req))
+			if code := errorCode(t, response); code != tt.wantCode {
+				t.Fatalf("ReorderList returned %s (%s), want %q", code, response.Body, tt.wantCode)
+			}
+			if tt.wantCode != "" {
+				if got := toBeReadIDs(t, testProfile(t)); !slices.Equal(got, []string{"a", "b", "c", "d"}) {
+					t.Errorf("a failed reorder changed the list to %v", got)
+				}
+				return
+			}
+
+			var body struct {
+				Items []models.CustomListItem `json:"items"`
+			}
+			if err := json.Unmarshal([]byte(response.Body), &body); err != nil {
+				t.Fatal(err)
+			}
+			got := bookIDs(t, body.Items, func(item models.CustomListItem) (string, int) { return item.BookID, item.Order })
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("response = %v, want %v", got, tt.want)
+			}
+
+			profile := testProfile(t)
+			var stored []string
+			switch tt.req.ListType {
+			case "toBeRead":
+				stored = toBeReadIDs(t, profile)
+			case "read":
+				stored = readIDs(t, profile)
+			default:
+				stored = shelfIDs(t, profile, tt.req.ListType)
+			}
+			if !slices.Equal(stored, tt.want) {
+				t.Errorf("stored list = %v, want %v", stored, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddToList(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       AddToListRequest
+		wantCode  shared.ErrorCode
+		wantTBR   []string
+		wantShelf []string // Holiday
+	}{
+		{"to be read", AddToListRequest{ListType: "toBeRead", BookID: "b"}, "", []string{"a", "b"}, []string{"a"}},
+		{"shelf", AddToListRequest{ListType: "Holiday", BookID: "b"}, "", []string{"a"}, []string{"a", "b"}},
+		{"already to be read", AddToListRequest{ListType: "toBeRead", BookID: "a"}, shared.CodeBookAlreadyInList, nil, nil},
+		{"already on the shelf", AddToListRequest{ListType: "s1", BookID: "a"}, shared.CodeBookAlreadyInList, nil, nil},
+		{"smart shelf", AddToListRequest{ListType: "Smart", BookID: "b"}, shared.CodeInvalidParameter, nil, nil},
+		{"unsaved book", AddToListRequest{ListType: "toBeRead", BookID: "unsaved"}, shared.CodeBookNotFound, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configureTestStores(t, models.Profile{Lists: models.UserLists{
+				ToBeRead: []models.ToBeReadItem{{BookID: "a"}},
+				Shelves: []models.Shelf{
+					{ShelfID: "s1", Name: "Holiday", Items: []models.CustomListItem{{BookID: "a"}}},
+					{ShelfID: "s2", Name: "Smart", Rules: &models.ShelfRules{Match: models.MatchAll,
+						Conditions: []models.ShelfRule{{Field: models.RatingField, Op: models.AtLeast, Number: 4}}}, Items: []models.CustomListItem{}},
+				},
+			}},
+				models.BookData{BookID: "a", Title: "A"},
+				models.BookData{BookID: "b", Title: "B"},
+			)
+
+			response := AddToList(testRequest(t, tt.req))
+			if code := errorCode(t, response); code != tt.wantCode {
+				t.Fatalf("AddToList returned %s (%s), want %q", code, response.Body, tt.wantCode)
+			}
+			if tt.wantCode != "" {
+				return
+			}
+
+			profile := testProfile(t)
+			if got := toBeReadIDs(t, profile); !slices.Equal(got, tt.wantTBR) {
+				t.Errorf("to be read = %v, want %v", got, tt.wantTBR)
+			}
+			if got := shelfIDs(t, profile, "Holiday"); !slices.Equal(got, tt.wantShelf) {
+				t.Errorf("Holiday = %v, want %v", got, tt.wantShelf)
+			}
+		})
+	}
+}
+
+func TestUpdateListItem(t *testing.T) {
+	value := func(v int) *int { return &v }
+	tests := []struct {
+		name       string
+		req        UpdateListItemRequest
+		wantCode   shared.ErrorCode
+		wantOrder  []string
+		wantRating int
+		wantReview string
+	}{
+		{"order only keeps the rating", UpdateListItemRequest{ListType: "read", BookID: "r1", Order: value(1)}, "", []string{"r2", "r1"}, 4, "Good"},
+		{"review only keeps the rating", UpdateListItemRequest{ListType: "read", BookID: "r1", Review: "Better"}, "", []string{"r1", "r2"}, 4, "Better"},
+		{"rating", UpdateListItemRequest{ListType: "read", BookID: "r1", Rating: value(2)}, "", []string{"r1", "r2"}, 2, "Good"},
+		{"rating of zero", UpdateListItemRequest{ListType: "read", BookID: "r1", Rating: value(0)}, shared.CodeValidationFailed, nil, 0, ""},
+		{"rating above five", UpdateListItemRequest{ListType: "read", BookID: "r1", Rating: value(6)}, shared.CodeValidationFailed, nil, 0, ""},
+		{"book not on the list", UpdateListItemRequest{ListType: "read", BookID: "x", Rating: value(3)}, shared.CodeListItemNotFound, nil, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configureTestStores(t, models.Profile{Lists: models.UserLists{
+				Read: []models.ReadItem{{BookID: "r1", Order: 0, Rating: 4, Review: "Good"}, {BookID: "r2", Order: 1}},
+			}})
+
+			response := UpdateListItem(testRequest(t, tt.req))
+			if code := errorCode(t, response); code != tt.wantCode {
+				t.Fatalf("UpdateListItem returned %s (%s), want %q", code, response.Body, tt.wantCode)
+			}
+			if tt.wantCode != "" {
+				return
+			}
+
+			profile := testProfile(t)
+			if got := readIDs(t, profile); !slices.Equal(got, tt.wantOrder) {
+				t.Errorf("read list = %v, want %v", got, tt.wantOrder)
+			}
+			for _, item := range profile.Lists.Read {
+				if item.BookID == "r1" && (item.Rating != tt.wantRating || item.Review != tt.wantReview) {
+					t.Errorf("r1 has rating %d and review %q, want %d and %q", item.Rating, item.Review, tt.wantRating, tt.wantReview)
+				}
+			}
+		})
+	}
+}
+
+func TestMoveListItem(t *testing.T) {
+	tests := []struct {
+		name        string
+		req         MoveListItemRequest
+		wantCode    shared.ErrorCode
+		wantTBR     []string
+		wantRead    []string
+		wantReading []string
+		wantShelf   []string // Holiday
+		wantLog     []string // notes of the reading log entries
+	}{
+		{
+			name:    "to be read to read",
+			req:     MoveListItemRequest{FromList: "toBeRead", ToList: "read", BookID: "a", Rating: 4, Review: "good"},
+			wantTBR: []string{"b"}, wantRead: []string{"r", "a"}, wantReading: []string{"c"}, wantShelf: []string{"a"},
+		},
+		{
+			name:    "read to to be read",
+			req:     MoveListItemRequest{FromList: "read", ToList: "toBeRead", BookID: "r"},
+			wantTBR: []string{"a", "b", "r"}, wantRead: []string{}, wantReading: []string{"c"}, wantShelf: []string{"a"},
+		},
+		{
+			name:    "start reading",
+			req:     MoveListItemRequest{FromList: "toBeRead", ToList: "currentlyReading", BookID: "b"},
+			wantTBR: []string{"a"}, wantRead: []string{"r"}, wantReading: []string{"c", "b"}, wantShelf: []string{"a"},
+			wantLog: []string{"Book Started"},
+		},
+		{
+			name:    "finish reading",
+			req:     MoveListItemRequest{FromList: "currentlyReading", ToList: "read", BookID: "c"},
+			wantTBR: []string{"a", "b"}, wantRead: []string{"r", "c"}, wantReading: []string{}, wantShelf: []string{"a"},
+			wantLog: []string{"Book Finished"},
+		},
+		{
+			name:    "stop reading",
+			req:     MoveListItemRequest{FromList: "currentlyReading", ToList: "Holiday", BookID: "c"},
+			wantTBR: []string{"a", "b"}, wantRead: []string{"r"}, wantReading: []string{}, wantShelf: []string{"a", "c"},
+			wantLog: []string{"Book Removed"},
+		},
+		{
+			name:    "to a new shelf",
+			req:     MoveListItemRequest{FromList: "toBeRead", ToList: "Beach", BookID: "b"},
+			wantTBR: []string{"a"}, wantRead: []string{"r"}, wantReading: []string{"c"}, wantShelf: []string{"a"},
+		},
+		{name: "already on the shelf", req: MoveListItemRequest{FromList: "toBeRead", ToList: "Holiday", BookID: "a"}, wantCode: shared.CodeBookAlreadyInList},
+		{name: "not on the list", req: MoveListItemRequest{FromList: "toBeRead", ToList: "read", BookID: "r"}, wantCode: shared.CodeListItemNotFound},
+		{name: "from an unknown shelf", req: MoveListItemRequest{FromList: "Nowhere", ToList: "read", BookID: "a"}, wantCode: shared.CodeListNotFound},
+		{name: "to an unknown shelf ID", req: MoveListItemRequest{FromList: "toBeRead", ToList: "7f1c7a54-9a0e-4c4e-9a59-1c0e3f2a8b11", BookID: "a"}, wantCode: shared.CodeListNotFound},
+		{name: "rating off the read list", req: MoveListItemRequest{FromList: "toBeRead", ToList: "Holiday", BookID: "b", Rating: 3}, wantCode: shared.CodeValidationFailed},
+		{name: "same list", req: MoveListItemRequest{FromList: "toBeRead", ToList: "toBeRead", BookID: "a"}, wantCode: shared.CodeValidationFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configureTestStores(t, models.Profile{
+				CurrentlyReading: []models.CurrentlyReadingItem{{Book: models.Book{BookID: "c", TotalPages: 200}}},
+				Lists: models.UserLists{
+					ToBeRead: []models.ToBeReadItem{{BookID: "a", Order: 0, Title: "A"}, {BookID: "b", Order: 1}},
+					Read:     []models.ReadItem{{BookID: "r", Order: 0}},
+					Shelves:  []models.Shelf{{ShelfID: "s1", Name: "Holiday", Items: []models.CustomListItem{{BookID: "a"}}}},
+				},
+			}, models.BookData{BookID: "b", Title: "B", PageCount: 300})
+
+			response := MoveListItem(testRequest(t, tt.req))
+			if code := errorCode(t, response); code != tt.wantCode {
+				t.Fatalf("MoveListItem returned %s (%s), want %q", code, response.Body, tt.wantCode)
+			}
+			profile := testProfile(t)
+			if tt.wantCode != "" {
+				if got := toBeReadIDs(t, profile); !slices.Equal(got, []string{"a", "b"}) {
+					t.Errorf("a failed move changed to be read to %v", got)
+				}
+				return
+			}
+
+			if got := toBeReadIDs(t, profile); !slices.Equal(got, tt.wantTBR) {
+				t.Errorf("to be read = %v, want %v", got, tt.wantTBR)
+			}
+			if got := readIDs(t, profile); !slices.Equal(got, tt.wantRead) {
+				t.Errorf("read = %v, want %v", got, tt.wantRead)
+			}
+			if got := readingIDs(profile); !slices.Equal(got, tt.wantReading) {
+				t.Errorf("currently reading = %v, want %v", got, tt.wantReading)
+			}
+			if got := shelfIDs(t, profile, "Holiday"); !slices.Equal(got, tt.wantShelf) {
+				t.Errorf("Holiday = %v, want %v", got, tt.wantShelf)
+			}
+			if tt.req.ToList == "Beach" && !slices.Equal(shelfIDs(t, profile, "Beach"), []string{tt.req.BookID}) {
+				t.Errorf("Beach = %v, want [%s]", shelfIDs(t, profile, "Beach"), tt.req.BookID)
+			}
+
+			switch tt.req.ToList {
+			case "read":
+				moved := profile.Lists.Read[len(profile.Lists.Read)-1]
+				if moved.Rating != tt.req.Rating || moved.Review != tt.req.Review || moved.CompletedDate == "" {
+					t.Errorf("read entry = %+v, want the rating, review and completed date", moved)
+				}
+			case "currentlyReading":
+				started := profile.CurrentlyReading[len(profile.CurrentlyReading)-1]
+				if started.Book.TotalPages != 300 || started.StartedDate == "" {
+					t.Errorf("currently reading entry = %+v, want the saved book's page count and a start date", started)
+				}
+			}
+
+			entries, err := stores.ReadingLog.Query(testUser, time.Time{}, time.Time{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			notes := []string{}
+			for _, entry := range entries {
+				notes = append(notes, entry.Notes)
+			}
+			if tt.wantLog == nil {
+				tt.wantLog = []string{}
+			}
+			if !slices.Equal(notes, tt.wantLog) {
+				t.Errorf("reading log = %v, want %v", notes, tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestGetListCursorSurvivesRenumbering(t *testing.T) {
+	var toBeRead []models.ToBeReadItem
+	for i, id := range []string{"a", "b", "c", "d", "e"} {
+		toBeRead = append(toBeRead, models.ToBeReadItem{BookID: id, Order: i})
+	}
+	configureTestStores(t, models.Profile{Lists: models.UserLists{ToBeRead: toBeRead}})
+
+	getPage := func(cursor string) ([]string, string) {
+		t.Helper()
+		request := testRequest(t, nil)
+		request.QueryStringParameters = map[string]string{"listType": "toBeRead", "limit": "3", "cursor": cursor}
+		response := GetList(request)
+		if response.StatusCode != 200 {
+			t.Fatalf("GetList returned %d: %s", response.StatusCode, response.Body)
+		}
+		var page struct {
+			Items      []models.ToBeReadItem `json:"items"`
+			NextCursor string                `json:"nextCursor"`
+		}
+		if err := json.Unmarshal([]byte(response.Body), &page); err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, item := range page.Items {
+			ids = append(ids, item.BookID)
+		}
+		return ids, page.NextCursor
+	}
+
+	first, cursor := getPage("")
+	if !slices.Equal(first, []string{"a", "b", "c"}) || cursor == "" {
+		t.Fatalf("first page = %v, cursor %q", first, cursor)
+	}
+
+	// Removing books from the first page renumbers the rest.
+	for _, id := range []string{"a", "b"} {
+		request := testRequest(t, nil)
+		request.QueryStringParameters = map[string]string{"listType": "toBeRead", "bookId": id}
+		if response := DeleteListItem(request); response.StatusCode != 200 {
+			t.Fatalf("DeleteListItem(%s) returned %d: %s", id, response.StatusCode, response.Body)
+		}
+	}
+
+	second, cursor := getPage(cursor)
+	if !slices.Equal(second, []string{"d", "e"}) || cursor != "" {
+		t.Errorf("second page = %v, cursor %q; want [d e] and no cursor", second, cursor)
+	}
+}
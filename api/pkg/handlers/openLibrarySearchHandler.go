@@ -1,35 +1,20 @@
 package handlers
 
 import (
-	"encoding/json"
-	"fmt"
-	"io/ioutil"
+	"context"
+	"errors"
 	"log"
-	"net/http"
-	"net/url"
+	"strings"
 	"time"
 
+	"github.com/FriedGlue/BookIt/api/pkg/httpclient"
+	"github.com/FriedGlue/BookIt/api/pkg/metadata"
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/FriedGlue/BookIt/api/pkg/pagination"
 	"github.com/FriedGlue/BookIt/api/pkg/shared"
 	"github.com/aws/aws-lambda-go/events"
 )
 
-// OpenLibrarySearchResponse represents the search results from Open Library API
-type OpenLibrarySearchResponse struct {
-	NumFound int                      `json:"num_found"`
-	Start    int                      `json:"start"`
-	Docs     []OpenLibrarySearchEntry `json:"docs"`
-}
-
-// OpenLibrarySearchEntry represents a single result in the search response
-type OpenLibrarySearchEntry struct {
-	Key          string   `json:"key"`
-	Title        string   `json:"title"`
-	AuthorName   []string `json:"author_name,omitempty"`
-	CoverI       int      `json:"cover_i,omitempty"`
-	ISBN         []string `json:"isbn,omitempty"`
-	FirstPublish int      `json:"first_publish_year,omitempty"`
-}
-
 // SearchResultEntry represents a combined search result entry
 type SearchResultEntry struct {
 	BookId        string   `json:"bookId"`
@@ -40,167 +25,263 @@ type SearchResultEntry struct {
 	OpenLibraryId string   `json:"openLibraryId,omitempty"`
 }
 
-// SearchOpenLibrary searches the Open Library API
-func SearchOpenLibrary(query string) ([]SearchResultEntry, error) {
-	if len(query) < 2 {
-		// Skip very short queries to prevent unnecessary API calls
-		return []SearchResultEntry{}, nil
-	}
+// CombinedSearchResponse is the body of GET /books/combined-search. Partial
+// is set when a source failed, so the items may be missing some matches.
+type CombinedSearchResponse struct {
+	Items   []SearchResultEntry `json:"items"`
+	Partial bool                `json:"partial"`
+	Sources []SearchSource      `json:"sources"`
+}
 
-	// URL encode the query
-	encodedQuery := url.QueryEscape(query)
-	url := fmt.Sprintf("https://openlibrary.org/search.json?q=%s&limit=10", encodedQuery)
+// SearchSource reports how one source of a combined search fared and how
+// long it took.
+type SearchSource struct {
+	Name       string  `json:"name"`
+	Status     string  `json:"status" validate:"oneof=ok failed unavailable timeout skipped"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"durationMs"`
+}
 
-	// Add timeout to prevent hanging requests
-	client := &http.Client{
-		Timeout: 5 * time.Second,
-	}
+// Search source statuses.
+const (
+	sourceOK          = "ok"
+	sourceFailed      = "failed"
+	sourceUnavailable = "unavailable" // the circuit breaker is open
+	sourceTimeout     = "timeout"
+	sourceSkipped     = "skipped"
+)
 
-	resp, err := client.Get(url)
-	if err != nil {
-		log.Printf("Error fetching from Open Library: %v", err)
-		return []SearchResultEntry{}, nil // Return empty results instead of error
-	}
-	defer resp.Body.Close()
+// Combined search gives up on a source after its timeout, and on the whole
+// search after combinedSearchTimeout.
+const (
+	combinedSearchTimeout  = 6 * time.Second
+	databaseSearchTimeout  = 3 * time.Second
+	catalogueSearchTimeout = 5 * time.Second
+)
 
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("Open Library API returned status code: %d", resp.StatusCode)
-		return []SearchResultEntry{}, nil // Return empty results on error
-	}
+// searchResultLimit is how many results are asked of the metadata providers.
+const searchResultLimit = 10
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		log.Printf("Error reading Open Library response: %v", err)
+// SearchCatalogue searches the metadata providers for books that are not
+// saved yet.
+func SearchCatalogue(ctx context.Context, query string) ([]SearchResultEntry, error) {
+	if len(query) < 2 {
+		// Skip very short queries to prevent unnecessary API calls
 		return []SearchResultEntry{}, nil
 	}
 
-	var searchResponse OpenLibrarySearchResponse
-	if err := json.Unmarshal(body, &searchResponse); err != nil {
-		log.Printf("Error parsing Open Library response: %v", err)
-		return []SearchResultEntry{}, nil
+	found, err := metadataProvider.SearchByText(ctx, query, searchResultLimit)
+	if err != nil {
+		return nil, err
 	}
 
-	var results []SearchResultEntry
-	for _, doc := range searchResponse.Docs {
-		// Extract the Open Library ID from the key
-		// Format: /works/OL12345W -> OL12345W
-		olId := ""
-		if len(doc.Key) > 7 {
-			olId = doc.Key[7:] // Remove "/works/"
-		}
-
-		// Skip entries without a valid ID
-		if olId == "" {
-			continue
-		}
-
-		// Build the thumbnail URL if a cover ID is available
-		thumbnail := ""
-		if doc.CoverI > 0 {
-			thumbnail = fmt.Sprintf("https://covers.openlibrary.org/b/id/%d-M.jpg", doc.CoverI)
-		}
-
+	results := []SearchResultEntry{}
+	for _, r := range found {
 		result := SearchResultEntry{
-			BookId:        olId, // Using Open Library ID as the book ID for external books
-			Title:         doc.Title,
-			Authors:       doc.AuthorName,
-			Thumbnail:     thumbnail,
-			Source:        "openLibrary",
-			OpenLibraryId: olId,
+			BookId:    r.WorkID, // Using the catalogue's ID as the book ID for external books
+			Title:     r.Title,
+			Authors:   r.Authors,
+			Thumbnail: r.Thumbnail,
+			Source:    r.Source,
+		}
+		if r.Source == metadata.OpenLibraryName {
+			result.OpenLibraryId = r.WorkID
 		}
 		results = append(results, result)
 	}
 
-	log.Printf("Open Library search for '%s' returned %d results", query, len(results))
+	log.Printf("Catalogue search for '%s' returned %d results", query, len(results))
 	return results, nil
 }
 
-// MergeSearchResults combines results from our database and Open Library
-func MergeSearchResults(dbResults []BookData, olResults []SearchResultEntry) []SearchResultEntry {
-	var mergedResults []SearchResultEntry
+// MergeSearchResults combines results from our database and the metadata providers
+func MergeSearchResults(dbResults []models.BookData, catalogueResults []SearchResultEntry) []SearchResultEntry {
+	var merged searchMerge
+	merged.addDatabase(dbResults)
+	merged.addCatalogue(catalogueResults)
+	return merged.items
+}
 
-	// First, convert our database results to the common format
-	for _, book := range dbResults {
-		thumbnail := book.CoverImageURL
-		result := SearchResultEntry{
+// searchMerge merges the results of the searches of a combined search in
+// whatever order they arrive: saved books first, then the catalogue results
+// that are not saved.
+type searchMerge struct {
+	items    []SearchResultEntry
+	savedIds map[string]bool
+}
+
+// addDatabase puts the saved books ahead of the results so far and drops
+// the catalogue results they are saved from.
+func (m *searchMerge) addDatabase(books []models.BookData) {
+	if m.savedIds == nil {
+		m.savedIds = make(map[string]bool)
+	}
+	saved := make([]SearchResultEntry, 0, len(books)+len(m.items))
+	for _, book := range books {
+		saved = append(saved, SearchResultEntry{
 			BookId:        book.BookID,
 			Title:         book.Title,
 			Authors:       book.Authors,
-			Thumbnail:     thumbnail,
+			Thumbnail:     book.CoverImageURL,
 			Source:        "database",
 			OpenLibraryId: book.OpenLibraryId,
+		})
+		for _, id := range catalogueIds(book) {
+			m.savedIds[id] = true
 		}
-		mergedResults = append(mergedResults, result)
 	}
-
-	// Track Open Library IDs that are already in our database
-	olIdsInDb := make(map[string]bool)
-	for _, book := range dbResults {
-		if book.OpenLibraryId != "" {
-			olIdsInDb[book.OpenLibraryId] = true
+	for _, item := range m.items {
+		if item.Source == "database" || !m.savedIds[item.BookId] {
+			saved = append(saved, item)
 		}
 	}
+	m.items = saved
+}
 
-	// Add Open Library results that aren't already in our database
-	for _, olResult := range olResults {
-		if _, exists := olIdsInDb[olResult.OpenLibraryId]; !exists {
-			mergedResults = append(mergedResults, olResult)
+// addCatalogue appends the catalogue results that are not saved.
+func (m *searchMerge) addCatalogue(results []SearchResultEntry) {
+	for _, result := range results {
+		if !m.savedIds[result.BookId] {
+			m.items = append(m.items, result)
 		}
 	}
-
-	return mergedResults
 }
 
-// CombinedSearch searches both our database and Open Library
+// CombinedSearch searches both our database and the metadata providers,
+// concurrently, and says in the response how each source fared
 func CombinedSearch(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
 	// Extract the query parameter
 	q := request.QueryStringParameters["q"]
 	if q == "" {
-		return shared.ErrorResponse(400, "Please provide a search query using the 'q' parameter")
+		return shared.Error(shared.CodeMissingParameter, "Please provide a search query using the 'q' parameter")
 	}
 
 	log.Printf("Combined search request for query: '%s'", q)
 
-	// Extract user info
-	userId, err := shared.GetUserIDFromToken(request)
-	if err != nil {
-		log.Printf("Warning: Unauthenticated search request: %v", err)
-		// Continue with the search even without authentication
+	log.Printf("Search request from user: %s", shared.UserID(request))
+
+	// Search our database and the catalogue at the same time
+	ctx, cancel := context.WithTimeout(shared.RequestContext(request), combinedSearchTimeout)
+	defer cancel()
+
+	type sourceResult struct {
+		source  SearchSource
+		db      []models.BookData
+		catalog []SearchResultEntry
+	}
+	arrived := make(chan sourceResult, 2)
+	pending := 1
+	go func() {
+		books, source := runSearchSource(ctx, "database", databaseSearchTimeout, func(ctx context.Context) ([]models.BookData, error) {
+			page, err := stores.Search.Search(ctx, q, pagination.Request{Limit: searchResultLimit})
+			if err != nil {
+				return nil, err
+			}
+			return page.Books, nil
+		})
+		arrived <- sourceResult{source: source, db: books}
+	}()
+
+	// Search the catalogue if query is not too short
+	catalogue := SearchSource{Name: metadataProvider.Name(), Status: sourceSkipped}
+	if len(q) >= 2 {
+		pending++
+		go func() {
+			results, source := runSearchSource(ctx, metadataProvider.Name(), catalogueSearchTimeout, func(ctx context.Context) ([]SearchResultEntry, error) {
+				return SearchCatalogue(ctx, q)
+			})
+			arrived <- sourceResult{source: source, catalog: results}
+		}()
 	} else {
-		log.Printf("Search request from user: %s", userId)
+		log.Printf("Query too short for catalogue search, skipping")
 	}
 
-	// Search our database
-	svc := shared.DynamoDBClient()
-	dbBooks, err := searchByPartialTitle(svc, q)
-	if err != nil {
-		log.Printf("Error searching database: %v", err)
-		// Continue with Open Library search even if database search fails
-		dbBooks = []BookData{}
+	// Merge the results as they arrive. Each source gives up at its own
+	// timeout, so this never waits past the slower of them.
+	database := SearchSource{Name: "database"}
+	var merged searchMerge
+	for ; pending > 0; pending-- {
+		result := <-arrived
+		log.Printf("Search source %s: %s after %.1fms", result.source.Name, result.source.Status, result.source.DurationMs)
+		if result.source.Name == "database" {
+			database = result.source
+			merged.addDatabase(result.db)
+		} else {
+			catalogue = result.source
+			merged.addCatalogue(result.catalog)
+		}
 	}
 
-	log.Printf("Database search returned %d results", len(dbBooks))
+	log.Printf("Merged search results: %d total items", len(merged.items))
 
-	// Search Open Library if query is not too short
-	var olResults []SearchResultEntry
-	if len(q) >= 2 {
-		olResults, err = SearchOpenLibrary(q)
-		if err != nil {
-			log.Printf("Error searching Open Library: %v", err)
-			// Continue with database results even if Open Library search fails
-			olResults = []SearchResultEntry{}
+	sources := []SearchSource{database, catalogue}
+	response := CombinedSearchResponse{Items: merged.items, Sources: sources}
+	if response.Items == nil {
+		response.Items = []SearchResultEntry{}
+	}
+	for _, source := range sources {
+		if source.Status != sourceOK && source.Status != sourceSkipped {
+			response.Partial = true
 		}
-		log.Printf("Open Library search returned %d results", len(olResults))
-	} else {
-		log.Printf("Query too short for Open Library search, skipping")
 	}
+	return shared.SuccessResponse(200, response)
+}
+
+// runSearchSource runs search with its own timeout within ctx and reports
+// how it went. search should give up once its context is done, but may
+// still be finishing a call that cannot be cancelled (the search index loads
+// books without a context); runSearchSource stops waiting for it all the
+// same, and returns no results.
+func runSearchSource[T any](ctx context.Context, name string, timeout time.Duration, search func(context.Context) (T, error)) (T, SearchSource) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
-	// Merge the results
-	mergedResults := MergeSearchResults(dbBooks, olResults)
+	type outcome struct {
+		results T
+		err     error
+	}
+	start := time.Now()
+	done := make(chan outcome, 1)
+	go func() {
+		results, err := search(ctx)
+		done <- outcome{results, err}
+	}()
 
-	log.Printf("Merged search results: %d total items", len(mergedResults))
+	var results T
+	source := SearchSource{Name: name, Status: sourceOK}
+	select {
+	case o := <-done:
+		switch {
+		case o.err == nil:
+			results = o.results
+		case errors.Is(o.err, httpclient.ErrCircuitOpen):
+			source.Status, source.Error = sourceUnavailable, "Temporarily unavailable"
+		case errors.Is(o.err, context.DeadlineExceeded):
+			source.Status, source.Error = sourceTimeout, "Timed out"
+		default:
+			source.Status, source.Error = sourceFailed, "Search failed"
+		}
+		if o.err != nil {
+			log.Printf("Error searching %s: %v", name, o.err)
+		}
+	case <-ctx.Done():
+		source.Status, source.Error = sourceTimeout, "Timed out"
+	}
+	source.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+	return results, source
+}
 
-	// Convert to JSON and return
-	responseBytes, _ := json.Marshal(mergedResults)
-	return shared.SuccessResponse(200, json.RawMessage(responseBytes))
+// catalogueIds returns the IDs a saved book has in the metadata providers'
+// catalogues: its Open Library work ID and any Google Books volume ID tag.
+func catalogueIds(book models.BookData) []string {
+	var ids []string
+	if book.OpenLibraryId != "" {
+		ids = append(ids, book.OpenLibraryId)
+	}
+	for _, tag := range book.Tags {
+		if id, ok := strings.CutPrefix(tag, "Google:"); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
 }
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+)
+
+func TestSearchMerge(t *testing.T) {
+	saved := []models.BookData{
+		{BookID: "b1", Title: "Dune", OpenLibraryId: "OL1W"},
+		{BookID: "b2", Title: "Emma", Tags: []string{"Google:g2"}},
+	}
+	catalogue := []SearchResultEntry{
+		{BookId: "OL1W", Source: "openLibrary"},
+		{BookId: "g2", Source: "googleBooks"},
+		{BookId: "OL3W", Source: "openLibrary"},
+	}
+	want := []string{"b1", "b2", "OL3W"}
+
+	tests := []struct {
+		name  string
+		merge func(*searchMerge)
+	}{
+		{"database first", func(m *searchMerge) { m.addDatabase(saved); m.addCatalogue(catalogue) }},
+		{"catalogue first", func(m *searchMerge) { m.addCatalogue(catalogue); m.addDatabase(saved) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var merged searchMerge
+			tt.merge(&merged)
+			var got []string
+			for _, item := range merged.items {
+				got = append(got, item.BookId)
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("merged %v, want %v", got, want)
+			}
+		})
+	}
+
+	var merged searchMerge
+	merged.addCatalogue(catalogue)
+	merged.addDatabase(nil)
+	if len(merged.items) != len(catalogue) {
+		t.Errorf("failed database search dropped catalogue results: %v", merged.items)
+	}
+}
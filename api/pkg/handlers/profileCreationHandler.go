@@ -2,15 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 
 	"github.com/FriedGlue/BookIt/api/pkg/models"
 	"github.com/FriedGlue/BookIt/api/pkg/shared"
+	"github.com/FriedGlue/BookIt/api/pkg/store"
 	"github.com/aws/aws-lambda-go/events"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 )
 
 // ----------------------- Handlers -----------------------
@@ -18,84 +17,27 @@ import (
 // GetProfile retrieves the user’s profile from DynamoDB
 func GetProfile(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
 	log.Println("GetProfile invoked")
-	userId, err := shared.GetUserIDFromToken(request)
-	if err != nil {
-		log.Printf("Error extracting userId: %v\n", err)
-		return shared.ErrorResponse(401, err.Error())
-	}
-
-	svc := shared.DynamoDBClient()
-
-	input := &dynamodb.GetItemInput{
-		TableName: aws.String(PROFILES_TABLE_NAME),
-		Key: map[string]*dynamodb.AttributeValue{
-			"_id": {S: aws.String(userId)},
-		},
-	}
+	userId := shared.UserID(request)
 
 	log.Printf("Fetching profile for userId: %s\n", userId)
-	result, err := svc.GetItem(input)
-	if err != nil {
-		log.Printf("DynamoDB GetItem error: %v\n", err)
-		return shared.ErrorResponse(500, fmt.Sprintf("DynamoDB GetItem error: %v", err))
-	}
-	if result.Item == nil {
-		log.Println("Profile not found")
-		return shared.ErrorResponse(404, "Profile not found")
-	}
-
-	var profile models.Profile
-	if err := dynamodbattribute.UnmarshalMap(result.Item, &profile); err != nil {
-		log.Printf("Error unmarshalling profile: %v\n", err)
-		return shared.ErrorResponse(500, "Error unmarshalling profile: "+err.Error())
-	}
-
-	responseBody, err := json.Marshal(profile)
+	profile, err := stores.Profiles.Get(userId)
 	if err != nil {
-		log.Printf("Error marshalling response: %v\n", err)
-		return shared.ErrorResponse(500, "Error marshalling profile response")
+		return storeErrorResponse(err, shared.CodeProfileNotFound, "Profile not found")
 	}
 
 	log.Println("Profile retrieval successful")
-	return events.APIGatewayProxyResponse{
-		StatusCode: 200,
-		Body:       string(responseBody),
-	}
+	return shared.SuccessResponse(200, profile)
 }
 
 // GetProfileAndUpdateReadingChallenges retrieves the user's profile from DynamoDB and updates reading challenges
 func GetProfileAndUpdateReadingChallenges(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
 	log.Println("GetProfileAndUpdateReadingChallenges invoked")
-	userId, err := shared.GetUserIDFromToken(request)
-	if err != nil {
-		log.Printf("Error extracting userId: %v\n", err)
-		return shared.ErrorResponse(401, err.Error())
-	}
-
-	svc := shared.DynamoDBClient()
-
-	input := &dynamodb.GetItemInput{
-		TableName: aws.String(PROFILES_TABLE_NAME),
-		Key: map[string]*dynamodb.AttributeValue{
-			"_id": {S: aws.String(userId)},
-		},
-	}
+	userId := shared.UserID(request)
 
 	log.Printf("Fetching profile for userId: %s\n", userId)
-	result, err := svc.GetItem(input)
+	profile, err := stores.Profiles.Get(userId)
 	if err != nil {
-		log.Printf("DynamoDB GetItem error: %v\n", err)
-		return shared.ErrorResponse(500, fmt.Sprintf("DynamoDB GetItem error: %v", err))
-	}
-	if result.Item == nil {
-		log.Println("Profile not found")
-		return shared.ErrorResponse(404, "Profile not found")
-	}
-
-	var profile models.Profile
-	if err := dynamodbattribute.UnmarshalMap(result.Item, &profile); err != nil {
-		log.Printf("Error unmarshalling profile: %v\n", err)
-		return shared.ErrorResponse(500, "Error unmarshalling profile: "+err.Error())
+		return storeErrorResponse(err, shared.CodeProfileNotFound, "Profile not found")
 	}
 
 	log.Println("Profile retrieval successful")
@@ -103,90 +45,66 @@ func GetProfileAndUpdateReadingChallenges(request events.APIGatewayProxyRequest)
 	// Update reading challenges if profile has any
 	if len(profile.Challenges) > 0 {
 		log.Printf("Updating %d reading challenges for user %s\n", len(profile.Challenges), userId)
-		updateChallenges(&profile)
+		if err := refreshChallenges(profile); err != nil {
+			return internalErrorResponse("Error updating reading challenges", err)
+		}
 		log.Println("Profile challenges update successful")
 	}
 
-	responseBody, err := json.Marshal(profile)
-	if err != nil {
-		log.Printf("Error marshalling response: %v\n", err)
-		return shared.ErrorResponse(500, "Error marshalling profile response")
-	}
-
-	return events.APIGatewayProxyResponse{
-		StatusCode: 200,
-		Body:       string(responseBody),
-	}
+	return shared.SuccessResponse(200, profile)
 }
 
 // CreateOrUpdateProfile either creates a new profile or updates an existing one
 func CreateOrUpdateProfile(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
 	log.Println("CreateOrUpdateProfile invoked")
-	userId, err := shared.GetUserIDFromToken(request)
-	if err != nil {
-		log.Printf("Error extracting userId: %v\n", err)
-		return shared.ErrorResponse(401, err.Error())
-	}
+	userId := shared.UserID(request)
 
 	var incomingProfile models.Profile
 	if err := json.Unmarshal([]byte(request.Body), &incomingProfile); err != nil {
 		log.Printf("Invalid JSON: %v\n", err)
-		return shared.ErrorResponse(400, "Invalid JSON: "+err.Error())
+		return shared.Error(shared.CodeInvalidJSON, "Invalid JSON: "+err.Error())
 	}
 
 	incomingProfile.ID = userId
 	log.Printf("Creating/Updating profile for userId: %s\n", userId)
 
-	item, err := dynamodbattribute.MarshalMap(incomingProfile)
-	if err != nil {
-		log.Printf("Error marshalling profile: %v\n", err)
-		return shared.ErrorResponse(500, "Error marshalling profile: "+err.Error())
+	// Replace an existing profile through Update so the write is still
+	// version-checked against concurrent changes.
+	replace := func(profile *models.Profile) error {
+		version := profile.Version
+		*profile = incomingProfile
+		profile.Version = version
+		return nil
+	}
+	_, err := stores.Profiles.Update(userId, replace)
+	if errors.Is(err, store.ErrNotFound) {
+		// A request creating the profile at the same time wins the create,
+		// and this one then replaces its profile like any other update.
+		err = stores.Profiles.Create(&incomingProfile)
+		if errors.Is(err, store.ErrAlreadyExists) {
+			_, err = stores.Profiles.Update(userId, replace)
+		}
 	}
-
-	svc := shared.DynamoDBClient()
-	input := &dynamodb.PutItemInput{
-		TableName: aws.String(PROFILES_TABLE_NAME),
-		Item:      item,
-	}
-	_, err = svc.PutItem(input)
 	if err != nil {
-		log.Printf("DynamoDB PutItem error: %v\n", err)
-		return shared.ErrorResponse(500, fmt.Sprintf("DynamoDB PutItem error: %v", err))
+		return storeErrorResponse(err, shared.CodeProfileNotFound, "Profile not found")
 	}
 
 	log.Printf("Profile created/updated for user %s\n", userId)
-	return events.APIGatewayProxyResponse{
-		StatusCode: 200,
-		Body:       fmt.Sprintf("Profile created or updated for user %s", userId),
-	}
+	return shared.MessageResponse(200, fmt.Sprintf("Profile created or updated for user %s", userId))
 }
 
 // DeleteProfile removes a user's profile from DynamoDB
 func DeleteProfile(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
 	log.Println("DeleteProfile invoked")
-	userId, err := shared.GetUserIDFromToken(request)
-	if err != nil {
-		log.Printf("Error extracting userId: %v\n", err)
-		return shared.ErrorResponse(401, err.Error())
-	}
+	userId := shared.UserID(request)
 
-	svc := shared.DynamoDBClient()
-	input := &dynamodb.DeleteItemInput{
-		TableName: aws.String(PROFILES_TABLE_NAME),
-		Key: map[string]*dynamodb.AttributeValue{
-			"_id": {S: aws.String(userId)},
-		},
+	if err := stores.ReadingLog.DeleteAll(userId); err != nil {
+		return internalErrorResponse("Error deleting reading log", err)
 	}
-
-	_, err = svc.DeleteItem(input)
-	if err != nil {
-		log.Printf("DynamoDB DeleteItem error: %v\n", err)
-		return shared.ErrorResponse(500, fmt.Sprintf("DynamoDB DeleteItem error: %v", err))
+	if err := stores.Profiles.Delete(userId); err != nil {
+		return storeErrorResponse(err, shared.CodeProfileNotFound, "Profile not found")
 	}
 
 	log.Printf("Profile deleted for user %s\n", userId)
-	return events.APIGatewayProxyResponse{
-		StatusCode: 200,
-		Body:       fmt.Sprintf("Profile deleted for user %s", userId),
-	}
+	return shared.MessageResponse(200, fmt.Sprintf("Profile deleted for user %s", userId))
 }
@@ -1,35 +1,55 @@
 package handlers
 
 import (
-	"encoding/json"
 	"log"
 	"math"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/google/uuid"
 
 	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/FriedGlue/BookIt/api/pkg/pagination"
 	"github.com/FriedGlue/BookIt/api/pkg/shared"
+	"github.com/FriedGlue/BookIt/api/pkg/validation"
 )
 
+// CreateChallengeRequest is the body of POST /challenges.
+type CreateChallengeRequest struct {
+	Name      string               `json:"name" validate:"required,max=100"`
+	Type      models.ChallengeType `json:"type" validate:"required,oneof=BOOKS PAGES"`
+	TimeFrame models.TimeFrame     `json:"timeframe" validate:"required,oneof=YEAR MONTH WEEK"`
+	StartDate time.Time            `json:"startDate" validate:"required"`
+	EndDate   time.Time            `json:"endDate" validate:"required"`
+	Target    int                  `json:"target" validate:"required,min=1"`
+}
+
+func (r CreateChallengeRequest) Validate() []validation.FieldError {
+	if !r.StartDate.IsZero() && !r.EndDate.IsZero() && !r.EndDate.After(r.StartDate) {
+		return []validation.FieldError{{Field: "endDate", Message: "must be after startDate"}}
+	}
+	return nil
+}
+
 // CreateChallenge creates a new reading challenge and appends it to the profile's Challenges field.
 func CreateChallenge(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
 	log.Println("CreateChallenge invoked")
 
-	var challenge models.ReadingChallenge
-	if err := json.Unmarshal([]byte(request.Body), &challenge); err != nil {
-		return shared.ErrorResponse(400, "Invalid request body")
+	var createReq CreateChallengeRequest
+	if apiErr := validation.Decode(request.Body, &createReq); apiErr != nil {
+		return shared.ErrorResponse(apiErr)
+	}
+	challenge := models.ReadingChallenge{
+		Name:      createReq.Name,
+		Type:      createReq.Type,
+		TimeFrame: createReq.TimeFrame,
+		StartDate: createReq.StartDate,
+		EndDate:   createReq.EndDate,
+		Target:    createReq.Target,
 	}
 
 	// Get user ID from token
-	userID, err := shared.GetUserIDFromToken(request)
-	if err != nil {
-		return shared.ErrorResponse(401, err.Error())
-	}
+	userID := shared.UserID(request)
 
 	// Initialize challenge fields
 	challenge.ID = uuid.New().String()
@@ -59,91 +79,63 @@ func CreateChallenge(request events.APIGatewayProxyRequest) events.APIGatewayPro
 		},
 	}
 
-	// Retrieve the user's profile from DynamoDB using the user ID (stored as "_id")
-	svc := shared.DynamoDBClient()
-	getInput := &dynamodb.GetItemInput{
-		TableName: aws.String(ProfilesTableName),
-		Key: map[string]*dynamodb.AttributeValue{
-			"_id": {S: aws.String(userID)},
-		},
-	}
-	result, err := svc.GetItem(getInput)
-	if err != nil {
-		return shared.ErrorResponse(500, "Error fetching profile")
-	}
-	if result.Item == nil {
-		return shared.ErrorResponse(404, "Profile not found")
-	}
-
-	var profile models.Profile
-	if err := dynamodbattribute.UnmarshalMap(result.Item, &profile); err != nil {
-		return shared.ErrorResponse(500, "Error unmarshalling profile")
-	}
-
-	//  If the challenge start date is in the past, check the reading log for existing progress ***
+	// If the challenge start date is in the past, check the reading log for existing progress
+	var entries []models.ReadingLogItem
 	if now.After(challenge.StartDate) {
-		aggProgress := aggregateChallengeProgress(&profile, challenge)
-		challenge.Progress.Current = aggProgress
-		if challenge.Target != 0 {
-			challenge.Progress.Percentage = float64(aggProgress) / float64(challenge.Target) * 100
+		var err error
+		entries, err = stores.ReadingLog.Query(userID, challenge.StartDate, challenge.EndDate)
+		if err != nil {
+			return internalErrorResponse("Error loading reading log", err)
 		}
-		challenge.Progress.Rate.CurrentPace = calculateCurrentPace(challenge, now)
-		scheduleDiff, status := calculateScheduleStatus(challenge, now)
-		challenge.Progress.Rate.ScheduleDiff = scheduleDiff
-		challenge.Progress.Rate.Status = status
 	}
 
-	// Append the new challenge to the profile's Challenges slice
-	profile.Challenges = append(profile.Challenges, challenge)
-
-	// Marshal the updated profile back to a map and write it back to DynamoDB
-	updatedProfile, err := dynamodbattribute.MarshalMap(profile)
-	if err != nil {
-		return shared.ErrorResponse(500, "Error marshalling updated profile")
-	}
+	_, err := stores.Profiles.Update(userID, func(profile *models.Profile) error {
+		if now.After(challenge.StartDate) {
+			aggProgress := aggregateChallengeProgress(entries, challenge)
+			challenge.Progress.Current = aggProgress
+			if challenge.Target != 0 {
+				challenge.Progress.Percentage = float64(aggProgress) / float64(challenge.Target) * 100
+			}
+			challenge.Progress.Rate.CurrentPace = calculateCurrentPace(challenge, now)
+			scheduleDiff, status := calculateScheduleStatus(challenge, now)
+			challenge.Progress.Rate.ScheduleDiff = scheduleDiff
+			challenge.Progress.Rate.Status = status
+		}
 
-	_, err = svc.PutItem(&dynamodb.PutItemInput{
-		TableName: aws.String(ProfilesTableName),
-		Item:      updatedProfile,
+		// Append the new challenge to the profile's Challenges slice
+		profile.Challenges = append(profile.Challenges, challenge)
+		return nil
 	})
 	if err != nil {
-		return shared.ErrorResponse(500, "Error saving updated profile")
+		return storeErrorResponse(err, shared.CodeProfileNotFound, "Profile not found")
 	}
 
 	return shared.SuccessResponse(201, challenge)
 }
 
-// GetChallenges retrieves all reading challenges from the profile.
+// GetChallenges retrieves the reading challenges from the profile, a page at
+// a time, ordered by start date.
 func GetChallenges(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
 	log.Println("GetChallenges invoked")
 
-	userID, err := shared.GetUserIDFromToken(request)
-	if err != nil {
-		return shared.ErrorResponse(401, err.Error())
+	userID := shared.UserID(request)
+	page, apiErr := pageRequest(request, pagination.DefaultLimit, pagination.MaxLimit)
+	if apiErr != nil {
+		return shared.ErrorResponse(apiErr)
 	}
 
-	svc := shared.DynamoDBClient()
-	getInput := &dynamodb.GetItemInput{
-		TableName: aws.String(ProfilesTableName),
-		Key: map[string]*dynamodb.AttributeValue{
-			"_id": {S: aws.String(userID)},
-		},
-	}
-	result, err := svc.GetItem(getInput)
+	profile, err := stores.Profiles.Get(userID)
 	if err != nil {
-		return shared.ErrorResponse(500, "Error fetching profile")
-	}
-	if result.Item == nil {
-		return shared.ErrorResponse(404, "Profile not found")
+		return storeErrorResponse(err, shared.CodeProfileNotFound, "Profile not found")
 	}
 
-	var profile models.Profile
-	if err := dynamodbattribute.UnmarshalMap(result.Item, &profile); err != nil {
-		return shared.ErrorResponse(500, "Error unmarshalling profile")
-	}
+	challenges, next := pagination.Slice(profile.Challenges, page, challengeKey)
+	return shared.PageResponse(200, challenges, pagination.Encode(next))
+}
 
-	// Return the challenges slice from the profile
-	return shared.SuccessResponse(200, profile.Challenges)
+// challengeKey orders challenges by start date, then ID.
+func challengeKey(challenge models.ReadingChallenge) string {
+	return challenge.StartDate.UTC().Format("20060102T150405") + "/" + challenge.ID
 }
 
 // calculateRequiredRate computes the required reading rate based on the challenge's timeframe.
@@ -182,86 +174,60 @@ func calculateRequiredRate(challenge models.ReadingChallenge) (float64, string)
 	return rate, unit
 }
 
+// UpdateChallengeRequest is the body of PUT /challenges/{id}. Progress is
+// recalculated from the reading log, so Current is accepted but not used.
+type UpdateChallengeRequest struct {
+	Current int `json:"current" validate:"min=0"`
+}
+
 // UpdateChallenge updates a specific reading challenge within the profile.
 func UpdateChallenge(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
 	log.Println("UpdateChallenge invoked")
 
 	challengeID := request.PathParameters["id"]
-	userID, err := shared.GetUserIDFromToken(request)
-	if err != nil {
-		return shared.ErrorResponse(401, err.Error())
-	}
-
-	var updateData struct {
-		Current int `json:"current"`
-	}
-	if err := json.Unmarshal([]byte(request.Body), &updateData); err != nil {
-		return shared.ErrorResponse(400, "Invalid request body")
-	}
+	userID := shared.UserID(request)
 
-	svc := shared.DynamoDBClient()
-	getInput := &dynamodb.GetItemInput{
-		TableName: aws.String(ProfilesTableName),
-		Key: map[string]*dynamodb.AttributeValue{
-			"_id": {S: aws.String(userID)},
-		},
-	}
-	result, err := svc.GetItem(getInput)
-	if err != nil {
-		return shared.ErrorResponse(500, "Error fetching profile")
-	}
-	if result.Item == nil {
-		return shared.ErrorResponse(404, "Profile not found")
-	}
-
-	var profile models.Profile
-	if err := dynamodbattribute.UnmarshalMap(result.Item, &profile); err != nil {
-		return shared.ErrorResponse(500, "Error unmarshalling profile")
+	var updateData UpdateChallengeRequest
+	if apiErr := validation.Decode(request.Body, &updateData); apiErr != nil {
+		return shared.ErrorResponse(apiErr)
 	}
 
 	// Use a common 'now' for all calculations.
 	now := time.Now()
 
-	found := false
-	for i, ch := range profile.Challenges {
-		if ch.ID == challengeID {
-			// Update progress using the aggregated value from the reading log.
-			aggProgress := aggregateChallengeProgress(&profile, ch)
-			profile.Challenges[i].Progress.Current = aggProgress
-			if ch.Target != 0 {
-				profile.Challenges[i].Progress.Percentage = float64(aggProgress) / float64(ch.Target) * 100
+	profile, err := stores.Profiles.Update(userID, func(profile *models.Profile) error {
+		for i, ch := range profile.Challenges {
+			if ch.ID == challengeID {
+				// Update progress using the aggregated value from the reading log.
+				entries, err := stores.ReadingLog.Query(userID, ch.StartDate, ch.EndDate)
+				if err != nil {
+					return err
+				}
+				aggProgress := aggregateChallengeProgress(entries, ch)
+				profile.Challenges[i].Progress.Current = aggProgress
+				if ch.Target != 0 {
+					profile.Challenges[i].Progress.Percentage = float64(aggProgress) / float64(ch.Target) * 100
+				}
+
+				// Update the reading pace with the common 'now'
+				profile.Challenges[i].Progress.Rate.CurrentPace = calculateCurrentPace(profile.Challenges[i], now)
+
+				// Update schedule difference and status.
+				scheduleDiff, status := calculateScheduleStatus(profile.Challenges[i], now)
+				profile.Challenges[i].Progress.Rate.ScheduleDiff = scheduleDiff
+				profile.Challenges[i].Progress.Rate.Status = status
+
+				profile.Challenges[i].UpdatedAt = now
+				return nil
 			}
-
-			// Update the reading pace with the common 'now'
-			profile.Challenges[i].Progress.Rate.CurrentPace = calculateCurrentPace(profile.Challenges[i], now)
-
-			// Update schedule difference and status.
-			scheduleDiff, status := calculateScheduleStatus(profile.Challenges[i], now)
-			profile.Challenges[i].Progress.Rate.ScheduleDiff = scheduleDiff
-			profile.Challenges[i].Progress.Rate.Status = status
-
-			profile.Challenges[i].UpdatedAt = now
-			found = true
-			break
 		}
-	}
-	if !found {
-		return shared.ErrorResponse(404, "Challenge not found")
-	}
-
-	updatedProfile, err := dynamodbattribute.MarshalMap(profile)
-	if err != nil {
-		return shared.ErrorResponse(500, "Error marshalling updated profile")
-	}
-	_, err = svc.PutItem(&dynamodb.PutItemInput{
-		TableName: aws.String(ProfilesTableName),
-		Item:      updatedProfile,
+		return shared.NewError(shared.CodeChallengeNotFound, "Challenge not found")
 	})
 	if err != nil {
-		return shared.ErrorResponse(500, "Error saving updated profile")
+		return storeErrorResponse(err, shared.CodeProfileNotFound, "Profile not found")
 	}
 
-	return shared.SuccessResponse(200, profile.Challenges)
+	return shared.ListResponse(200, profile.Challenges)
 }
 
 // DeleteChallenge deletes a specific reading challenge from the profile.
@@ -269,74 +235,70 @@ func DeleteChallenge(request events.APIGatewayProxyRequest) events.APIGatewayPro
 	log.Println("DeleteChallenge invoked")
 
 	challengeID := request.PathParameters["id"]
-	userID, err := shared.GetUserIDFromToken(request)
-	if err != nil {
-		return shared.ErrorResponse(401, err.Error())
-	}
+	userID := shared.UserID(request)
+
+	_, err := stores.Profiles.Update(userID, func(profile *models.Profile) error {
+		// Find the challenge index to delete
+		indexToDelete := -1
+		for i, ch := range profile.Challenges {
+			if ch.ID == challengeID {
+				indexToDelete = i
+				break
+			}
+		}
+		if indexToDelete < 0 {
+			return shared.NewError(shared.CodeChallengeNotFound, "Challenge not found")
+		}
 
-	svc := shared.DynamoDBClient()
-	// Retrieve the user's profile
-	getInput := &dynamodb.GetItemInput{
-		TableName: aws.String(ProfilesTableName),
-		Key: map[string]*dynamodb.AttributeValue{
-			"_id": {S: aws.String(userID)},
-		},
-	}
-	result, err := svc.GetItem(getInput)
+		// Remove the challenge from the slice
+		profile.Challenges = append(profile.Challenges[:indexToDelete], profile.Challenges[indexToDelete+1:]...)
+		return nil
+	})
 	if err != nil {
-		return shared.ErrorResponse(500, "Error fetching profile")
-	}
-	if result.Item == nil {
-		return shared.ErrorResponse(404, "Profile not found")
+		return storeErrorResponse(err, shared.CodeProfileNotFound, "Profile not found")
 	}
 
-	var profile models.Profile
-	if err := dynamodbattribute.UnmarshalMap(result.Item, &profile); err != nil {
-		return shared.ErrorResponse(500, "Error unmarshalling profile")
+	return shared.MessageResponse(200, "Challenge deleted successfully")
+}
+
+// refreshChallenges loads the reading log entries covering every challenge on
+// the profile and recalculates their progress. pending holds entries that are
+// about to be appended to the log and should already count.
+func refreshChallenges(profile *models.Profile, pending ...models.ReadingLogItem) error {
+	if len(profile.Challenges) == 0 {
+		return nil
 	}
 
-	// Find the challenge index to delete
-	indexToDelete := -1
-	for i, ch := range profile.Challenges {
-		if ch.ID == challengeID {
-			indexToDelete = i
-			break
+	from, to := profile.Challenges[0].StartDate, profile.Challenges[0].EndDate
+	for _, ch := range profile.Challenges[1:] {
+		if ch.StartDate.Before(from) {
+			from = ch.StartDate
+		}
+		if ch.EndDate.After(to) {
+			to = ch.EndDate
 		}
 	}
-	if indexToDelete < 0 {
-		return shared.ErrorResponse(404, "Challenge not found")
-	}
-
-	// Remove the challenge from the slice
-	profile.Challenges = append(profile.Challenges[:indexToDelete], profile.Challenges[indexToDelete+1:]...)
 
-	// Write the updated profile back to DynamoDB
-	updatedProfile, err := dynamodbattribute.MarshalMap(profile)
+	entries, err := stores.ReadingLog.Query(profile.ID, from, to)
 	if err != nil {
-		return shared.ErrorResponse(500, "Error marshalling updated profile")
+		return err
 	}
-	_, err = svc.PutItem(&dynamodb.PutItemInput{
-		TableName: aws.String(ProfilesTableName),
-		Item:      updatedProfile,
-	})
-	if err != nil {
-		return shared.ErrorResponse(500, "Error deleting challenge from profile")
-	}
-
-	return shared.SuccessResponse(200, map[string]string{"message": "Challenge deleted successfully"})
+	updateChallenges(profile, append(entries, pending...))
+	return nil
 }
 
-// updateChallenges recalculates progress for each reading challenge in the profile.
+// updateChallenges recalculates progress for each reading challenge in the profile
+// from the given reading log entries.
 // It uses your existing calculation functions (calculateCurrentPace and calculateScheduleStatus)
 // to update the challenge fields.
-func updateChallenges(profile *models.Profile) {
+func updateChallenges(profile *models.Profile, entries []models.ReadingLogItem) {
 	now := time.Now()
 	// Loop through every challenge on the profile
 	for i, ch := range profile.Challenges {
 		log.Printf("Updating challenge %s: target=%d, type=%s, timeframe=%s", ch.ID, ch.Target, ch.Type, ch.TimeFrame)
 
 		// Compute the aggregated progress based on challenge type.
-		aggProgress := aggregateChallengeProgress(profile, ch)
+		aggProgress := aggregateChallengeProgress(entries, ch)
 		log.Printf("Aggregated progress for challenge %s: %d", ch.ID, aggProgress)
 
 		// Set the current progress and update percentage.
@@ -413,13 +375,13 @@ func calculateScheduleStatus(challenge models.ReadingChallenge, now time.Time) (
 }
 
 // aggregateChallengeProgress aggregates the total progress for a given challenge.
-// It only counts reading log entries with a Date on or after the challenge's start date.
-func aggregateChallengeProgress(profile *models.Profile, challenge models.ReadingChallenge) int {
+// It only counts reading log entries dated within the challenge's start and end dates.
+func aggregateChallengeProgress(entries []models.ReadingLogItem, challenge models.ReadingChallenge) int {
 	total := 0
 	switch challenge.Type {
 	case models.BooksChallenge:
 		// For a books challenge, count log entries that indicate a completed book.
-		for _, logEntry := range profile.ReadingLog {
+		for _, logEntry := range entries {
 			// Parse the date string into time.Time
 			logDate, err := time.Parse(time.RFC3339, logEntry.Date)
 			if err != nil {
@@ -436,7 +398,7 @@ func aggregateChallengeProgress(profile *models.Profile, challenge models.Readin
 		log.Printf("Challenge %s (Books): total completed books = %d", challenge.ID, total)
 	case models.PagesChallenge:
 		// For a pages challenge, sum the pages read from the appropriate log entries.
-		for _, logEntry := range profile.ReadingLog {
+		for _, logEntry := range entries {
 			// Parse the date string into time.Time
 			logDate, err := time.Parse(time.RFC3339, logEntry.Date)
 			if err != nil {
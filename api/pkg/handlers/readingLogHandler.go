@@ -1,70 +1,49 @@
 package handlers
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
-	"os"
+	"time"
 
 	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/FriedGlue/BookIt/api/pkg/pagination"
 	"github.com/FriedGlue/BookIt/api/pkg/shared"
+	"github.com/FriedGlue/BookIt/api/pkg/validation"
 	"github.com/aws/aws-lambda-go/events"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 )
 
-// ProfilesTableName is read from the environment.
-var ProfilesTableName = os.Getenv("PROFILES_TABLE_NAME")
-
 // HandleGetReadingLog is a Lambda handler to retrieve a user's reading log.
+// The optional "from" and "to" query parameters (RFC3339 or YYYY-MM-DD)
+// limit the entries returned to that date range. Entries come oldest first,
+// a page at a time (?limit= and ?cursor=).
 func GetReadingLog(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
 	log.Println("GetReadingLog invoked")
-	userId, err := shared.GetUserIDFromToken(request)
-	if err != nil {
-		log.Printf("Error extracting userId: %v\n", err)
-		return shared.ErrorResponse(401, err.Error())
-	}
+	userId := shared.UserID(request)
 
-	svc := shared.DynamoDBClient()
-	getInput := &dynamodb.GetItemInput{
-		TableName: aws.String(ProfilesTableName),
-		Key: map[string]*dynamodb.AttributeValue{
-			"_id": {S: aws.String(userId)},
-		},
-	}
-
-	result, err := svc.GetItem(getInput)
+	from, err := parseReadingLogDate(request.QueryStringParameters["from"], false)
 	if err != nil {
-		return shared.ErrorResponse(500, fmt.Sprintf("Error retrieving profile: %v", err))
+		return shared.Error(shared.CodeInvalidParameter, fmt.Sprintf("Invalid from date: %v", err))
 	}
-	if result.Item == nil {
-		return shared.ErrorResponse(404, "Profile not found")
+	to, err := parseReadingLogDate(request.QueryStringParameters["to"], true)
+	if err != nil {
+		return shared.Error(shared.CodeInvalidParameter, fmt.Sprintf("Invalid to date: %v", err))
 	}
 
-	var profile models.Profile
-	if err := dynamodbattribute.UnmarshalMap(result.Item, &profile); err != nil {
-		return shared.ErrorResponse(500, fmt.Sprintf("Error unmarshalling profile: %v", err))
+	page, apiErr := pageRequest(request, pagination.DefaultLimit, pagination.MaxLimit)
+	if apiErr != nil {
+		return shared.ErrorResponse(apiErr)
 	}
 
-	// Marshal the reading log to JSON.
-	responseBody, err := json.Marshal(profile.ReadingLog)
+	entries, next, err := stores.ReadingLog.QueryPage(userId, from, to, page)
 	if err != nil {
-		return shared.ErrorResponse(500, fmt.Sprintf("Error marshalling reading log: %v", err))
-	}
-
-	return events.APIGatewayProxyResponse{
-		StatusCode: 200,
-		Body:       string(responseBody),
-		Headers: map[string]string{
-			"Content-Type": "application/json",
-		},
+		return internalErrorResponse("Error loading reading log", err)
 	}
+	return shared.PageResponse(200, entries, pagination.Encode(next))
 }
 
 type UpdateReadingLogItemRequest struct {
-	ReadingLogItemId string `json:"readingLogItemId"`
-	PagesRead        int    `json:"pagesRead,omitempty"`
+	ReadingLogItemId string `json:"readingLogItemId" validate:"required"`
+	PagesRead        int    `json:"pagesRead,omitempty" validate:"min=0"`
 	Notes            string `json:"notes,omitempty"`
 }
 
@@ -72,161 +51,75 @@ func UpdateReadingLogItem(request events.APIGatewayProxyRequest) events.APIGatew
 	log.Println("UpdateReadingLog invoked")
 
 	// Extract the user ID from the token.
-	userId, err := shared.GetUserIDFromToken(request)
-	if err != nil {
-		log.Printf("Error extracting userId: %v\n", err)
-		return shared.ErrorResponse(401, err.Error())
-	}
-
-	// Retrieve the user's profile from DynamoDB.
-	svc := shared.DynamoDBClient()
-	getInput := &dynamodb.GetItemInput{
-		TableName: aws.String("PROFILES_TABLE_NAME"),
-		Key: map[string]*dynamodb.AttributeValue{
-			"_id": {S: aws.String(userId)},
-		},
-	}
-
-	result, err := svc.GetItem(getInput)
-	if err != nil {
-		return shared.ErrorResponse(500, fmt.Sprintf("Error retrieving profile: %v", err))
-	}
-	if result.Item == nil {
-		return shared.ErrorResponse(404, "Profile not found")
-	}
-
-	var profile models.Profile
-	if err := dynamodbattribute.UnmarshalMap(result.Item, &profile); err != nil {
-		return shared.ErrorResponse(500, fmt.Sprintf("Error unmarshalling profile: %v", err))
-	}
+	userId := shared.UserID(request)
 
 	// Unmarshal the request body into our update request structure.
 	var updateReq UpdateReadingLogItemRequest
-	if err := json.Unmarshal([]byte(request.Body), &updateReq); err != nil {
-		return shared.ErrorResponse(400, fmt.Sprintf("Invalid request body: %v", err))
-	}
-
-	if updateReq.ReadingLogItemId == "" {
-		return shared.ErrorResponse(400, "Missing readingLogItemId in request body")
+	if apiErr := validation.Decode(request.Body, &updateReq); apiErr != nil {
+		return shared.ErrorResponse(apiErr)
 	}
 
-	// Find the index of the reading log item using the provided readingLogItemId.
-	var indexToUpdate int
-	var found bool
-	for i, item := range profile.ReadingLog {
-		// Assumes the log item's identifier is stored in BookID.
-		if item.BookID == updateReq.ReadingLogItemId {
-			indexToUpdate = i
-			found = true
-			break
-		}
-	}
-	if !found {
-		return shared.ErrorResponse(404, "Reading log item not found")
-	}
-
-	// Update the reading log item.
-	// Here, we update PagesRead and Notes; you could also update a timestamp.
-	profile.ReadingLog[indexToUpdate].PagesRead = updateReq.PagesRead
-	profile.ReadingLog[indexToUpdate].Notes = updateReq.Notes
-	// Optionally, update a last-updated timestamp:
-	// profile.ReadingLog[indexToUpdate].LastUpdated = time.Now().Format(time.RFC3339)
-
-	// Marshal the updated profile back into a map for DynamoDB.
-	updatedProfile, err := dynamodbattribute.MarshalMap(profile)
+	_, err := stores.ReadingLog.Update(userId, updateReq.ReadingLogItemId, func(item *models.ReadingLogItem) error {
+		item.PagesRead = updateReq.PagesRead
+		item.Notes = updateReq.Notes
+		return nil
+	})
 	if err != nil {
-		log.Printf("Error marshalling updated profile: %v\n", err)
-		return shared.ErrorResponse(500, "Error marshalling updated profile: "+err.Error())
-	}
-
-	putInput := &dynamodb.PutItemInput{
-		TableName: aws.String("PROFILES_TABLE_NAME"),
-		Item:      updatedProfile,
-	}
-	_, err = svc.PutItem(putInput)
-	if err != nil {
-		log.Printf("DynamoDB PutItem error: %v\n", err)
-		return shared.ErrorResponse(500, fmt.Sprintf("DynamoDB PutItem error: %v", err))
+		return storeErrorResponse(err, shared.CodeReadingLogEntryNotFound, "Reading log item not found")
 	}
 
 	log.Printf("Reading log item updated for user %s\n", userId)
-	return events.APIGatewayProxyResponse{
-		StatusCode: 200,
-		Body:       "Reading log item updated successfully",
-	}
+	return shared.MessageResponse(200, "Reading log item updated successfully")
 }
 
 func DeleteReadingLogItem(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
 	log.Println("DeleteReadingLog invoked")
-	userId, err := shared.GetUserIDFromToken(request)
-	if err != nil {
-		log.Printf("Error extracting userId: %v\n", err)
-		return shared.ErrorResponse(401, err.Error())
-	}
-
-	svc := shared.DynamoDBClient()
-	getInput := &dynamodb.GetItemInput{
-		TableName: aws.String(ProfilesTableName),
-		Key: map[string]*dynamodb.AttributeValue{
-			"_id": {S: aws.String(userId)},
-		},
-	}
-
-	result, err := svc.GetItem(getInput)
-	if err != nil {
-		return shared.ErrorResponse(500, fmt.Sprintf("Error retrieving profile: %v", err))
-	}
-	if result.Item == nil {
-		return shared.ErrorResponse(404, "Profile not found")
-	}
-
-	var profile models.Profile
-	if err := dynamodbattribute.UnmarshalMap(result.Item, &profile); err != nil {
-		return shared.ErrorResponse(500, fmt.Sprintf("Error unmarshalling profile: %v", err))
-	}
+	userId := shared.UserID(request)
 
 	readingLogId, hasReadingLogId := request.QueryStringParameters["readingLogId"]
 	if !hasReadingLogId || readingLogId == "" {
-		return shared.ErrorResponse(400, "Missing query string parameter: readingLogId")
+		return shared.Error(shared.CodeMissingParameter, "Missing query string parameter: readingLogId")
 	}
 
-	// Find the index of the book in the reading log
-	var indexToDelete int
-	var found bool
-	for i, item := range profile.ReadingLog {
-		if item.Id == readingLogId {
-			indexToDelete = i
-			found = true
-			break
-		}
-	}
-	if !found {
-		return shared.ErrorResponse(404, "Entry not found in reading log")
+	if err := stores.ReadingLog.Delete(userId, readingLogId); err != nil {
+		return storeErrorResponse(err, shared.CodeReadingLogEntryNotFound, "Entry not found in reading log")
 	}
 
-	// Remove the book from the reading log
-	profile.ReadingLog = append(profile.ReadingLog[:indexToDelete], profile.ReadingLog[indexToDelete+1:]...)
+	log.Printf("Reading log item delete for user %s\n", userId)
+	return shared.MessageResponse(201, "Reading log item deleted successfully")
+}
 
-	// Update the profile in DynamoDB
-	updatedProfile, err := dynamodbattribute.MarshalMap(profile)
-	if err != nil {
-		log.Printf("Error marshalling updated profile: %v\n", err)
-		return shared.ErrorResponse(500, "Error marshalling updated profile: "+err.Error())
-	}
+// updateWithLog updates the user's profile like stores.Profiles.Update and
+// appends the reading log entries mutate returns in the same write, so the
+// log never misses a change to the profile or records one that failed. It
+// returns the entries as stored.
+func updateWithLog(userId string, mutate func(*models.Profile) ([]models.ReadingLogItem, error)) (*models.Profile, []models.ReadingLogItem, error) {
+	return stores.Profiles.UpdateWithLog(userId, stores.ReadingLog, func(profile *models.Profile) ([]models.ReadingLogItem, error) {
+		entries, err := mutate(profile)
+		// Entry dates only have second precision, so take the IDs from the
+		// clock to keep entries written in the same second in order.
+		for i := range entries {
+			entries[i].Id = models.NewReadingLogID(time.Now())
+		}
+		return entries, err
+	})
+}
 
-	putInput := &dynamodb.PutItemInput{
-		TableName: aws.String(PROFILES_TABLE_NAME),
-		Item:      updatedProfile,
+// parseReadingLogDate parses a date range bound. A bare date used as the end
+// of a range covers that whole day. An empty value returns the zero time.
+func parseReadingLogDate(value string, endOfRange bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
 	}
-	_, err = svc.PutItem(putInput)
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
 	if err != nil {
-		log.Printf("DynamoDB PutItem error: %v\n", err)
-		return shared.ErrorResponse(500, fmt.Sprintf("DynamoDB PutItem error: %v", err))
+		return time.Time{}, fmt.Errorf("expected RFC3339 or YYYY-MM-DD, got %q", value)
 	}
-
-	log.Printf("Reading log item delete for user %s\n", userId)
-	return events.APIGatewayProxyResponse{
-		StatusCode: 201,
-		Body:       "Reading log item deleted successfully",
+	if endOfRange {
+		t = t.Add(24*time.Hour - time.Nanosecond)
 	}
+	return t, nil
 }
@@ -1,293 +1,220 @@
 package handlers
 
 import (
-	"encoding/json"
-	"fmt"
-	"io/ioutil"
+	"context"
+	"errors"
 	"log"
-	"net/http"
-	"strings"
 
+	"github.com/FriedGlue/BookIt/api/pkg/metadata"
+	"github.com/FriedGlue/BookIt/api/pkg/models"
 	"github.com/FriedGlue/BookIt/api/pkg/shared"
+	"github.com/FriedGlue/BookIt/api/pkg/store"
+	"github.com/FriedGlue/BookIt/api/pkg/validation"
 	"github.com/aws/aws-lambda-go/events"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/google/uuid"
 )
 
-// OpenLibraryResponse represents the data structure returned from Open Library API
-type OpenLibraryResponse struct {
-	Description   Description    `json:"description"`
-	Title         string         `json:"title"`
-	URL           string         `json:"url"`
-	NumberOfPages int            `json:"number_of_pages"`
-	Subjects      []string       `json:"subjects"`
-	PublishDate   string         `json:"publish_date"`
-	Authors       []Author       `json:"authors"`
-	PublishPlaces []PublishPlace `json:"publish_places"`
-	Cover         []Cover        `json:"cover"`
-	Publishers    []Publisher    `json:"publishers"`
-	Identifiers   Identifiers    `json:"identifiers"`
-}
-
-type Description struct {
-	Value string `json:"value"`
-	Type  string `json:"type"`
-}
-
-type Publisher struct {
-	Name string `json:"name"`
-}
-
-type Author struct {
-	URL  string `json:"url"`
-	Name string `json:"name"`
-}
-
-type Excerpt struct {
-	Comment string `json:"comment"`
-	Text    string `json:"text"`
-}
-
-type PublishPlace struct {
-	Name string `json:"name"`
-}
-
-type Cover struct {
-	Small  string `json:"small"`
-	Medium string `json:"medium"`
-	Large  string `json:"large"`
-}
-
-type Identifiers struct {
-	ISBN13       []string `json:"isbn_13"`
-	ISBN10       []string `json:"isbn_10"`
-	Google       []string `json:"google"`
-	LCCN         []string `json:"lccn"`
-	OCLC         []string `json:"oclc"`
-	Goodreads    []string `json:"goodreads"`
-	LibraryThing []string `json:"librarything"`
+// SaveExternalBookRequest is the body of POST /books/save-external-book.
+type SaveExternalBookRequest struct {
+	BookId string `json:"bookId" validate:"required"` // catalogue work ID or our own book ID
 }
 
 // SaveExternalBook handles POST requests to /books/save-external-book
 func SaveExternalBook(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
-	// First try to extract user info using the standard method
-	userId, err := shared.GetUserIDFromToken(request)
-	if err != nil {
-		log.Printf("Authentication error: %v", err)
-		return shared.ErrorResponse(401, "Authentication required")
-	}
+	userId := shared.UserID(request)
 
 	// Log user making the request for debugging
 	log.Printf("Processing save-external-book request from user: %s", userId)
 
 	// Parse request body
-	var requestBody struct {
-		BookId string `json:"bookId"`
-	}
+	var requestBody SaveExternalBookRequest
 
-	err = json.Unmarshal([]byte(request.Body), &requestBody)
-	if err != nil {
-		return shared.ErrorResponse(400, "Invalid request body")
+	if apiErr := validation.Decode(request.Body, &requestBody); apiErr != nil {
+		return shared.ErrorResponse(apiErr)
 	}
 
 	// Log the request for debugging
 	log.Printf("Processing save-external-book request for bookId: %s", requestBody.BookId)
 
-	// Determine if this is an Open Library ID
 	bookId := requestBody.BookId
-	if len(bookId) > 2 && bookId[:2] == "OL" {
+	if metadata.IsOpenLibraryID(bookId) {
 		// This is an Open Library ID
 		// Check if the book already exists in our database
-		svc := shared.DynamoDBClient()
-		existingBooks, err := searchByOpenLibraryId(svc, bookId)
+		existingBooks, err := searchByOpenLibraryId(bookId)
 		if err == nil && len(existingBooks) > 0 {
 			// Book already exists, return it
 			log.Printf("Book already exists in database: %s", bookId)
 			return shared.SuccessResponse(200, existingBooks[0])
 		}
-
-		// Book doesn't exist, fetch from Open Library
-		log.Printf("Fetching book from Open Library: %s", bookId)
-		bookData, err := fetchBookFromOpenLibrary(bookId)
+	} else {
+		// This may be one of our own IDs, try to find it in our database
+		books, err := searchByBookId(bookId)
 		if err != nil {
-			log.Printf("Error fetching from Open Library: %v", err)
-			return shared.ErrorResponse(500, fmt.Sprintf("Error fetching book from Open Library: %v", err))
+			return internalErrorResponse("Error loading book", err)
 		}
-
-		// Save the book to our database
-		log.Printf("Saving book to database: %s", bookId)
-		savedBook, err := saveBookToDynamoDB(svc, bookData, bookId, userId)
-		if err != nil {
-			log.Printf("Error saving to database: %v", err)
-			return shared.ErrorResponse(500, fmt.Sprintf("Error saving book to database: %v", err))
+		if len(books) > 0 {
+			log.Printf("Found book in database: %s", bookId)
+			return shared.SuccessResponse(200, books[0])
 		}
+	}
 
-		// Return the saved book
-		log.Printf("Successfully saved book: %s", bookId)
-		return shared.SuccessResponse(200, savedBook)
-	} else {
-		// This is not an Open Library ID, try to find it in our database
-		svc := shared.DynamoDBClient()
-		books, err := searchByBookId(svc, bookId)
-		if err != nil || len(books) == 0 {
-			log.Printf("Book not found in database: %s", bookId)
-			return shared.ErrorResponse(404, "Book not found")
-		}
+	// Book doesn't exist, fetch it from the metadata providers
+	log.Printf("Fetching book from %s: %s", metadataProvider.Name(), bookId)
+	bookData, err := metadataProvider.LookupByWorkID(shared.RequestContext(request), bookId)
+	if err != nil {
+		return metadataErrorResponse(err, "ID "+bookId)
+	}
 
-		// Return the found book
-		log.Printf("Found book in database: %s", bookId)
-		return shared.SuccessResponse(200, books[0])
+	// Save the book to our database
+	log.Printf("Saving book to database: %s", bookId)
+	savedBook, err := saveExternalBook(shared.RequestContext(request), bookData, bookId)
+	if err != nil {
+		return internalErrorResponse("Error saving book to database", err)
 	}
+
+	// Return the saved book
+	log.Printf("Successfully saved book: %s", bookId)
+	return shared.SuccessResponse(200, savedBook)
 }
 
-// fetchBookFromOpenLibrary fetches book data from Open Library API
-func fetchBookFromOpenLibrary(openLibraryId string) (*OpenLibraryResponse, error) {
-	// Format: OL12345W -> /works/OL12345W
-	url := fmt.Sprintf("https://openlibrary.org/works/%s.json?jscmd=data", openLibraryId)
+// maxSavedEditions bounds the editions of a work saved along with it.
+const maxSavedEditions = 10
 
-	resp, err := http.Get(url)
+// saveExternalBook stores a work found by the metadata providers, with its
+// editions when the providers list them. It returns the edition to read:
+// the first one with a page count, or the work's own record if there are no
+// editions.
+func saveExternalBook(ctx context.Context, newBook *models.BookData, workId string) (*models.BookData, error) {
+	linkAuthors(ctx, newBook)
+	work, err := ensureWork(newBook)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch book: %s", resp.Status)
+	editions, err := metadataProvider.LookupEditions(ctx, workId, maxSavedEditions)
+	if err != nil && !errors.Is(err, metadata.ErrNotFound) {
+		// The work is still worth saving without them
+		log.Printf("Error fetching editions of %s: %v", workId, err)
+	}
+	if len(editions) == 0 {
+		return saveEdition(newBook, work)
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
+	primary := 0
+	for i, edition := range editions {
+		if edition.PageCount > 0 {
+			primary = i
+			break
+		}
+	}
+	saved, err := saveEdition(&editions[primary], work)
 	if err != nil {
 		return nil, err
 	}
-
-	// The API returns a JSON object where the key is the ISBN or ID
-	var responseMap map[string]*OpenLibraryResponse
-	err = json.Unmarshal(body, &responseMap)
-	if err != nil {
-		// Try parsing as a direct object in case the API response format changes
-		var bookData OpenLibraryResponse
-		err = json.Unmarshal(body, &bookData)
-		if err != nil {
-			return nil, err
+	for i := range editions {
+		if i == primary {
+			continue
+		}
+		if _, err := saveEdition(&editions[i], work); err != nil {
+			log.Printf("Error saving edition %s of %s: %v", editions[i].ISBN13, workId, err)
 		}
-		return &bookData, nil
-	}
-
-	// Extract the first book from the map
-	for _, bookData := range responseMap {
-		return bookData, nil
 	}
-
-	return nil, fmt.Errorf("no book data found in the response")
+	log.Printf("Saved %d editions of %s", len(editions), workId)
+	return saved, nil
 }
 
-// saveBookToDynamoDB saves the book data to DynamoDB
-func saveBookToDynamoDB(svc *dynamodb.DynamoDB, bookData *OpenLibraryResponse, openLibraryId string, userId string) (*BookData, error) {
-	// Prepare book data for DynamoDB
-	newBook := BookData{
-		BookID:         uuid.New().String(),
-		Title:          bookData.Title,
-		TitleLowercase: strings.ToLower(bookData.Title),
-		PageCount:      bookData.NumberOfPages,
-		OpenLibraryId:  openLibraryId,
-		Tags:           []string{},
-		Description:    bookData.Description.Value,
-	}
-
-	if bookData.Description.Value != "" {
-		newBook.Description = bookData.Description.Value
-	}
-
-	// Set a default page count if it's zero
-	if newBook.PageCount == 0 {
-		// Default to 300 pages if we don't have the actual count
-		newBook.PageCount = 300
-		log.Printf("Setting default page count (300) for book with no page information: %s", openLibraryId)
-	}
-
-	// Add publish date as a tag if available
-	if bookData.PublishDate != "" {
-		newBook.Tags = append(newBook.Tags, "Published:"+bookData.PublishDate)
+// ensureWork files a book under its work, saving the work if it is new.
+// Books without a WorkID join their Open Library work, or a new work of
+// their own.
+func ensureWork(book *models.BookData) (*models.Work, error) {
+	if book.WorkID == "" {
+		if metadata.IsOpenLibraryID(book.OpenLibraryId) {
+			book.WorkID = book.OpenLibraryId
+		} else {
+			book.WorkID = uuid.New().String()
+		}
 	}
 
-	// Set cover image URL if available
-	if len(bookData.Cover) > 0 && bookData.Cover[0].Large != "" {
-		newBook.CoverImageURL = bookData.Cover[0].Large
-	} else if len(bookData.Cover) > 0 && bookData.Cover[0].Medium != "" {
-		newBook.CoverImageURL = bookData.Cover[0].Medium
-	} else if len(bookData.Cover) > 0 && bookData.Cover[0].Small != "" {
-		newBook.CoverImageURL = bookData.Cover[0].Small
+	work, err := stores.Works.Get(book.WorkID)
+	if err == nil {
+		return work, nil
 	}
-
-	// Set ISBNs if available
-	if len(bookData.Identifiers.ISBN13) > 0 {
-		newBook.ISBN13 = bookData.Identifiers.ISBN13[0]
+	if !errors.Is(err, store.ErrNotFound) {
+		return nil, err
 	}
-	if len(bookData.Identifiers.ISBN10) > 0 {
-		newBook.ISBN10 = bookData.Identifiers.ISBN10[0]
+	newWork := models.WorkFromEdition(*book)
+	if err := stores.Works.Put(&newWork); err != nil {
+		return nil, err
 	}
+	return &newWork, nil
+}
 
-	// Get author names if available
-	if len(bookData.Authors) > 0 {
-		var authorNames []string
-		for _, author := range bookData.Authors {
-			if author.Name != "" {
-				authorNames = append(authorNames, author.Name)
-			}
-		}
-		if len(authorNames) > 0 {
-			newBook.Authors = authorNames
+// saveEdition stores an edition of work under a new book ID, filling in
+// what editions share from the work. An edition whose ISBN is already saved
+// is filed under the work instead of being saved twice.
+func saveEdition(edition *models.BookData, work *models.Work) (*models.BookData, error) {
+	normalizeISBNs(edition)
+	if edition.ISBN13 != "" {
+		existing, err := searchByISBN(edition.ISBN13)
+		if err != nil {
+			return nil, err
 		}
-	}
-
-	// Add subjects as tags
-	if len(bookData.Subjects) > 0 {
-		for _, subject := range bookData.Subjects {
-			if subject != "" {
-				newBook.Tags = append(newBook.Tags, subject)
+		if len(existing) > 0 {
+			if existing[0].WorkID != "" {
+				return &existing[0], nil
 			}
+			return stores.Books.Update(existing[0].BookID, func(book *models.BookData) error {
+				book.WorkID = work.WorkID
+				if book.OpenLibraryId == "" {
+					book.OpenLibraryId = work.OpenLibraryId
+				}
+				return nil
+			})
 		}
 	}
 
-	// Add publisher information as a tag
-	if len(bookData.Publishers) > 0 && bookData.Publishers[0].Name != "" {
-		newBook.Tags = append(newBook.Tags, "Publisher:"+bookData.Publishers[0].Name)
+	edition.BookID = uuid.New().String()
+	edition.WorkID = work.WorkID
+	if len(edition.Authors) == 0 {
+		edition.Authors = work.Authors
 	}
-
-	// Create additional identifiers as tags
-	addIdentifierAsTag := func(idType string, values []string) {
-		if len(values) > 0 {
-			newBook.Tags = append(newBook.Tags, fmt.Sprintf("%s:%s", idType, values[0]))
-		}
+	if len(edition.AuthorIDs) == 0 {
+		edition.AuthorIDs = work.AuthorIDs
 	}
-
-	addIdentifierAsTag("Google", bookData.Identifiers.Google)
-	addIdentifierAsTag("LCCN", bookData.Identifiers.LCCN)
-	addIdentifierAsTag("OCLC", bookData.Identifiers.OCLC)
-	addIdentifierAsTag("Goodreads", bookData.Identifiers.Goodreads)
-	addIdentifierAsTag("LibraryThing", bookData.Identifiers.LibraryThing)
-
-	// Always add the OpenLibrary tag
-	newBook.Tags = append(newBook.Tags, "OpenLibrary:"+openLibraryId)
-
-	// Convert book to DynamoDB attribute value
-	item, err := dynamodbattribute.MarshalMap(newBook)
-	if err != nil {
-		return nil, err
+	if edition.Description == "" {
+		edition.Description = work.Description
+	}
+	if edition.CoverImageURL == "" {
+		edition.CoverImageURL = work.CoverImageURL
+	}
+	if edition.OpenLibraryId == "" {
+		edition.OpenLibraryId = work.OpenLibraryId
 	}
+	edition.Tags = mergeTags(work.Tags, edition.Tags)
 
-	// Save to DynamoDB
-	input := &dynamodb.PutItemInput{
-		TableName: aws.String(BOOKS_TABLE_NAME),
-		Item:      item,
+	// Set a default page count if it's zero
+	if edition.PageCount == 0 {
+		// Default to 300 pages if we don't have the actual count
+		edition.PageCount = 300
+		log.Printf("Setting default page count (300) for book with no page information: %s", work.WorkID)
 	}
 
-	_, err = svc.PutItem(input)
-	if err != nil {
+	if err := stores.Books.Put(edition); err != nil {
 		return nil, err
 	}
+	linkSeries(edition)
+	return edition, nil
+}
 
-	return &newBook, nil
+// mergeTags returns the tags of a followed by those of b it lacks.
+func mergeTags(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	var tags []string
+	for _, tag := range append(append([]string{}, a...), b...) {
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
 }
@@ -0,0 +1,411 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/FriedGlue/BookIt/api/pkg/search"
+	"github.com/FriedGlue/BookIt/api/pkg/shared"
+	"github.com/FriedGlue/BookIt/api/pkg/store"
+	"github.com/FriedGlue/BookIt/api/pkg/validation"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+)
+
+// maxSeriesEntries bounds the entries of a series edited by hand.
+const maxSeriesEntries = 500
+
+// CreateSeriesRequest is the body of POST /series.
+type CreateSeriesRequest struct {
+	Name    string               `json:"name" validate:"required,max=200"`
+	Entries []SeriesEntryRequest `json:"entries" validate:"max=500"`
+}
+
+func (r CreateSeriesRequest) Validate() []validation.FieldError {
+	return validateSeriesEntries(r.Entries)
+}
+
+// UpdateSeriesRequest is the body of PUT /series/{seriesId}. Only the fields
+// present are changed; entries, when given, replace the whole list.
+type UpdateSeriesRequest struct {
+	Name    *string               `json:"name,omitempty"`
+	Entries *[]SeriesEntryRequest `json:"entries,omitempty"`
+}
+
+func (r UpdateSeriesRequest) Validate() []validation.FieldError {
+	if r.Name != nil && strings.TrimSpace(*r.Name) == "" {
+		return []validation.FieldError{{Field: "name", Message: "must not be empty"}}
+	}
+	if r.Name != nil && len([]rune(*r.Name)) > 200 {
+		return []validation.FieldError{{Field: "name", Message: "must have at most 200 characters"}}
+	}
+	if r.Entries == nil {
+		return nil
+	}
+	if len(*r.Entries) > maxSeriesEntries {
+		return []validation.FieldError{{Field: "entries", Message: fmt.Sprintf("must have at most %d items", maxSeriesEntries)}}
+	}
+	return validateSeriesEntries(*r.Entries)
+}
+
+// SeriesEntryRequest is one entry of a series being edited. It names the
+// work by workId, or by bookId for a saved edition of it; the title is
+// taken from the work or book when left out.
+type SeriesEntryRequest struct {
+	Position float64 `json:"position"`
+	WorkID   string  `json:"workId,omitempty"`
+	BookID   string  `json:"bookId,omitempty"`
+	Title    string  `json:"title,omitempty"`
+}
+
+// validateSeriesEntries checks that every entry names a work and that no
+// two share a position.
+func validateSeriesEntries(entries []SeriesEntryRequest) []validation.FieldError {
+	positions := make(map[float64]bool, len(entries))
+	for i, entry := range entries {
+		field := fmt.Sprintf("entries[%d]", i)
+		switch {
+		case entry.WorkID == "" && entry.BookID == "" && strings.TrimSpace(entry.Title) == "":
+			return []validation.FieldError{{Field: field, Message: "must have a workId, bookId or title"}}
+		case entry.Position < 0:
+			return []validation.FieldError{{Field: field + ".position", Message: "must be at least 0"}}
+		case positions[entry.Position]:
+			return []validation.FieldError{{Field: field + ".position", Message: "must be unique within the series"}}
+		}
+		positions[entry.Position] = true
+	}
+	return nil
+}
+
+// SeriesProgress is where the caller is in a series they have started.
+type SeriesProgress struct {
+	SeriesID         string               `json:"seriesId"`
+	Name             string               `json:"name"`
+	Read             int                  `json:"read"`  // entries on the read list
+	Total            int                  `json:"total"` // entries in the series
+	CurrentlyReading []models.SeriesEntry `json:"currentlyReading,omitempty"`
+	Next             *models.SeriesEntry  `json:"next,omitempty"` // absent once every entry is read or being read
+}
+
+// CreateSeries creates a series by hand.
+func CreateSeries(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	var input CreateSeriesRequest
+	if apiErr := validation.Decode(request.Body, &input); apiErr != nil {
+		return shared.ErrorResponse(apiErr)
+	}
+
+	entries, err := resolveSeriesEntries(input.Entries)
+	if err != nil {
+		return storeErrorResponse(err, shared.CodeBookNotFound, "Book not found")
+	}
+	series := models.Series{
+		SeriesID: uuid.New().String(),
+		Name:     strings.TrimSpace(input.Name),
+		Entries:  entries,
+	}
+	if err := stores.Series.Put(&series); err != nil {
+		return internalErrorResponse("Error saving series", err)
+	}
+
+	log.Printf("User %s created series %s %q\n", shared.UserID(request), series.SeriesID, series.Name)
+	return shared.SuccessResponse(201, series)
+}
+
+// GetSeries returns a series with its entries in reading order.
+func GetSeries(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	seriesId := request.PathParameters["seriesId"]
+	if seriesId == "" {
+		return shared.Error(shared.CodeMissingParameter, "Missing path parameter: seriesId")
+	}
+
+	series, err := stores.Series.Get(seriesId)
+	if err != nil {
+		return storeErrorResponse(err, shared.CodeSeriesNotFound, fmt.Sprintf("No series found with ID: %s", seriesId))
+	}
+	return shared.SuccessResponse(200, series)
+}
+
+// UpdateSeries renames a series or replaces its entries, correcting what
+// the catalogue gave or filling in what it lacks.
+func UpdateSeries(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	seriesId := request.PathParameters["seriesId"]
+	if seriesId == "" {
+		return shared.Error(shared.CodeMissingParameter, "Missing path parameter: seriesId")
+	}
+
+	var updates UpdateSeriesRequest
+	if apiErr := validation.Decode(request.Body, &updates); apiErr != nil {
+		return shared.ErrorResponse(apiErr)
+	}
+
+	series, err := stores.Series.Get(seriesId)
+	if err != nil {
+		return storeErrorResponse(err, shared.CodeSeriesNotFound, fmt.Sprintf("No series found with ID: %s", seriesId))
+	}
+	if updates.Name != nil {
+		series.Name = strings.TrimSpace(*updates.Name)
+	}
+	if updates.Entries != nil {
+		entries, err := resolveSeriesEntries(*updates.Entries)
+		if err != nil {
+			return storeErrorResponse(err, shared.CodeBookNotFound, "Book not found")
+		}
+		series.Entries = entries
+	}
+	if err := stores.Series.Put(series); err != nil {
+		return internalErrorResponse("Error saving series", err)
+	}
+
+	log.Printf("User %s updated series %s\n", shared.UserID(request), seriesId)
+	return shared.SuccessResponse(200, series)
+}
+
+// DeleteSeries removes a series. The books in it are kept.
+func DeleteSeries(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	seriesId := request.PathParameters["seriesId"]
+	if seriesId == "" {
+		return shared.Error(shared.CodeMissingParameter, "Missing path parameter: seriesId")
+	}
+
+	if _, err := stores.Series.Get(seriesId); err != nil {
+		return storeErrorResponse(err, shared.CodeSeriesNotFound, fmt.Sprintf("No series found with ID: %s", seriesId))
+	}
+	if err := stores.Series.Delete(seriesId); err != nil {
+		return internalErrorResponse("Error deleting series", err)
+	}
+
+	log.Printf("User %s deleted series %s\n", shared.UserID(request), seriesId)
+	return shared.MessageResponse(200, "Series deleted successfully")
+}
+
+// GetNextInSeries lists the series the caller has started, by having an
+// entry on their read list or currently reading, each with the first entry
+// in reading order they have done neither with. Series are found through
+// the works of the caller's saved books, so a series whose only started
+// entries are books that are not saved is left out. With ?novellas=false
+// entries between whole positions, such as 2.5, are never suggested.
+func GetNextInSeries(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	novellas := true
+	if value, ok := request.QueryStringParameters["novellas"]; ok {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return shared.Error(shared.CodeInvalidParameter, "Invalid novellas: must be true or false")
+		}
+		novellas = parsed
+	}
+
+	profile, err := stores.Profiles.Get(shared.UserID(request))
+	if err != nil {
+		return storeErrorResponse(err, shared.CodeProfileNotFound, "Profile not found")
+	}
+	read, err := shelvedWorks(readItemRefs(profile))
+	if err != nil {
+		return internalErrorResponse("Error loading read books", err)
+	}
+	reading, err := shelvedWorks(currentlyReadingRefs(profile))
+	if err != nil {
+		return internalErrorResponse("Error loading currently reading books", err)
+	}
+
+	// Only the series of the works being read or read can have been started
+	started, err := stores.Series.QueryByWorkIDs(append(read.works(), reading.works()...))
+	if err != nil {
+		return internalErrorResponse("Error loading series", err)
+	}
+	progress := []SeriesProgress{}
+	for _, series := range started {
+		entry := SeriesProgress{SeriesID: series.SeriesID, Name: series.Name, Total: len(series.Entries)}
+		for _, e := range sortedEntries(series.Entries) {
+			switch {
+			case read.has(e):
+				entry.Read++
+			case reading.has(e):
+				entry.CurrentlyReading = append(entry.CurrentlyReading, e)
+			case entry.Next == nil && (novellas || e.Position == math.Trunc(e.Position)):
+				next := e
+				entry.Next = &next
+			}
+		}
+		if entry.Read == 0 && len(entry.CurrentlyReading) == 0 {
+			continue
+		}
+		if entry.Next != nil && entry.Next.BookID == "" && entry.Next.WorkID != "" {
+			// Point at a saved edition, so the client can add it to a list
+			editions, err := stores.Books.QueryByWorkID(entry.Next.WorkID)
+			if err != nil {
+				log.Printf("Error loading editions of %s: %v", entry.Next.WorkID, err)
+			} else if len(editions) > 0 {
+				entry.Next.BookID = editions[0].BookID
+			}
+		}
+		progress = append(progress, entry)
+	}
+
+	sort.Slice(progress, func(i, j int) bool {
+		if progress[i].Name != progress[j].Name {
+			return progress[i].Name < progress[j].Name
+		}
+		return progress[i].SeriesID < progress[j].SeriesID
+	})
+	return shared.ListResponse(200, progress)
+}
+
+// linkSeries files a saved book under the series the catalogue says it
+// belongs to, creating series that are new. A work already in a series
+// keeps its entry, so editing a series by hand is not undone by saving
+// another edition. Failures are logged, as the book is saved regardless.
+func linkSeries(book *models.BookData) {
+	for _, membership := range book.Series {
+		seriesID := seriesSlug(membership.Name)
+		if seriesID == "" {
+			continue
+		}
+		series, err := stores.Series.Get(seriesID)
+		if errors.Is(err, store.ErrNotFound) {
+			series = &models.Series{SeriesID: seriesID, Name: membership.Name}
+		} else if err != nil {
+			log.Printf("Error loading series %s: %v", seriesID, err)
+			continue
+		}
+
+		workID := book.WorkKey()
+		if slices.ContainsFunc(series.Entries, func(e models.SeriesEntry) bool { return e.WorkID == workID }) {
+			continue
+		}
+		series.Entries = sortedEntries(append(series.Entries, models.SeriesEntry{
+			Position: membership.Position,
+			WorkID:   workID,
+			BookID:   book.BookID,
+			Title:    book.Title,
+		}))
+		if err := stores.Series.Put(series); err != nil {
+			log.Printf("Error saving series %s: %v", seriesID, err)
+			continue
+		}
+		log.Printf("Added %s to series %s at %v", workID, seriesID, membership.Position)
+	}
+}
+
+// seriesSlug makes the ID of a series imported from the catalogue from its
+// name, so every book naming the series joins the same one.
+func seriesSlug(name string) string {
+	return strings.Join(search.Tokenize(name), "-")
+}
+
+// resolveSeriesEntries turns the entries of a request into series entries
+// in reading order, filling in the work and title of entries given by book
+// and the title of entries given by work.
+func resolveSeriesEntries(requested []SeriesEntryRequest) ([]models.SeriesEntry, error) {
+	entries := make([]models.SeriesEntry, 0, len(requested))
+	for _, r := range requested {
+		entry := models.SeriesEntry{Position: r.Position, WorkID: r.WorkID, BookID: r.BookID, Title: strings.TrimSpace(r.Title)}
+		if entry.BookID != "" {
+			book, err := stores.Books.Get(entry.BookID)
+			if errors.Is(err, store.ErrNotFound) {
+				return nil, shared.NewError(shared.CodeBookNotFound, fmt.Sprintf("No book found with ID: %s", entry.BookID))
+			}
+			if err != nil {
+				return nil, err
+			}
+			if entry.WorkID == "" {
+				entry.WorkID = book.WorkKey()
+			}
+			if entry.Title == "" {
+				entry.Title = book.Title
+			}
+		}
+		if entry.Title == "" {
+			work, err := stores.Works.Get(entry.WorkID)
+			if errors.Is(err, store.ErrNotFound) {
+				return nil, shared.NewError(shared.CodeWorkNotFound, fmt.Sprintf("No work found with ID: %s, give the entry a title", entry.WorkID))
+			}
+			if err != nil {
+				return nil, err
+			}
+			entry.Title = work.Title
+		}
+		entries = append(entries, entry)
+	}
+	return sortedEntries(entries), nil
+}
+
+// sortedEntries orders entries by position.
+func sortedEntries(entries []models.SeriesEntry) []models.SeriesEntry {
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Position < entries[j].Position })
+	return entries
+}
+
+// shelfRef is a book on one of the caller's lists.
+type shelfRef struct {
+	bookID string
+	title  string
+}
+
+func readItemRefs(profile *models.Profile) []shelfRef {
+	refs := make([]shelfRef, 0, len(profile.Lists.Read))
+	for _, item := range profile.Lists.Read {
+		refs = append(refs, shelfRef{bookID: item.BookID, title: item.Title})
+	}
+	return refs
+}
+
+func currentlyReadingRefs(profile *models.Profile) []shelfRef {
+	refs := make([]shelfRef, 0, len(profile.CurrentlyReading))
+	for _, item := range profile.CurrentlyReading {
+		refs = append(refs, shelfRef{bookID: item.Book.BookID, title: item.Book.Title})
+	}
+	return refs
+}
+
+// workSet is the works of the books on a list. Books that are not saved,
+// such as those added by ISBN, are only known by their title.
+type workSet struct {
+	bookIDs map[string]bool
+	workIDs map[string]bool
+	titles  map[string]bool
+}
+
+// shelvedWorks loads the books on a list to find their works.
+func shelvedWorks(refs []shelfRef) (workSet, error) {
+	set := workSet{bookIDs: map[string]bool{}, workIDs: map[string]bool{}, titles: map[string]bool{}}
+	ids := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		set.bookIDs[ref.bookID] = true
+		ids = append(ids, ref.bookID)
+		if key := normalizedTitle(ref.title); key != "" {
+			set.titles[key] = true
+		}
+	}
+
+	books, err := stores.Books.GetMany(ids)
+	if err != nil {
+		return set, err
+	}
+	for _, book := range books {
+		set.workIDs[book.WorkKey()] = true
+	}
+	return set, nil
+}
+
+// works returns the IDs of the works in the set.
+func (s workSet) works() []string {
+	works := make([]string, 0, len(s.workIDs))
+	for workID := range s.workIDs {
+		works = append(works, workID)
+	}
+	return works
+}
+
+// has reports whether the entry's work is in the set.
+func (s workSet) has(entry models.SeriesEntry) bool {
+	return (entry.WorkID != "" && s.workIDs[entry.WorkID]) ||
+		(entry.BookID != "" && s.bookIDs[entry.BookID]) ||
+		s.titles[normalizedTitle(entry.Title)]
+}
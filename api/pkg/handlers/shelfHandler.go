@@ -0,0 +1,421 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/FriedGlue/BookIt/api/pkg/shared"
+	"github.com/FriedGlue/BookIt/api/pkg/smartshelf"
+	"github.com/FriedGlue/BookIt/api/pkg/validation"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+)
+
+// Limits on a shelf that its request tags cannot express: list names given
+// in other requests create shelves too, and the rules are nested.
+const (
+	maxShelfName  = 100
+	maxShelfRules = 20
+)
+
+// CreateShelfRequest is the body of POST /shelves.
+type CreateShelfRequest struct {
+	Name        string                 `json:"name" validate:"required,max=100"`
+	Description string                 `json:"description,omitempty" validate:"max=1000"`
+	Icon        string                 `json:"icon,omitempty" validate:"max=64"`
+	Visibility  models.ShelfVisibility `json:"visibility,omitempty" validate:"oneof=private friends public"` // private when left out
+	DefaultSort models.ShelfSort       `json:"defaultSort,omitempty" validate:"oneof=manual title author dateAdded"`
+	Rules       *models.ShelfRules     `json:"rules,omitempty"` // makes it a smart shelf
+}
+
+func (r CreateShelfRequest) Validate() []validation.FieldError {
+	var errs []validation.FieldError
+	// An empty name is already reported by the required rule.
+	if r.Name != "" {
+		errs = append(errs, validateShelfName(r.Name)...)
+	}
+	return append(errs, validateShelfRules(r.Rules)...)
+}
+
+// UpdateShelfRequest is the body of PUT /shelves/{shelfId}. Only the fields
+// present are changed, and an empty description or icon clears it.
+type UpdateShelfRequest struct {
+	Name        *string                 `json:"name,omitempty" validate:"max=100"`
+	Description *string                 `json:"description,omitempty" validate:"max=1000"`
+	Icon        *string                 `json:"icon,omitempty" validate:"max=64"`
+	Visibility  *models.ShelfVisibility `json:"visibility,omitempty" validate:"oneof=private friends public"`
+	DefaultSort *models.ShelfSort       `json:"defaultSort,omitempty" validate:"oneof=manual title author dateAdded"`
+	Rules       *models.ShelfRules      `json:"rules,omitempty"` // replaces a smart shelf's rules, or makes an empty shelf smart
+}
+
+func (r UpdateShelfRequest) Validate() []validation.FieldError {
+	var errs []validation.FieldError
+	if r.Name != nil {
+		errs = append(errs, validateShelfName(*r.Name)...)
+	}
+	return append(errs, validateShelfRules(r.Rules)...)
+}
+
+// validateShelfRules checks the rules of a smart shelf, if given: text
+// fields take a value and eq or ne, number fields a number. Each condition
+// reports the first thing wrong with it.
+func validateShelfRules(rules *models.ShelfRules) []validation.FieldError {
+	if rules == nil {
+		return nil
+	}
+	var errs []validation.FieldError
+	if rules.Match != "" && rules.Match != models.MatchAll && rules.Match != models.MatchAny {
+		errs = append(errs, validation.FieldError{Field: "rules.match", Message: "must be one of all, any"})
+	}
+	if len(rules.Conditions) == 0 || len(rules.Conditions) > maxShelfRules {
+		errs = append(errs, validation.FieldError{Field: "rules.conditions", Message: fmt.Sprintf("must have between 1 and %d items", maxShelfRules)})
+	}
+
+	fields := []models.RuleField{
+		models.ListField, models.TagField, models.AuthorField, models.RatingField, models.ReadYearField,
+		models.LoggedYearField, models.PageCountField, models.AuthorReadCountField,
+	}
+	for i, rule := range rules.Conditions {
+		field := fmt.Sprintf("rules.conditions[%d]", i)
+		switch {
+		case !slices.Contains(fields, rule.Field):
+			errs = append(errs, validation.FieldError{Field: field + ".field", Message: "must be one of list, tag, author, rating, readYear, loggedYear, pageCount, authorReadCount"})
+		case !slices.Contains([]models.RuleOp{models.Equal, models.NotEqual, models.AtLeast, models.AtMost}, rule.Op):
+			errs = append(errs, validation.FieldError{Field: field + ".op", Message: "must be one of eq, ne, gte, lte"})
+		case rule.Field.TextField() && rule.Op != models.Equal && rule.Op != models.NotEqual:
+			errs = append(errs, validation.FieldError{Field: field + ".op", Message: fmt.Sprintf("must be eq or ne for %s", rule.Field)})
+		case rule.Field.TextField() && strings.TrimSpace(rule.Value) == "":
+			errs = append(errs, validation.FieldError{Field: field + ".value", Message: fmt.Sprintf("is required for %s", rule.Field)})
+		case !rule.Field.TextField() && rule.Value != "":
+			errs = append(errs, validation.FieldError{Field: field + ".value", Message: fmt.Sprintf("must not be given for %s, which takes number", rule.Field)})
+		}
+	}
+	return errs
+}
+
+// validateShelfName checks what the tags on a shelf name cannot: that it is
+// more than spaces, and not the name of a built-in list, since listType
+// could not tell the shelf from the list.
+func validateShelfName(name string) []validation.FieldError {
+	trimmed := strings.TrimSpace(name)
+	switch {
+	case trimmed == "":
+		return []validation.FieldError{{Field: "name", Message: "must not be empty"}}
+	case isBuiltInList(trimmed):
+		return []validation.FieldError{{Field: "name", Message: "must not be the name of a built-in list"}}
+	}
+	return nil
+}
+
+// ShelfResponse describes a shelf without its books, which GET /list pages
+// through.
+type ShelfResponse struct {
+	ShelfID     string                 `json:"shelfId"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Icon        string                 `json:"icon,omitempty"`
+	Visibility  models.ShelfVisibility `json:"visibility"`
+	DefaultSort models.ShelfSort       `json:"defaultSort"`
+	CreatedAt   string                 `json:"createdAt,omitempty"`
+	Rules       *models.ShelfRules     `json:"rules,omitempty"`
+	BookCount   int                    `json:"bookCount"`
+}
+
+func newShelfResponse(shelf models.Shelf) ShelfResponse {
+	return ShelfResponse{
+		ShelfID:     shelf.ShelfID,
+		Name:        shelf.Name,
+		Description: shelf.Description,
+		Icon:        shelf.Icon,
+		Visibility:  shelf.Visibility,
+		DefaultSort: shelf.DefaultSort,
+		CreatedAt:   shelf.CreatedAt,
+		Rules:       shelf.Rules,
+		BookCount:   len(shelf.Items),
+	}
+}
+
+// GetShelves lists the caller's shelves in the order they were created.
+func GetShelves(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	log.Println("GetShelves invoked")
+	userId := shared.UserID(request)
+
+	profile, err := stores.Profiles.Get(userId)
+	if err != nil {
+		return storeErrorResponse(err, shared.CodeProfileNotFound, "Profile not found")
+	}
+	profile.Lists.Normalize()
+	if err := fillSmartShelves(userId, profile); err != nil {
+		return internalErrorResponse("Error evaluating smart shelves", err)
+	}
+
+	shelves := make([]ShelfResponse, 0, len(profile.Lists.Shelves))
+	for _, shelf := range profile.Lists.Shelves {
+		shelves = append(shelves, newShelfResponse(shelf))
+	}
+	return shared.ListResponse(200, shelves)
+}
+
+// CreateShelf creates an empty shelf.
+func CreateShelf(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	log.Println("CreateShelf invoked")
+	userId := shared.UserID(request)
+
+	var createReq CreateShelfRequest
+	if apiErr := validation.Decode(request.Body, &createReq); apiErr != nil {
+		return shared.ErrorResponse(apiErr)
+	}
+
+	shelf := models.NewShelf(strings.TrimSpace(createReq.Name), time.Now().Format(time.RFC3339))
+	shelf.Description = createReq.Description
+	shelf.Icon = createReq.Icon
+	if createReq.Visibility != "" {
+		shelf.Visibility = createReq.Visibility
+	}
+	if createReq.DefaultSort != "" {
+		shelf.DefaultSort = createReq.DefaultSort
+	}
+	shelf.Rules = normalizedRules(createReq.Rules)
+
+	profile, err := updateLists(userId, func(profile *models.Profile) error {
+		if err := checkShelfNameFree(profile, shelf.Name, ""); err != nil {
+			return err
+		}
+		profile.Lists.Shelves = append(profile.Lists.Shelves, shelf)
+		return nil
+	})
+	if err != nil {
+		return storeErrorResponse(err, shared.CodeProfileNotFound, "Profile not found")
+	}
+
+	return shelfResponse(userId, profile, shelf.ShelfID, 201)
+}
+
+// UpdateShelf renames a shelf or changes its description, icon, visibility
+// or default sort. Its books stay on it.
+func UpdateShelf(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	log.Println("UpdateShelf invoked")
+	userId := shared.UserID(request)
+
+	shelfId := request.PathParameters["shelfId"]
+	if shelfId == "" {
+		return shared.Error(shared.CodeMissingParameter, "Missing path parameter: shelfId")
+	}
+
+	var updateReq UpdateShelfRequest
+	if apiErr := validation.Decode(request.Body, &updateReq); apiErr != nil {
+		return shared.ErrorResponse(apiErr)
+	}
+
+	var updatedID string
+	profile, err := updateLists(userId, func(profile *models.Profile) error {
+		shelf := profile.Lists.Shelf(shelfId)
+		if shelf == nil {
+			return shared.NewError(shared.CodeListNotFound, "Shelf not found")
+		}
+		if updateReq.Name != nil {
+			name := strings.TrimSpace(*updateReq.Name)
+			if err := checkShelfNameFree(profile, name, shelf.ShelfID); err != nil {
+				return err
+			}
+			shelf.Name = name
+		}
+		if updateReq.Description != nil {
+			shelf.Description = *updateReq.Description
+		}
+		if updateReq.Icon != nil {
+			shelf.Icon = *updateReq.Icon
+		}
+		if updateReq.Visibility != nil {
+			shelf.Visibility = *updateReq.Visibility
+		}
+		if updateReq.DefaultSort != nil {
+			shelf.DefaultSort = *updateReq.DefaultSort
+		}
+		if updateReq.Rules != nil {
+			if !shelf.Smart() && len(shelf.Items) > 0 {
+				return shared.NewError(shared.CodeInvalidParameter, "Only an empty shelf can become a smart shelf")
+			}
+			shelf.Rules = normalizedRules(updateReq.Rules)
+		}
+		updatedID = shelf.ShelfID
+		return nil
+	})
+	if err != nil {
+		return storeErrorResponse(err, shared.CodeProfileNotFound, "Profile not found")
+	}
+
+	return shelfResponse(userId, profile, updatedID, 200)
+}
+
+// shelfResponse answers with the shelf of the profile just written, with
+// the books of a smart shelf counted.
+func shelfResponse(userId string, profile *models.Profile, shelfId string, status int) events.APIGatewayProxyResponse {
+	if err := fillSmartShelves(userId, profile); err != nil {
+		return internalErrorResponse("Error evaluating smart shelves", err)
+	}
+	return shared.SuccessResponse(status, newShelfResponse(*profile.Lists.Shelf(shelfId)))
+}
+
+// DeleteShelf deletes a shelf along with its books.
+func DeleteShelf(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	log.Println("DeleteShelf invoked")
+	userId := shared.UserID(request)
+
+	shelfId := request.PathParameters["shelfId"]
+	if shelfId == "" {
+		return shared.Error(shared.CodeMissingParameter, "Missing path parameter: shelfId")
+	}
+
+	_, err := updateLists(userId, func(profile *models.Profile) error {
+		shelf := profile.Lists.Shelf(shelfId)
+		if shelf == nil {
+			return shared.NewError(shared.CodeListNotFound, "Shelf not found")
+		}
+		profile.Lists.RemoveShelf(shelf.ShelfID)
+		return nil
+	})
+	if err != nil {
+		return storeErrorResponse(err, shared.CodeProfileNotFound, "Profile not found")
+	}
+
+	return shared.MessageResponse(200, "Shelf deleted successfully")
+}
+
+// isBuiltInList reports whether name is one of the lists every profile has.
+func isBuiltInList(name string) bool {
+	return name == "toBeRead" || name == "read" || name == "currentlyReading"
+}
+
+// checkShelfNameFree returns LIST_ALREADY_EXISTS if a shelf other than the
+// one with ID self already has the name, or has it as its ID.
+func checkShelfNameFree(profile *models.Profile, name, self string) error {
+	if shelf := profile.Lists.Shelf(name); shelf != nil && shelf.ShelfID != self {
+		return shared.NewError(shared.CodeListAlreadyExists, fmt.Sprintf("A shelf named %s already exists", name))
+	}
+	return nil
+}
+
+// findOrCreateShelf returns the shelf with the given ID or name, creating
+// one of that name if there is none, as POST /list always has. Books cannot
+// be put on smart shelves.
+func findOrCreateShelf(profile *models.Profile, ref, now string) (*models.Shelf, error) {
+	if shelf := profile.Lists.Shelf(ref); shelf != nil {
+		if shelf.Smart() {
+			return nil, smartShelfError(shelf)
+		}
+		return shelf, nil
+	}
+	if err := checkNewShelfRef(ref); err != nil {
+		return nil, err
+	}
+	profile.Lists.Shelves = append(profile.Lists.Shelves, models.NewShelf(ref, now))
+	return &profile.Lists.Shelves[len(profile.Lists.Shelves)-1], nil
+}
+
+// checkNewShelfRef returns the error for a list that matches no shelf and
+// would be created: a shelf ID names a shelf that was deleted, and a name
+// must pass the rules of POST /shelves, so a misspelt built-in list such as
+// currentlyReading is not taken for a new shelf.
+func checkNewShelfRef(ref string) error {
+	if _, err := uuid.Parse(ref); err == nil {
+		return shared.NewError(shared.CodeListNotFound, fmt.Sprintf("No shelf found with ID: %s", ref))
+	}
+	if ref != strings.TrimSpace(ref) {
+		return shared.NewError(shared.CodeInvalidParameter, fmt.Sprintf("Invalid shelf name %q: must not start or end with spaces", ref))
+	}
+	if len([]rune(ref)) > maxShelfName {
+		return shared.NewError(shared.CodeInvalidParameter, fmt.Sprintf("Invalid shelf name %q: must have at most %d characters", ref, maxShelfName))
+	}
+	if errs := validateShelfName(ref); errs != nil {
+		return shared.NewError(shared.CodeInvalidParameter, fmt.Sprintf("Invalid shelf name %q: %s", ref, errs[0].Message))
+	}
+	return nil
+}
+
+// smartShelfError is returned for requests changing the books of a smart
+// shelf.
+func smartShelfError(shelf *models.Shelf) error {
+	return shared.NewError(shared.CodeInvalidParameter, fmt.Sprintf("%s is a smart shelf; its books come from its rules", shelf.Name))
+}
+
+// normalizedRules returns a copy of rules with the default match filled in.
+func normalizedRules(rules *models.ShelfRules) *models.ShelfRules {
+	if rules == nil {
+		return nil
+	}
+	result := models.ShelfRules{Match: rules.Match, Conditions: slices.Clone(rules.Conditions)}
+	if result.Match == "" {
+		result.Match = models.MatchAll
+	}
+	for i := range result.Conditions {
+		result.Conditions[i].Value = strings.TrimSpace(result.Conditions[i].Value)
+	}
+	return &result
+}
+
+// fillSmartShelves sets the Items of the profile's smart shelves to the
+// books their rules pick, for showing them. The profile must not be written
+// back afterwards.
+func fillSmartShelves(userId string, profile *models.Profile) error {
+	if !slices.ContainsFunc(profile.Lists.Shelves, func(shelf models.Shelf) bool { return shelf.Smart() }) {
+		return nil
+	}
+
+	data := smartshelf.Data{Profile: profile, Books: make(map[string]models.BookData)}
+	books, err := stores.Books.GetMany(smartshelf.BookIDs(profile))
+	if err != nil {
+		return fmt.Errorf("loading books: %w", err)
+	}
+	for _, book := range books {
+		data.Books[book.BookID] = book
+	}
+	if smartshelf.UsesReadingLog(profile) {
+		data.ReadingLog, err = stores.ReadingLog.Query(userId, time.Time{}, time.Time{})
+		if err != nil {
+			return fmt.Errorf("loading reading log: %w", err)
+		}
+	}
+
+	for i := range profile.Lists.Shelves {
+		if shelf := &profile.Lists.Shelves[i]; shelf.Smart() {
+			shelf.Items = smartshelf.Evaluate(*shelf.Rules, data)
+		}
+	}
+	return nil
+}
+
+// shelfKey returns the sort key paging a shelf in the given order. Title
+// and author keys end in the manual order key, which keeps them unique.
+func shelfKey(order models.ShelfSort) func(models.CustomListItem) string {
+	switch order {
+	case models.TitleSort:
+		return func(item models.CustomListItem) string {
+			return strings.ToLower(item.Title) + "\x00" + customListKey(item)
+		}
+	case models.AuthorSort:
+		return func(item models.CustomListItem) string {
+			author := ""
+			if len(item.Authors) > 0 {
+				author = strings.ToLower(item.Authors[0])
+			}
+			return author + "\x00" + strings.ToLower(item.Title) + "\x00" + customListKey(item)
+		}
+	case models.DateAddedSort:
+		return func(item models.CustomListItem) string {
+			// Newest first; books without a date come last.
+			var added int64
+			if date, err := time.Parse(time.RFC3339, item.AddedDate); err == nil {
+				added = max(date.Unix(), 0)
+			}
+			return fmt.Sprintf("%019d/%s", math.MaxInt64-added, customListKey(item))
+		}
+	default:
+		return customListKey
+	}
+}
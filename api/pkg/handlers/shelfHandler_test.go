@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/FriedGlue/BookIt/api/pkg/validation"
+)
+
+func TestShelfRequestValidation(t *testing.T) {
+	ptr := func(s string) *string { return &s }
+	visibility := models.ShelfVisibility("everyone")
+	sort := models.TitleSort
+	badRules := &models.ShelfRules{Match: "some", Conditions: []models.ShelfRule{
+		{Field: "colour", Op: models.Equal, Value: "red"},
+		{Field: models.AuthorField, Op: models.AtLeast, Value: "Le Guin"},
+		{Field: models.RatingField, Op: models.AtLeast, Value: "4"},
+	}}
+
+	tests := []struct {
+		name    string
+		request any
+		want    []string // fields with errors, each once
+	}{
+		{
+			name:    "create",
+			request: CreateShelfRequest{Name: "Holiday", Visibility: models.PublicShelf},
+		},
+		{
+			name:    "create without a name",
+			request: CreateShelfRequest{},
+			want:    []string{"name"},
+		},
+		{
+			name:    "create with a blank name",
+			request: CreateShelfRequest{Name: "   "},
+			want:    []string{"name"},
+		},
+		{
+			name:    "create with everything wrong",
+			request: CreateShelfRequest{Name: "read", Icon: strings.Repeat("x", 65), Visibility: visibility, Rules: badRules},
+			want:    []string{"icon", "visibility", "name", "rules.match", "rules.conditions[0].field", "rules.conditions[1].op", "rules.conditions[2].value"},
+		},
+		{
+			name:    "create with a long name",
+			request: CreateShelfRequest{Name: strings.Repeat("x", 101)},
+			want:    []string{"name"},
+		},
+		{
+			name:    "update nothing",
+			request: UpdateShelfRequest{},
+		},
+		{
+			name:    "update",
+			request: UpdateShelfRequest{Name: ptr("Beach"), Description: ptr(""), DefaultSort: &sort},
+		},
+		{
+			name:    "update with everything wrong",
+			request: UpdateShelfRequest{Name: ptr(""), Description: ptr(strings.Repeat("x", 1001)), Visibility: &visibility, Rules: &models.ShelfRules{}},
+			want:    []string{"description", "visibility", "name", "rules.conditions"},
+		},
+		{
+			name:    "update with a long name",
+			request: UpdateShelfRequest{Name: ptr(strings.Repeat("x", 101))},
+			want:    []string{"name"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fields []string
+			for _, err := range validation.Struct(tt.request) {
+				fields = append(fields, err.Field)
+			}
+			if !slices.Equal(fields, tt.want) {
+				t.Errorf("errors on %v, want %v", fields, tt.want)
+			}
+		})
+	}
+}
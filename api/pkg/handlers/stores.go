@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/FriedGlue/BookIt/api/pkg/httpclient"
+	"github.com/FriedGlue/BookIt/api/pkg/metadata"
+	"github.com/FriedGlue/BookIt/api/pkg/pagination"
+	"github.com/FriedGlue/BookIt/api/pkg/search"
+	"github.com/FriedGlue/BookIt/api/pkg/shared"
+	"github.com/FriedGlue/BookIt/api/pkg/store"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Stores groups the persistence dependencies used by the handlers.
+type Stores struct {
+	Profiles   store.ProfileStore
+	Books      store.BookStore
+	Works      store.WorkStore
+	Authors    store.AuthorStore
+	Series     store.SeriesStore
+	ReadingLog store.ReadingLogStore
+
+	// Search finds books by the words in them. Books should be an
+	// IndexedBookStore writing to the same index, so it stays up to date.
+	Search *search.Index
+
+	// AuthorSearch finds authors by name. Authors should be an
+	// IndexedAuthorStore writing to the same index.
+	AuthorSearch *search.AuthorIndex
+}
+
+var stores Stores
+
+// Configure sets the stores used by every handler. Binaries call it once at
+// startup, before serving any request.
+func Configure(s Stores) {
+	stores = s
+}
+
+var metadataProvider metadata.MetadataProvider
+
+// ConfigureMetadata sets the catalogue used to look up books that are not
+// saved yet. Like Configure, it is called once at startup.
+func ConfigureMetadata(provider metadata.MetadataProvider) {
+	metadataProvider = provider
+}
+
+// metadataErrorResponse converts an error from a metadata lookup into an
+// API response.
+func metadataErrorResponse(err error, what string) events.APIGatewayProxyResponse {
+	if errors.Is(err, metadata.ErrNotFound) {
+		return shared.Error(shared.CodeBookNotFound, fmt.Sprintf("No book found for %s", what))
+	}
+	log.Printf("Error looking up %s with %s: %v\n", what, metadataProvider.Name(), err)
+	if errors.Is(err, httpclient.ErrCircuitOpen) {
+		return shared.Error(shared.CodeServiceUnavailable, "Book lookups are unavailable right now, please try again later")
+	}
+	return shared.Error(shared.CodeExternalService, fmt.Sprintf("Error fetching book details for %s", what))
+}
+
+// storeErrorResponse converts an error returned by a store call into an API
+// response. Mutators abort with a *shared.APIError, which is returned as-is;
+// notFoundCode and notFoundMessage are used when the item being loaded is
+// missing.
+func storeErrorResponse(err error, notFoundCode shared.ErrorCode, notFoundMessage string) events.APIGatewayProxyResponse {
+	var apiErr *shared.APIError
+	switch {
+	case errors.As(err, &apiErr):
+		return shared.ErrorResponse(apiErr)
+	case errors.Is(err, store.ErrNotFound):
+		return shared.Error(notFoundCode, notFoundMessage)
+	case errors.Is(err, store.ErrConflict):
+		return shared.Error(shared.CodeConcurrentModification, "Profile was modified by another request, please retry")
+	default:
+		return internalErrorResponse("Store error", err)
+	}
+}
+
+// pageRequest reads the limit and cursor query parameters of a paginated
+// endpoint.
+func pageRequest(request events.APIGatewayProxyRequest, defaultLimit, maxLimit int) (pagination.Request, *shared.APIError) {
+	page, err := pagination.FromQuery(request.QueryStringParameters, defaultLimit, maxLimit)
+	switch {
+	case errors.Is(err, pagination.ErrInvalidLimit):
+		return page, shared.NewError(shared.CodeInvalidParameter, "Invalid limit: must be a positive number")
+	case err != nil:
+		return page, shared.NewError(shared.CodeInvalidParameter, "Invalid cursor")
+	}
+	return page, nil
+}
+
+// internalErrorResponse logs err and returns a generic 500 that does not
+// leak the underlying error to the client.
+func internalErrorResponse(context string, err error) events.APIGatewayProxyResponse {
+	log.Printf("%s: %v\n", context, err)
+	return shared.Error(shared.CodeInternal, context)
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/FriedGlue/BookIt/api/pkg/shared"
+	"github.com/FriedGlue/BookIt/api/pkg/store"
+)
+
+func TestStoreErrorResponse(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want shared.ErrorCode
+	}{
+		{"not found", fmt.Errorf("loading: %w", store.ErrNotFound), shared.CodeProfileNotFound},
+		{"conflict", store.ErrConflict, shared.CodeConcurrentModification},
+		{"api error", shared.NewError(shared.CodeBookAlreadyInList, "Book already in list"), shared.CodeBookAlreadyInList},
+		{"other", errors.New("throttled"), shared.CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := storeErrorResponse(tt.err, shared.CodeProfileNotFound, "Profile not found")
+			if code := errorCode(t, response); code != tt.want {
+				t.Errorf("storeErrorResponse(%v) = %s, want %s", tt.err, code, tt.want)
+			}
+		})
+	}
+}
+
+// conflictingProfiles is a ProfileStore whose updates always conflict.
+type conflictingProfiles struct {
+	store.ProfileStore
+}
+
+func (conflictingProfiles) Update(string, func(*models.Profile) error) (*models.Profile, error) {
+	return nil, store.ErrConflict
+}
+
+func TestConflictingUpdateReturns409(t *testing.T) {
+	configureTestStores(t, models.Profile{Lists: models.UserLists{ToBeRead: []models.ToBeReadItem{{BookID: "a"}}}})
+	stores.Profiles = conflictingProfiles{stores.Profiles}
+
+	response := ReorderList(testRequest(t, ReorderListRequest{ListType: "toBeRead", BookIDs: []string{"a"}}))
+	if response.StatusCode != 409 || errorCode(t, response) != shared.CodeConcurrentModification {
+		t.Errorf("ReorderList returned %d %s, want 409 %s", response.StatusCode, response.Body, shared.CodeConcurrentModification)
+	}
+}
+
+// failingReadingLog is a ReadingLogStore whose appends always fail.
+type failingReadingLog struct {
+	store.ReadingLogStore
+}
+
+func (failingReadingLog) Append(string, *models.ReadingLogItem) error {
+	return errors.New("throttled")
+}
+
+func TestReadingLogFailureLeavesProfileUnchanged(t *testing.T) {
+	configureTestStores(t, models.Profile{Lists: models.UserLists{ToBeRead: []models.ToBeReadItem{{BookID: "a"}}}},
+		models.BookData{BookID: "a", Title: "A", PageCount: 100})
+	stores.ReadingLog = failingReadingLog{stores.ReadingLog}
+
+	response := MoveListItem(testRequest(t, MoveListItemRequest{BookID: "a", FromList: "toBeRead", ToList: "currentlyReading"}))
+	if code := errorCode(t, response); code != shared.CodeInternal {
+		t.Fatalf("MoveListItem returned %s (%s), want %s", code, response.Body, shared.CodeInternal)
+	}
+	profile := testProfile(t)
+	if got := toBeReadIDs(t, profile); len(got) != 1 || len(profile.CurrentlyReading) != 0 {
+		t.Errorf("to be read = %v and currently reading = %v, want the book left on to be read", got, readingIDs(profile))
+	}
+}
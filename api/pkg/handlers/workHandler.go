@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/FriedGlue/BookIt/api/pkg/metadata"
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/FriedGlue/BookIt/api/pkg/pagination"
+	"github.com/FriedGlue/BookIt/api/pkg/search"
+	"github.com/FriedGlue/BookIt/api/pkg/shared"
+	"github.com/FriedGlue/BookIt/api/pkg/store"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// WorkWithEditions is a work with its saved editions.
+type WorkWithEditions struct {
+	Work     models.Work       `json:"work"`
+	Editions []models.BookData `json:"editions"`
+}
+
+// GetWork returns a work and every saved edition of it.
+func GetWork(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	workId := request.PathParameters["workId"]
+	if workId == "" {
+		return shared.Error(shared.CodeMissingParameter, "Missing path parameter: workId")
+	}
+
+	result, err := loadWork(workId)
+	if err != nil {
+		return storeErrorResponse(err, shared.CodeWorkNotFound, fmt.Sprintf("No work found with ID: %s", workId))
+	}
+	return shared.SuccessResponse(200, result)
+}
+
+// GetWorkEditions lists the saved editions of a work.
+func GetWorkEditions(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	workId := request.PathParameters["workId"]
+	if workId == "" {
+		return shared.Error(shared.CodeMissingParameter, "Missing path parameter: workId")
+	}
+
+	result, err := loadWork(workId)
+	if err != nil {
+		return storeErrorResponse(err, shared.CodeWorkNotFound, fmt.Sprintf("No work found with ID: %s", workId))
+	}
+	return shared.ListResponse(200, result.Editions)
+}
+
+// SearchWorks searches saved books like GET /books/search?q= and groups the
+// results by work, each work with the editions that matched.
+func SearchWorks(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	q := request.QueryStringParameters["q"]
+	if q == "" {
+		return shared.Error(shared.CodeMissingParameter, "Missing query parameter: q")
+	}
+
+	pageReq, apiErr := pageRequest(request, search.DefaultLimit, search.MaxLimit)
+	if apiErr != nil {
+		return shared.ErrorResponse(apiErr)
+	}
+	page, err := stores.Search.SearchWorks(shared.RequestContext(request), q, pageReq)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		return shared.Error(shared.CodeInvalidParameter, "Invalid cursor")
+	}
+	if err != nil {
+		return internalErrorResponse("Error searching works", err)
+	}
+
+	results := make([]WorkWithEditions, 0, len(page.Works))
+	for _, match := range page.Works {
+		work, err := stores.Works.Get(match.WorkID)
+		if errors.Is(err, store.ErrNotFound) {
+			// Books saved before works were stored, or outside the API
+			fallback := models.WorkFromEdition(match.Editions[0])
+			work = &fallback
+		} else if err != nil {
+			return internalErrorResponse("Error loading work", err)
+		}
+		results = append(results, WorkWithEditions{Work: *work, Editions: match.Editions})
+	}
+	return shared.PageResponse(200, results, pagination.Encode(page.Next))
+}
+
+// loadWork returns a work and its editions. A work that is not saved but has
+// editions, as for books saved before works were stored, is made from its
+// first edition.
+func loadWork(workId string) (*WorkWithEditions, error) {
+	editions, err := stores.Books.QueryByWorkID(workId)
+	if err != nil {
+		return nil, err
+	}
+	if len(editions) == 0 && metadata.IsOpenLibraryID(workId) {
+		// Books saved before works were stored only have the Open Library ID
+		if editions, err = stores.Books.QueryByOpenLibraryID(workId); err != nil {
+			return nil, err
+		}
+	}
+	if editions == nil {
+		editions = []models.BookData{}
+	}
+
+	work, err := stores.Works.Get(workId)
+	if errors.Is(err, store.ErrNotFound) && len(editions) > 0 {
+		fallback := models.WorkFromEdition(editions[0])
+		work, err = &fallback, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &WorkWithEditions{Work: *work, Editions: editions}, nil
+}
@@ -0,0 +1,118 @@
+package httpclient
+
+import (
+	"sync"
+	"time"
+)
+
+// breakerState is the state of a Breaker.
+type breakerState int
+
+const (
+	// closed lets every call through.
+	closed breakerState = iota
+	// open rejects every call until the cool-down has passed.
+	open
+	// halfOpen lets a single trial call through to see if the service is
+	// back.
+	halfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case open:
+		return "open"
+	case halfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// Breaker is a circuit breaker. After Threshold consecutive failures it opens
+// and rejects calls for Cooldown, so a service that is down is not hammered
+// and callers fail fast. It then lets one trial call through: success closes
+// it again, failure reopens it. It is safe for concurrent use, and lives as
+// long as the process, so a warm Lambda remembers that a service is down.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	trialing bool
+}
+
+// NewBreaker returns a closed breaker that opens after threshold consecutive
+// failures and stays open for cooldown.
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// Allow reports whether a call may go ahead. Every allowed call must be
+// followed by Record.
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case open:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = halfOpen
+		b.trialing = true
+		return true
+	case halfOpen:
+		// Only the trial call is let through.
+		if b.trialing {
+			return false
+		}
+		b.trialing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// Record reports the outcome of an allowed call.
+func (b *Breaker) Record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == halfOpen {
+		b.trialing = false
+	}
+	if success {
+		b.state = closed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == halfOpen || b.failures >= b.threshold {
+		b.state = open
+		b.openedAt = b.now()
+	}
+}
+
+// Abandon is used in place of Record when an allowed call ended without
+// telling whether the service is healthy, e.g. because the caller gave up.
+// A trial call abandoned this way is replaced by the next call.
+func (b *Breaker) Abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == halfOpen {
+		b.trialing = false
+	}
+}
+
+// State returns "closed", "open" or "half-open", for logs and metrics.
+func (b *Breaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state.String()
+}
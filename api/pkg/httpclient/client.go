@@ -0,0 +1,232 @@
+// Package httpclient is the HTTP client for calls to external services such
+// as Open Library. Every call gets a deadline derived from the caller's
+// context, transient failures (network errors, 5xx and 429 responses) are
+// retried with jittered exponential backoff, and a circuit breaker per
+// service makes calls fail fast while the service is down.
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling the service while its circuit
+// breaker is open.
+var ErrCircuitOpen = errors.New("service unavailable: circuit breaker open")
+
+// maxBodyBytes bounds how much of a response body is read.
+const maxBodyBytes = 10 << 20
+
+// Options tunes a Client. Zero fields take the defaults noted.
+type Options struct {
+	// AttemptTimeout bounds each attempt. Default 4s.
+	AttemptTimeout time.Duration
+
+	// MaxAttempts is the number of tries, including the first. Default 3.
+	MaxAttempts int
+
+	// BaseBackoff is the backoff ceiling before the first retry; it doubles
+	// on every retry up to MaxBackoff. The actual wait is a random duration
+	// up to the ceiling. Defaults 200ms and 2s.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+
+	// DeadlineMargin is kept free before the caller's deadline, so a Lambda
+	// still has time to answer after giving up on the service. Default
+	// 500ms.
+	DeadlineMargin time.Duration
+
+	// BreakerThreshold consecutive failed calls open the breaker for
+	// BreakerCooldown. Defaults 5 and 30s.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+
+	// Transport sends the requests. Default http.DefaultTransport.
+	Transport http.RoundTripper
+}
+
+// Response is a response whose body has been read in full.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Client makes GET requests to one external service. It is safe for
+// concurrent use and is meant to be created once per service at startup.
+type Client struct {
+	name    string
+	opts    Options
+	http    *http.Client
+	breaker *Breaker
+}
+
+// New returns a client for the service called name, which is used in logs
+// and errors.
+func New(name string, opts Options) *Client {
+	if opts.AttemptTimeout <= 0 {
+		opts.AttemptTimeout = 4 * time.Second
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 3
+	}
+	if opts.BaseBackoff <= 0 {
+		opts.BaseBackoff = 200 * time.Millisecond
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 2 * time.Second
+	}
+	if opts.DeadlineMargin <= 0 {
+		opts.DeadlineMargin = 500 * time.Millisecond
+	}
+	if opts.BreakerThreshold <= 0 {
+		opts.BreakerThreshold = 5
+	}
+	if opts.BreakerCooldown <= 0 {
+		opts.BreakerCooldown = 30 * time.Second
+	}
+	transport := opts.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return &Client{
+		name:    name,
+		opts:    opts,
+		http:    &http.Client{Transport: transport},
+		breaker: NewBreaker(opts.BreakerThreshold, opts.BreakerCooldown),
+	}
+}
+
+// Name returns the service name the client was created with.
+func (c *Client) Name() string { return c.name }
+
+// BreakerState returns the state of the client's circuit breaker.
+func (c *Client) BreakerState() string { return c.breaker.State() }
+
+// Get fetches url, retrying transient failures until ctx's deadline (less
+// the margin) or MaxAttempts is reached. Any response that is not retried,
+// or the last retried one, is returned with a nil error whatever its status;
+// an error means no usable response was received.
+func (c *Client) Get(ctx context.Context, url string) (*Response, error) {
+	if !c.breaker.Allow() {
+		return nil, fmt.Errorf("%s: %w", c.name, ErrCircuitOpen)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline.Add(-c.opts.DeadlineMargin))
+		defer cancel()
+	}
+
+	resp, err := c.getWithRetries(ctx, url)
+	switch {
+	case err != nil && ctx.Err() != nil:
+		// The caller ran out of time, which says nothing about the service.
+		c.breaker.Abandon()
+	case err != nil:
+		c.breaker.Record(false)
+	default:
+		c.breaker.Record(!retryable(resp.StatusCode))
+	}
+	return resp, err
+}
+
+func (c *Client) getWithRetries(ctx context.Context, url string) (*Response, error) {
+	var resp *Response
+	var err error
+	for attempt := 1; ; attempt++ {
+		resp, err = c.attempt(ctx, url)
+		if err == nil && !retryable(resp.StatusCode) {
+			return resp, nil
+		}
+		if attempt == c.opts.MaxAttempts || ctx.Err() != nil {
+			break
+		}
+
+		wait, ok := c.backoff(attempt, resp)
+		if !ok {
+			break
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			break
+		}
+		log.Printf("%s: attempt %d of %d failed (%s), retrying in %v", c.name, attempt, c.opts.MaxAttempts, describe(resp, err), wait)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("%s: %w", c.name, ctx.Err())
+		case <-timer.C:
+		}
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", c.name, err)
+	}
+	return resp, nil
+}
+
+// attempt makes one request, bounded by AttemptTimeout.
+func (c *Client) attempt(ctx context.Context, url string) (*Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.AttemptTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "BookIt (https://getbookit.org)")
+
+	httpResp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(httpResp.Body, maxBodyBytes))
+	if err != nil {
+		return nil, err
+	}
+	return &Response{StatusCode: httpResp.StatusCode, Header: httpResp.Header, Body: body}, nil
+}
+
+// backoff returns how long to wait before the next attempt: a random
+// duration up to BaseBackoff doubled per attempt, capped at MaxBackoff.
+// A Retry-After header on a 429 or 503 takes precedence; if it asks for
+// longer than MaxBackoff, ok is false and the call is not retried.
+func (c *Client) backoff(attempt int, resp *Response) (wait time.Duration, ok bool) {
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			wait = time.Duration(seconds) * time.Second
+			return wait, wait <= c.opts.MaxBackoff
+		}
+	}
+
+	ceiling := c.opts.BaseBackoff << (attempt - 1)
+	if ceiling > c.opts.MaxBackoff || ceiling <= 0 {
+		ceiling = c.opts.MaxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1)), true
+}
+
+// retryable reports whether a response status is worth retrying.
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+func describe(resp *Response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return "status " + strconv.Itoa(resp.StatusCode)
+}
@@ -0,0 +1,169 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// scriptedTransport answers each request with the next status in statuses,
+// or fails it when the status is 0. The last status repeats.
+type scriptedTransport struct {
+	mu       sync.Mutex
+	statuses []int
+	header   http.Header
+	calls    int
+}
+
+func (s *scriptedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.statuses[len(s.statuses)-1]
+	if s.calls < len(s.statuses) {
+		status = s.statuses[s.calls]
+	}
+	s.calls++
+	if status == 0 {
+		return nil, errors.New("connection reset")
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     s.header,
+		Body:       io.NopCloser(strings.NewReader(`{}`)),
+		Request:    req,
+	}, nil
+}
+
+func testClient(transport http.RoundTripper) *Client {
+	return New("test", Options{
+		MaxAttempts:      3,
+		BaseBackoff:      time.Millisecond,
+		MaxBackoff:       5 * time.Millisecond,
+		BreakerThreshold: 2,
+		BreakerCooldown:  time.Hour,
+		Transport:        transport,
+	})
+}
+
+func TestGetRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int
+		header     http.Header
+		wantCalls  int
+		wantStatus int
+		wantErr    bool
+	}{
+		{name: "success", statuses: []int{200}, wantCalls: 1, wantStatus: 200},
+		{name: "client error is not retried", statuses: []int{404}, wantCalls: 1, wantStatus: 404},
+		{name: "server error then success", statuses: []int{503, 200}, wantCalls: 2, wantStatus: 200},
+		{name: "network error then success", statuses: []int{0, 0, 200}, wantCalls: 3, wantStatus: 200},
+		{name: "gives up after max attempts", statuses: []int{500}, wantCalls: 3, wantStatus: 500},
+		{name: "network errors exhaust attempts", statuses: []int{0}, wantCalls: 3, wantErr: true},
+		{name: "short Retry-After is honoured", statuses: []int{429, 200}, header: http.Header{"Retry-After": {"0"}}, wantCalls: 2, wantStatus: 200},
+		{name: "long Retry-After is not waited for", statuses: []int{429, 200}, header: http.Header{"Retry-After": {"60"}}, wantCalls: 1, wantStatus: 429},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &scriptedTransport{statuses: tt.statuses, header: tt.header}
+			resp, err := testClient(transport).Get(context.Background(), "http://example.test/books")
+
+			if transport.calls != tt.wantCalls {
+				t.Errorf("made %d calls, want %d", transport.calls, tt.wantCalls)
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Get returned status %d, want an error", resp.StatusCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestGetOpensBreaker(t *testing.T) {
+	transport := &scriptedTransport{statuses: []int{500}}
+	client := testClient(transport)
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Get(context.Background(), "http://example.test"); err != nil {
+			t.Fatalf("call %d: %v", i+1, err)
+		}
+	}
+	if state := client.BreakerState(); state != "open" {
+		t.Fatalf("breaker is %s after 2 failed calls, want open", state)
+	}
+
+	calls := transport.calls
+	if _, err := client.Get(context.Background(), "http://example.test"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Get with open breaker = %v, want ErrCircuitOpen", err)
+	}
+	if transport.calls != calls {
+		t.Errorf("open breaker let %d calls through", transport.calls-calls)
+	}
+}
+
+func TestGetCancelledDoesNotCountAgainstService(t *testing.T) {
+	client := testClient(&scriptedTransport{statuses: []int{0}})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.Get(ctx, "http://example.test"); err == nil {
+			t.Fatal("Get with a cancelled context succeeded")
+		}
+	}
+	if state := client.BreakerState(); state != "closed" {
+		t.Errorf("breaker is %s after cancelled calls, want closed", state)
+	}
+}
+
+func TestBreakerStates(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := NewBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	step := func(desc string, allowed bool, state string) {
+		t.Helper()
+		if got := b.Allow(); got != allowed {
+			t.Errorf("%s: Allow() = %v, want %v", desc, got, allowed)
+		}
+		if got := b.State(); got != state {
+			t.Errorf("%s: state %s, want %s", desc, got, state)
+		}
+	}
+
+	step("new breaker", true, "closed")
+	b.Record(false)
+	step("one failure", true, "closed")
+	b.Record(true)
+	step("success resets the count", true, "closed")
+	b.Record(false)
+	step("first failure again", true, "closed")
+	b.Record(false)
+	step("threshold reached", false, "open")
+
+	now = now.Add(time.Minute)
+	step("cool-down over", true, "half-open")
+	step("second call during trial", false, "half-open")
+	b.Record(false)
+	step("failed trial reopens", false, "open")
+
+	now = now.Add(time.Minute)
+	step("second trial", true, "half-open")
+	b.Abandon()
+	step("abandoned trial is replaced", true, "half-open")
+	b.Record(true)
+	step("successful trial closes", true, "closed")
+}
@@ -0,0 +1,163 @@
+// Package isbn normalizes, validates and converts International Standard
+// Book Numbers. Books are stored under their ISBN-13, the form every book
+// has; an ISBN-10 is the same number for books with the 978 prefix, so either
+// form can be converted to the other for them.
+package isbn
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrInvalid is returned for a string that is not a valid ISBN-10 or
+	// ISBN-13.
+	ErrInvalid = errors.New("invalid ISBN")
+
+	// ErrNoISBN10 is returned by To10 for an ISBN-13 with the 979 prefix,
+	// which has no ISBN-10 form.
+	ErrNoISBN10 = errors.New("ISBN has no ISBN-10 form")
+)
+
+// labels are the prefixes ISBNs are often printed with, longest first.
+var labels = []string{"ISBN-13", "ISBN-10", "ISBN13", "ISBN10", "ISBN"}
+
+// Normalize strips the "ISBN" label, hyphens and spaces an ISBN is usually
+// printed with and upper-cases an ISBN-10's X check digit. It does not
+// validate s.
+func Normalize(s string) string {
+	s = strings.TrimSpace(s)
+	for _, label := range labels {
+		if len(s) >= len(label) && strings.EqualFold(s[:len(label)], label) {
+			s = strings.TrimLeft(s[len(label):], ": ")
+			break
+		}
+	}
+
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '-', ' ', '‐', '‑', '–':
+		case 'x':
+			b.WriteByte('X')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Parse validates s, an ISBN-10 or ISBN-13 in any common notation, and
+// returns its ISBN-13.
+func Parse(s string) (string, error) {
+	n := Normalize(s)
+	switch len(n) {
+	case 10:
+		return To13(n)
+	case 13:
+		if !Valid13(n) {
+			return "", fmt.Errorf("%w: %q is not a valid ISBN-13", ErrInvalid, s)
+		}
+		return n, nil
+	default:
+		return "", fmt.Errorf("%w: %q does not have 10 or 13 digits", ErrInvalid, s)
+	}
+}
+
+// Valid10 reports whether s is a normalized ISBN-10 with a correct check
+// digit.
+func Valid10(s string) bool {
+	if len(s) != 10 {
+		return false
+	}
+	for i := 0; i < 9; i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+	if !isDigit(s[9]) && s[9] != 'X' {
+		return false
+	}
+	return check10(s[:9]) == s[9]
+}
+
+// Valid13 reports whether s is a normalized ISBN-13 (978 or 979 prefix) with
+// a correct check digit.
+func Valid13(s string) bool {
+	if len(s) != 13 || !(strings.HasPrefix(s, "978") || strings.HasPrefix(s, "979")) {
+		return false
+	}
+	for i := 0; i < 13; i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+	return check13(s[:12]) == s[12]
+}
+
+// To13 converts an ISBN-10 to its ISBN-13.
+func To13(isbn10 string) (string, error) {
+	n := Normalize(isbn10)
+	if !Valid10(n) {
+		return "", fmt.Errorf("%w: %q is not a valid ISBN-10", ErrInvalid, isbn10)
+	}
+	body := "978" + n[:9]
+	return body + string(check13(body)), nil
+}
+
+// To10 converts an ISBN, in either form, to its ISBN-10. ISBN-13s with the
+// 979 prefix return ErrNoISBN10.
+func To10(isbn string) (string, error) {
+	n, err := Parse(isbn)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(n, "978") {
+		return "", ErrNoISBN10
+	}
+	body := n[3:12]
+	return body + string(check10(body)), nil
+}
+
+// Equal reports whether a and b are the same ISBN, in whichever form and
+// notation. Strings that are not valid ISBNs are equal if they normalize to
+// the same non-empty string.
+func Equal(a, b string) bool {
+	na, errA := Parse(a)
+	nb, errB := Parse(b)
+	if errA == nil && errB == nil {
+		return na == nb
+	}
+	return Normalize(a) != "" && Normalize(a) == Normalize(b)
+}
+
+// check10 returns the ISBN-10 check digit for nine digits.
+func check10(body string) byte {
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += (10 - i) * int(body[i]-'0')
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return 'X'
+	}
+	return byte('0' + check)
+}
+
+// check13 returns the ISBN-13 check digit for twelve digits.
+func check13(body string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += weight * int(body[i]-'0')
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
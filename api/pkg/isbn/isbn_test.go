@@ -0,0 +1,109 @@
+package isbn
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+		err  error
+	}{
+		{"isbn-13", "9780306406157", "9780306406157", nil},
+		{"isbn-10", "0306406152", "9780306406157", nil},
+		{"hyphens and label", "ISBN-13: 978-0-306-40615-7", "9780306406157", nil},
+		{"isbn-10 with lower case x", "0-8044-2957-x", "9780804429573", nil},
+		{"979 prefix", "979-10-90636-07-1", "9791090636071", nil},
+		{"bad isbn-13 check digit", "9780306406158", "", ErrInvalid},
+		{"bad isbn-10 check digit", "0306406153", "", ErrInvalid},
+		{"unknown prefix", "9770306406157", "", ErrInvalid},
+		{"wrong length", "030640615", "", ErrInvalid},
+		{"letters", "03064o6152", "", ErrInvalid},
+		{"empty", "", "", ErrInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Parse(%q) error = %v, want %v", tt.in, err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("Parse(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTo10(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+		err  error
+	}{
+		{"isbn-13", "9780306406157", "0306406152", nil},
+		{"x check digit", "978-0-8044-2957-3", "080442957X", nil},
+		{"already isbn-10", "0306406152", "0306406152", nil},
+		{"979 prefix", "9791090636071", "", ErrNoISBN10},
+		{"invalid", "9780306406158", "", ErrInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := To10(tt.in)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("To10(%q) error = %v, want %v", tt.in, err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("To10(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTo13(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+		err  error
+	}{
+		{"isbn-10", "0306406152", "9780306406157", nil},
+		{"x check digit", "080442957X", "9780804429573", nil},
+		{"isbn-13 is not an isbn-10", "9780306406157", "", ErrInvalid},
+		{"bad check digit", "0306406153", "", ErrInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := To13(tt.in)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("To13(%q) error = %v, want %v", tt.in, err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("To13(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"0306406152", "9780306406157", true},
+		{"ISBN 0-306-40615-2", "978 0 306 40615 7", true},
+		{"080442957x", "080442957X", true},
+		{"9780306406157", "9780804429573", false},
+		{"not-an-isbn", "not an isbn", true},
+		{"not an isbn", "0306406152", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		if got := Equal(tt.a, tt.b); got != tt.want {
+			t.Errorf("Equal(%q, %q) = %t, want %t", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package localserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/FriedGlue/BookIt/api/pkg/auth"
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/FriedGlue/BookIt/api/pkg/router"
+	"github.com/FriedGlue/BookIt/api/pkg/routes"
+	"github.com/FriedGlue/BookIt/api/pkg/shared"
+	"github.com/FriedGlue/BookIt/api/pkg/store"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+)
+
+// DevAuth serves the /auth endpoints without Cognito. Any username and
+// password sign in; the user's sub is derived from the username so it stays
+// the same across restarts, and a profile is created on first sign in the way
+// the profile-creator Lambda does after a Cognito sign-up is confirmed.
+type DevAuth struct {
+	tokens   *DevTokens
+	profiles store.ProfileStore
+	router   *router.Router
+}
+
+// NewDevAuth returns a DevAuth that issues tokens with tokens and creates
+// profiles in profiles.
+func NewDevAuth(tokens *DevTokens, profiles store.ProfileStore) *DevAuth {
+	a := &DevAuth{tokens: tokens, profiles: profiles}
+	a.router = router.New(a.Routes()...)
+	return a
+}
+
+// Routes lists the endpoints DevAuth serves in place of routes.AuthRoutes.
+func (a *DevAuth) Routes() []router.Route {
+	return []router.Route{
+		{Method: http.MethodPost, Pattern: "/auth/signup", Handler: a.signUp},
+		{Method: http.MethodPost, Pattern: "/auth/confirm", Handler: a.confirm},
+		{Method: http.MethodPost, Pattern: "/auth/signin", Handler: a.signIn},
+		{Method: http.MethodPost, Pattern: "/auth/refresh", Handler: a.refresh},
+		{Method: http.MethodPost, Pattern: "/auth/signout", Handler: a.signOut},
+	}
+}
+
+// Route handles a request under /auth. It has the same signature and
+// response shapes as routes.Auth.
+func (a *DevAuth) Route(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return routes.Public(ctx, request, a.router.Route), nil
+}
+
+func (a *DevAuth) signUp(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	var payload struct {
+		Username string `json:"username"`
+		Email    string `json:"email"`
+	}
+	if err := json.Unmarshal([]byte(request.Body), &payload); err != nil {
+		return shared.Error(shared.CodeInvalidJSON, "Invalid JSON: "+err.Error())
+	}
+	if payload.Username == "" {
+		return shared.Error(shared.CodeMissingParameter, "username is required")
+	}
+
+	if _, err := a.ensureProfile(payload.Username, payload.Email); err != nil {
+		log.Printf("Error creating dev profile: %v\n", err)
+		return shared.Error(shared.CodeInternal, "Error creating profile")
+	}
+	return shared.MessageResponse(200, fmt.Sprintf("User '%s' sign-up initiated. Any confirmation code is accepted locally.", payload.Username))
+}
+
+func (a *DevAuth) confirm(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	var payload struct {
+		Username string `json:"username"`
+	}
+	if err := json.Unmarshal([]byte(request.Body), &payload); err != nil {
+		return shared.Error(shared.CodeInvalidJSON, "Invalid JSON: "+err.Error())
+	}
+	if payload.Username == "" {
+		return shared.Error(shared.CodeMissingParameter, "username and code are required")
+	}
+	return shared.MessageResponse(200, fmt.Sprintf("User '%s' confirmed successfully.", payload.Username))
+}
+
+func (a *DevAuth) signIn(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	var payload struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.Unmarshal([]byte(request.Body), &payload); err != nil {
+		return shared.Error(shared.CodeInvalidJSON, "Invalid JSON: "+err.Error())
+	}
+	if payload.Username == "" || payload.Password == "" {
+		return shared.Error(shared.CodeMissingParameter, "username and password are required")
+	}
+
+	sub, err := a.ensureProfile(payload.Username, "")
+	if err != nil {
+		log.Printf("Error creating dev profile: %v\n", err)
+		return shared.Error(shared.CodeInternal, "Error creating profile")
+	}
+
+	idToken, err := a.tokens.Issue(sub, payload.Username, "id")
+	if err != nil {
+		log.Printf("Error issuing dev token: %v\n", err)
+		return shared.Error(shared.CodeInternal, "Error issuing token")
+	}
+	refreshToken, err := a.tokens.Issue(sub, payload.Username, "refresh")
+	if err != nil {
+		log.Printf("Error issuing dev token: %v\n", err)
+		return shared.Error(shared.CodeInternal, "Error issuing token")
+	}
+
+	response := shared.SuccessResponse(200, auth.Tokens{
+		IdToken:      idToken,
+		AccessToken:  idToken,
+		RefreshToken: refreshToken,
+	})
+	response.Headers["Set-Cookie"] = fmt.Sprintf("refreshToken=%s; Path=/; Max-Age=2592000; HttpOnly; SameSite=Lax", refreshToken)
+	return response
+}
+
+func (a *DevAuth) signOut(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	return shared.MessageResponse(200, "Signed out successfully")
+}
+
+func (a *DevAuth) refresh(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	token, ok := bearerToken(request.Headers["Authorization"])
+	if !ok {
+		return shared.Error(shared.CodeUnauthorized, "Invalid Authorization header format")
+	}
+
+	claims, err := a.tokens.Verify(token, "refresh")
+	if err != nil {
+		return shared.Error(shared.CodeAuthFailed, fmt.Sprintf("Refresh token error: %v", err))
+	}
+	sub, _ := claims["sub"].(string)
+	username, _ := claims["cognito:username"].(string)
+
+	idToken, err := a.tokens.Issue(sub, username, "id")
+	if err != nil {
+		log.Printf("Error issuing dev token: %v\n", err)
+		return shared.Error(shared.CodeInternal, "Error issuing token")
+	}
+
+	return shared.SuccessResponse(200, auth.Tokens{
+		IdToken:     idToken,
+		AccessToken: idToken,
+	})
+}
+
+// ensureProfile creates the user's profile if it does not exist yet and
+// returns the user's sub.
+func (a *DevAuth) ensureProfile(username, email string) (string, error) {
+	sub := DevUserID(username)
+
+	_, err := a.profiles.Get(sub)
+	if err == nil {
+		return sub, nil
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		return "", fmt.Errorf("error loading profile: %w", err)
+	}
+
+	profile := models.NewProfile(sub, username)
+	profile.ProfileInformation.Email = email
+	err = a.profiles.Create(&profile)
+	if errors.Is(err, store.ErrAlreadyExists) {
+		return sub, nil // created by a concurrent request
+	}
+	if err != nil {
+		return "", fmt.Errorf("error creating profile: %w", err)
+	}
+	log.Printf("Created local profile for %s (%s)\n", username, sub)
+	return sub, nil
+}
+
+// DevUserID returns the stable sub used for a local user.
+func DevUserID(username string) string {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte("bookit-local:"+username)).String()
+}
+
+func bearerToken(header string) (string, bool) {
+	parts := strings.Split(header, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" || parts[1] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
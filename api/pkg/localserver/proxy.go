@@ -0,0 +1,91 @@
+package localserver
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+)
+
+// toProxyRequest translates an incoming HTTP request into the event API
+// Gateway would send to the Lambda. claims, if not nil, is exposed the same
+// way the Cognito authorizer does, under RequestContext.Authorizer["claims"].
+func toProxyRequest(r *http.Request, claims map[string]interface{}) (events.APIGatewayProxyRequest, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return events.APIGatewayProxyRequest{}, fmt.Errorf("error reading request body: %w", err)
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Resource:                        r.URL.Path,
+		Path:                            r.URL.Path,
+		HTTPMethod:                      r.Method,
+		Headers:                         map[string]string{},
+		MultiValueHeaders:               map[string][]string{},
+		QueryStringParameters:           map[string]string{},
+		MultiValueQueryStringParameters: map[string][]string{},
+		RequestContext: events.APIGatewayProxyRequestContext{
+			RequestID:  uuid.New().String(),
+			Path:       r.URL.Path,
+			HTTPMethod: r.Method,
+			Stage:      "local",
+			Identity: events.APIGatewayRequestIdentity{
+				SourceIP:  r.RemoteAddr,
+				UserAgent: r.UserAgent(),
+			},
+		},
+	}
+
+	for name, values := range r.Header {
+		request.Headers[name] = values[0]
+		request.MultiValueHeaders[name] = values
+	}
+	for name, values := range r.URL.Query() {
+		request.QueryStringParameters[name] = values[0]
+		request.MultiValueQueryStringParameters[name] = values
+	}
+
+	if utf8.Valid(body) {
+		request.Body = string(body)
+	} else {
+		request.Body = base64.StdEncoding.EncodeToString(body)
+		request.IsBase64Encoded = true
+	}
+
+	if claims != nil {
+		request.RequestContext.Authorizer = map[string]interface{}{"claims": claims}
+	}
+	return request, nil
+}
+
+// writeProxyResponse writes a Lambda proxy response to w.
+func writeProxyResponse(w http.ResponseWriter, response events.APIGatewayProxyResponse) {
+	for name, value := range response.Headers {
+		w.Header().Set(name, value)
+	}
+	for name, values := range response.MultiValueHeaders {
+		w.Header().Del(name)
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+
+	status := response.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+
+	if response.IsBase64Encoded {
+		body, err := base64.StdEncoding.DecodeString(response.Body)
+		if err == nil {
+			w.Write(body)
+			return
+		}
+	}
+	io.WriteString(w, response.Body)
+}
@@ -0,0 +1,127 @@
+// Package localserver serves the Lambda routing functions over plain
+// net/http so the API and the Svelte client can run without SAM or AWS.
+// Requests are translated into API Gateway proxy events, and the Cognito
+// authorizer is replaced by locally verified dev tokens (see DevTokens) or,
+// when enabled, an X-Dev-User header naming the user.
+package localserver
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/FriedGlue/BookIt/api/pkg/shared"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// DevUserHeader names the user a request is made as when AllowDevHeader is
+// set, e.g. "X-Dev-User: alice".
+const DevUserHeader = "X-Dev-User"
+
+// LambdaHandler is the signature shared by routes.Orchestrator, routes.Auth
+// and DevAuth.Route.
+type LambdaHandler func(context.Context, events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+
+// Config configures the local server.
+type Config struct {
+	// Orchestrator handles every route outside /auth. Requests only reach
+	// it once they have been authenticated.
+	Orchestrator LambdaHandler
+
+	// Auth handles /auth routes, which need no authentication.
+	Auth LambdaHandler
+
+	// Tokens verifies the bearer tokens sent to Orchestrator routes.
+	Tokens *DevTokens
+
+	// DevAuth creates profiles for users named by the X-Dev-User header.
+	DevAuth *DevAuth
+
+	// AllowDevHeader lets requests authenticate with the X-Dev-User header
+	// instead of a token.
+	AllowDevHeader bool
+
+	// Admins are the usernames put in AdminGroup, which Cognito would
+	// report in the cognito:groups claim.
+	Admins     []string
+	AdminGroup string
+}
+
+type server struct {
+	cfg Config
+}
+
+// New returns an http.Handler serving the API described by cfg.
+func New(cfg Config) http.Handler {
+	return &server{cfg: cfg}
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var response events.APIGatewayProxyResponse
+	if r.URL.Path == "/auth" || strings.HasPrefix(r.URL.Path, "/auth/") {
+		response = s.invoke(r, s.cfg.Auth, nil)
+	} else {
+		// Requests without valid credentials get no claims, and the
+		// orchestrator's authentication middleware rejects them.
+		response = s.invoke(r, s.cfg.Orchestrator, s.authenticate(r))
+	}
+	writeProxyResponse(w, response)
+}
+
+// invoke runs handler with the proxy event for r.
+func (s *server) invoke(r *http.Request, handler LambdaHandler, claims map[string]interface{}) events.APIGatewayProxyResponse {
+	request, err := toProxyRequest(r, claims)
+	if err != nil {
+		return shared.Error(shared.CodeBadRequest, err.Error())
+	}
+
+	response, err := handler(r.Context(), request)
+	if err != nil {
+		// Lambda turns handler errors into a 502 from API Gateway.
+		log.Printf("Handler error: %v\n", err)
+		return events.APIGatewayProxyResponse{StatusCode: 502, Body: `{"message":"Internal server error"}`}
+	}
+	return response
+}
+
+// authenticate returns the claims the Cognito authorizer would attach to r,
+// or nil if r carries no valid credentials.
+func (s *server) authenticate(r *http.Request) map[string]interface{} {
+	if username := r.Header.Get(DevUserHeader); username != "" && s.cfg.AllowDevHeader {
+		sub, err := s.cfg.DevAuth.ensureProfile(username, "")
+		if err != nil {
+			log.Printf("Error preparing dev user %s: %v\n", username, err)
+			return nil
+		}
+		return s.withGroups(map[string]interface{}{"sub": sub, "cognito:username": username})
+	}
+
+	// The Cognito authorizer accepts the raw token as well as "Bearer <token>".
+	header := r.Header.Get("Authorization")
+	token, ok := bearerToken(header)
+	if !ok {
+		token = header
+	}
+	if token == "" {
+		return nil
+	}
+
+	claims, err := s.cfg.Tokens.Verify(token, "id")
+	if err != nil {
+		log.Printf("Rejected token: %v\n", err)
+		return nil
+	}
+	return s.withGroups(claims)
+}
+
+// withGroups adds the cognito:groups claim for admins.
+func (s *server) withGroups(claims map[string]interface{}) map[string]interface{} {
+	username, _ := claims["cognito:username"].(string)
+	for _, admin := range s.cfg.Admins {
+		if admin == username && s.cfg.AdminGroup != "" {
+			claims["cognito:groups"] = s.cfg.AdminGroup
+		}
+	}
+	return claims
+}
@@ -0,0 +1,285 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/FriedGlue/BookIt/api/pkg/cache"
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+)
+
+// CacheTTLs sets how long each kind of answer is cached. Zero fields take
+// the defaults noted.
+type CacheTTLs struct {
+	// Search results. Default 1h.
+	Search time.Duration
+
+	// Books found by ISBN or work ID, editions of works, and authors with
+	// their works. Default 24h.
+	Lookup time.Duration
+
+	// Lookups that found nothing, so a missing book is not asked for again
+	// on every request. Default 1h.
+	NotFound time.Duration
+}
+
+// Cached answers from a cache when it can and asks the provider it wraps
+// otherwise. Failures are never cached.
+type Cached struct {
+	next  MetadataProvider
+	cache cache.Cache
+	ttls  CacheTTLs
+
+	hits, misses atomic.Uint64
+}
+
+// NewCached wraps next with c.
+func NewCached(next MetadataProvider, c cache.Cache, ttls CacheTTLs) *Cached {
+	if ttls.Search <= 0 {
+		ttls.Search = time.Hour
+	}
+	if ttls.Lookup <= 0 {
+		ttls.Lookup = 24 * time.Hour
+	}
+	if ttls.NotFound <= 0 {
+		ttls.NotFound = time.Hour
+	}
+	return &Cached{next: next, cache: c, ttls: ttls}
+}
+
+// Name returns the wrapped provider's name.
+func (c *Cached) Name() string { return c.next.Name() }
+
+// Stats returns how many calls were answered from the cache and how many
+// went to the provider since startup.
+func (c *Cached) Stats() (hits, misses uint64) {
+	return c.hits.Load(), c.misses.Load()
+}
+
+// CacheStats counts the cache hits and misses of a catalogue since startup.
+type CacheStats struct {
+	Providers []ProviderCacheStats `json:"providers"`
+
+	// Tiers are the counts of each tier of the caches the providers share,
+	// when the caches keep them.
+	Tiers []cache.TierStats `json:"tiers"`
+}
+
+// ProviderCacheStats counts the calls to one provider answered from the
+// cache and the calls that went to the provider.
+type ProviderCacheStats struct {
+	Provider string `json:"provider"`
+	Hits     uint64 `json:"hits"`
+	Misses   uint64 `json:"misses"`
+}
+
+// Stats collects the cache counts of the cached providers in p, which may be
+// a Chain. Providers without a cache are left out.
+func Stats(p MetadataProvider) CacheStats {
+	stats := CacheStats{Providers: []ProviderCacheStats{}, Tiers: []cache.TierStats{}}
+	var seen []cache.Cache
+	var collect func(MetadataProvider)
+	collect = func(p MetadataProvider) {
+		switch p := p.(type) {
+		case Chain:
+			for _, next := range p {
+				collect(next)
+			}
+		case *Cached:
+			hits, misses := p.Stats()
+			stats.Providers = append(stats.Providers, ProviderCacheStats{Provider: p.Name(), Hits: hits, Misses: misses})
+			tiered, ok := p.cache.(interface{ Stats() []cache.TierStats })
+			if ok && !slices.Contains(seen, p.cache) {
+				seen = append(seen, p.cache)
+				stats.Tiers = append(stats.Tiers, tiered.Stats()...)
+			}
+		}
+	}
+	collect(p)
+	return stats
+}
+
+// cachedLookup is what is stored for a lookup: the book, or NotFound.
+type cachedLookup struct {
+	Book     *models.BookData `json:"book,omitempty"`
+	NotFound bool             `json:"notFound,omitempty"`
+}
+
+// SearchByText searches for the normalized query and caches the results
+// under it, so "Dune " and "dune" share an entry.
+func (c *Cached) SearchByText(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	query = NormalizeQuery(query)
+	key := fmt.Sprintf("search:%s:%d:%s", c.next.Name(), limit, query)
+
+	var results []SearchResult
+	if c.load(ctx, key, &results) {
+		return results, nil
+	}
+
+	results, err := c.next.SearchByText(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	c.store(ctx, key, results, c.ttls.Search)
+	return results, nil
+}
+
+// LookupByISBN caches the book under the ISBN.
+func (c *Cached) LookupByISBN(ctx context.Context, isbn string) (*models.BookData, error) {
+	return c.lookup(ctx, "isbn:"+c.next.Name()+":"+isbn, func() (*models.BookData, error) {
+		return c.next.LookupByISBN(ctx, isbn)
+	})
+}
+
+// LookupByWorkID caches the book under the work ID.
+func (c *Cached) LookupByWorkID(ctx context.Context, workID string) (*models.BookData, error) {
+	return c.lookup(ctx, "work:"+c.next.Name()+":"+workID, func() (*models.BookData, error) {
+		return c.next.LookupByWorkID(ctx, workID)
+	})
+}
+
+// cachedEditions is what is stored for an editions lookup.
+type cachedEditions struct {
+	Editions []models.BookData `json:"editions,omitempty"`
+	NotFound bool              `json:"notFound,omitempty"`
+}
+
+// LookupEditions caches the editions under the work ID and limit.
+func (c *Cached) LookupEditions(ctx context.Context, workID string, limit int) ([]models.BookData, error) {
+	key := fmt.Sprintf("editions:%s:%d:%s", c.next.Name(), limit, workID)
+
+	var cached cachedEditions
+	if c.load(ctx, key, &cached) {
+		if cached.NotFound {
+			return nil, ErrNotFound
+		}
+		return cached.Editions, nil
+	}
+
+	editions, err := c.next.LookupEditions(ctx, workID, limit)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		c.store(ctx, key, cachedEditions{NotFound: true}, c.ttls.NotFound)
+		return nil, err
+	case err != nil:
+		return nil, err
+	}
+	c.store(ctx, key, cachedEditions{Editions: editions}, c.ttls.Lookup)
+	return editions, nil
+}
+
+// cachedAuthor is what is stored for an author lookup.
+type cachedAuthor struct {
+	Author   *models.Author `json:"author,omitempty"`
+	NotFound bool           `json:"notFound,omitempty"`
+}
+
+// LookupAuthor caches the author under their ID.
+func (c *Cached) LookupAuthor(ctx context.Context, authorID string) (*models.Author, error) {
+	key := "author:" + c.next.Name() + ":" + authorID
+
+	var cached cachedAuthor
+	if c.load(ctx, key, &cached) {
+		if cached.NotFound || cached.Author == nil {
+			return nil, ErrNotFound
+		}
+		return cached.Author, nil
+	}
+
+	author, err := c.next.LookupAuthor(ctx, authorID)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		c.store(ctx, key, cachedAuthor{NotFound: true}, c.ttls.NotFound)
+		return nil, err
+	case err != nil:
+		return nil, err
+	}
+	c.store(ctx, key, cachedAuthor{Author: author}, c.ttls.Lookup)
+	return author, nil
+}
+
+// cachedAuthorWorks is what is stored for a lookup of an author's works.
+type cachedAuthorWorks struct {
+	Works    []SearchResult `json:"works,omitempty"`
+	NotFound bool           `json:"notFound,omitempty"`
+}
+
+// LookupAuthorWorks caches the works under the author ID and limit.
+func (c *Cached) LookupAuthorWorks(ctx context.Context, authorID string, limit int) ([]SearchResult, error) {
+	key := fmt.Sprintf("authorWorks:%s:%d:%s", c.next.Name(), limit, authorID)
+
+	var cached cachedAuthorWorks
+	if c.load(ctx, key, &cached) {
+		if cached.NotFound {
+			return nil, ErrNotFound
+		}
+		return cached.Works, nil
+	}
+
+	works, err := c.next.LookupAuthorWorks(ctx, authorID, limit)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		c.store(ctx, key, cachedAuthorWorks{NotFound: true}, c.ttls.NotFound)
+		return nil, err
+	case err != nil:
+		return nil, err
+	}
+	c.store(ctx, key, cachedAuthorWorks{Works: works}, c.ttls.Lookup)
+	return works, nil
+}
+
+func (c *Cached) lookup(ctx context.Context, key string, find func() (*models.BookData, error)) (*models.BookData, error) {
+	var cached cachedLookup
+	if c.load(ctx, key, &cached) {
+		if cached.NotFound || cached.Book == nil {
+			return nil, ErrNotFound
+		}
+		return cached.Book, nil
+	}
+
+	book, err := find()
+	switch {
+	case errors.Is(err, ErrNotFound):
+		c.store(ctx, key, cachedLookup{NotFound: true}, c.ttls.NotFound)
+		return nil, err
+	case err != nil:
+		return nil, err
+	}
+	c.store(ctx, key, cachedLookup{Book: book}, c.ttls.Lookup)
+	return book, nil
+}
+
+// load decodes the value cached under key into v and reports whether there
+// was one.
+func (c *Cached) load(ctx context.Context, key string, v interface{}) bool {
+	value, ok, err := c.cache.Get(ctx, key)
+	if err == nil && ok && json.Unmarshal(value, v) == nil {
+		c.hits.Add(1)
+		return true
+	}
+	c.misses.Add(1)
+	return false
+}
+
+func (c *Cached) store(ctx context.Context, key string, v interface{}, ttl time.Duration) {
+	value, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Metadata cache: encoding %s: %v", key, err)
+		return
+	}
+	if err := c.cache.Set(ctx, key, value, ttl); err != nil {
+		log.Printf("Metadata cache: storing %s: %v", key, err)
+	}
+}
+
+// NormalizeQuery lowercases a search query and collapses its whitespace.
+func NormalizeQuery(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
@@ -0,0 +1,115 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/FriedGlue/BookIt/api/pkg/cache"
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+)
+
+// countingProvider counts the ISBN lookups that reach the fixture it wraps,
+// and fails them while err is set.
+type countingProvider struct {
+	*Fixture
+	lookups int
+	err     error
+}
+
+func (p *countingProvider) LookupByISBN(ctx context.Context, isbn string) (*models.BookData, error) {
+	p.lookups++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return p.Fixture.LookupByISBN(ctx, isbn)
+}
+
+func TestCached(t *testing.T) {
+	ctx := context.Background()
+	provider := &countingProvider{Fixture: NewFixture([]models.BookData{{Title: "Dune", ISBN13: "9780441172719"}})}
+	cached := NewCached(provider, cache.NewLRU(10), CacheTTLs{})
+
+	tests := []struct {
+		name        string
+		isbn        string
+		providerErr error
+		wantTitle   string
+		wantErr     error
+		wantLookups int
+	}{
+		{name: "miss goes to provider", isbn: "9780441172719", wantTitle: "Dune", wantLookups: 1},
+		{name: "hit", isbn: "9780441172719", wantTitle: "Dune", wantLookups: 1},
+		{name: "not found", isbn: "9780000000002", wantErr: ErrNotFound, wantLookups: 2},
+		{name: "not found is cached", isbn: "9780000000002", wantErr: ErrNotFound, wantLookups: 2},
+		{name: "failure", isbn: "9781234567897", providerErr: errors.New("timeout"), wantLookups: 3},
+		{name: "failure is not cached", isbn: "9781234567897", wantErr: ErrNotFound, wantLookups: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider.err = tt.providerErr
+			book, err := cached.LookupByISBN(ctx, tt.isbn)
+			switch {
+			case tt.providerErr != nil:
+				if !errors.Is(err, tt.providerErr) {
+					t.Errorf("err = %v, want %v", err, tt.providerErr)
+				}
+			case !errors.Is(err, tt.wantErr):
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			case book != nil && book.Title != tt.wantTitle:
+				t.Errorf("title = %q, want %q", book.Title, tt.wantTitle)
+			}
+			if provider.lookups != tt.wantLookups {
+				t.Errorf("provider called %d times, want %d", provider.lookups, tt.wantLookups)
+			}
+		})
+	}
+
+	if hits, misses := cached.Stats(); hits != 2 || misses != 4 {
+		t.Errorf("Stats() = %d hits, %d misses; want 2, 4", hits, misses)
+	}
+}
+
+func TestCachedSearchNormalizesQuery(t *testing.T) {
+	ctx := context.Background()
+	cached := NewCached(NewFixture([]models.BookData{{Title: "Dune"}}), cache.NewLRU(10), CacheTTLs{})
+
+	for _, query := range []string{"Dune ", "dune", "  DUNE"} {
+		results, err := cached.SearchByText(ctx, query, 5)
+		if err != nil || len(results) != 1 {
+			t.Fatalf("SearchByText(%q) = %v, %v", query, results, err)
+		}
+	}
+	if hits, misses := cached.Stats(); hits != 2 || misses != 1 {
+		t.Errorf("Stats() = %d hits, %d misses; want 2, 1", hits, misses)
+	}
+}
+
+func TestStats(t *testing.T) {
+	ctx := context.Background()
+	shared := cache.NewTiered(cache.NewLRU(10))
+	first := NewCached(&countingProvider{Fixture: NewFixture(nil)}, shared, CacheTTLs{})
+	second := NewCached(NewFixture(nil), shared, CacheTTLs{})
+	uncached := NewFixture(nil)
+
+	first.LookupByISBN(ctx, "9780441172719")
+	first.LookupByISBN(ctx, "9780441172719")
+	second.LookupAuthor(ctx, "OL1A")
+
+	stats := Stats(Chain{first, uncached, second})
+	if len(stats.Providers) != 2 {
+		t.Fatalf("Stats has %d providers, want the 2 cached ones", len(stats.Providers))
+	}
+	if p := stats.Providers[0]; p.Hits != 1 || p.Misses != 1 {
+		t.Errorf("first provider counted %d hits, %d misses; want 1, 1", p.Hits, p.Misses)
+	}
+	if p := stats.Providers[1]; p.Hits != 0 || p.Misses != 1 {
+		t.Errorf("second provider counted %d hits, %d misses; want 0, 1", p.Hits, p.Misses)
+	}
+	if len(stats.Tiers) != 1 {
+		t.Fatalf("shared cache reported %d times, want once", len(stats.Tiers))
+	}
+	if tier := stats.Tiers[0]; tier.Hits != 1 || tier.Misses != 2 {
+		t.Errorf("tier counted %d hits, %d misses; want 1, 2", tier.Hits, tier.Misses)
+	}
+}
@@ -0,0 +1,169 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/FriedGlue/BookIt/api/pkg/isbn"
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+)
+
+// Fixture answers from a fixed set of books, for working offline. It reads
+// the same JSON array of books as bookit-server's -seed-books flag.
+type Fixture struct {
+	books []models.BookData
+}
+
+// NewFixture returns a provider that knows only books.
+func NewFixture(books []models.BookData) *Fixture {
+	return &Fixture{books: books}
+}
+
+// LoadFixture reads a JSON array of books from path.
+func LoadFixture(path string) (*Fixture, error) {
+	if path == "" {
+		return nil, fmt.Errorf("the fixture metadata provider needs a fixture file")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var books []models.BookData
+	if err := json.Unmarshal(data, &books); err != nil {
+		return nil, fmt.Errorf("reading fixture %s: %w", path, err)
+	}
+	return NewFixture(books), nil
+}
+
+// Name returns "fixture".
+func (f *Fixture) Name() string { return FixtureName }
+
+// SearchByText matches query against titles and author names, ignoring
+// case.
+func (f *Fixture) SearchByText(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	query = strings.ToLower(query)
+	results := []SearchResult{}
+	for _, book := range f.books {
+		if len(results) == limit {
+			break
+		}
+		if !strings.Contains(strings.ToLower(book.Title), query) && !containsFold(book.Authors, query) {
+			continue
+		}
+		results = append(results, SearchResult{
+			WorkID:    book.WorkKey(),
+			Title:     book.Title,
+			Authors:   book.Authors,
+			Thumbnail: book.CoverImageURL,
+			Source:    FixtureName,
+		})
+	}
+	return results, nil
+}
+
+// LookupByISBN matches either ISBN of each book, in either form.
+func (f *Fixture) LookupByISBN(ctx context.Context, number string) (*models.BookData, error) {
+	for _, book := range f.books {
+		if isbn.Equal(book.ISBN13, number) || isbn.Equal(book.ISBN10, number) {
+			return f.copyOf(book), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// LookupByWorkID matches the work ID, the Open Library ID, or the book ID
+// for books without either.
+func (f *Fixture) LookupByWorkID(ctx context.Context, workID string) (*models.BookData, error) {
+	for _, book := range f.books {
+		if workID != "" && book.WorkKey() == workID {
+			return f.copyOf(book), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// LookupEditions returns every book of the work, as LookupByWorkID matches
+// them.
+func (f *Fixture) LookupEditions(ctx context.Context, workID string, limit int) ([]models.BookData, error) {
+	var editions []models.BookData
+	for _, book := range f.books {
+		if len(editions) == limit {
+			break
+		}
+		if workID != "" && book.WorkKey() == workID {
+			editions = append(editions, *f.copyOf(book))
+		}
+	}
+	if len(editions) == 0 {
+		return nil, ErrNotFound
+	}
+	return editions, nil
+}
+
+// LookupAuthor makes an author of the name listed alongside authorID in the
+// first book linked to it, taking AuthorIDs and Authors to be in the same
+// order.
+func (f *Fixture) LookupAuthor(ctx context.Context, authorID string) (*models.Author, error) {
+	for _, book := range f.books {
+		for i, id := range book.AuthorIDs {
+			if id == authorID && i < len(book.Authors) {
+				return &models.Author{AuthorID: authorID, Name: book.Authors[i]}, nil
+			}
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// LookupAuthorWorks returns the works of the books linked to authorID.
+func (f *Fixture) LookupAuthorWorks(ctx context.Context, authorID string, limit int) ([]SearchResult, error) {
+	var works []SearchResult
+	seen := make(map[string]bool)
+	for _, book := range f.books {
+		if len(works) == limit {
+			break
+		}
+		if !slices.Contains(book.AuthorIDs, authorID) || seen[book.WorkKey()] {
+			continue
+		}
+		seen[book.WorkKey()] = true
+		works = append(works, SearchResult{
+			WorkID:    book.WorkKey(),
+			Title:     book.Title,
+			Authors:   book.Authors,
+			Thumbnail: book.CoverImageURL,
+			Source:    FixtureName,
+		})
+	}
+	if len(works) == 0 {
+		return nil, ErrNotFound
+	}
+	return works, nil
+}
+
+// copyOf returns a copy of book that callers may modify and save. Like the
+// other providers it leaves the book ID for the caller to assign.
+func (f *Fixture) copyOf(book models.BookData) *models.BookData {
+	book.BookID = ""
+	book.Authors = append([]string(nil), book.Authors...)
+	book.AuthorIDs = append([]string(nil), book.AuthorIDs...)
+	book.Tags = append([]string{}, book.Tags...)
+	book.Series = append([]models.SeriesMembership(nil), book.Series...)
+	if book.TitleLowercase == "" {
+		book.TitleLowercase = strings.ToLower(book.Title)
+	}
+	return &book
+}
+
+func containsFold(values []string, lowerSubstr string) bool {
+	for _, v := range values {
+		if strings.Contains(strings.ToLower(v), lowerSubstr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/FriedGlue/BookIt/api/pkg/httpclient"
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+)
+
+const googleBooksBaseURL = "https://www.googleapis.com/books/v1"
+
+// GoogleBooks looks books up with the Google Books volumes API.
+type GoogleBooks struct {
+	client  *httpclient.Client
+	baseURL string
+	apiKey  string
+}
+
+// NewGoogleBooks returns a provider that uses client for its requests.
+// apiKey may be empty.
+func NewGoogleBooks(client *httpclient.Client, apiKey string) *GoogleBooks {
+	return &GoogleBooks{client: client, baseURL: googleBooksBaseURL, apiKey: apiKey}
+}
+
+// Name returns "googleBooks".
+func (g *GoogleBooks) Name() string { return GoogleBooksName }
+
+type gbVolumeList struct {
+	TotalItems int        `json:"totalItems"`
+	Items      []gbVolume `json:"items"`
+}
+
+type gbVolume struct {
+	ID         string `json:"id"`
+	VolumeInfo struct {
+		Title               string   `json:"title"`
+		Subtitle            string   `json:"subtitle"`
+		Authors             []string `json:"authors"`
+		Publisher           string   `json:"publisher"`
+		PublishedDate       string   `json:"publishedDate"`
+		Description         string   `json:"description"`
+		PageCount           int      `json:"pageCount"`
+		Categories          []string `json:"categories"`
+		IndustryIdentifiers []struct {
+			Type       string `json:"type"`
+			Identifier string `json:"identifier"`
+		} `json:"industryIdentifiers"`
+		ImageLinks struct {
+			SmallThumbnail string `json:"smallThumbnail"`
+			Thumbnail      string `json:"thumbnail"`
+		} `json:"imageLinks"`
+	} `json:"volumeInfo"`
+}
+
+// SearchByText runs a full-text volume search.
+func (g *GoogleBooks) SearchByText(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	var list gbVolumeList
+	if err := getJSON(ctx, g.client, g.volumesURL(query, limit), &list); err != nil {
+		return nil, err
+	}
+
+	results := []SearchResult{}
+	for _, volume := range list.Items {
+		results = append(results, SearchResult{
+			WorkID:    volume.ID,
+			Title:     volume.VolumeInfo.Title,
+			Authors:   volume.VolumeInfo.Authors,
+			Thumbnail: volume.thumbnail(),
+			Source:    GoogleBooksName,
+		})
+	}
+	return results, nil
+}
+
+// LookupByISBN returns the first volume with the given ISBN.
+func (g *GoogleBooks) LookupByISBN(ctx context.Context, isbn string) (*models.BookData, error) {
+	var list gbVolumeList
+	if err := getJSON(ctx, g.client, g.volumesURL("isbn:"+isbn, 1), &list); err != nil {
+		return nil, err
+	}
+	if len(list.Items) == 0 {
+		return nil, ErrNotFound
+	}
+	return list.Items[0].toBookData(), nil
+}
+
+// LookupByWorkID fetches a volume by its Google Books ID.
+func (g *GoogleBooks) LookupByWorkID(ctx context.Context, workID string) (*models.BookData, error) {
+	if IsOpenLibraryID(workID) {
+		return nil, ErrNotFound
+	}
+
+	u := fmt.Sprintf("%s/volumes/%s", g.baseURL, url.PathEscape(workID))
+	if g.apiKey != "" {
+		u += "?key=" + url.QueryEscape(g.apiKey)
+	}
+
+	var volume gbVolume
+	if err := getJSON(ctx, g.client, u, &volume); err != nil {
+		return nil, err
+	}
+	if volume.ID == "" {
+		return nil, ErrNotFound
+	}
+	return volume.toBookData(), nil
+}
+
+// LookupEditions returns ErrNotFound: Google Books volumes are editions, and
+// it has no work grouping them.
+func (g *GoogleBooks) LookupEditions(ctx context.Context, workID string, limit int) ([]models.BookData, error) {
+	return nil, ErrNotFound
+}
+
+// LookupAuthor returns ErrNotFound: Google Books only has author names.
+func (g *GoogleBooks) LookupAuthor(ctx context.Context, authorID string) (*models.Author, error) {
+	return nil, ErrNotFound
+}
+
+// LookupAuthorWorks returns ErrNotFound, as LookupAuthor does.
+func (g *GoogleBooks) LookupAuthorWorks(ctx context.Context, authorID string, limit int) ([]SearchResult, error) {
+	return nil, ErrNotFound
+}
+
+func (g *GoogleBooks) volumesURL(query string, limit int) string {
+	u := fmt.Sprintf("%s/volumes?q=%s&maxResults=%d", g.baseURL, url.QueryEscape(query), limit)
+	if g.apiKey != "" {
+		u += "&key=" + url.QueryEscape(g.apiKey)
+	}
+	return u
+}
+
+// thumbnail returns the volume's cover, upgraded to https.
+func (v gbVolume) thumbnail() string {
+	link := v.VolumeInfo.ImageLinks.Thumbnail
+	if link == "" {
+		link = v.VolumeInfo.ImageLinks.SmallThumbnail
+	}
+	return strings.Replace(link, "http://", "https://", 1)
+}
+
+// toBookData converts a volume, tagging it the same way Open Library
+// records are tagged.
+func (v gbVolume) toBookData() *models.BookData {
+	info := v.VolumeInfo
+	title := info.Title
+	if info.Subtitle != "" {
+		title += ": " + info.Subtitle
+	}
+
+	book := &models.BookData{
+		Title:          title,
+		TitleLowercase: strings.ToLower(title),
+		Authors:        info.Authors,
+		PageCount:      info.PageCount,
+		CoverImageURL:  v.thumbnail(),
+		Description:    info.Description,
+		Tags:           []string{},
+	}
+	for _, id := range info.IndustryIdentifiers {
+		switch id.Type {
+		case "ISBN_13":
+			book.ISBN13 = id.Identifier
+		case "ISBN_10":
+			book.ISBN10 = id.Identifier
+		}
+	}
+
+	if info.PublishedDate != "" {
+		book.Tags = append(book.Tags, "Published:"+info.PublishedDate)
+	}
+	book.Tags = append(book.Tags, info.Categories...)
+	if info.Publisher != "" {
+		book.Tags = append(book.Tags, "Publisher:"+info.Publisher)
+	}
+	book.Tags = append(book.Tags, "Google:"+v.ID)
+	return book
+}
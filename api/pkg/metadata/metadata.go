@@ -0,0 +1,338 @@
+// Package metadata looks up book metadata in external catalogues such as
+// Open Library and Google Books. Handlers depend on the MetadataProvider
+// interface, so the catalogues can be reordered, combined or replaced by a
+// local fixture file without touching them.
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/FriedGlue/BookIt/api/pkg/cache"
+	"github.com/FriedGlue/BookIt/api/pkg/httpclient"
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+)
+
+// ErrNotFound is returned when a provider has no record of the requested
+// book. A Chain moves on to the next provider when it sees it.
+var ErrNotFound = errors.New("book not found in catalogue")
+
+// Provider names, as used in SearchResult.Source and Config.Providers.
+const (
+	OpenLibraryName = "openLibrary"
+	GoogleBooksName = "googleBooks"
+	FixtureName     = "fixture"
+)
+
+var (
+	openLibraryIDPattern       = regexp.MustCompile(`^OL[0-9]+[WM]$`)
+	openLibraryAuthorIDPattern = regexp.MustCompile(`^OL[0-9]+A$`)
+
+	// seriesPositionPattern splits a series statement such as "Discworld ;
+	// 5", "Harry Potter #2", "The Expanse (2.5)" or "Dune, book 1" into the
+	// name and the position.
+	seriesPositionPattern = regexp.MustCompile(`(?i)^(.*?)(?:\s*[,;:(#]\s*|\s+)(?:(?:book|bk\.?|vol\.?|volume|no\.?|number)\s*)?#?([0-9]+(?:\.[0-9]+)?)\)?$`)
+)
+
+// IsOpenLibraryID reports whether id looks like an Open Library work or
+// edition ID such as "OL12345W".
+func IsOpenLibraryID(id string) bool {
+	return openLibraryIDPattern.MatchString(id)
+}
+
+// IsOpenLibraryAuthorID reports whether id looks like an Open Library author
+// ID such as "OL26320A".
+func IsOpenLibraryAuthorID(id string) bool {
+	return openLibraryAuthorIDPattern.MatchString(id)
+}
+
+// ParseSeries reads a catalogue's series statement into a membership. A
+// statement without a position is taken whole as the name.
+func ParseSeries(statement string) models.SeriesMembership {
+	statement = strings.TrimSpace(statement)
+	match := seriesPositionPattern.FindStringSubmatch(statement)
+	if match == nil || strings.TrimSpace(match[1]) == "" {
+		return models.SeriesMembership{Name: statement}
+	}
+	position, err := strconv.ParseFloat(match[2], 64)
+	if err != nil {
+		return models.SeriesMembership{Name: statement}
+	}
+	return models.SeriesMembership{Name: strings.TrimSpace(match[1]), Position: position}
+}
+
+// MetadataProvider looks up books in one catalogue. Calls give up when ctx
+// is done.
+type MetadataProvider interface {
+	// Name identifies the provider, e.g. "openLibrary".
+	Name() string
+
+	// SearchByText returns up to limit books matching a free-text query.
+	SearchByText(ctx context.Context, query string, limit int) ([]SearchResult, error)
+
+	// LookupByISBN returns the book with the given ISBN-10 or ISBN-13, or
+	// ErrNotFound.
+	LookupByISBN(ctx context.Context, isbn string) (*models.BookData, error)
+
+	// LookupByWorkID returns the book with the provider's own ID for it
+	// (an Open Library work ID or a Google Books volume ID), or ErrNotFound.
+	LookupByWorkID(ctx context.Context, workID string) (*models.BookData, error)
+
+	// LookupEditions returns up to limit editions of a work, identified by
+	// the provider's own ID for it, or ErrNotFound if the provider does not
+	// know the work or has no notion of editions.
+	LookupEditions(ctx context.Context, workID string, limit int) ([]models.BookData, error)
+
+	// LookupAuthor returns the author with the given Open Library author
+	// ID, or ErrNotFound.
+	LookupAuthor(ctx context.Context, authorID string) (*models.Author, error)
+
+	// LookupAuthorWorks returns up to limit works by the author with the
+	// given Open Library author ID, or ErrNotFound.
+	LookupAuthorWorks(ctx context.Context, authorID string, limit int) ([]SearchResult, error)
+}
+
+// SearchResult is one book found by SearchByText.
+type SearchResult struct {
+	WorkID    string
+	Title     string
+	Authors   []string
+	Thumbnail string
+	Source    string
+}
+
+// Chain asks each of its providers in turn and returns the first answer.
+// A provider that returns nothing, or fails, is skipped in favour of the
+// next one.
+type Chain []MetadataProvider
+
+// Name lists the providers in priority order.
+func (c Chain) Name() string {
+	names := make([]string, len(c))
+	for i, p := range c {
+		names[i] = p.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+// SearchByText returns the results of the first provider that finds any.
+// It only fails if every provider failed.
+func (c Chain) SearchByText(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	var lastErr error
+	answered := false
+	for _, p := range c {
+		results, err := p.SearchByText(ctx, query, limit)
+		if err != nil {
+			log.Printf("Metadata search with %s failed: %v", p.Name(), err)
+			lastErr = err
+			continue
+		}
+		answered = true
+		if len(results) > 0 {
+			return results, nil
+		}
+	}
+	if !answered && lastErr != nil {
+		return nil, lastErr
+	}
+	return []SearchResult{}, nil
+}
+
+// LookupByISBN returns the first provider's record of isbn.
+func (c Chain) LookupByISBN(ctx context.Context, isbn string) (*models.BookData, error) {
+	return c.lookup("ISBN "+isbn, func(p MetadataProvider) (*models.BookData, error) {
+		return p.LookupByISBN(ctx, isbn)
+	})
+}
+
+// LookupByWorkID returns the first provider's record of workID.
+func (c Chain) LookupByWorkID(ctx context.Context, workID string) (*models.BookData, error) {
+	return c.lookup("work "+workID, func(p MetadataProvider) (*models.BookData, error) {
+		return p.LookupByWorkID(ctx, workID)
+	})
+}
+
+// LookupEditions returns the editions listed by the first provider that
+// knows the work.
+func (c Chain) LookupEditions(ctx context.Context, workID string, limit int) ([]models.BookData, error) {
+	var lastErr error
+	for _, p := range c {
+		editions, err := p.LookupEditions(ctx, workID, limit)
+		if err == nil && len(editions) > 0 {
+			return editions, nil
+		}
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			log.Printf("Metadata lookup of editions of %s with %s failed: %v", workID, p.Name(), err)
+			lastErr = err
+		}
+	}
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return nil, ErrNotFound
+}
+
+// LookupAuthor returns the first provider's record of the author.
+func (c Chain) LookupAuthor(ctx context.Context, authorID string) (*models.Author, error) {
+	var lastErr error
+	for _, p := range c {
+		author, err := p.LookupAuthor(ctx, authorID)
+		if err == nil {
+			return author, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			log.Printf("Metadata lookup of author %s with %s failed: %v", authorID, p.Name(), err)
+			lastErr = err
+		}
+	}
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return nil, ErrNotFound
+}
+
+// LookupAuthorWorks returns the works listed by the first provider that
+// knows the author.
+func (c Chain) LookupAuthorWorks(ctx context.Context, authorID string, limit int) ([]SearchResult, error) {
+	var lastErr error
+	for _, p := range c {
+		works, err := p.LookupAuthorWorks(ctx, authorID, limit)
+		if err == nil && len(works) > 0 {
+			return works, nil
+		}
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			log.Printf("Metadata lookup of works by %s with %s failed: %v", authorID, p.Name(), err)
+			lastErr = err
+		}
+	}
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return nil, ErrNotFound
+}
+
+// lookup calls find on each provider until one returns a book. If none did
+// and any of them failed, the last failure is returned so callers can tell
+// "not found" from "could not ask".
+func (c Chain) lookup(what string, find func(MetadataProvider) (*models.BookData, error)) (*models.BookData, error) {
+	var lastErr error
+	for _, p := range c {
+		book, err := find(p)
+		if err == nil {
+			return book, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			log.Printf("Metadata lookup of %s with %s failed: %v", what, p.Name(), err)
+			lastErr = err
+		}
+	}
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return nil, ErrNotFound
+}
+
+// Config chooses the providers and their order.
+type Config struct {
+	// Providers are provider names in priority order. Empty means Open
+	// Library only.
+	Providers []string
+
+	// GoogleBooksAPIKey is optional; without it Google Books applies its
+	// anonymous quota.
+	GoogleBooksAPIKey string
+
+	// FixturePath is the JSON file read by the fixture provider.
+	FixturePath string
+
+	// HTTP tunes the timeouts, retries and circuit breaker of the client
+	// each provider makes its requests with.
+	HTTP httpclient.Options
+
+	// Cache, if set, holds the answers of the Open Library and Google Books
+	// providers for CacheTTLs.
+	Cache     cache.Cache
+	CacheTTLs CacheTTLs
+}
+
+// ConfigFromEnv reads METADATA_PROVIDERS (comma-separated names),
+// GOOGLE_BOOKS_API_KEY and METADATA_FIXTURE_PATH.
+func ConfigFromEnv() Config {
+	var providers []string
+	for _, name := range strings.Split(os.Getenv("METADATA_PROVIDERS"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			providers = append(providers, name)
+		}
+	}
+	return Config{
+		Providers:         providers,
+		GoogleBooksAPIKey: os.Getenv("GOOGLE_BOOKS_API_KEY"),
+		FixturePath:       os.Getenv("METADATA_FIXTURE_PATH"),
+	}
+}
+
+// New builds the providers named in cfg. A single provider is returned as
+// is; several are wrapped in a Chain.
+func New(cfg Config) (MetadataProvider, error) {
+	names := cfg.Providers
+	if len(names) == 0 {
+		names = []string{OpenLibraryName}
+	}
+
+	cached := func(p MetadataProvider) MetadataProvider {
+		if cfg.Cache == nil {
+			return p
+		}
+		return NewCached(p, cfg.Cache, cfg.CacheTTLs)
+	}
+
+	var chain Chain
+	for _, name := range names {
+		switch strings.ToLower(name) {
+		case strings.ToLower(OpenLibraryName):
+			chain = append(chain, cached(NewOpenLibrary(httpclient.New(OpenLibraryName, cfg.HTTP))))
+		case strings.ToLower(GoogleBooksName):
+			chain = append(chain, cached(NewGoogleBooks(httpclient.New(GoogleBooksName, cfg.HTTP), cfg.GoogleBooksAPIKey)))
+		case FixtureName:
+			fixture, err := LoadFixture(cfg.FixturePath)
+			if err != nil {
+				return nil, err
+			}
+			chain = append(chain, fixture)
+		default:
+			return nil, fmt.Errorf("unknown metadata provider %q", name)
+		}
+	}
+	if len(chain) == 1 {
+		return chain[0], nil
+	}
+	return chain, nil
+}
+
+// getJSON fetches url and decodes the JSON response into v. A 404 is
+// reported as ErrNotFound.
+func getJSON(ctx context.Context, client *httpclient.Client, url string, v interface{}) error {
+	resp, err := client.Get(ctx, url)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+	}
+	if err := json.Unmarshal(resp.Body, v); err != nil {
+		return fmt.Errorf("decoding %s: %w", url, err)
+	}
+	return nil
+}
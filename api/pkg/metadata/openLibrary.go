@@ -0,0 +1,469 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/FriedGlue/BookIt/api/pkg/httpclient"
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+)
+
+const openLibraryBaseURL = "https://openlibrary.org"
+
+// OpenLibrary looks books up at openlibrary.org.
+type OpenLibrary struct {
+	client  *httpclient.Client
+	baseURL string
+}
+
+// NewOpenLibrary returns a provider that uses client for its requests.
+func NewOpenLibrary(client *httpclient.Client) *OpenLibrary {
+	return &OpenLibrary{client: client, baseURL: openLibraryBaseURL}
+}
+
+// Name returns "openLibrary".
+func (o *OpenLibrary) Name() string { return OpenLibraryName }
+
+// olSearchResponse shapes the search.json response.
+type olSearchResponse struct {
+	NumFound int `json:"num_found"`
+	Docs     []struct {
+		Key        string   `json:"key"`
+		Title      string   `json:"title"`
+		AuthorName []string `json:"author_name,omitempty"`
+		CoverI     int      `json:"cover_i,omitempty"`
+	} `json:"docs"`
+}
+
+// SearchByText searches Open Library's catalogue of works.
+func (o *OpenLibrary) SearchByText(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	var response olSearchResponse
+	u := fmt.Sprintf("%s/search.json?q=%s&limit=%d", o.baseURL, url.QueryEscape(query), limit)
+	if err := getJSON(ctx, o.client, u, &response); err != nil {
+		return nil, err
+	}
+
+	results := []SearchResult{}
+	for _, doc := range response.Docs {
+		// Format: /works/OL12345W -> OL12345W
+		workID := strings.TrimPrefix(doc.Key, "/works/")
+		if workID == "" || workID == doc.Key {
+			continue
+		}
+
+		thumbnail := ""
+		if doc.CoverI > 0 {
+			thumbnail = fmt.Sprintf("https://covers.openlibrary.org/b/id/%d-M.jpg", doc.CoverI)
+		}
+		results = append(results, SearchResult{
+			WorkID:    workID,
+			Title:     doc.Title,
+			Authors:   doc.AuthorName,
+			Thumbnail: thumbnail,
+			Source:    OpenLibraryName,
+		})
+	}
+	return results, nil
+}
+
+// olBook shapes the "jscmd=data" records returned by both the books API and
+// the works endpoint.
+type olBook struct {
+	Title         string        `json:"title"`
+	Description   olText        `json:"description"`
+	NumberOfPages int           `json:"number_of_pages"`
+	PublishDate   string        `json:"publish_date"`
+	Authors       []olAuthorRef `json:"authors"`
+	Subjects      []olSubject   `json:"subjects"`
+	Publishers    []olNamed     `json:"publishers"`
+	Cover         olCover       `json:"cover"`
+	Identifiers   struct {
+		ISBN13       []string `json:"isbn_13"`
+		ISBN10       []string `json:"isbn_10"`
+		Google       []string `json:"google"`
+		LCCN         []string `json:"lccn"`
+		OCLC         []string `json:"oclc"`
+		Goodreads    []string `json:"goodreads"`
+		LibraryThing []string `json:"librarything"`
+	} `json:"identifiers"`
+}
+
+type olNamed struct {
+	Name string `json:"name"`
+}
+
+// olAuthorRef is an author of a record. Data records give the name and a URL
+// such as "https://openlibrary.org/authors/OL26320A/J._R._R._Tolkien"; work
+// records only give {"author": {"key": "/authors/OL26320A"}}.
+type olAuthorRef struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Author struct {
+		Key string `json:"key"`
+	} `json:"author"`
+}
+
+// id returns the Open Library author ID, or "" if the reference has none.
+func (a olAuthorRef) id() string {
+	ref := a.Author.Key
+	if ref == "" {
+		ref = a.URL
+	}
+	_, rest, found := strings.Cut(ref, "/authors/")
+	if !found {
+		return ""
+	}
+	id, _, _ := strings.Cut(rest, "/")
+	if !IsOpenLibraryAuthorID(id) {
+		return ""
+	}
+	return id
+}
+
+type olCover struct {
+	Small  string `json:"small"`
+	Medium string `json:"medium"`
+	Large  string `json:"large"`
+}
+
+// olText is a description, which Open Library returns either as a plain
+// string or as {"type": "/type/text", "value": "..."}.
+type olText string
+
+func (t *olText) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*t = olText(s)
+		return nil
+	}
+	var typed struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(data, &typed); err != nil {
+		return err
+	}
+	*t = olText(typed.Value)
+	return nil
+}
+
+// olSubject is a subject, returned either as a plain string or as
+// {"name": "...", "url": "..."}.
+type olSubject string
+
+func (s *olSubject) UnmarshalJSON(data []byte) error {
+	var named olNamed
+	if err := json.Unmarshal(data, &named); err == nil {
+		*s = olSubject(named.Name)
+		return nil
+	}
+	var plain string
+	if err := json.Unmarshal(data, &plain); err != nil {
+		return err
+	}
+	*s = olSubject(plain)
+	return nil
+}
+
+// LookupByISBN uses the books API, keyed by "ISBN:<isbn>".
+func (o *OpenLibrary) LookupByISBN(ctx context.Context, isbn string) (*models.BookData, error) {
+	var response map[string]olBook
+	u := fmt.Sprintf("%s/api/books?bibkeys=ISBN:%s&format=json&jscmd=data", o.baseURL, url.QueryEscape(isbn))
+	if err := getJSON(ctx, o.client, u, &response); err != nil {
+		return nil, err
+	}
+
+	record, ok := response["ISBN:"+isbn]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	book := record.toBookData()
+	if book.ISBN13 == "" && book.ISBN10 == "" {
+		if len(isbn) == 10 {
+			book.ISBN10 = isbn
+		} else {
+			book.ISBN13 = isbn
+		}
+	}
+	return book, nil
+}
+
+// LookupByWorkID fetches an Open Library work such as "OL12345W".
+func (o *OpenLibrary) LookupByWorkID(ctx context.Context, workID string) (*models.BookData, error) {
+	if !IsOpenLibraryID(workID) {
+		return nil, ErrNotFound
+	}
+
+	// The endpoint answers with the work itself, or with a map keyed by the
+	// ID when it treats the request as a data query.
+	var raw json.RawMessage
+	u := fmt.Sprintf("%s/works/%s.json?jscmd=data", o.baseURL, url.PathEscape(workID))
+	if err := getJSON(ctx, o.client, u, &raw); err != nil {
+		return nil, err
+	}
+
+	var record olBook
+	var keyed map[string]olBook
+	if err := json.Unmarshal(raw, &keyed); err == nil && len(keyed) == 1 {
+		for _, r := range keyed {
+			record = r
+		}
+	} else if err := json.Unmarshal(raw, &record); err != nil {
+		return nil, fmt.Errorf("decoding work %s: %w", workID, err)
+	}
+	if record.Title == "" {
+		return nil, ErrNotFound
+	}
+
+	book := record.toBookData()
+	book.OpenLibraryId = workID
+	book.Tags = append(book.Tags, "OpenLibrary:"+workID)
+	return book, nil
+}
+
+// olEditions shapes the works/<id>/editions.json response.
+type olEditions struct {
+	Entries []olEdition `json:"entries"`
+}
+
+type olEdition struct {
+	Key            string   `json:"key"` // "/books/OL123M"
+	Title          string   `json:"title"`
+	Subtitle       string   `json:"subtitle"`
+	NumberOfPages  int      `json:"number_of_pages"`
+	PublishDate    string   `json:"publish_date"`
+	Publishers     []string `json:"publishers"`
+	PhysicalFormat string   `json:"physical_format"`
+	Covers         []int    `json:"covers"`
+	ISBN13         []string `json:"isbn_13"`
+	ISBN10         []string `json:"isbn_10"`
+	Series         []string `json:"series"` // e.g. "Discworld ; 5"
+}
+
+// LookupEditions lists the editions of an Open Library work. Editions carry
+// no authors or description; those belong to the work. Series are only
+// recorded on editions, so this is where they come from.
+func (o *OpenLibrary) LookupEditions(ctx context.Context, workID string, limit int) ([]models.BookData, error) {
+	if !IsOpenLibraryID(workID) {
+		return nil, ErrNotFound
+	}
+
+	var response olEditions
+	u := fmt.Sprintf("%s/works/%s/editions.json?limit=%d", o.baseURL, url.PathEscape(workID), limit)
+	if err := getJSON(ctx, o.client, u, &response); err != nil {
+		return nil, err
+	}
+
+	editions := []models.BookData{}
+	for _, entry := range response.Entries {
+		if entry.Title == "" {
+			continue
+		}
+		editions = append(editions, entry.toBookData(workID))
+	}
+	if len(editions) == 0 {
+		return nil, ErrNotFound
+	}
+	return editions, nil
+}
+
+func (e olEdition) toBookData(workID string) models.BookData {
+	book := models.BookData{
+		Title:          e.Title,
+		TitleLowercase: strings.ToLower(e.Title),
+		PageCount:      e.NumberOfPages,
+		PublishDate:    e.PublishDate,
+		Format:         e.PhysicalFormat,
+		OpenLibraryId:  workID,
+		Tags:           []string{"OpenLibrary:" + workID},
+	}
+	if e.Subtitle != "" {
+		book.Title += ": " + e.Subtitle
+		book.TitleLowercase = strings.ToLower(book.Title)
+	}
+	if len(e.Publishers) > 0 {
+		book.Publisher = e.Publishers[0]
+	}
+	if len(e.Covers) > 0 && e.Covers[0] > 0 {
+		book.CoverImageURL = fmt.Sprintf("https://covers.openlibrary.org/b/id/%d-L.jpg", e.Covers[0])
+	}
+	if len(e.ISBN13) > 0 {
+		book.ISBN13 = e.ISBN13[0]
+	}
+	if len(e.ISBN10) > 0 {
+		book.ISBN10 = e.ISBN10[0]
+	}
+	for _, statement := range e.Series {
+		if series := ParseSeries(statement); series.Name != "" {
+			book.Series = append(book.Series, series)
+		}
+	}
+	if edition := strings.TrimPrefix(e.Key, "/books/"); edition != e.Key {
+		book.Tags = append(book.Tags, "OpenLibraryEdition:"+edition)
+	}
+	return book
+}
+
+// olAuthor shapes the authors/<id>.json response. Authors merged into
+// another are returned as a redirect to it.
+type olAuthor struct {
+	Name           string   `json:"name"`
+	PersonalName   string   `json:"personal_name"`
+	AlternateNames []string `json:"alternate_names"`
+	Bio            olText   `json:"bio"`
+	Photos         []int    `json:"photos"`
+	BirthDate      string   `json:"birth_date"`
+	DeathDate      string   `json:"death_date"`
+	Location       string   `json:"location"` // "/authors/OL123A" for redirects
+}
+
+// LookupAuthor fetches an Open Library author such as "OL26320A", following
+// one redirect to the author it was merged into.
+func (o *OpenLibrary) LookupAuthor(ctx context.Context, authorID string) (*models.Author, error) {
+	if !IsOpenLibraryAuthorID(authorID) {
+		return nil, ErrNotFound
+	}
+
+	var record olAuthor
+	u := fmt.Sprintf("%s/authors/%s.json", o.baseURL, url.PathEscape(authorID))
+	if err := getJSON(ctx, o.client, u, &record); err != nil {
+		return nil, err
+	}
+	if target := strings.TrimPrefix(record.Location, "/authors/"); record.Name == "" && IsOpenLibraryAuthorID(target) {
+		u = fmt.Sprintf("%s/authors/%s.json", o.baseURL, url.PathEscape(target))
+		if err := getJSON(ctx, o.client, u, &record); err != nil {
+			return nil, err
+		}
+	}
+	if record.Name == "" {
+		return nil, ErrNotFound
+	}
+
+	author := &models.Author{
+		AuthorID:  authorID,
+		Name:      record.Name,
+		Bio:       string(record.Bio),
+		BirthDate: record.BirthDate,
+		DeathDate: record.DeathDate,
+	}
+	for _, name := range append([]string{record.PersonalName}, record.AlternateNames...) {
+		if name != "" && name != record.Name && !slices.Contains(author.AlternateNames, name) {
+			author.AlternateNames = append(author.AlternateNames, name)
+		}
+	}
+	if len(record.Photos) > 0 && record.Photos[0] > 0 {
+		author.PhotoURL = fmt.Sprintf("https://covers.openlibrary.org/a/id/%d-L.jpg", record.Photos[0])
+	}
+	return author, nil
+}
+
+// olAuthorWorks shapes the authors/<id>/works.json response.
+type olAuthorWorks struct {
+	Entries []struct {
+		Key    string `json:"key"` // "/works/OL123W"
+		Title  string `json:"title"`
+		Covers []int  `json:"covers"`
+	} `json:"entries"`
+}
+
+// LookupAuthorWorks lists the works of an Open Library author.
+func (o *OpenLibrary) LookupAuthorWorks(ctx context.Context, authorID string, limit int) ([]SearchResult, error) {
+	if !IsOpenLibraryAuthorID(authorID) {
+		return nil, ErrNotFound
+	}
+
+	var response olAuthorWorks
+	u := fmt.Sprintf("%s/authors/%s/works.json?limit=%d", o.baseURL, url.PathEscape(authorID), limit)
+	if err := getJSON(ctx, o.client, u, &response); err != nil {
+		return nil, err
+	}
+
+	works := []SearchResult{}
+	for _, entry := range response.Entries {
+		workID := strings.TrimPrefix(entry.Key, "/works/")
+		if entry.Title == "" || workID == entry.Key {
+			continue
+		}
+		thumbnail := ""
+		if len(entry.Covers) > 0 && entry.Covers[0] > 0 {
+			thumbnail = fmt.Sprintf("https://covers.openlibrary.org/b/id/%d-M.jpg", entry.Covers[0])
+		}
+		works = append(works, SearchResult{
+			WorkID:    workID,
+			Title:     entry.Title,
+			Thumbnail: thumbnail,
+			Source:    OpenLibraryName,
+		})
+	}
+	if len(works) == 0 {
+		return nil, ErrNotFound
+	}
+	return works, nil
+}
+
+// toBookData converts an Open Library record. Subjects, the publish date,
+// the publisher and identifiers from other catalogues become tags.
+func (r olBook) toBookData() *models.BookData {
+	book := &models.BookData{
+		Title:          r.Title,
+		TitleLowercase: strings.ToLower(r.Title),
+		PageCount:      r.NumberOfPages,
+		Description:    string(r.Description),
+		Tags:           []string{},
+	}
+
+	for _, author := range r.Authors {
+		if author.Name != "" {
+			book.Authors = append(book.Authors, author.Name)
+		}
+		if id := author.id(); id != "" && !slices.Contains(book.AuthorIDs, id) {
+			book.AuthorIDs = append(book.AuthorIDs, id)
+		}
+	}
+
+	// Pick the largest cover image
+	book.CoverImageURL = r.Cover.Large
+	if book.CoverImageURL == "" {
+		book.CoverImageURL = r.Cover.Medium
+	}
+	if book.CoverImageURL == "" {
+		book.CoverImageURL = r.Cover.Small
+	}
+
+	if len(r.Identifiers.ISBN13) > 0 {
+		book.ISBN13 = r.Identifiers.ISBN13[0]
+	}
+	if len(r.Identifiers.ISBN10) > 0 {
+		book.ISBN10 = r.Identifiers.ISBN10[0]
+	}
+
+	if r.PublishDate != "" {
+		book.PublishDate = r.PublishDate
+		book.Tags = append(book.Tags, "Published:"+r.PublishDate)
+	}
+	for _, subject := range r.Subjects {
+		if subject != "" {
+			book.Tags = append(book.Tags, string(subject))
+		}
+	}
+	if len(r.Publishers) > 0 && r.Publishers[0].Name != "" {
+		book.Publisher = r.Publishers[0].Name
+		book.Tags = append(book.Tags, "Publisher:"+r.Publishers[0].Name)
+	}
+
+	addIdentifierAsTag := func(idType string, values []string) {
+		if len(values) > 0 {
+			book.Tags = append(book.Tags, fmt.Sprintf("%s:%s", idType, values[0]))
+		}
+	}
+	addIdentifierAsTag("Google", r.Identifiers.Google)
+	addIdentifierAsTag("LCCN", r.Identifiers.LCCN)
+	addIdentifierAsTag("OCLC", r.Identifiers.OCLC)
+	addIdentifierAsTag("Goodreads", r.Identifiers.Goodreads)
+	addIdentifierAsTag("LibraryThing", r.Identifiers.LibraryThing)
+
+	return book
+}
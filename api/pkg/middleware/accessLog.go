@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/FriedGlue/BookIt/api/pkg/shared"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// accessLogEntry is one line of the access log.
+type accessLogEntry struct {
+	RequestID  string  `json:"requestId"`
+	Method     string  `json:"method"`
+	Path       string  `json:"path"`
+	Status     int     `json:"status"`
+	DurationMs float64 `json:"durationMs"`
+	UserID     string  `json:"userId,omitempty"`
+	SourceIP   string  `json:"sourceIp,omitempty"`
+}
+
+// AccessLog logs one JSON line per request with its outcome and duration.
+func AccessLog() Middleware {
+	return func(next Handler) Handler {
+		return func(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+			start := time.Now()
+			response := next(request)
+
+			line, _ := json.Marshal(accessLogEntry{
+				RequestID:  request.RequestContext.RequestID,
+				Method:     request.HTTPMethod,
+				Path:       request.Path,
+				Status:     response.StatusCode,
+				DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+				UserID:     shared.UserID(request),
+				SourceIP:   request.RequestContext.Identity.SourceIP,
+			})
+			log.Println(string(line))
+			return response
+		}
+	}
+}
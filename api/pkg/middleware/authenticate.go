@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/FriedGlue/BookIt/api/pkg/shared"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Authenticate rejects requests without a Cognito "sub" claim with a 401 and
+// stores the user ID in the request context for shared.UserID. CORS preflight
+// requests carry no credentials and are let through. It adds to the context
+// passed on by Context.
+func Authenticate() Middleware {
+	return func(next Handler) Handler {
+		return func(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+			if request.HTTPMethod == http.MethodOptions {
+				return next(request)
+			}
+
+			userID, err := shared.GetUserIDFromToken(request)
+			if err != nil {
+				log.Printf("Error extracting userId: %v\n", err)
+				return shared.Error(shared.CodeUnauthorized, err.Error())
+			}
+
+			return next(shared.WithContext(request, shared.WithUserID(shared.RequestContext(request), userID)))
+		}
+	}
+}
+
+// RequireGroup rejects requests from users outside the Cognito group with a
+// 403. It wraps single routes, inside Authenticate.
+func RequireGroup(group string) Middleware {
+	return func(next Handler) Handler {
+		return func(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+			if request.HTTPMethod != http.MethodOptions && !shared.InGroup(request, group) {
+				log.Printf("User %s is not in group %s\n", shared.UserID(request), group)
+				return shared.Error(shared.CodeForbidden, "This endpoint is restricted to the "+group+" group")
+			}
+			return next(request)
+		}
+	}
+}
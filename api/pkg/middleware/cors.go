@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// CORS adds cross-origin headers to every response. The request's Origin is
+// echoed back when it is one of allowedOrigins; "*" allows any origin.
+// Requests from other origins get no Access-Control-Allow-Origin header, so
+// browsers refuse to expose the response.
+func CORS(allowedOrigins []string) Middleware {
+	allowAny := false
+	allowed := map[string]bool{}
+	for _, origin := range allowedOrigins {
+		origin = strings.TrimSpace(origin)
+		if origin == "*" {
+			allowAny = true
+		}
+		allowed[origin] = true
+	}
+
+	return func(next Handler) Handler {
+		return func(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+			response := next(request)
+
+			// Handlers must not set their own CORS headers.
+			for name := range response.Headers {
+				if strings.HasPrefix(strings.ToLower(name), "access-control-") {
+					delete(response.Headers, name)
+				}
+			}
+
+			origin := header(request, "Origin")
+			setHeader(&response, "Vary", "Origin")
+			if origin == "" || !(allowAny || allowed[origin]) {
+				return response
+			}
+
+			setHeader(&response, "Access-Control-Allow-Origin", origin)
+			setHeader(&response, "Access-Control-Allow-Credentials", "true")
+			setHeader(&response, "Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			setHeader(&response, "Access-Control-Allow-Headers", "Content-Type, Authorization, X-Amz-Date, X-Api-Key, X-Amz-Security-Token, X-Request-Id")
+			setHeader(&response, "Access-Control-Expose-Headers", RequestIDHeader)
+			if allow := response.Headers["Allow"]; allow != "" && request.HTTPMethod == "OPTIONS" {
+				setHeader(&response, "Access-Control-Allow-Methods", allow)
+			}
+			return response
+		}
+	}
+}
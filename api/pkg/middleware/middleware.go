@@ -0,0 +1,60 @@
+// Package middleware wraps API handlers with cross-cutting behaviour:
+// request IDs, access logging, CORS, panic recovery and authentication.
+//
+// A typical chain, outermost first, is
+//
+//	middleware.Chain(router.Route,
+//		middleware.RequestID(),
+//		middleware.Context(ctx),
+//		middleware.AccessLog(),
+//		middleware.CORS(origins),
+//		middleware.Recover(),
+//		middleware.Authenticate(),
+//	)
+//
+// Context must run before anything that reads or stores request-scoped
+// values (AccessLog, Authenticate).
+package middleware
+
+import (
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Handler is the signature of every API handler.
+type Handler func(events.APIGatewayProxyRequest) events.APIGatewayProxyResponse
+
+// Middleware wraps a Handler with extra behaviour.
+type Middleware func(Handler) Handler
+
+// Chain wraps h with middlewares. The first middleware is the outermost: it
+// sees the request first and the response last.
+func Chain(h Handler, middlewares ...Middleware) Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
+
+// setHeader sets a response header, allocating the header map if needed.
+func setHeader(response *events.APIGatewayProxyResponse, name, value string) {
+	if response.Headers == nil {
+		response.Headers = map[string]string{}
+	}
+	response.Headers[name] = value
+}
+
+// header returns a request header, matching the name case-insensitively as
+// API Gateway does not normalise header names.
+func header(request events.APIGatewayProxyRequest, name string) string {
+	if value, ok := request.Headers[name]; ok {
+		return value
+	}
+	for k, v := range request.Headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func ok(events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	return events.APIGatewayProxyResponse{StatusCode: 200}
+}
+
+func TestChainOrder(t *testing.T) {
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+				calls = append(calls, name+" in")
+				response := next(request)
+				calls = append(calls, name+" out")
+				return response
+			}
+		}
+	}
+	handler := func(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+		calls = append(calls, "handler")
+		return ok(request)
+	}
+
+	Chain(handler, trace("outer"), trace("inner"))(events.APIGatewayProxyRequest{})
+
+	want := []string{"outer in", "inner in", "handler", "inner out", "outer out"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	panicking := func(events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+		panic("boom")
+	}
+
+	response := Recover()(panicking)(events.APIGatewayProxyRequest{HTTPMethod: "GET", Path: "/books"})
+	if response.StatusCode != 500 {
+		t.Errorf("panicking handler returned %d, want 500", response.StatusCode)
+	}
+	if response := Recover()(ok)(events.APIGatewayProxyRequest{}); response.StatusCode != 200 {
+		t.Errorf("handler returned %d through Recover, want 200", response.StatusCode)
+	}
+}
+
+func TestRecoverInsideCORS(t *testing.T) {
+	panicking := func(events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+		panic("boom")
+	}
+	handler := Chain(panicking, CORS([]string{"https://app.example"}), Recover())
+
+	response := handler(events.APIGatewayProxyRequest{Headers: map[string]string{"Origin": "https://app.example"}})
+	if response.StatusCode != 500 {
+		t.Errorf("status = %d, want 500", response.StatusCode)
+	}
+	if got := response.Headers["Access-Control-Allow-Origin"]; got != "https://app.example" {
+		t.Errorf("Access-Control-Allow-Origin = %q on a recovered panic, want the origin", got)
+	}
+}
+
+func TestCORS(t *testing.T) {
+	tests := []struct {
+		name        string
+		allowed     []string
+		method      string
+		headers     map[string]string
+		handler     Handler
+		wantOrigin  string
+		wantMethods string
+	}{
+		{
+			name:        "allowed origin",
+			allowed:     []string{"https://app.example", "http://localhost:3000"},
+			method:      "GET",
+			headers:     map[string]string{"Origin": "http://localhost:3000"},
+			wantOrigin:  "http://localhost:3000",
+			wantMethods: "GET, POST, PUT, DELETE, OPTIONS",
+		},
+		{
+			name:    "other origin",
+			allowed: []string{"https://app.example"},
+			method:  "GET",
+			headers: map[string]string{"Origin": "https://evil.example"},
+		},
+		{
+			name:    "no origin",
+			allowed: []string{"*"},
+			method:  "GET",
+		},
+		{
+			name:        "wildcard echoes origin",
+			allowed:     []string{" * "},
+			method:      "GET",
+			headers:     map[string]string{"origin": "https://any.example"},
+			wantOrigin:  "https://any.example",
+			wantMethods: "GET, POST, PUT, DELETE, OPTIONS",
+		},
+		{
+			name:    "preflight uses route's Allow",
+			allowed: []string{"https://app.example"},
+			method:  http.MethodOptions,
+			headers: map[string]string{"Origin": "https://app.example"},
+			handler: func(events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+				return events.APIGatewayProxyResponse{StatusCode: 200, Headers: map[string]string{"Allow": "GET, OPTIONS"}}
+			},
+			wantOrigin:  "https://app.example",
+			wantMethods: "GET, OPTIONS",
+		},
+		{
+			name:    "handler headers are dropped",
+			allowed: []string{"https://app.example"},
+			method:  "GET",
+			headers: map[string]string{"Origin": "https://evil.example"},
+			handler: func(events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+				return events.APIGatewayProxyResponse{StatusCode: 200, Headers: map[string]string{"Access-Control-Allow-Origin": "*"}}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := tt.handler
+			if handler == nil {
+				handler = ok
+			}
+			response := CORS(tt.allowed)(handler)(events.APIGatewayProxyRequest{HTTPMethod: tt.method, Headers: tt.headers})
+
+			if got := response.Headers["Access-Control-Allow-Origin"]; got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := response.Headers["Access-Control-Allow-Methods"]; got != tt.wantMethods {
+				t.Errorf("Access-Control-Allow-Methods = %q, want %q", got, tt.wantMethods)
+			}
+			if got := response.Headers["Vary"]; got != "Origin" {
+				t.Errorf("Vary = %q, want Origin", got)
+			}
+		})
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	handler := RequestID()(func(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+		seen = request.RequestContext.RequestID
+		return ok(request)
+	})
+
+	response := handler(events.APIGatewayProxyRequest{})
+	if seen == "" || response.Headers[RequestIDHeader] != seen {
+		t.Errorf("generated ID %q, echoed %q", seen, response.Headers[RequestIDHeader])
+	}
+
+	request := events.APIGatewayProxyRequest{Headers: map[string]string{"x-request-id": "caller"}}
+	request.RequestContext.RequestID = "gateway"
+	response = handler(request)
+	if seen != "gateway" {
+		t.Errorf("handler saw request ID %q, want the gateway's", seen)
+	}
+	if got := response.Headers[RequestIDHeader]; got != "caller" {
+		t.Errorf("%s = %q, want the caller's ID echoed", RequestIDHeader, got)
+	}
+}
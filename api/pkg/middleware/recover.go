@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"log"
+	"runtime/debug"
+
+	"github.com/FriedGlue/BookIt/api/pkg/shared"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Recover turns a panic in the rest of the chain into a 500 response instead
+// of failing the whole invocation.
+func Recover() Middleware {
+	return func(next Handler) Handler {
+		return func(request events.APIGatewayProxyRequest) (response events.APIGatewayProxyResponse) {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("Panic serving %s %s (request %s): %v\n%s",
+						request.HTTPMethod, request.Path, request.RequestContext.RequestID, r, debug.Stack())
+					response = shared.Error(shared.CodeInternal, "Internal server error")
+				}
+			}()
+			return next(request)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/FriedGlue/BookIt/api/pkg/shared"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader is the response header carrying the request ID. A caller
+// may send its own ID in the same request header, and gets it echoed back.
+const RequestIDHeader = "X-Request-Id"
+
+// RequestID makes sure every request has an ID in RequestContext.RequestID,
+// generating one when API Gateway did not give one. The ID always comes
+// from the server, so two requests never share one; the caller's
+// X-Request-Id is only echoed in the response, and the server's ID is
+// returned when the caller sent none.
+func RequestID() Middleware {
+	return func(next Handler) Handler {
+		return func(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+			if request.RequestContext.RequestID == "" {
+				request.RequestContext.RequestID = uuid.New().String()
+			}
+
+			response := next(request)
+			echoed := header(request, RequestIDHeader)
+			if echoed == "" {
+				echoed = request.RequestContext.RequestID
+			}
+			setHeader(&response, RequestIDHeader, echoed)
+			return response
+		}
+	}
+}
+
+// Context passes ctx with the request to the rest of the chain so handlers
+// can reach it through shared.RequestContext.
+func Context(ctx context.Context) Middleware {
+	return func(next Handler) Handler {
+		return func(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+			return next(shared.WithContext(request, ctx))
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package models
+
+// Author is a writer, an item in the Authors table keyed by their Open
+// Library author ID (e.g. "OL26320A"). Books and works name their authors
+// in Authors and link them through AuthorIDs.
+type Author struct {
+	AuthorID       string   `json:"authorId"`
+	Name           string   `json:"name"`
+	AlternateNames []string `json:"alternateNames,omitempty"` // pen names and other spellings
+	Bio            string   `json:"bio,omitempty"`
+	PhotoURL       string   `json:"photoUrl,omitempty"`
+	BirthDate      string   `json:"birthDate,omitempty"`
+	DeathDate      string   `json:"deathDate,omitempty"`
+}
@@ -0,0 +1,39 @@
+package models
+
+// BookData represents a single book record in the Books table. Each record
+// is one edition of a work: the ISBNs, page count, cover and publisher belong
+// to the edition, while reviews and lists are about the Work named by WorkID.
+type BookData struct {
+	BookID         string   `json:"bookId"`
+	WorkID         string   `json:"workId,omitempty"`
+	ISBN10         string   `json:"isbn10,omitempty"`
+	ISBN13         string   `json:"isbn13,omitempty"`
+	Title          string   `json:"title,omitempty"`
+	TitleLowercase string   `json:"titleLowercase,omitempty"`
+	Authors        []string `json:"authors,omitempty"`
+	AuthorIDs      []string `json:"authorIds,omitempty"` // Open Library author IDs, see Author
+	PageCount      int      `json:"pageCount,omitempty"`
+	CoverImageURL  string   `json:"coverImageUrl,omitempty"`
+	Publisher      string   `json:"publisher,omitempty"`
+	PublishDate    string   `json:"publishDate,omitempty"`
+	Format         string   `json:"format,omitempty"` // e.g. "Paperback"
+	Tags           []string `json:"tags,omitempty"`
+	OpenLibraryId  string   `json:"openLibraryId,omitempty"`
+	Description    string   `json:"description,omitempty"`
+
+	Series []SeriesMembership `json:"series,omitempty"`
+}
+
+// WorkKey returns the ID of the book's work. Books saved before works
+// existed have no WorkID and stand for a work of their own, keyed by their
+// Open Library ID or else their book ID.
+func (b BookData) WorkKey() string {
+	switch {
+	case b.WorkID != "":
+		return b.WorkID
+	case b.OpenLibraryId != "":
+		return b.OpenLibraryId
+	default:
+		return b.BookID
+	}
+}
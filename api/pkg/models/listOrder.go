@@ -0,0 +1,60 @@
+package models
+
+import "sort"
+
+// The items of every list in UserLists are kept in display order, with
+// each item's Order equal to its index. Profiles written before that held
+// duplicate and missing Orders, and custom lists in the CustomLists map;
+// Normalize repairs them.
+
+// Normalize moves the lists of CustomLists to Shelves, puts every list in
+// Order order, ties broken by book ID as GET /list shows them, then
+// renumbers it from 0. It reports whether anything changed.
+func (l *UserLists) Normalize() bool {
+	changed := l.migrateCustomLists()
+	changed = normalize(l.ToBeRead, func(item *ToBeReadItem) (*int, string) { return &item.Order, item.BookID }) || changed
+	changed = normalize(l.Read, func(item *ReadItem) (*int, string) { return &item.Order, item.BookID }) || changed
+	for _, shelf := range l.Shelves {
+		changed = normalize(shelf.Items, func(item *CustomListItem) (*int, string) { return &item.Order, item.BookID }) || changed
+	}
+	return changed
+}
+
+// Renumber sets the Order of every item to its index, after items were
+// added, removed or moved.
+func (l *UserLists) Renumber() {
+	renumber(l.ToBeRead, func(item *ToBeReadItem) *int { return &item.Order })
+	renumber(l.Read, func(item *ReadItem) *int { return &item.Order })
+	for _, shelf := range l.Shelves {
+		renumber(shelf.Items, func(item *CustomListItem) *int { return &item.Order })
+	}
+}
+
+// normalize sorts items by their order and book ID in place and renumbers
+// them. field returns an item's Order and book ID.
+func normalize[T any](items []T, field func(*T) (*int, string)) bool {
+	less := func(a, b *T) bool {
+		orderA, idA := field(a)
+		orderB, idB := field(b)
+		if max(*orderA, 0) != max(*orderB, 0) {
+			return max(*orderA, 0) < max(*orderB, 0)
+		}
+		return idA < idB
+	}
+
+	changed := !sort.SliceIsSorted(items, func(i, j int) bool { return less(&items[i], &items[j]) })
+	sort.SliceStable(items, func(i, j int) bool { return less(&items[i], &items[j]) })
+	for i := range items {
+		if order, _ := field(&items[i]); *order != i {
+			changed = true
+		}
+	}
+	renumber(items, func(item *T) *int { order, _ := field(item); return order })
+	return changed
+}
+
+func renumber[T any](items []T, order func(*T) *int) {
+	for i := range items {
+		*order(&items[i]) = i
+	}
+}
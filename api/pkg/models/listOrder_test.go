@@ -0,0 +1,101 @@
+package models
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name        string
+		items       []ToBeReadItem
+		wantIDs     []string
+		wantChanged bool
+	}{
+		{"already normalized", tbr("a", 0, "b", 1, "c", 2), []string{"a", "b", "c"}, false},
+		{"out of order", tbr("a", 2, "b", 0, "c", 1), []string{"b", "c", "a"}, true},
+		{"duplicate orders broken by book ID", tbr("c", 0, "a", 0, "b", 1), []string{"a", "c", "b"}, true},
+		{"gaps", tbr("a", 3, "b", 7), []string{"a", "b"}, true},
+		{"negative orders count as 0", tbr("b", 0, "a", -1), []string{"a", "b"}, true},
+		{"empty", nil, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lists := UserLists{ToBeRead: tt.items}
+			if changed := lists.Normalize(); changed != tt.wantChanged {
+				t.Errorf("Normalize() = %t, want %t", changed, tt.wantChanged)
+			}
+			var ids []string
+			for i, item := range lists.ToBeRead {
+				ids = append(ids, item.BookID)
+				if item.Order != i {
+					t.Errorf("%s has order %d at index %d", item.BookID, item.Order, i)
+				}
+			}
+			if !slices.Equal(ids, tt.wantIDs) {
+				t.Errorf("order = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestNormalizeMigratesCustomLists(t *testing.T) {
+	lists := UserLists{
+		Shelves: []Shelf{{ShelfID: "s1", Name: "Favourites", Items: []CustomListItem{{BookID: "a"}}}},
+		CustomLists: map[string][]CustomListItem{
+			"Favourites": {{BookID: "b", Order: 5}},
+			"Holiday":    {{BookID: "c", Order: 1}, {BookID: "d", Order: 0}},
+		},
+	}
+	if !lists.Normalize() {
+		t.Fatal("Normalize() = false, want true")
+	}
+	if lists.CustomLists != nil {
+		t.Errorf("CustomLists = %v, want nil", lists.CustomLists)
+	}
+
+	want := map[string][]string{"Favourites": {"a", "b"}, "Holiday": {"d", "c"}}
+	if len(lists.Shelves) != len(want) {
+		t.Fatalf("got %d shelves, want %d", len(lists.Shelves), len(want))
+	}
+	for name, wantIDs := range want {
+		shelf := lists.Shelf(name)
+		if shelf == nil {
+			t.Fatalf("shelf %q is missing", name)
+		}
+		var ids []string
+		for _, item := range shelf.Items {
+			ids = append(ids, item.BookID)
+		}
+		if !slices.Equal(ids, wantIDs) {
+			t.Errorf("shelf %q = %v, want %v", name, ids, wantIDs)
+		}
+	}
+	if lists.Normalize() {
+		t.Error("second Normalize() = true, want false")
+	}
+}
+
+func TestRenumber(t *testing.T) {
+	lists := UserLists{
+		ToBeRead: tbr("a", 4, "b", 4),
+		Read:     []ReadItem{{BookID: "c", Order: 9}},
+		Shelves:  []Shelf{{Items: []CustomListItem{{BookID: "d", Order: 2}, {BookID: "e", Order: 1}}}},
+	}
+	lists.Renumber()
+	if lists.ToBeRead[0].Order != 0 || lists.ToBeRead[1].Order != 1 || lists.Read[0].Order != 0 {
+		t.Errorf("lists were not renumbered: %+v", lists)
+	}
+	if items := lists.Shelves[0].Items; items[0].BookID != "d" || items[0].Order != 0 || items[1].Order != 1 {
+		t.Errorf("shelf was not renumbered in place: %+v", items)
+	}
+}
+
+// tbr builds to be read items from pairs of book IDs and orders.
+func tbr(pairs ...any) []ToBeReadItem {
+	var items []ToBeReadItem
+	for i := 0; i < len(pairs); i += 2 {
+		items = append(items, ToBeReadItem{BookID: pairs[i].(string), Order: pairs[i+1].(int)})
+	}
+	return items
+}
@@ -1,5 +1,11 @@
 package models
 
+// Profile is a user's item in the Profiles table.
+//
+// ReadingLog is deprecated: entries live in the ReadingLog table, and the
+// field only holds entries written before that table existed until the
+// migrate-reading-log command moves them out. Version is incremented on every
+// write and used for optimistic locking.
 type Profile struct {
 	ID                 string                 `json:"_id"`
 	ProfileInformation ProfileInformation     `json:"profileInformation"`
@@ -7,6 +13,7 @@ type Profile struct {
 	Lists              UserLists              `json:"lists,omitempty"`
 	ReadingLog         []ReadingLogItem       `json:"readingLog,omitempty"`
 	Challenges         []ReadingChallenge     `json:"challenges,omitempty"`
+	Version            int64                  `json:"version"`
 }
 
 type ProfileInformation struct {
@@ -36,9 +43,13 @@ type ReadingProgress struct {
 	Notes        string  `json:"notes,omitempty"`
 }
 
+// UserLists holds a user's lists. CustomLists is deprecated: custom lists
+// are Shelves, and the map only holds lists written before shelves existed
+// until Normalize moves them over.
 type UserLists struct {
 	ToBeRead    []ToBeReadItem              `json:"toBeRead,omitempty"`
 	Read        []ReadItem                  `json:"read,omitempty"`
+	Shelves     []Shelf                     `json:"shelves,omitempty"`
 	CustomLists map[string][]CustomListItem `json:"customLists,omitempty"`
 }
 
@@ -72,6 +83,7 @@ type CustomListItem struct {
 }
 
 type ReadingLogItem struct {
+	UserID        string `json:"userId,omitempty"`
 	Id            string `json:"_id"`
 	BookID        string `json:"bookId"`
 	Title         string `json:"title"`
@@ -80,3 +92,20 @@ type ReadingLogItem struct {
 	PagesRead     int    `json:"pagesRead,omitempty"`
 	Notes         string `json:"notes,omitempty"`
 }
+
+// NewProfile returns an empty profile for the given user with all lists initialised.
+func NewProfile(userID, username string) Profile {
+	return Profile{
+		ID: userID,
+		ProfileInformation: ProfileInformation{
+			Username: username,
+		},
+		CurrentlyReading: []CurrentlyReadingItem{},
+		Lists: UserLists{
+			ToBeRead: []ToBeReadItem{},
+			Read:     []ReadItem{},
+			Shelves:  []Shelf{},
+		},
+		Challenges: []ReadingChallenge{},
+	}
+}
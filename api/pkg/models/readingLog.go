@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// readingLogIDLayout is a fixed-width UTC timestamp, so reading log IDs sort
+// chronologically as plain strings.
+const readingLogIDLayout = "2006-01-02T15:04:05.000000000Z"
+
+// NewReadingLogID returns a reading log entry ID for an entry made at t. IDs
+// sort by time and are unique thanks to a random suffix.
+func NewReadingLogID(t time.Time) string {
+	return ReadingLogID(t, uuid.New().String()[:8])
+}
+
+// ReadingLogID builds a reading log entry ID from a timestamp and a suffix
+// that makes it unique among entries with the same timestamp.
+func ReadingLogID(t time.Time, suffix string) string {
+	return ReadingLogIDPrefix(t) + "#" + suffix
+}
+
+// ReadingLogIDPrefix returns the sortable timestamp part of reading log IDs
+// for entries made at t, for use as a range bound.
+func ReadingLogIDPrefix(t time.Time) string {
+	return t.UTC().Format(readingLogIDLayout)
+}
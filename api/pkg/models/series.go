@@ -0,0 +1,27 @@
+package models
+
+// Series is a sequence of works in reading order, an item in the Series
+// table. Series imported from the catalogue are keyed by a slug of their
+// name (e.g. "the-wheel-of-time"); series created by hand get a UUID.
+type Series struct {
+	SeriesID string        `json:"seriesId"`
+	Name     string        `json:"name"`
+	Entries  []SeriesEntry `json:"entries"` // ordered by Position
+}
+
+// SeriesEntry is one work of a series. Positions need not be whole numbers:
+// a novella set between the second and third books is 2.5.
+type SeriesEntry struct {
+	Position float64 `json:"position"`
+	WorkID   string  `json:"workId,omitempty"`
+	BookID   string  `json:"bookId,omitempty"` // a saved edition to read it in
+	Title    string  `json:"title"`
+}
+
+// SeriesMembership is the series a book belongs to as the catalogue gave it,
+// kept on the book so the series can be rebuilt. Position is 0 when the
+// catalogue gave none.
+type SeriesMembership struct {
+	Name     string  `json:"name"`
+	Position float64 `json:"position,omitempty"`
+}
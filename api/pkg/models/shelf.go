@@ -0,0 +1,179 @@
+package models
+
+import (
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+type ShelfVisibility string
+type ShelfSort string
+
+const (
+	PrivateShelf ShelfVisibility = "private"
+	FriendsShelf ShelfVisibility = "friends"
+	PublicShelf  ShelfVisibility = "public"
+
+	// ManualSort shows a shelf in the order its books were put in, as
+	// rearranged with PUT /list/order.
+	ManualSort    ShelfSort = "manual"
+	TitleSort     ShelfSort = "title"
+	AuthorSort    ShelfSort = "author"
+	DateAddedSort ShelfSort = "dateAdded" // newest first
+)
+
+// Shelf is a custom list of a user's. Its ID stays the same when it is
+// renamed; requests may name the shelf by either.
+//
+// A shelf with Rules is a smart shelf: its books are the ones matching the
+// rules whenever it is shown, and Items is stored empty.
+type Shelf struct {
+	ShelfID     string           `json:"shelfId"`
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Icon        string           `json:"icon,omitempty"` // an emoji or an icon name for clients
+	Visibility  ShelfVisibility  `json:"visibility"`
+	DefaultSort ShelfSort        `json:"defaultSort"`
+	CreatedAt   string           `json:"createdAt,omitempty"`
+	Rules       *ShelfRules      `json:"rules,omitempty"`
+	Items       []CustomListItem `json:"items"`
+}
+
+// Smart reports whether the shelf's books come from rules.
+func (s *Shelf) Smart() bool {
+	return s.Rules != nil
+}
+
+type RuleMatch string
+type RuleField string
+type RuleOp string
+
+const (
+	MatchAll RuleMatch = "all"
+	MatchAny RuleMatch = "any"
+
+	// Text fields, compared with Value ignoring case.
+	ListField   RuleField = "list"   // a list or static shelf holding the book
+	TagField    RuleField = "tag"    // a tag of the saved book
+	AuthorField RuleField = "author" // one of the book's authors
+
+	// Number fields, compared with Number. A book read or logged in several
+	// years matches if any of them does.
+	RatingField          RuleField = "rating"          // the latest rating given, 0 for none
+	ReadYearField        RuleField = "readYear"        // a year the book was finished in
+	LoggedYearField      RuleField = "loggedYear"      // a year with reading log entries for the book
+	PageCountField       RuleField = "pageCount"       // of the saved book, 0 when unknown
+	AuthorReadCountField RuleField = "authorReadCount" // books on the read list by the book's most read author
+
+	Equal    RuleOp = "eq"
+	NotEqual RuleOp = "ne"
+	AtLeast  RuleOp = "gte"
+	AtMost   RuleOp = "lte"
+)
+
+// ShelfRules pick the books of a smart shelf from those on the user's
+// lists: the books matching all or any of the conditions.
+type ShelfRules struct {
+	Match      RuleMatch   `json:"match"`
+	Conditions []ShelfRule `json:"conditions"`
+}
+
+// ShelfRule is one condition on a book, such as {"field": "rating", "op":
+// "gte", "number": 4} or {"field": "tag", "op": "eq", "value": "fantasy"}.
+// Text fields only take eq and ne.
+type ShelfRule struct {
+	Field  RuleField `json:"field"`
+	Op     RuleOp    `json:"op"`
+	Value  string    `json:"value,omitempty"`
+	Number int       `json:"number,omitempty"`
+}
+
+// TextField reports whether the field is compared with Value rather than
+// Number.
+func (f RuleField) TextField() bool {
+	return f == ListField || f == TagField || f == AuthorField
+}
+
+// NewShelf returns an empty private shelf in manual order.
+func NewShelf(name, createdAt string) Shelf {
+	return Shelf{
+		ShelfID:     uuid.New().String(),
+		Name:        name,
+		Visibility:  PrivateShelf,
+		DefaultSort: ManualSort,
+		CreatedAt:   createdAt,
+		Items:       []CustomListItem{},
+	}
+}
+
+// Shelf returns the shelf with the given ID or, failing that, name, or nil.
+func (l *UserLists) Shelf(ref string) *Shelf {
+	for i := range l.Shelves {
+		if l.Shelves[i].ShelfID == ref {
+			return &l.Shelves[i]
+		}
+	}
+	for i := range l.Shelves {
+		if l.Shelves[i].Name == ref {
+			return &l.Shelves[i]
+		}
+	}
+	return nil
+}
+
+// RemoveShelf deletes the shelf with the given ID.
+func (l *UserLists) RemoveShelf(shelfID string) {
+	for i := range l.Shelves {
+		if l.Shelves[i].ShelfID == shelfID {
+			l.Shelves = append(l.Shelves[:i], l.Shelves[i+1:]...)
+			return
+		}
+	}
+}
+
+// legacyShelfNamespace derives the IDs of shelves migrated from
+// CustomLists, which must come out the same every time a profile that has
+// not been written back yet is read.
+var legacyShelfNamespace = uuid.MustParse("6f1c3e0a-4d5b-4a8e-9f0c-2b7d1e5a9c34")
+
+// migrateCustomLists turns the lists of the deprecated CustomLists map into
+// shelves named after their keys and reports whether there were any. A
+// shelf's creation date is the earliest date a book was added to it.
+func (l *UserLists) migrateCustomLists() bool {
+	if len(l.CustomLists) == 0 {
+		l.CustomLists = nil
+		return false
+	}
+
+	names := make([]string, 0, len(l.CustomLists))
+	for name := range l.CustomLists {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		items := l.CustomLists[name]
+		if shelf := l.Shelf(name); shelf != nil {
+			shelf.Items = append(shelf.Items, items...)
+			continue
+		}
+		shelf := Shelf{
+			ShelfID:     uuid.NewSHA1(legacyShelfNamespace, []byte(name)).String(),
+			Name:        name,
+			Visibility:  PrivateShelf,
+			DefaultSort: ManualSort,
+			Items:       items,
+		}
+		if shelf.Items == nil {
+			shelf.Items = []CustomListItem{}
+		}
+		for _, item := range items {
+			if item.AddedDate != "" && (shelf.CreatedAt == "" || item.AddedDate < shelf.CreatedAt) {
+				shelf.CreatedAt = item.AddedDate
+			}
+		}
+		l.Shelves = append(l.Shelves, shelf)
+	}
+	l.CustomLists = nil
+	return true
+}
@@ -0,0 +1,32 @@
+package models
+
+// Work is a book as written, independent of its editions: the title,
+// authors, description and subjects every edition shares. It is an item in
+// the Works table, and its editions are the BookData records with its
+// WorkID. Works saved from Open Library use the Open Library work ID (e.g.
+// "OL12345W") as their WorkID.
+type Work struct {
+	WorkID        string   `json:"workId"`
+	Title         string   `json:"title,omitempty"`
+	Authors       []string `json:"authors,omitempty"`
+	AuthorIDs     []string `json:"authorIds,omitempty"`
+	Description   string   `json:"description,omitempty"`
+	CoverImageURL string   `json:"coverImageUrl,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+	OpenLibraryId string   `json:"openLibraryId,omitempty"`
+}
+
+// WorkFromEdition returns the work of an edition, for editions whose work
+// is not saved.
+func WorkFromEdition(book BookData) Work {
+	return Work{
+		WorkID:        book.WorkKey(),
+		Title:         book.Title,
+		Authors:       book.Authors,
+		AuthorIDs:     book.AuthorIDs,
+		Description:   book.Description,
+		CoverImageURL: book.CoverImageURL,
+		Tags:          book.Tags,
+		OpenLibraryId: book.OpenLibraryId,
+	}
+}
@@ -0,0 +1,297 @@
+// Package openapi builds an OpenAPI 3 document from a description of each
+// endpoint and the Go types of its request and response bodies. Schemas are
+// derived from the types' json tags and the validation package's `validate`
+// tags, so the document stays in step with what the handlers accept.
+package openapi
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/FriedGlue/BookIt/api/pkg/shared"
+)
+
+// Version is the OpenAPI version the documents conform to.
+const Version = "3.0.3"
+
+// Document is the root of an OpenAPI document.
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Paths      map[string]map[string]Operation `json:"paths"`
+	Components Components                      `json:"components"`
+}
+
+// Info holds the API title and version.
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Components holds the schemas referenced from the operations.
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme describes how callers authenticate.
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// Operation describes one method on one path.
+type Operation struct {
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	OperationID string                `json:"operationId"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security"`
+}
+
+// Parameter is a path, query or header parameter.
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody is the JSON body an operation accepts.
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response is one possible response of an operation.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType wraps the schema of a body.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Param documents a query or header parameter of an Endpoint. Path
+// parameters are taken from the route pattern.
+type Param struct {
+	Name        string
+	In          string // "query" unless set to "header"
+	Description string
+	Required    bool
+}
+
+// Endpoint describes a route for the document.
+type Endpoint struct {
+	Summary     string
+	Description string
+	Tag         string
+
+	// Public endpoints need no bearer token.
+	Public bool
+
+	Params []Param
+
+	// Body is a value of the request body type, or nil if the endpoint
+	// takes no body.
+	Body interface{}
+
+	// Response is a value of the success body type. With List set the body
+	// is documented as {"items": [Response...]}; use Message{} for
+	// endpoints that only confirm what they did. Paged adds the
+	// nextCursor of a paginated list.
+	Response interface{}
+	List     bool
+	Paged    bool
+
+	// Status is the success status code, 200 if zero.
+	Status int
+
+	// Errors lists the error codes the handler returns itself. Codes every
+	// endpoint can return (INTERNAL_ERROR, UNAUTHORIZED for endpoints that
+	// are not public, INVALID_JSON and VALIDATION_FAILED for endpoints with a
+	// body) are added automatically.
+	Errors []shared.ErrorCode
+}
+
+// Message is the body of responses that only confirm an action.
+type Message struct {
+	Message string `json:"message"`
+}
+
+// errorBody is the envelope every error is returned in.
+type errorBody struct {
+	Error shared.APIError `json:"error"`
+}
+
+// Builder accumulates operations and the schemas they reference.
+type Builder struct {
+	doc     Document
+	schemas *schemaRegistry
+}
+
+// NewBuilder starts a document with the given title and API version.
+func NewBuilder(title, version string) *Builder {
+	b := &Builder{
+		doc: Document{
+			OpenAPI: Version,
+			Info:    Info{Title: title, Version: version},
+			Paths:   map[string]map[string]Operation{},
+			Components: Components{
+				SecuritySchemes: map[string]SecurityScheme{
+					"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+				},
+			},
+		},
+		schemas: newSchemaRegistry(),
+	}
+	b.schemas.ref(errorBody{}, "Error")
+	if apiErr := b.schemas.schemas["APIError"]; apiErr != nil {
+		for _, code := range shared.Codes() {
+			apiErr.Properties["code"].Enum = append(apiErr.Properties["code"].Enum, string(code))
+		}
+	}
+	return b
+}
+
+// Add documents method and pattern, a router pattern such as
+// "/books/{bookId}".
+func (b *Builder) Add(method, pattern string, endpoint Endpoint) {
+	op := Operation{
+		Summary:     endpoint.Summary,
+		Description: endpoint.Description,
+		OperationID: operationID(method, pattern),
+		Responses:   map[string]Response{},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}
+	if endpoint.Public {
+		op.Security = []map[string][]string{}
+	}
+	if endpoint.Tag != "" {
+		op.Tags = []string{endpoint.Tag}
+	}
+
+	for _, name := range pathParams(pattern) {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"},
+		})
+	}
+	for _, p := range endpoint.Params {
+		in := p.In
+		if in == "" {
+			in = "query"
+		}
+		op.Parameters = append(op.Parameters, Parameter{
+			Name: p.Name, In: in, Description: p.Description, Required: p.Required, Schema: &Schema{Type: "string"},
+		})
+	}
+
+	if endpoint.Body != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  jsonContent(b.schemas.of(endpoint.Body)),
+		}
+	}
+
+	status := endpoint.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := Response{Description: http.StatusText(status)}
+	if endpoint.Response != nil {
+		schema := b.schemas.of(endpoint.Response)
+		if endpoint.List {
+			schema = &Schema{
+				Type:       "object",
+				Properties: map[string]*Schema{"items": {Type: "array", Items: schema}},
+				Required:   []string{"items"},
+			}
+			if endpoint.Paged {
+				schema.Properties["nextCursor"] = &Schema{Type: "string"}
+			}
+		}
+		success.Content = jsonContent(schema)
+	}
+	op.Responses[strconv.Itoa(status)] = success
+
+	for status, codes := range errorsByStatus(endpoint) {
+		op.Responses[strconv.Itoa(status)] = Response{
+			Description: http.StatusText(status) + ": " + strings.Join(codes, ", "),
+			Content:     jsonContent(&Schema{Ref: "#/components/schemas/Error"}),
+		}
+	}
+
+	if b.doc.Paths[pattern] == nil {
+		b.doc.Paths[pattern] = map[string]Operation{}
+	}
+	b.doc.Paths[pattern][strings.ToLower(method)] = op
+}
+
+// Document returns the document built so far.
+func (b *Builder) Document() *Document {
+	doc := b.doc
+	doc.Components.Schemas = b.schemas.schemas
+	return &doc
+}
+
+// errorsByStatus groups the error codes an endpoint can return by status.
+func errorsByStatus(endpoint Endpoint) map[int][]string {
+	codes := append([]shared.ErrorCode{shared.CodeInternal}, endpoint.Errors...)
+	if !endpoint.Public {
+		codes = append(codes, shared.CodeUnauthorized)
+	}
+	if endpoint.Body != nil {
+		codes = append(codes, shared.CodeInvalidJSON, shared.CodeValidationFailed)
+	}
+
+	byStatus := map[int][]string{}
+	seen := map[shared.ErrorCode]bool{}
+	for _, code := range codes {
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+		byStatus[code.Status()] = append(byStatus[code.Status()], string(code))
+	}
+	for _, list := range byStatus {
+		sort.Strings(list)
+	}
+	return byStatus
+}
+
+func jsonContent(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: schema}}
+}
+
+// pathParams returns the names of the {param} segments of pattern.
+func pathParams(pattern string) []string {
+	var names []string
+	for _, segment := range strings.Split(pattern, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			names = append(names, segment[1:len(segment)-1])
+		}
+	}
+	return names
+}
+
+// operationID derives a stable camelCase ID such as "getBooksBookId" from
+// the method and pattern.
+func operationID(method, pattern string) string {
+	id := strings.ToLower(method)
+	for _, segment := range strings.Split(pattern, "/") {
+		segment = strings.Trim(segment, "{}")
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool { return r == '-' || r == '.' || r == '_' }) {
+			id += strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	return id
+}
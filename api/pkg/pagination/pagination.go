@@ -0,0 +1,144 @@
+// Package pagination implements the cursor pagination shared by every
+// endpoint returning a collection: the client asks for up to ?limit= items
+// and passes back the nextCursor of each page as ?cursor= to get the next.
+//
+// A cursor wraps the sort key of the last item returned, so a page resumes
+// after that item even if items were added or removed before it, as long as
+// the keys of the remaining items stay the same. Where they do not, as with
+// list positions that are renumbered when a book is removed, SliceAfterID
+// also wraps the item's ID and resumes after the item wherever it moved.
+// Cursors are opaque to clients; only this package encodes and decodes them.
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidCursor is returned for a cursor this package did not issue.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrInvalidLimit is returned for a limit that is not a positive number.
+var ErrInvalidLimit = errors.New("limit must be a positive number")
+
+// Request is a decoded page request. After is the sort key of the last item
+// of the previous page, empty for the first page.
+type Request struct {
+	Limit int
+	After string
+}
+
+// Page sizes used unless an endpoint needs its own.
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+)
+
+// cursorPrefix versions the cursor format.
+const cursorPrefix = "v1:"
+
+// FromQuery reads the limit and cursor query parameters. A missing limit
+// means defaultLimit, and larger limits are reduced to maxLimit.
+func FromQuery(params map[string]string, defaultLimit, maxLimit int) (Request, error) {
+	req := Request{Limit: defaultLimit}
+	if raw := params["limit"]; raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			return Request{}, ErrInvalidLimit
+		}
+		req.Limit = min(limit, maxLimit)
+	}
+
+	after, err := Decode(params["cursor"])
+	if err != nil {
+		return Request{}, err
+	}
+	req.After = after
+	return req, nil
+}
+
+// Encode returns the cursor resuming after key, or "" for an empty key so
+// the last page has no nextCursor.
+func Encode(key string) string {
+	if key == "" {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + key))
+}
+
+// Decode returns the key wrapped by cursor, or "" for an empty cursor.
+func Decode(cursor string) (string, error) {
+	if cursor == "" {
+		return "", nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimSpace(cursor))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	key, ok := strings.CutPrefix(string(raw), cursorPrefix)
+	if !ok || key == "" {
+		return "", ErrInvalidCursor
+	}
+	return key, nil
+}
+
+// Slice returns one page of items, which are ordered by key, and the key to
+// resume after, empty on the last page. key must be unique per item and
+// give the order to page in; items are sorted by it in place.
+func Slice[T any](items []T, req Request, key func(T) string) ([]T, string) {
+	sort.SliceStable(items, func(i, j int) bool { return key(items[i]) < key(items[j]) })
+
+	start := 0
+	if req.After != "" {
+		start = sort.Search(len(items), func(i int) bool { return key(items[i]) > req.After })
+	}
+	end := len(items)
+	if req.Limit > 0 {
+		end = min(start+req.Limit, len(items))
+	}
+
+	page := items[start:end]
+	if page == nil {
+		page = []T{}
+	}
+	if end < len(items) && len(page) > 0 {
+		return page, key(page[len(page)-1])
+	}
+	return page, ""
+}
+
+// idSeparator ends the ID at the start of a key from SliceAfterID. IDs
+// never contain it.
+const idSeparator = "\x00"
+
+// SliceAfterID is Slice for items whose keys may change between requests.
+// The key to resume after also carries the ID of the last item returned,
+// and the next page starts after that item in the current order. Only if
+// the item is gone does it resume by comparing keys.
+func SliceAfterID[T any](items []T, req Request, key, id func(T) string) ([]T, string) {
+	if afterID, afterKey, ok := strings.Cut(req.After, idSeparator); ok {
+		req.After = afterKey
+		for _, item := range items {
+			if id(item) == afterID {
+				req.After = key(item)
+				break
+			}
+		}
+	}
+
+	page, next := Slice(items, req, key)
+	if next == "" {
+		return page, ""
+	}
+	return page, id(page[len(page)-1]) + idSeparator + next
+}
+
+// OrderKey builds a sort key from a position and an ID, so that items
+// sorted by the key are in position order, with ties broken by ID.
+func OrderKey(position int, id string) string {
+	return fmt.Sprintf("%010d/%s", max(position, 0), id)
+}
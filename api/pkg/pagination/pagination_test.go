@@ -0,0 +1,150 @@
+package pagination
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	for _, key := range []string{"a", "0000000003/book-1", "Ünïcode key/with spaces"} {
+		got, err := Decode(Encode(key))
+		if err != nil || got != key {
+			t.Errorf("Decode(Encode(%q)) = %q, %v", key, got, err)
+		}
+	}
+	if Encode("") != "" {
+		t.Errorf("Encode(\"\") = %q, want no cursor", Encode(""))
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "%%%"},
+		{"no version prefix", "YWJj"},
+		{"empty key", Encode("x")[:4]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode(tt.cursor); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("Decode(%q) error = %v, want %v", tt.cursor, err, ErrInvalidCursor)
+			}
+		})
+	}
+}
+
+func TestFromQuery(t *testing.T) {
+	tests := []struct {
+		name   string
+		params map[string]string
+		want   Request
+		err    error
+	}{
+		{"defaults", nil, Request{Limit: 10}, nil},
+		{"limit", map[string]string{"limit": "5"}, Request{Limit: 5}, nil},
+		{"limit above the maximum", map[string]string{"limit": "500"}, Request{Limit: 20}, nil},
+		{"cursor", map[string]string{"cursor": Encode("b")}, Request{Limit: 10, After: "b"}, nil},
+		{"zero limit", map[string]string{"limit": "0"}, Request{}, ErrInvalidLimit},
+		{"non-numeric limit", map[string]string{"limit": "ten"}, Request{}, ErrInvalidLimit},
+		{"bad cursor", map[string]string{"cursor": "YWJj"}, Request{}, ErrInvalidCursor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FromQuery(tt.params, 10, 20)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("FromQuery(%v) error = %v, want %v", tt.params, err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("FromQuery(%v) = %+v, want %+v", tt.params, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlice(t *testing.T) {
+	items := []string{"d", "b", "e", "a", "c"}
+	tests := []struct {
+		name     string
+		req      Request
+		want     []string
+		wantNext string
+	}{
+		{"first page", Request{Limit: 2}, []string{"a", "b"}, "b"},
+		{"middle page", Request{Limit: 2, After: "b"}, []string{"c", "d"}, "d"},
+		{"last page", Request{Limit: 2, After: "d"}, []string{"e"}, ""},
+		{"exactly the rest", Request{Limit: 3, After: "b"}, []string{"c", "d", "e"}, ""},
+		{"after a removed item", Request{Limit: 2, After: "bb"}, []string{"c", "d"}, "d"},
+		{"past the end", Request{Limit: 2, After: "z"}, []string{}, ""},
+		{"no limit", Request{}, []string{"a", "b", "c", "d", "e"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, next := Slice(slices.Clone(items), tt.req, func(s string) string { return s })
+			if !slices.Equal(page, tt.want) || next != tt.wantNext {
+				t.Errorf("Slice(%+v) = %v, %q, want %v, %q", tt.req, page, next, tt.want, tt.wantNext)
+			}
+		})
+	}
+}
+
+// listItem is an item of a list whose positions are renumbered.
+type listItem struct {
+	id       string
+	position int
+}
+
+func TestSliceAfterID(t *testing.T) {
+	key := func(item listItem) string { return OrderKey(item.position, item.id) }
+	id := func(item listItem) string { return item.id }
+	ids := func(items []listItem) []string {
+		var out []string
+		for _, item := range items {
+			out = append(out, item.id)
+		}
+		return out
+	}
+	list := []listItem{{"a", 0}, {"b", 1}, {"c", 2}, {"d", 3}, {"e", 4}}
+
+	first, next := SliceAfterID(slices.Clone(list), Request{Limit: 3}, key, id)
+	if !slices.Equal(ids(first), []string{"a", "b", "c"}) || next != "c\x00"+OrderKey(2, "c") {
+		t.Fatalf("first page = %v, %q", ids(first), next)
+	}
+
+	tests := []struct {
+		name     string
+		items    []listItem
+		want     []string
+		wantNext bool
+	}{
+		{"unchanged", list, []string{"d", "e"}, false},
+		{"earlier items removed and renumbered", []listItem{{"c", 0}, {"d", 1}, {"e", 2}}, []string{"d", "e"}, false},
+		{"last item moved", []listItem{{"a", 0}, {"c", 1}, {"b", 2}, {"d", 3}, {"e", 4}}, []string{"b", "d", "e"}, false},
+		{"last item removed", []listItem{{"a", 0}, {"b", 1}, {"d", 2}, {"e", 3}}, []string{"d", "e"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, next := SliceAfterID(slices.Clone(tt.items), Request{Limit: 3, After: next}, key, id)
+			if !slices.Equal(ids(page), tt.want) || (next != "") != tt.wantNext {
+				t.Errorf("second page = %v, %q; want %v, next %v", ids(page), next, tt.want, tt.wantNext)
+			}
+		})
+	}
+
+	// Keys from Slice, without an ID, are compared as before.
+	page, _ := SliceAfterID(slices.Clone(list), Request{Limit: 2, After: OrderKey(1, "b")}, key, id)
+	if !slices.Equal(ids(page), []string{"c", "d"}) {
+		t.Errorf("page after a plain key = %v, want [c d]", ids(page))
+	}
+}
+
+func TestOrderKey(t *testing.T) {
+	keys := []string{OrderKey(10, "a"), OrderKey(2, "b"), OrderKey(2, "a"), OrderKey(-1, "z")}
+	slices.Sort(keys)
+	want := []string{OrderKey(0, "z"), OrderKey(2, "a"), OrderKey(2, "b"), OrderKey(10, "a")}
+	if !slices.Equal(keys, want) {
+		t.Errorf("sorted keys = %v, want %v", keys, want)
+	}
+}
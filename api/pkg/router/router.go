@@ -0,0 +1,236 @@
+// Package router dispatches API Gateway proxy requests to handlers using a
+// table of method and path patterns.
+//
+// Patterns are slash-separated segments, each either a literal ("books") or a
+// parameter in braces ("{bookId}"). Matched parameters are copied into the
+// request's PathParameters. When several patterns match a path, the one with a
+// literal segment where the others have a parameter wins, so "/books/search"
+// is preferred over "/books/{bookId}" regardless of the order of the table.
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/FriedGlue/BookIt/api/pkg/shared"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// HandlerFunc is the signature of every API handler.
+type HandlerFunc func(events.APIGatewayProxyRequest) events.APIGatewayProxyResponse
+
+// Route binds a method and path pattern to a handler.
+type Route struct {
+	Method  string
+	Pattern string
+	Handler HandlerFunc
+}
+
+// Router matches requests against a fixed set of routes.
+type Router struct {
+	routes   []Route
+	patterns []pattern
+}
+
+type pattern []segment
+
+type segment struct {
+	literal string
+	param   string
+}
+
+// New builds a Router from routes. It panics if a pattern is malformed or a
+// method and pattern pair is registered twice, since route tables are fixed at
+// compile time.
+func New(routes ...Route) *Router {
+	r := &Router{}
+	seen := map[string]bool{}
+	for _, route := range routes {
+		p, err := parsePattern(route.Pattern)
+		if err != nil {
+			panic(err)
+		}
+		key := route.Method + " " + p.shapeKey()
+		if seen[key] {
+			panic(fmt.Sprintf("router: duplicate route %s %s", route.Method, route.Pattern))
+		}
+		seen[key] = true
+
+		r.routes = append(r.routes, route)
+		r.patterns = append(r.patterns, p)
+	}
+	return r
+}
+
+// Routes returns the routes in the order they were registered.
+func (r *Router) Routes() []Route {
+	return append([]Route(nil), r.routes...)
+}
+
+// Route dispatches request to the matching handler. Unknown paths get a 404;
+// known paths requested with an unsupported method get a 405 with an Allow
+// header. OPTIONS requests to known paths are answered directly, as CORS
+// preflight requests.
+func (r *Router) Route(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	segments := splitPath(request.Path)
+
+	// Keep only the matching patterns of the most specific shape.
+	var candidates []int
+	for i, p := range r.patterns {
+		if _, ok := p.match(segments); !ok {
+			continue
+		}
+		switch {
+		case len(candidates) == 0 || p.sameShape(r.patterns[candidates[0]]):
+			candidates = append(candidates, i)
+		case p.moreSpecificThan(r.patterns[candidates[0]]):
+			candidates = []int{i}
+		}
+	}
+	if len(candidates) == 0 {
+		return shared.Error(shared.CodeRouteNotFound, fmt.Sprintf("No route for %s %s", request.HTTPMethod, request.Path))
+	}
+
+	allowed := make([]string, 0, len(candidates))
+	for _, i := range candidates {
+		route := r.routes[i]
+		allowed = append(allowed, route.Method)
+		if route.Method != request.HTTPMethod {
+			continue
+		}
+
+		params, _ := r.patterns[i].match(segments)
+		if len(params) > 0 {
+			merged := make(map[string]string, len(request.PathParameters)+len(params))
+			for k, v := range request.PathParameters {
+				merged[k] = v
+			}
+			for k, v := range params {
+				merged[k] = v
+			}
+			request.PathParameters = merged
+		}
+		return route.Handler(request)
+	}
+
+	allow := allowHeader(allowed)
+	if request.HTTPMethod == http.MethodOptions {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Headers:    map[string]string{"Allow": allow},
+		}
+	}
+	response := shared.Error(shared.CodeMethodNotAllowed, fmt.Sprintf("Method Not Allowed for %s", r.patterns[candidates[0]]))
+	response.Headers["Allow"] = allow
+	return response
+}
+
+func parsePattern(raw string) (pattern, error) {
+	if !strings.HasPrefix(raw, "/") {
+		return nil, fmt.Errorf("router: pattern %q must start with /", raw)
+	}
+
+	var p pattern
+	for _, part := range splitPath(raw) {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			name := part[1 : len(part)-1]
+			if name == "" {
+				return nil, fmt.Errorf("router: empty parameter name in %q", raw)
+			}
+			p = append(p, segment{param: name})
+			continue
+		}
+		if strings.ContainsAny(part, "{}") {
+			return nil, fmt.Errorf("router: malformed segment %q in %q", part, raw)
+		}
+		p = append(p, segment{literal: part})
+	}
+	return p, nil
+}
+
+// splitPath splits a URL path into its non-empty segments, so "/books/",
+// "/books" and "books" are equivalent.
+func splitPath(path string) []string {
+	var segments []string
+	for _, part := range strings.Split(path, "/") {
+		if part != "" {
+			segments = append(segments, part)
+		}
+	}
+	return segments
+}
+
+func (p pattern) match(segments []string) (map[string]string, bool) {
+	if len(p) != len(segments) {
+		return nil, false
+	}
+	var params map[string]string
+	for i, s := range p {
+		if s.param == "" {
+			if s.literal != segments[i] {
+				return nil, false
+			}
+			continue
+		}
+		if params == nil {
+			params = map[string]string{}
+		}
+		params[s.param] = segments[i]
+	}
+	return params, true
+}
+
+// moreSpecificThan reports whether p has a literal segment at the first
+// position where p and other differ in kind.
+func (p pattern) moreSpecificThan(other pattern) bool {
+	for i := range p {
+		if (p[i].param == "") != (other[i].param == "") {
+			return p[i].param == ""
+		}
+	}
+	return false
+}
+
+// sameShape reports whether p and other have literals and parameters in the
+// same positions.
+func (p pattern) sameShape(other pattern) bool {
+	return !p.moreSpecificThan(other) && !other.moreSpecificThan(p)
+}
+
+// shapeKey identifies the paths p matches, ignoring parameter names.
+func (p pattern) shapeKey() string {
+	var b strings.Builder
+	for _, s := range p {
+		b.WriteByte('/')
+		if s.param == "" {
+			b.WriteString(s.literal)
+		} else {
+			b.WriteString("{}")
+		}
+	}
+	return b.String()
+}
+
+func (p pattern) String() string {
+	var b strings.Builder
+	for _, s := range p {
+		b.WriteByte('/')
+		if s.param != "" {
+			b.WriteString("{" + s.param + "}")
+		} else {
+			b.WriteString(s.literal)
+		}
+	}
+	if b.Len() == 0 {
+		return "/"
+	}
+	return b.String()
+}
+
+func allowHeader(methods []string) string {
+	list := append([]string{http.MethodOptions}, methods...)
+	sort.Strings(list)
+	return strings.Join(list, ", ")
+}
@@ -0,0 +1,116 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/FriedGlue/BookIt/api/pkg/shared"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// named returns a handler that answers with its name and the bookId it was
+// routed with.
+func named(name string) HandlerFunc {
+	return func(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+		return events.APIGatewayProxyResponse{StatusCode: 200, Body: name + ":" + request.PathParameters["bookId"]}
+	}
+}
+
+func testRouter() *Router {
+	return New(
+		Route{http.MethodGet, "/books/{bookId}", named("getBook")},
+		Route{http.MethodPut, "/books/{bookId}", named("updateBook")},
+		Route{http.MethodGet, "/books/search", named("search")},
+		Route{http.MethodPost, "/books", named("createBook")},
+		Route{http.MethodGet, "/books/{bookId}/editions", named("editions")},
+	)
+}
+
+func TestRoute(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantBody   string
+		wantCode   shared.ErrorCode
+		wantAllow  string
+	}{
+		{name: "parameter", method: "GET", path: "/books/b1", wantStatus: 200, wantBody: "getBook:b1"},
+		{name: "method selects handler", method: "PUT", path: "/books/b1", wantStatus: 200, wantBody: "updateBook:b1"},
+		{name: "literal beats parameter", method: "GET", path: "/books/search", wantStatus: 200, wantBody: "search:"},
+		{name: "trailing slash", method: "POST", path: "/books/", wantStatus: 200, wantBody: "createBook:"},
+		{name: "nested parameter", method: "GET", path: "/books/b2/editions", wantStatus: 200, wantBody: "editions:b2"},
+		{name: "unknown path", method: "GET", path: "/authors", wantStatus: 404, wantCode: shared.CodeRouteNotFound},
+		{name: "too many segments", method: "GET", path: "/books/b1/editions/e1", wantStatus: 404, wantCode: shared.CodeRouteNotFound},
+		{name: "unsupported method", method: "DELETE", path: "/books/b1", wantStatus: 405, wantCode: shared.CodeMethodNotAllowed, wantAllow: "GET, OPTIONS, PUT"},
+		{name: "literal route allows only its methods", method: "PUT", path: "/books/search", wantStatus: 405, wantCode: shared.CodeMethodNotAllowed, wantAllow: "GET, OPTIONS"},
+		{name: "preflight", method: "OPTIONS", path: "/books/b1", wantStatus: 200, wantAllow: "GET, OPTIONS, PUT"},
+		{name: "preflight of unknown path", method: "OPTIONS", path: "/authors", wantStatus: 404, wantCode: shared.CodeRouteNotFound},
+	}
+
+	r := testRouter()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := r.Route(events.APIGatewayProxyRequest{HTTPMethod: tt.method, Path: tt.path})
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantBody != "" && response.Body != tt.wantBody {
+				t.Errorf("body = %q, want %q", response.Body, tt.wantBody)
+			}
+			if allow := response.Headers["Allow"]; allow != tt.wantAllow {
+				t.Errorf("Allow = %q, want %q", allow, tt.wantAllow)
+			}
+			if tt.wantCode != "" {
+				var body struct {
+					Error shared.APIError `json:"error"`
+				}
+				if err := json.Unmarshal([]byte(response.Body), &body); err != nil {
+					t.Fatalf("error body is not JSON: %s", response.Body)
+				}
+				if body.Error.Code != tt.wantCode {
+					t.Errorf("code = %s, want %s", body.Error.Code, tt.wantCode)
+				}
+			}
+		})
+	}
+}
+
+func TestRouteKeepsExistingPathParameters(t *testing.T) {
+	r := New(Route{http.MethodGet, "/books/{bookId}", func(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+		return events.APIGatewayProxyResponse{StatusCode: 200, Body: request.PathParameters["proxy"] + "," + request.PathParameters["bookId"]}
+	}})
+
+	response := r.Route(events.APIGatewayProxyRequest{
+		HTTPMethod:     http.MethodGet,
+		Path:           "/books/b1",
+		PathParameters: map[string]string{"proxy": "books/b1"},
+	})
+	if response.Body != "books/b1,b1" {
+		t.Errorf("body = %q, want %q", response.Body, "books/b1,b1")
+	}
+}
+
+func TestNewPanics(t *testing.T) {
+	tests := []struct {
+		name   string
+		routes []Route
+	}{
+		{"relative pattern", []Route{{http.MethodGet, "books", named("a")}}},
+		{"empty parameter", []Route{{http.MethodGet, "/books/{}", named("a")}}},
+		{"malformed segment", []Route{{http.MethodGet, "/books/{id", named("a")}}},
+		{"duplicate shape", []Route{{http.MethodGet, "/books/{id}", named("a")}, {http.MethodGet, "/books/{bookId}", named("b")}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("New(%v) did not panic", tt.routes)
+				}
+			}()
+			New(tt.routes...)
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/FriedGlue/BookIt/api/pkg/auth"
+	"github.com/FriedGlue/BookIt/api/pkg/router"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// AuthRoutes lists every route served by the Auth Lambda.
+func AuthRoutes() []router.Route {
+	return []router.Route{
+		{Method: http.MethodPost, Pattern: "/auth/signup", Handler: auth.HandleSignUp},
+		{Method: http.MethodPost, Pattern: "/auth/confirm", Handler: auth.HandleConfirmSignUp},
+		{Method: http.MethodPost, Pattern: "/auth/signin", Handler: auth.HandleSignIn},
+		{Method: http.MethodPost, Pattern: "/auth/refresh", Handler: auth.HandleRefresh},
+		{Method: http.MethodPost, Pattern: "/auth/signout", Handler: auth.HandleSignOut},
+	}
+}
+
+var authRouter = router.New(AuthRoutes()...)
+
+// Auth routes the /auth endpoints to the Cognito-backed auth handlers. It is
+// the entry point of the Auth Lambda.
+func Auth(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return Public(ctx, request, authRouter.Route), nil
+}
@@ -0,0 +1,62 @@
+package routes
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/FriedGlue/BookIt/api/pkg/middleware"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// allowedOrigins lists the origins allowed to call the API from a browser,
+// read from the comma-separated ALLOWED_ORIGINS environment variable.
+var allowedOrigins = strings.Split(envOr("ALLOWED_ORIGINS", "http://localhost:5173"), ",")
+
+// adminGroup is the Cognito group allowed to call the /admin routes, read
+// from the ADMIN_GROUP environment variable.
+var adminGroup = envOr("ADMIN_GROUP", "admin")
+
+// Public serves request with handler behind the middleware shared by every
+// route, without requiring authentication. It is used for the /auth routes.
+func Public(ctx context.Context, request events.APIGatewayProxyRequest, handler middleware.Handler) events.APIGatewayProxyResponse {
+	return middleware.Chain(handler, common(ctx)...)(request)
+}
+
+// Authenticated is like Public but first rejects requests that carry no
+// Cognito user.
+func Authenticated(ctx context.Context, request events.APIGatewayProxyRequest, handler middleware.Handler) events.APIGatewayProxyResponse {
+	return middleware.Chain(handler, append(common(ctx), middleware.Authenticate())...)(request)
+}
+
+func common(ctx context.Context) []middleware.Middleware {
+	return []middleware.Middleware{
+		middleware.RequestID(),
+		middleware.Context(ctx),
+		middleware.AccessLog(),
+		middleware.CORS(allowedOrigins),
+		jsonContentType,
+		middleware.Recover(),
+	}
+}
+
+// jsonContentType marks responses as JSON unless the handler said otherwise.
+func jsonContentType(next middleware.Handler) middleware.Handler {
+	return func(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+		response := next(request)
+		if response.Headers == nil {
+			response.Headers = map[string]string{}
+		}
+		if response.Headers["Content-Type"] == "" {
+			response.Headers["Content-Type"] = "application/json"
+		}
+		return response
+	}
+}
+
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,504 @@
+package routes
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/FriedGlue/BookIt/api/pkg/auth"
+	"github.com/FriedGlue/BookIt/api/pkg/dedupe"
+	"github.com/FriedGlue/BookIt/api/pkg/handlers"
+	"github.com/FriedGlue/BookIt/api/pkg/metadata"
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/FriedGlue/BookIt/api/pkg/openapi"
+	"github.com/FriedGlue/BookIt/api/pkg/router"
+	"github.com/FriedGlue/BookIt/api/pkg/shared"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// openAPIPath is where the API description is served.
+const openAPIPath = "/openapi.json"
+
+// OpenAPIRoutes lists the routes that describe the API. They are served by
+// the Orchestrator Lambda but need no authentication.
+func OpenAPIRoutes() []router.Route {
+	return []router.Route{
+		{Method: http.MethodGet, Pattern: openAPIPath, Handler: serveOpenAPI},
+	}
+}
+
+var openAPIRouter = router.New(OpenAPIRoutes()...)
+
+// The document is built on first request rather than when the package
+// loads, so a route added without an entry in endpointDocs fails
+// TestOpenAPIDescribesEveryRoute and, at worst, GET /openapi.json, instead
+// of every Lambda at cold start.
+var (
+	openAPIOnce     sync.Once
+	openAPIDocument *openapi.Document
+	openAPIErr      error
+)
+
+// OpenAPI returns the OpenAPI 3 description of every route.
+func OpenAPI() (*openapi.Document, error) {
+	openAPIOnce.Do(func() {
+		openAPIDocument, openAPIErr = BuildOpenAPI()
+	})
+	return openAPIDocument, openAPIErr
+}
+
+func serveOpenAPI(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	doc, err := OpenAPI()
+	if err != nil {
+		log.Printf("Error building OpenAPI document: %v\n", err)
+		return shared.Error(shared.CodeInternal, "Error building OpenAPI document")
+	}
+	return shared.SuccessResponse(http.StatusOK, doc)
+}
+
+// BuildOpenAPI describes every route in OrchestratorRoutes, AuthRoutes and
+// OpenAPIRoutes using endpointDocs. It fails if a route has no entry in
+// endpointDocs or an entry no longer matches a route.
+func BuildOpenAPI() (*openapi.Document, error) {
+	var all []router.Route
+	all = append(all, OrchestratorRoutes()...)
+	all = append(all, AuthRoutes()...)
+	all = append(all, OpenAPIRoutes()...)
+
+	builder := openapi.NewBuilder("BookIt API", "1.0.0")
+	documented := map[string]bool{}
+	var missing []string
+	for _, route := range all {
+		key := route.Method + " " + route.Pattern
+		endpoint, ok := endpointDocs[key]
+		if !ok {
+			missing = append(missing, key)
+			continue
+		}
+		documented[key] = true
+		builder.Add(route.Method, route.Pattern, endpoint)
+	}
+
+	var stale []string
+	for key := range endpointDocs {
+		if !documented[key] {
+			stale = append(stale, key)
+		}
+	}
+	sort.Strings(stale)
+
+	switch {
+	case len(missing) > 0:
+		return nil, fmt.Errorf("routes: no OpenAPI schema for %s; add them to endpointDocs", strings.Join(missing, ", "))
+	case len(stale) > 0:
+		return nil, fmt.Errorf("routes: endpointDocs describes routes that do not exist: %s", strings.Join(stale, ", "))
+	}
+	return builder.Document(), nil
+}
+
+// pageParams are the query parameters of paginated endpoints.
+var pageParams = []openapi.Param{
+	{Name: "limit", Description: "Items per page, 50 by default and at most 200"},
+	{Name: "cursor", Description: "nextCursor of the previous page"},
+}
+
+// endpointDocs describes each route, keyed by method and pattern exactly as
+// they appear in the route tables.
+var endpointDocs = map[string]openapi.Endpoint{
+	// Books
+	"GET /books": {
+		Tag: "Books", Summary: "List the books, a page at a time",
+		Description: "Books come in the table's scan order, which is stable but not alphabetical.",
+		Params:      pageParams,
+		Response:    models.BookData{}, List: true, Paged: true,
+		Errors: []shared.ErrorCode{shared.CodeInvalidParameter},
+	},
+	"POST /books": {
+		Tag: "Books", Summary: "Create a book from the metadata providers' record of its ISBN",
+		Body: handlers.CreateBookRequest{}, Response: openapi.Message{},
+		Errors: []shared.ErrorCode{shared.CodeInvalidJSON, shared.CodeValidationFailed, shared.CodeBookNotFound, shared.CodeExternalService, shared.CodeServiceUnavailable},
+	},
+	"DELETE /books": {
+		Tag: "Books", Summary: "Delete every book with an ISBN",
+		Params:   []openapi.Param{{Name: "isbn", Required: true}},
+		Response: openapi.Message{},
+		Errors:   []shared.ErrorCode{shared.CodeMissingParameter, shared.CodeInvalidParameter, shared.CodeBookNotFound},
+	},
+	"GET /books/search": {
+		Tag: "Books", Summary: "Search saved books",
+		Description: "Exactly one of the parameters is used, in the order bookId, isbn, openLibraryId, q. " +
+			"q matches words of the title, authors, tags and description, best match first; its last word also matches as a prefix unless q ends in a space. " +
+			"Only q results are paginated.",
+		Params: []openapi.Param{
+			{Name: "bookId"}, {Name: "isbn", Description: "ISBN-10 or ISBN-13; either finds the same book"}, {Name: "openLibraryId"},
+			{Name: "q", Description: "Words to search for"},
+			{Name: "limit", Description: "Results per page for q, 20 by default and at most 50"},
+			{Name: "cursor", Description: "nextCursor of the previous page"},
+		},
+		Response: models.BookData{}, List: true, Paged: true,
+		Errors: []shared.ErrorCode{shared.CodeMissingParameter, shared.CodeInvalidParameter, shared.CodeBookNotFound},
+	},
+	"GET /books/combined-search": {
+		Tag: "Books", Summary: "Search saved books and the metadata providers together",
+		Description: "The database and the catalogue are searched concurrently. partial is set when a source failed or timed out; sources says how each one fared and how long it took.",
+		Params:      []openapi.Param{{Name: "q", Required: true}},
+		Response:    handlers.CombinedSearchResponse{},
+		Errors:      []shared.ErrorCode{shared.CodeMissingParameter},
+	},
+	"POST /books/save-external-book": {
+		Tag: "Books", Summary: "Save a book found by the metadata providers, or return it if already saved",
+		Description: "A work from Open Library is saved with up to 10 of its editions, and the first edition with a page count is returned.",
+		Body:        handlers.SaveExternalBookRequest{}, Response: models.BookData{},
+		Errors: []shared.ErrorCode{shared.CodeBookNotFound, shared.CodeExternalService, shared.CodeServiceUnavailable},
+	},
+	"GET /books/{bookId}": {
+		Tag: "Books", Summary: "Get a book",
+		Response: models.BookData{},
+		Errors:   []shared.ErrorCode{shared.CodeBookNotFound},
+	},
+	"PUT /books/{bookId}": {
+		Tag: "Books", Summary: "Update the fields of a book that are present in the body",
+		Body: handlers.UpdateBookRequest{}, Response: openapi.Message{},
+		Errors: []shared.ErrorCode{shared.CodeInvalidJSON, shared.CodeValidationFailed, shared.CodeBookNotFound},
+	},
+
+	// Works
+	"GET /works/search": {
+		Tag: "Works", Summary: "Search saved books, grouped by work",
+		Description: "q matches like GET /books/search. Works are ranked by their best matching edition, and each lists the editions that matched.",
+		Params: []openapi.Param{
+			{Name: "q", Required: true, Description: "Words to search for"},
+			{Name: "limit", Description: "Works per page, 20 by default and at most 50"},
+			{Name: "cursor", Description: "nextCursor of the previous page"},
+		},
+		Response: handlers.WorkWithEditions{}, List: true, Paged: true,
+		Errors: []shared.ErrorCode{shared.CodeMissingParameter, shared.CodeInvalidParameter},
+	},
+	"GET /works/{workId}": {
+		Tag: "Works", Summary: "Get a work and its saved editions",
+		Response: handlers.WorkWithEditions{},
+		Errors:   []shared.ErrorCode{shared.CodeWorkNotFound},
+	},
+	"GET /works/{workId}/editions": {
+		Tag: "Works", Summary: "List the saved editions of a work",
+		Response: models.BookData{}, List: true,
+		Errors: []shared.ErrorCode{shared.CodeWorkNotFound},
+	},
+
+	// Authors
+	"GET /authors/search": {
+		Tag: "Authors", Summary: "Search saved authors by name",
+		Description: "q matches words of the author's name and alternate names, best match first; its last word also matches as a prefix unless q ends in a space.",
+		Params: []openapi.Param{
+			{Name: "q", Required: true, Description: "Words to search for"},
+			{Name: "limit", Description: "Authors per page, 20 by default and at most 50"},
+			{Name: "cursor", Description: "nextCursor of the previous page"},
+		},
+		Response: models.Author{}, List: true, Paged: true,
+		Errors: []shared.ErrorCode{shared.CodeMissingParameter, shared.CodeInvalidParameter},
+	},
+	"GET /authors/{authorId}": {
+		Tag: "Authors", Summary: "Get an author and their bibliography",
+		Description: "authorId is an Open Library author ID such as OL26320A; authors not saved yet are fetched from the metadata providers. " +
+			"The bibliography lists the providers' works by the author, then works only known from saved books. " +
+			"Each entry has the caller's status for it (currentlyReading, read or toBeRead), the rating given and the custom lists holding it.",
+		Response: handlers.AuthorResponse{},
+		Errors:   []shared.ErrorCode{shared.CodeAuthorNotFound, shared.CodeExternalService, shared.CodeServiceUnavailable},
+	},
+
+	// Series
+	"POST /series": {
+		Tag: "Series", Summary: "Create a series",
+		Description: "Each entry names its work by workId, or by bookId for a saved edition, and has a position in reading order; " +
+			"positions may be fractional, such as 2.5 for a novella, and must be unique. The title is taken from the work or book when left out. " +
+			"Series are shared by every user, so this requires the admin group.",
+		Body: handlers.CreateSeriesRequest{}, Response: models.Series{}, Status: http.StatusCreated,
+		Errors: []shared.ErrorCode{shared.CodeForbidden, shared.CodeInvalidJSON, shared.CodeValidationFailed, shared.CodeBookNotFound, shared.CodeWorkNotFound},
+	},
+	"GET /series/next": {
+		Tag: "Series", Summary: "Get the next unread book in each series being read",
+		Description: "Lists every series with an entry on the caller's read list or currently reading, with the first entry in reading order that is neither. " +
+			"next is absent once the caller has read or is reading every entry.",
+		Params:   []openapi.Param{{Name: "novellas", Description: "false to never suggest entries at fractional positions"}},
+		Response: handlers.SeriesProgress{}, List: true,
+		Errors: []shared.ErrorCode{shared.CodeInvalidParameter, shared.CodeProfileNotFound},
+	},
+	"GET /series/{seriesId}": {
+		Tag: "Series", Summary: "Get a series and its entries in reading order",
+		Response: models.Series{},
+		Errors:   []shared.ErrorCode{shared.CodeSeriesNotFound},
+	},
+	"PUT /series/{seriesId}": {
+		Tag: "Series", Summary: "Rename a series or replace its entries",
+		Description: "Only the fields present are changed; entries replaces the whole list and follows the rules of POST /series. Requires the admin group.",
+		Body:        handlers.UpdateSeriesRequest{}, Response: models.Series{},
+		Errors: []shared.ErrorCode{shared.CodeForbidden, shared.CodeInvalidJSON, shared.CodeValidationFailed, shared.CodeSeriesNotFound, shared.CodeBookNotFound, shared.CodeWorkNotFound},
+	},
+	"DELETE /series/{seriesId}": {
+		Tag: "Series", Summary: "Delete a series",
+		Description: "The books in the series are kept. Requires the admin group.",
+		Response:    openapi.Message{},
+		Errors:      []shared.ErrorCode{shared.CodeForbidden, shared.CodeSeriesNotFound},
+	},
+
+	// Currently reading
+	"GET /currently-reading": {
+		Tag: "Currently reading", Summary: "List the books being read",
+		Response: models.CurrentlyReadingItem{}, List: true,
+		Errors: []shared.ErrorCode{shared.CodeProfileNotFound},
+	},
+	"POST /currently-reading": {
+		Tag: "Currently reading", Summary: "Start reading a book",
+		Body: handlers.NewCurrentlyReadingItemRequest{}, Response: openapi.Message{}, Status: http.StatusCreated,
+		Errors: []shared.ErrorCode{shared.CodeProfileNotFound, shared.CodeBookNotFound, shared.CodeBookAlreadyInList, shared.CodeExternalService, shared.CodeServiceUnavailable, shared.CodeConcurrentModification},
+	},
+	"PUT /currently-reading": {
+		Tag: "Currently reading", Summary: "Record progress on a book being read",
+		Body: handlers.UpdateCurrentlyReadingRequest{}, Response: openapi.Message{},
+		Errors: []shared.ErrorCode{shared.CodeProfileNotFound, shared.CodeListItemNotFound, shared.CodeConcurrentModification},
+	},
+	"DELETE /currently-reading": {
+		Tag: "Currently reading", Summary: "Stop reading a book",
+		Params:   []openapi.Param{{Name: "bookId", Required: true}},
+		Response: openapi.Message{},
+		Errors:   []shared.ErrorCode{shared.CodeMissingParameter, shared.CodeProfileNotFound, shared.CodeListItemNotFound, shared.CodeConcurrentModification},
+	},
+	"POST /currently-reading/start-reading": {
+		Tag: "Currently reading", Summary: "Move a book from a list to currently reading",
+		Body: handlers.StartReadingRequest{}, Response: openapi.Message{},
+		Errors: []shared.ErrorCode{shared.CodeValidationFailed, shared.CodeProfileNotFound, shared.CodeBookNotFound, shared.CodeListNotFound, shared.CodeListItemNotFound, shared.CodeBookAlreadyInList, shared.CodeConcurrentModification},
+	},
+	"POST /currently-reading/finish-reading": {
+		Tag: "Currently reading", Summary: "Move a book from currently reading to the read list",
+		Body: handlers.FinishReadingRequest{}, Response: openapi.Message{},
+		Errors: []shared.ErrorCode{shared.CodeProfileNotFound, shared.CodeListItemNotFound, shared.CodeConcurrentModification},
+	},
+	"PUT /currently-reading/edition": {
+		Tag: "Currently reading", Summary: "Read another edition of a book being read",
+		Description: "The edition must be of the same work. Progress keeps the same fraction of the book read.",
+		Body:        handlers.SwitchEditionRequest{}, Response: models.CurrentlyReadingItem{},
+		Errors: []shared.ErrorCode{shared.CodeInvalidJSON, shared.CodeValidationFailed, shared.CodeInvalidParameter, shared.CodeProfileNotFound, shared.CodeBookNotFound, shared.CodeListItemNotFound, shared.CodeBookAlreadyInList, shared.CodeConcurrentModification},
+	},
+
+	// Lists
+	"GET /list": {
+		Tag: "Lists", Summary: "Get all lists, or one list",
+		Description: "Lists are paged in display order. Without listType the first page of every list is returned, and nextCursors holds the cursor for each list with more. " +
+			"Custom lists are keyed by shelf name, and shelves describes each shelf; a shelf is shown in its defaultSort order, and a smart shelf holds the books its rules pick. " +
+			"With listType (toBeRead, read or a shelf ID or name) the body is {\"items\": [...], \"nextCursor\": ...} holding a page of that list.",
+		Params:   append([]openapi.Param{{Name: "listType"}}, pageParams...),
+		Response: handlers.AllListsResponse{},
+		Errors:   []shared.ErrorCode{shared.CodeInvalidParameter, shared.CodeProfileNotFound, shared.CodeListNotFound},
+	},
+	"POST /list": {
+		Tag: "Lists", Summary: "Add a book to a list, or create a custom bookshelf",
+		Description: "With the listName parameter an empty private shelf of that name is created and the body is ignored; POST /shelves creates one with its settings. " +
+			"Adding a book to a shelf name that does not exist creates the shelf, if the name follows the rules of POST /shelves; an unknown shelf ID is LIST_NOT_FOUND. " +
+			"A book already on toBeRead or the shelf is BOOK_ALREADY_IN_LIST, and a smart shelf takes no books, as with op add of POST /list/bulk.",
+		Params: []openapi.Param{{Name: "listName"}},
+		Body:   handlers.AddToListRequest{}, Response: openapi.Message{}, Status: http.StatusCreated,
+		Errors: []shared.ErrorCode{shared.CodeInvalidParameter, shared.CodeProfileNotFound, shared.CodeBookNotFound, shared.CodeListNotFound, shared.CodeBookAlreadyInList, shared.CodeListAlreadyExists, shared.CodeConcurrentModification},
+	},
+	"PUT /list": {
+		Tag: "Lists", Summary: "Update a book in a list",
+		Description: "order moves the book to that index of the list, as PUT /list/order does.",
+		Body:        handlers.UpdateListItemRequest{}, Response: openapi.Message{},
+		Errors: []shared.ErrorCode{shared.CodeInvalidJSON, shared.CodeValidationFailed, shared.CodeProfileNotFound, shared.CodeListItemNotFound, shared.CodeConcurrentModification},
+	},
+	"DELETE /list": {
+		Tag: "Lists", Summary: "Remove a book from a list, or delete a custom bookshelf",
+		Description: "With the listName parameter the shelf of that ID or name is deleted; otherwise listType and bookId are required.",
+		Params:      []openapi.Param{{Name: "listName"}, {Name: "listType"}, {Name: "bookId"}},
+		Response:    openapi.Message{},
+		Errors:      []shared.ErrorCode{shared.CodeMissingParameter, shared.CodeProfileNotFound, shared.CodeListItemNotFound, shared.CodeConcurrentModification},
+	},
+	"POST /list/move": {
+		Tag: "Lists", Summary: "Move a book from one list to another",
+		Description: "fromList and toList are toBeRead, read, currentlyReading or a shelf ID or name; a shelf that does not exist yet is created. " +
+			"The move is a single profile write, and the date added, thumbnail, title and authors carry over. " +
+			"Moving a book to currentlyReading starts it from the first page, and moving it out records it as finished (to read) or removed in the reading log. " +
+			"rating and review may only be given when moving to read.",
+		Body: handlers.MoveListItemRequest{}, Response: openapi.Message{},
+		Errors: []shared.ErrorCode{shared.CodeInvalidJSON, shared.CodeValidationFailed, shared.CodeProfileNotFound, shared.CodeListNotFound, shared.CodeListItemNotFound, shared.CodeBookAlreadyInList, shared.CodeConcurrentModification},
+	},
+	"PUT /list/order": {
+		Tag: "Lists", Summary: "Reorder a list",
+		Description: "Either moves bookId to index position (past the end moves it to the end), or puts the list in the order of bookIds, which must hold every book of the list. " +
+			"The whole list is renumbered in one profile write, so each book's order is its index. Returns the reordered list.",
+		Body: handlers.ReorderListRequest{}, Response: models.CustomListItem{}, List: true,
+		Errors: []shared.ErrorCode{shared.CodeInvalidJSON, shared.CodeValidationFailed, shared.CodeInvalidParameter, shared.CodeProfileNotFound, shared.CodeListNotFound, shared.CodeListItemNotFound, shared.CodeConcurrentModification},
+	},
+	"POST /list/bulk": {
+		Tag: "Lists", Summary: "Apply a batch of list operations in one write",
+		Description: "Up to 500 operations, applied in order in a single profile write, of which at most 99 may move a book into or out of currentlyReading, since their reading log entries are written with the profile. op add puts the saved book on listType, failing with BOOK_ALREADY_IN_LIST if it is on toBeRead or the shelf already; " +
+			"remove takes it off listType, which may be currentlyReading; move moves it from fromList to toList, like POST /list/move; " +
+			"tag puts it on each of shelves it is not on yet, creating missing ones, and leaves it where it is. " +
+			"Each operation succeeds or fails on its own: results holds, in request order, whether it applied and the error if not. A failed operation changes nothing.",
+		Body: handlers.BulkListRequest{}, Response: handlers.BulkListResponse{},
+		Errors: []shared.ErrorCode{shared.CodeInvalidJSON, shared.CodeValidationFailed, shared.CodeProfileNotFound, shared.CodeConcurrentModification},
+	},
+
+	// Shelves
+	"GET /shelves": {
+		Tag: "Shelves", Summary: "List the caller's shelves",
+		Description: "Shelves come in the order they were created, without their books; GET /list?listType= with the shelf ID or name pages through those.",
+		Response:    handlers.ShelfResponse{}, List: true,
+		Errors: []shared.ErrorCode{shared.CodeProfileNotFound},
+	},
+	"POST /shelves": {
+		Tag: "Shelves", Summary: "Create a shelf",
+		Description: "visibility is private, friends or public, and private when left out. defaultSort is the order GET /list shows the shelf in: " +
+			"manual (the order set with PUT /list/order, the default), title, author or dateAdded (newest first). " +
+			"The name must not be toBeRead, read or currentlyReading, nor the name or ID of another shelf. " +
+			"With rules it is a smart shelf, showing the books on the caller's lists that match all (or, with match any, any) of the conditions. " +
+			"Conditions on list, tag and author take a value and eq or ne; conditions on rating, readYear, loggedYear, pageCount and authorReadCount take a number and eq, ne, gte or lte. " +
+			"Books cannot be added to, moved to or from, or reordered on a smart shelf.",
+		Body: handlers.CreateShelfRequest{}, Response: handlers.ShelfResponse{}, Status: http.StatusCreated,
+		Errors: []shared.ErrorCode{shared.CodeInvalidJSON, shared.CodeValidationFailed, shared.CodeProfileNotFound, shared.CodeListAlreadyExists, shared.CodeConcurrentModification},
+	},
+	"PUT /shelves/{shelfId}": {
+		Tag: "Shelves", Summary: "Rename a shelf or change its settings",
+		Description: "Only the fields present are changed, and an empty description or icon clears it. The shelf keeps its ID and books when renamed. " +
+			"rules replaces a smart shelf's rules, or makes an empty shelf smart. shelfId may also be the shelf's name.",
+		Body: handlers.UpdateShelfRequest{}, Response: handlers.ShelfResponse{},
+		Errors: []shared.ErrorCode{shared.CodeInvalidJSON, shared.CodeValidationFailed, shared.CodeInvalidParameter, shared.CodeProfileNotFound, shared.CodeListNotFound, shared.CodeListAlreadyExists, shared.CodeConcurrentModification},
+	},
+	"DELETE /shelves/{shelfId}": {
+		Tag: "Shelves", Summary: "Delete a shelf and its books",
+		Description: "shelfId may also be the shelf's name.",
+		Response:    openapi.Message{},
+		Errors:      []shared.ErrorCode{shared.CodeProfileNotFound, shared.CodeListNotFound, shared.CodeConcurrentModification},
+	},
+
+	// Profile
+	"GET /getProfileExact": {
+		Tag: "Profile", Summary: "Get the profile as stored",
+		Response: models.Profile{},
+		Errors:   []shared.ErrorCode{shared.CodeProfileNotFound},
+	},
+	"GET /profile": {
+		Tag: "Profile", Summary: "Get the profile with reading challenge progress brought up to date",
+		Response: models.Profile{},
+		Errors:   []shared.ErrorCode{shared.CodeProfileNotFound, shared.CodeConcurrentModification},
+	},
+	"POST /profile": {
+		Tag: "Profile", Summary: "Create or update the profile",
+		Body: models.Profile{}, Response: openapi.Message{},
+		Errors: []shared.ErrorCode{shared.CodeProfileNotFound, shared.CodeConcurrentModification},
+	},
+	"PUT /profile": {
+		Tag: "Profile", Summary: "Create or update the profile",
+		Body: models.Profile{}, Response: openapi.Message{},
+		Errors: []shared.ErrorCode{shared.CodeProfileNotFound, shared.CodeConcurrentModification},
+	},
+	"DELETE /profile": {
+		Tag: "Profile", Summary: "Delete the profile and reading log",
+		Response: openapi.Message{},
+		Errors:   []shared.ErrorCode{shared.CodeProfileNotFound},
+	},
+
+	// Reading log
+	"GET /reading-log": {
+		Tag: "Reading log", Summary: "List reading log entries, oldest first",
+		Params: []openapi.Param{
+			{Name: "from", Description: "RFC3339 time or YYYY-MM-DD date"},
+			{Name: "to", Description: "RFC3339 time or YYYY-MM-DD date, inclusive"},
+			pageParams[0], pageParams[1],
+		},
+		Response: models.ReadingLogItem{}, List: true, Paged: true,
+		Errors: []shared.ErrorCode{shared.CodeInvalidParameter},
+	},
+	"PUT /reading-log": {
+		Tag: "Reading log", Summary: "Update a reading log entry",
+		Body: handlers.UpdateReadingLogItemRequest{}, Response: openapi.Message{},
+		Errors: []shared.ErrorCode{shared.CodeReadingLogEntryNotFound},
+	},
+	"DELETE /reading-log": {
+		Tag: "Reading log", Summary: "Delete a reading log entry",
+		Params:   []openapi.Param{{Name: "readingLogId", Required: true}},
+		Response: openapi.Message{}, Status: http.StatusCreated,
+		Errors: []shared.ErrorCode{shared.CodeMissingParameter, shared.CodeReadingLogEntryNotFound},
+	},
+
+	// Challenges
+	"GET /challenges": {
+		Tag: "Challenges", Summary: "List reading challenges by start date",
+		Params:   pageParams,
+		Response: models.ReadingChallenge{}, List: true, Paged: true,
+		Errors: []shared.ErrorCode{shared.CodeInvalidParameter, shared.CodeProfileNotFound},
+	},
+	"POST /challenges": {
+		Tag: "Challenges", Summary: "Create a reading challenge",
+		Body: handlers.CreateChallengeRequest{}, Response: models.ReadingChallenge{}, Status: http.StatusCreated,
+		Errors: []shared.ErrorCode{shared.CodeProfileNotFound, shared.CodeConcurrentModification},
+	},
+	"PUT /challenges/{id}": {
+		Tag: "Challenges", Summary: "Recalculate a challenge's progress from the reading log",
+		Body: handlers.UpdateChallengeRequest{}, Response: models.ReadingChallenge{}, List: true,
+		Errors: []shared.ErrorCode{shared.CodeProfileNotFound, shared.CodeChallengeNotFound, shared.CodeConcurrentModification},
+	},
+	"DELETE /challenges/{id}": {
+		Tag: "Challenges", Summary: "Delete a reading challenge",
+		Response: openapi.Message{},
+		Errors:   []shared.ErrorCode{shared.CodeProfileNotFound, shared.CodeChallengeNotFound, shared.CodeConcurrentModification},
+	},
+
+	// Admin
+	"GET /admin/books/duplicates": {
+		Tag: "Admin", Summary: "List groups of books stored more than once",
+		Description: "Books are grouped when they share an ISBN (in either form) or an Open Library ID, or have nearly the same title and share an author. " +
+			"Groups found only by title and author are guesses and list titleAuthor among their reasons. Requires the admin group.",
+		Response: dedupe.Group{}, List: true,
+		Errors: []shared.ErrorCode{shared.CodeForbidden},
+	},
+	"POST /admin/books/merge": {
+		Tag: "Admin", Summary: "Merge duplicate books into one",
+		Description: "Missing metadata of the canonical book is filled in from the duplicates, every list, currently reading, reading log and series reference to them is pointed at it, and they are deleted. " +
+			"Safe to repeat if it fails part way. Requires the admin group.",
+		Body: handlers.MergeBooksRequest{}, Response: dedupe.Result{},
+		Errors: []shared.ErrorCode{shared.CodeForbidden, shared.CodeInvalidJSON, shared.CodeValidationFailed, shared.CodeInvalidParameter, shared.CodeBookNotFound, shared.CodeConcurrentModification},
+	},
+	"GET /admin/cache/stats": {
+		Tag: "Admin", Summary: "Count metadata cache hits and misses",
+		Description: "Hits and misses of each cached metadata provider, and of each cache tier, since the serving Lambda instance started; instances count separately. Requires the admin group.",
+		Response:    metadata.CacheStats{},
+		Errors:      []shared.ErrorCode{shared.CodeForbidden},
+	},
+
+	// Auth
+	"POST /auth/signup": {
+		Tag: "Auth", Summary: "Sign up", Public: true,
+		Body: auth.SignUpPayload{}, Response: openapi.Message{},
+		Errors: []shared.ErrorCode{shared.CodeMissingParameter, shared.CodeUsernameTaken, shared.CodeInvalidPassword, shared.CodeInvalidParameter, shared.CodeExternalService},
+	},
+	"POST /auth/confirm": {
+		Tag: "Auth", Summary: "Confirm a sign up with the emailed code", Public: true,
+		Body: auth.ConfirmSignUpPayload{}, Response: openapi.Message{},
+		Errors: []shared.ErrorCode{shared.CodeMissingParameter, shared.CodeInvalidConfirmationCode, shared.CodeExternalService},
+	},
+	"POST /auth/signin": {
+		Tag: "Auth", Summary: "Sign in", Public: true,
+		Description: "Also sets the refresh token in an HttpOnly refreshToken cookie.",
+		Body:        auth.SignInPayload{}, Response: auth.Tokens{},
+		Errors: []shared.ErrorCode{shared.CodeMissingParameter, shared.CodeAuthFailed, shared.CodeUserNotConfirmed, shared.CodeExternalService},
+	},
+	"POST /auth/refresh": {
+		Tag: "Auth", Summary: "Exchange a refresh token for new ID and access tokens", Public: true,
+		Params:   []openapi.Param{{Name: "Authorization", In: "header", Required: true, Description: "Bearer <RefreshToken>"}},
+		Response: auth.Tokens{},
+		Errors:   []shared.ErrorCode{shared.CodeUnauthorized, shared.CodeAuthFailed, shared.CodeExternalService},
+	},
+	"POST /auth/signout": {
+		Tag: "Auth", Summary: "Clear the refresh token cookie", Public: true,
+		Response: openapi.Message{},
+	},
+
+	// API description
+	"GET /openapi.json": {
+		Tag: "Meta", Summary: "This OpenAPI document", Public: true,
+		Response: map[string]interface{}{},
+	},
+}
@@ -0,0 +1,40 @@
+package routes
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/FriedGlue/BookIt/api/pkg/router"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestOpenAPIDescribesEveryRoute(t *testing.T) {
+	doc, err := BuildOpenAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tables := map[string][]router.Route{
+		"OrchestratorRoutes": OrchestratorRoutes(),
+		"AuthRoutes":         AuthRoutes(),
+		"OpenAPIRoutes":      OpenAPIRoutes(),
+	}
+	for table, routes := range tables {
+		for _, route := range routes {
+			if _, ok := doc.Paths[route.Pattern][strings.ToLower(route.Method)]; !ok {
+				t.Errorf("%s: %s %s is missing from the OpenAPI document", table, route.Method, route.Pattern)
+			}
+		}
+	}
+}
+
+func TestServeOpenAPI(t *testing.T) {
+	response := serveOpenAPI(events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: openAPIPath})
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s returned %d: %s", openAPIPath, response.StatusCode, response.Body)
+	}
+	if !strings.Contains(response.Body, `"openapi"`) {
+		t.Errorf("GET %s did not return an OpenAPI document: %.200s", openAPIPath, response.Body)
+	}
+}
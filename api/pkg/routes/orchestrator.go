@@ -0,0 +1,127 @@
+// Package routes maps API Gateway proxy requests onto the handlers. The Lambda
+// binaries and the local development server share these routing functions.
+package routes
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/FriedGlue/BookIt/api/pkg/handlers"
+	"github.com/FriedGlue/BookIt/api/pkg/middleware"
+	"github.com/FriedGlue/BookIt/api/pkg/router"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// OrchestratorRoutes lists every route served by the Orchestrator Lambda.
+func OrchestratorRoutes() []router.Route {
+	return []router.Route{
+		// Books
+		{Method: http.MethodGet, Pattern: "/books", Handler: handlers.GetBooks},
+		{Method: http.MethodPost, Pattern: "/books", Handler: handlers.CreateBook},
+		{Method: http.MethodDelete, Pattern: "/books", Handler: handlers.DeleteBook},
+		{Method: http.MethodGet, Pattern: "/books/search", Handler: handlers.SearchBooks},
+		{Method: http.MethodGet, Pattern: "/books/combined-search", Handler: handlers.CombinedSearch},
+		{Method: http.MethodPost, Pattern: "/books/save-external-book", Handler: handlers.SaveExternalBook},
+		{Method: http.MethodGet, Pattern: "/books/{bookId}", Handler: handlers.GetBooks},
+		{Method: http.MethodPut, Pattern: "/books/{bookId}", Handler: handlers.UpdateBook},
+
+		// Works
+		{Method: http.MethodGet, Pattern: "/works/search", Handler: handlers.SearchWorks},
+		{Method: http.MethodGet, Pattern: "/works/{workId}", Handler: handlers.GetWork},
+		{Method: http.MethodGet, Pattern: "/works/{workId}/editions", Handler: handlers.GetWorkEditions},
+
+		// Authors
+		{Method: http.MethodGet, Pattern: "/authors/search", Handler: handlers.SearchAuthors},
+		{Method: http.MethodGet, Pattern: "/authors/{authorId}", Handler: handlers.GetAuthor},
+
+		// Series are shared by every user, so only admins edit them
+		{Method: http.MethodPost, Pattern: "/series", Handler: adminOnly(handlers.CreateSeries)},
+		{Method: http.MethodGet, Pattern: "/series/next", Handler: handlers.GetNextInSeries},
+		{Method: http.MethodGet, Pattern: "/series/{seriesId}", Handler: handlers.GetSeries},
+		{Method: http.MethodPut, Pattern: "/series/{seriesId}", Handler: adminOnly(handlers.UpdateSeries)},
+		{Method: http.MethodDelete, Pattern: "/series/{seriesId}", Handler: adminOnly(handlers.DeleteSeries)},
+
+		// Currently reading
+		{Method: http.MethodGet, Pattern: "/currently-reading", Handler: handlers.GetCurrentlyReading},
+		{Method: http.MethodPost, Pattern: "/currently-reading", Handler: handlers.AddToCurrentlyReading},
+		{Method: http.MethodPut, Pattern: "/currently-reading", Handler: handlers.UpdateCurrentlyReading},
+		{Method: http.MethodDelete, Pattern: "/currently-reading", Handler: handlers.RemoveFromCurrentlyReading},
+		{Method: http.MethodPost, Pattern: "/currently-reading/start-reading", Handler: handlers.StartReading},
+		{Method: http.MethodPost, Pattern: "/currently-reading/finish-reading", Handler: handlers.FinishReading},
+		{Method: http.MethodPut, Pattern: "/currently-reading/edition", Handler: handlers.SwitchEdition},
+
+		// Lists
+		{Method: http.MethodGet, Pattern: "/list", Handler: handlers.GetList},
+		{Method: http.MethodPost, Pattern: "/list", Handler: createListItemOrBookshelf},
+		{Method: http.MethodPut, Pattern: "/list", Handler: handlers.UpdateListItem},
+		{Method: http.MethodDelete, Pattern: "/list", Handler: deleteListItemOrBookshelf},
+		{Method: http.MethodPost, Pattern: "/list/move", Handler: handlers.MoveListItem},
+		{Method: http.MethodPut, Pattern: "/list/order", Handler: handlers.ReorderList},
+		{Method: http.MethodPost, Pattern: "/list/bulk", Handler: handlers.BulkUpdateLists},
+
+		// Shelves
+		{Method: http.MethodGet, Pattern: "/shelves", Handler: handlers.GetShelves},
+		{Method: http.MethodPost, Pattern: "/shelves", Handler: handlers.CreateShelf},
+		{Method: http.MethodPut, Pattern: "/shelves/{shelfId}", Handler: handlers.UpdateShelf},
+		{Method: http.MethodDelete, Pattern: "/shelves/{shelfId}", Handler: handlers.DeleteShelf},
+
+		// Profile
+		{Method: http.MethodGet, Pattern: "/getProfileExact", Handler: handlers.GetProfile},
+		{Method: http.MethodGet, Pattern: "/profile", Handler: handlers.GetProfileAndUpdateReadingChallenges},
+		{Method: http.MethodPost, Pattern: "/profile", Handler: handlers.CreateOrUpdateProfile},
+		{Method: http.MethodPut, Pattern: "/profile", Handler: handlers.CreateOrUpdateProfile},
+		{Method: http.MethodDelete, Pattern: "/profile", Handler: handlers.DeleteProfile},
+
+		// Reading log
+		{Method: http.MethodGet, Pattern: "/reading-log", Handler: handlers.GetReadingLog},
+		{Method: http.MethodPut, Pattern: "/reading-log", Handler: handlers.UpdateReadingLogItem},
+		{Method: http.MethodDelete, Pattern: "/reading-log", Handler: handlers.DeleteReadingLogItem},
+
+		// Challenges
+		{Method: http.MethodGet, Pattern: "/challenges", Handler: handlers.GetChallenges},
+		{Method: http.MethodPost, Pattern: "/challenges", Handler: handlers.CreateChallenge},
+		{Method: http.MethodPut, Pattern: "/challenges/{id}", Handler: handlers.UpdateChallenge},
+		{Method: http.MethodDelete, Pattern: "/challenges/{id}", Handler: handlers.DeleteChallenge},
+
+		// Admin
+		{Method: http.MethodGet, Pattern: "/admin/books/duplicates", Handler: adminOnly(handlers.FindDuplicateBooks)},
+		{Method: http.MethodPost, Pattern: "/admin/books/merge", Handler: adminOnly(handlers.MergeBooks)},
+		{Method: http.MethodGet, Pattern: "/admin/cache/stats", Handler: adminOnly(handlers.GetCacheStats)},
+	}
+}
+
+var orchestratorRouter = router.New(OrchestratorRoutes()...)
+
+// Orchestrator routes API requests for books, profiles, lists, reading logs and
+// challenges to their handlers, and serves the OpenAPI document without
+// authentication. It is the entry point of the Orchestrator Lambda and of the
+// local development server.
+func Orchestrator(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if request.Path == openAPIPath {
+		return Public(ctx, request, openAPIRouter.Route), nil
+	}
+	return Authenticated(ctx, request, orchestratorRouter.Route), nil
+}
+
+// createListItemOrBookshelf creates a custom bookshelf when the listName query
+// parameter is given and otherwise adds a book to a list.
+func createListItemOrBookshelf(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	if request.QueryStringParameters["listName"] != "" {
+		return handlers.CreateCustomBookshelf(request)
+	}
+	return handlers.AddToList(request)
+}
+
+// adminOnly restricts a route to members of the admin Cognito group.
+func adminOnly(handler router.HandlerFunc) router.HandlerFunc {
+	return router.HandlerFunc(middleware.RequireGroup(adminGroup)(middleware.Handler(handler)))
+}
+
+// deleteListItemOrBookshelf deletes a custom bookshelf when the listName query
+// parameter is given and otherwise removes a book from a list.
+func deleteListItemOrBookshelf(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	if request.QueryStringParameters["listName"] != "" {
+		return handlers.DeleteCustomBookshelf(request)
+	}
+	return handlers.DeleteListItem(request)
+}
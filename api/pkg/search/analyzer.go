@@ -0,0 +1,214 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+)
+
+// Field weights: a word in the title says more about a book than the same
+// word in its description.
+const (
+	titleWeight       = 4.0
+	authorWeight      = 3.0
+	tagWeight         = 1.5
+	descriptionWeight = 0.5
+)
+
+// Prefixes of title and author words are indexed under "^<prefix>" so the
+// word being typed can be matched before it is finished. Prefixes shorter
+// than minPrefix match too much to be useful; words longer than maxPrefix
+// are indexed up to maxPrefix characters.
+const (
+	prefixMarker = "^"
+	minPrefix    = 2
+	maxPrefix    = 12
+)
+
+// maxDescriptionCount caps how often a description word is counted, so a
+// long blurb repeating a word cannot outrank a title.
+const maxDescriptionCount = 3
+
+// identifierTags are tag prefixes that hold IDs and dates rather than words
+// worth searching.
+var identifierTags = []string{"OpenLibrary:", "OpenLibraryEdition:", "Google:", "LCCN:", "OCLC:", "Goodreads:", "Published:", "ISBN:"}
+
+// Terms returns the index terms of book with their weights: the stemmed
+// words of its title, authors, tags and description, and the prefixes of its
+// title and author words.
+func Terms(book models.BookData) map[string]float64 {
+	terms := make(map[string]float64)
+	addWords(terms, book.Title, titleWeight, true)
+	for _, author := range book.Authors {
+		addWords(terms, author, authorWeight, true)
+	}
+	for _, tag := range book.Tags {
+		if isIdentifierTag(tag) {
+			continue
+		}
+		if _, value, ok := strings.Cut(tag, ":"); ok {
+			tag = value
+		}
+		addWords(terms, tag, tagWeight, false)
+	}
+
+	counts := make(map[string]int)
+	for _, word := range Tokenize(book.Description) {
+		if isStopWord(word) {
+			continue
+		}
+		term := Stem(word)
+		if counts[term] < maxDescriptionCount {
+			counts[term]++
+		}
+	}
+	for term, count := range counts {
+		terms[term] = max(terms[term], descriptionWeight*float64(count))
+	}
+	return terms
+}
+
+// AuthorTerms returns the index terms of author: the stemmed words of their
+// name and alternate names, and the prefixes of those words. The name
+// weighs like a book title, alternate names like a book's authors.
+func AuthorTerms(author models.Author) map[string]float64 {
+	terms := make(map[string]float64)
+	addWords(terms, author.Name, titleWeight, true)
+	for _, name := range author.AlternateNames {
+		addWords(terms, name, authorWeight, true)
+	}
+	return terms
+}
+
+// addWords adds the stemmed words of text to terms at weight, keeping the
+// higher weight of a term already there, and their prefixes if asked.
+func addWords(terms map[string]float64, text string, weight float64, withPrefixes bool) {
+	for _, word := range Tokenize(text) {
+		if withPrefixes {
+			addPrefixes(terms, word, weight)
+		}
+		if isStopWord(word) {
+			continue
+		}
+		terms[Stem(word)] = max(terms[Stem(word)], weight)
+	}
+}
+
+func addPrefixes(terms map[string]float64, word string, weight float64) {
+	runes := []rune(word)
+	for n := minPrefix; n <= min(len(runes), maxPrefix); n++ {
+		term := prefixMarker + string(runes[:n])
+		terms[term] = max(terms[term], weight)
+	}
+}
+
+func isIdentifierTag(tag string) bool {
+	for _, prefix := range identifierTags {
+		if strings.HasPrefix(tag, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Tokenize splits text into lowercase words with their diacritics removed,
+// so "Brontë" and "bronte" are the same word. Apostrophes are dropped rather
+// than splitting a word, so "Ender's" is "enders".
+func Tokenize(text string) []string {
+	var words []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			words = append(words, word.String())
+			word.Reset()
+		}
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case r == '\'' || r == '’' || unicode.Is(unicode.Mn, r):
+			// Apostrophes and combining marks (the accents of decomposed
+			// text) are dropped without ending the word.
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if folded, ok := foldings[r]; ok {
+				word.WriteString(folded)
+			} else {
+				word.WriteRune(r)
+			}
+		default:
+			flush()
+		}
+	}
+	flush()
+	return words
+}
+
+// foldings maps accented Latin letters to their plain equivalents.
+var foldings = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'æ': "ae", 'ç': "c", 'ć': "c", 'č': "c", 'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ğ': "g", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'į': "i", 'ı': "i",
+	'ł': "l", 'ľ': "l", 'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o", 'œ': "oe",
+	'ř': "r", 'ś': "s", 'š': "s", 'ş': "s", 'ß': "ss", 'ť': "t", 'ţ': "t", 'þ': "th",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u", 'ų': "u",
+	'ý': "y", 'ÿ': "y", 'ź': "z", 'ż': "z", 'ž': "z",
+}
+
+// Stem reduces an English word to a stem shared by its common inflections,
+// so "stories" and "story", or "dragons" and "dragon", match each other. It
+// is deliberately light: it only has to map a query word and an indexed word
+// to the same term, not produce a real word.
+func Stem(word string) string {
+	if len(word) < 4 || strings.ContainsAny(word, "0123456789") {
+		return word
+	}
+
+	switch {
+	case strings.HasSuffix(word, "sses"):
+		word = word[:len(word)-2]
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		word = word[:len(word)-3] + "y"
+	case strings.HasSuffix(word, "ss"), strings.HasSuffix(word, "us"), strings.HasSuffix(word, "is"):
+	case strings.HasSuffix(word, "s"):
+		word = word[:len(word)-1]
+	}
+
+	for _, suffix := range []string{"ing", "ed"} {
+		stem, ok := strings.CutSuffix(word, suffix)
+		if ok && len(stem) >= 3 && strings.ContainsAny(stem, "aeiouy") {
+			word = undouble(stem)
+			break
+		}
+	}
+
+	if len(word) >= 4 && strings.HasSuffix(word, "e") {
+		word = word[:len(word)-1]
+	}
+	return word
+}
+
+// undouble drops the last letter of a stem ending in a doubled consonant,
+// so "running" stems like "run".
+func undouble(stem string) string {
+	n := len(stem)
+	if n >= 2 && stem[n-1] < unicode.MaxASCII && stem[n-1] == stem[n-2] && !strings.ContainsRune("aeiouylsz", rune(stem[n-1])) {
+		return stem[:n-1]
+	}
+	return stem
+}
+
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"by": true, "for": true, "from": true, "in": true, "into": true, "is": true, "it": true,
+	"of": true, "on": true, "or": true, "that": true, "the": true, "this": true, "to": true,
+	"was": true, "with": true,
+}
+
+// isStopWord reports whether word is too common to be worth indexing on its
+// own. Stop words in titles and author names are still indexed as prefixes.
+func isStopWord(word string) bool {
+	return stopWords[word]
+}
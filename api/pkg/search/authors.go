@@ -0,0 +1,97 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/FriedGlue/BookIt/api/pkg/pagination"
+	"github.com/FriedGlue/BookIt/api/pkg/store"
+)
+
+// AuthorIndex searches saved authors by name, with the same analysis and
+// ranking as Index. It needs a SearchIndexStore of its own, so authors do
+// not count as books when ranking book searches.
+type AuthorIndex struct {
+	terms   store.SearchIndexStore
+	authors store.AuthorStore
+}
+
+// NewAuthorIndex returns an index kept in terms, which loads the authors it
+// finds from authors.
+func NewAuthorIndex(terms store.SearchIndexStore, authors store.AuthorStore) *AuthorIndex {
+	return &AuthorIndex{terms: terms, authors: authors}
+}
+
+// AuthorPage is one page of author search results, best match first. Next
+// is the pagination key to resume after, empty on the last page.
+type AuthorPage struct {
+	Authors []models.Author
+	Next    string
+}
+
+// Add indexes author, replacing whatever was indexed for them before.
+func (x *AuthorIndex) Add(author models.Author) error {
+	return x.terms.Replace(author.AuthorID, AuthorTerms(author))
+}
+
+// Search returns the authors whose names match every word of query, ranked
+// and paged like Index.Search.
+func (x *AuthorIndex) Search(ctx context.Context, query string, req pagination.Request) (*AuthorPage, error) {
+	offset, limit, err := pageBounds(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ranked, err := rank(ctx, x.terms, query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &AuthorPage{Authors: []models.Author{}}
+	if offset >= len(ranked) {
+		return page, nil
+	}
+	end := min(offset+limit, len(ranked))
+	for _, authorID := range ranked[offset:end] {
+		author, err := x.authors.Get(authorID)
+		if errors.Is(err, store.ErrNotFound) {
+			log.Printf("Author index lists missing author %s", authorID)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		page.Authors = append(page.Authors, *author)
+	}
+	if end < len(ranked) {
+		page.Next = strconv.Itoa(end)
+	}
+	return page, nil
+}
+
+// IndexedAuthorStore is a store.AuthorStore that keeps an AuthorIndex up to
+// date with every author it writes. Like IndexedBookStore, a failure to
+// update the index is logged and the write still succeeds.
+type IndexedAuthorStore struct {
+	store.AuthorStore
+	index *AuthorIndex
+}
+
+// NewIndexedAuthorStore wraps authors so that writes through it update
+// index.
+func NewIndexedAuthorStore(authors store.AuthorStore, index *AuthorIndex) *IndexedAuthorStore {
+	return &IndexedAuthorStore{AuthorStore: authors, index: index}
+}
+
+func (s *IndexedAuthorStore) Put(author *models.Author) error {
+	if err := s.AuthorStore.Put(author); err != nil {
+		return err
+	}
+	if err := s.index.Add(*author); err != nil {
+		log.Printf("Error indexing author %s: %v", author.AuthorID, err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package search
+
+import (
+	"log"
+
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/FriedGlue/BookIt/api/pkg/store"
+)
+
+// IndexedBookStore is a store.BookStore that keeps an Index up to date with
+// every book it writes. The book store stays the source of truth: if the
+// index cannot be updated the write still succeeds and the failure is
+// logged, and the reindex-books command repairs the index.
+type IndexedBookStore struct {
+	store.BookStore
+	index *Index
+}
+
+// NewIndexedBookStore wraps books so that writes through it update index.
+func NewIndexedBookStore(books store.BookStore, index *Index) *IndexedBookStore {
+	return &IndexedBookStore{BookStore: books, index: index}
+}
+
+func (s *IndexedBookStore) Put(book *models.BookData) error {
+	if err := s.BookStore.Put(book); err != nil {
+		return err
+	}
+	s.add(*book)
+	return nil
+}
+
+func (s *IndexedBookStore) Update(bookID string, mutate func(*models.BookData) error) (*models.BookData, error) {
+	book, err := s.BookStore.Update(bookID, mutate)
+	if err != nil {
+		return nil, err
+	}
+	s.add(*book)
+	return book, nil
+}
+
+func (s *IndexedBookStore) Delete(bookID string) error {
+	if err := s.BookStore.Delete(bookID); err != nil {
+		return err
+	}
+	if err := s.index.Remove(bookID); err != nil {
+		log.Printf("Error removing book %s from the search index: %v", bookID, err)
+	}
+	return nil
+}
+
+func (s *IndexedBookStore) add(book models.BookData) {
+	if err := s.index.Add(book); err != nil {
+		log.Printf("Error indexing book %s: %v", book.BookID, err)
+	}
+}
@@ -0,0 +1,316 @@
+// Package search finds saved books by the words in their title, authors,
+// tags and description. Books are broken into terms by Terms and kept in an
+// inverted index (a store.SearchIndexStore) that IndexedBookStore updates on
+// every write; Index.Search ranks the books matching every word of a query.
+// AuthorIndex does the same for the names of saved authors.
+package search
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/FriedGlue/BookIt/api/pkg/pagination"
+	"github.com/FriedGlue/BookIt/api/pkg/store"
+)
+
+// Page sizes for Search, smaller than other endpoints' as each result is
+// loaded from the book store.
+const (
+	DefaultLimit = 20
+	MaxLimit     = 50
+)
+
+// maxQueryWords bounds the index lookups made for one query.
+const maxQueryWords = 8
+
+// prefixDiscount scales the score of a word matched only as the prefix of a
+// longer one, so "tolk" ranks a book by Tolkien above one that merely
+// mentions a "tolkienesque" world when both match otherwise.
+const prefixDiscount = 0.6
+
+// Index searches the books in an inverted index.
+type Index struct {
+	terms store.SearchIndexStore
+	books store.BookStore
+}
+
+// NewIndex returns an index kept in terms, which loads the books it finds
+// from books.
+func NewIndex(terms store.SearchIndexStore, books store.BookStore) *Index {
+	return &Index{terms: terms, books: books}
+}
+
+// Page is one page of search results, best match first. Next is the
+// pagination key to resume after, empty on the last page.
+type Page struct {
+	Books []models.BookData
+	Next  string
+}
+
+// Add indexes book, replacing whatever was indexed for it before.
+func (x *Index) Add(book models.BookData) error {
+	return x.terms.Replace(book.BookID, Terms(book))
+}
+
+// Remove drops bookID from the index.
+func (x *Index) Remove(bookID string) error {
+	return x.terms.Remove(bookID)
+}
+
+// Search returns the books matching every word of query, ranked by how
+// strongly and in which fields they match and by how rare each word is. The
+// last word also matches as a prefix unless query ends in a space, so results
+// can be shown while the user types. It gives up with ctx's error once ctx
+// is done.
+func (x *Index) Search(ctx context.Context, query string, req pagination.Request) (*Page, error) {
+	// The ranking is recomputed for every page, so pages are keyed by
+	// position in it.
+	offset, limit, err := pageBounds(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ranked, err := rank(ctx, x.terms, query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Page{Books: []models.BookData{}}
+	if offset >= len(ranked) {
+		return page, nil
+	}
+	end := min(offset+limit, len(ranked))
+	books, err := x.loadMany(ctx, ranked[offset:end])
+	if err != nil {
+		return nil, err
+	}
+	page.Books = append(page.Books, books...)
+	if end < len(ranked) {
+		page.Next = strconv.Itoa(end)
+	}
+	return page, nil
+}
+
+// WorkPage is one page of search results grouped by work, best match first.
+// Next is the pagination key to resume after, empty on the last page.
+type WorkPage struct {
+	Works []WorkMatch
+	Next  string
+}
+
+// WorkMatch is a work with the editions of it that matched, best first.
+type WorkMatch struct {
+	WorkID   string
+	Editions []models.BookData
+}
+
+// maxWorkScan bounds the matching books loaded to group one page of results
+// by work.
+const maxWorkScan = 500
+
+// loadBatch is how many ranked books SearchWorks loads at a time, the most
+// one BatchGetItem call returns.
+const loadBatch = 100
+
+// SearchWorks is Search with the results grouped by work (see
+// models.BookData.WorkKey), each work ranked by its best matching edition.
+// A work lists the editions that rank high enough to be loaded for its
+// page; others may be missing.
+func (x *Index) SearchWorks(ctx context.Context, query string, req pagination.Request) (*WorkPage, error) {
+	// Pages are keyed by position in the ranking of works.
+	offset, limit, err := pageBounds(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ranked, err := rank(ctx, x.terms, query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Group books until the work after this page turns up, which shows
+	// there is a next page.
+	var works []*WorkMatch
+	byWork := make(map[string]*WorkMatch)
+	scan := ranked[:min(len(ranked), maxWorkScan)]
+	for start := 0; start < len(scan) && len(works) <= offset+limit; start += loadBatch {
+		books, err := x.loadMany(ctx, scan[start:min(start+loadBatch, len(scan))])
+		if err != nil {
+			return nil, err
+		}
+		for _, book := range books {
+			if len(works) > offset+limit {
+				break
+			}
+			key := book.WorkKey()
+			match, ok := byWork[key]
+			if !ok {
+				match = &WorkMatch{WorkID: key}
+				byWork[key] = match
+				works = append(works, match)
+			}
+			match.Editions = append(match.Editions, book)
+		}
+	}
+
+	page := &WorkPage{Works: []WorkMatch{}}
+	if offset >= len(works) {
+		return page, nil
+	}
+	end := min(offset+limit, len(works))
+	for _, match := range works[offset:end] {
+		page.Works = append(page.Works, *match)
+	}
+	if end < len(works) {
+		page.Next = strconv.Itoa(end)
+	}
+	return page, nil
+}
+
+// pageBounds reads the offset and limit of a page of ranked results.
+func pageBounds(req pagination.Request) (offset, limit int, err error) {
+	if req.After != "" {
+		if offset, err = strconv.Atoi(req.After); err != nil || offset < 0 {
+			return 0, 0, pagination.ErrInvalidCursor
+		}
+	}
+	limit = req.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	return offset, limit, nil
+}
+
+// loadMany returns the books with the given IDs in the same order, in one
+// GetMany call. Books that no longer exist are left out. The book store
+// takes no context, so ctx is only checked before the call.
+func (x *Index) loadMany(ctx context.Context, bookIDs []string) ([]models.BookData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	found, err := x.books.GetMany(bookIDs)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]models.BookData, len(found))
+	for _, book := range found {
+		byID[book.BookID] = book
+	}
+
+	books := make([]models.BookData, 0, len(bookIDs))
+	for _, bookID := range bookIDs {
+		book, ok := byID[bookID]
+		if !ok {
+			// The book was deleted without its postings; skip it rather
+			// than fail the search.
+			log.Printf("Search index lists missing book %s", bookID)
+			continue
+		}
+		books = append(books, book)
+	}
+	return books, nil
+}
+
+// rank returns the IDs of the documents in terms matching query, best
+// first.
+func rank(ctx context.Context, terms store.SearchIndexStore, query string) ([]string, error) {
+	words := Tokenize(query)
+	if len(words) > maxQueryWords {
+		words = words[:maxQueryWords]
+	}
+	typing := strings.TrimRightFunc(query, unicode.IsSpace) == query
+
+	// Each query word is matched by a group of books, each with a weight.
+	var groups []map[string]float64
+	for i, word := range words {
+		asPrefix := typing && i == len(words)-1
+		if isStopWord(word) && !asPrefix {
+			continue
+		}
+		group, err := match(ctx, terms, word, asPrefix)
+		if err != nil {
+			return nil, err
+		}
+		if len(group) == 0 {
+			return nil, nil
+		}
+		groups = append(groups, group)
+	}
+	if len(groups) == 0 {
+		return nil, nil
+	}
+
+	documents, err := terms.DocumentCount(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Start from the smallest group: a book must be in every one.
+	sort.Slice(groups, func(i, j int) bool { return len(groups[i]) < len(groups[j]) })
+	scores := make(map[string]float64, len(groups[0]))
+	for bookID := range groups[0] {
+		scores[bookID] = 0
+	}
+	for _, group := range groups {
+		idf := inverseDocumentFrequency(max(documents, len(group)), len(group))
+		for bookID := range scores {
+			weight, ok := group[bookID]
+			if !ok {
+				delete(scores, bookID)
+				continue
+			}
+			scores[bookID] += weight * idf
+		}
+	}
+
+	ranked := make([]string, 0, len(scores))
+	for bookID := range scores {
+		ranked = append(ranked, bookID)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if scores[a] != scores[b] {
+			return scores[a] > scores[b]
+		}
+		return a < b
+	})
+	return ranked, nil
+}
+
+// match returns the documents containing word and its weight in each. A
+// word matched as a prefix also finds the words it begins, at a discount.
+func match(ctx context.Context, terms store.SearchIndexStore, word string, asPrefix bool) (map[string]float64, error) {
+	group := make(map[string]float64)
+	if !isStopWord(word) {
+		postings, err := terms.Postings(ctx, Stem(word))
+		if err != nil {
+			return nil, err
+		}
+		group = postings
+	}
+
+	if asPrefix && len([]rune(word)) >= minPrefix {
+		prefix := []rune(word)
+		prefix = prefix[:min(len(prefix), maxPrefix)]
+		postings, err := terms.Postings(ctx, prefixMarker+string(prefix))
+		if err != nil {
+			return nil, err
+		}
+		for bookID, weight := range postings {
+			group[bookID] = max(group[bookID], weight*prefixDiscount)
+		}
+	}
+	return group, nil
+}
+
+// inverseDocumentFrequency weighs a word found in matches of documents
+// books: the rarer it is, the more a match on it counts.
+func inverseDocumentFrequency(documents, matches int) float64 {
+	return math.Log(1 + (float64(documents-matches)+0.5)/(float64(matches)+0.5))
+}
@@ -0,0 +1,196 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/FriedGlue/BookIt/api/pkg/pagination"
+	"github.com/FriedGlue/BookIt/api/pkg/store"
+)
+
+// testIndex indexes books through an IndexedBookStore, as the handlers do.
+func testIndex(t *testing.T, books ...models.BookData) (*Index, *store.MemoryBookStore) {
+	t.Helper()
+	bookStore := store.NewMemoryBookStore()
+	index := NewIndex(store.NewMemorySearchIndexStore(), bookStore)
+	indexed := NewIndexedBookStore(bookStore, index)
+	for i := range books {
+		if err := indexed.Put(&books[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return index, bookStore
+}
+
+var ctx = context.Background()
+
+var testBooks = []models.BookData{
+	{BookID: "hobbit", WorkID: "w-hobbit", Title: "The Hobbit", Authors: []string{"J. R. R. Tolkien"}},
+	{BookID: "hobbit-annotated", WorkID: "w-hobbit", Title: "The Annotated Hobbit", Authors: []string{"J. R. R. Tolkien", "Douglas A. Anderson"}},
+	{BookID: "fellowship", Title: "The Fellowship of the Ring", Authors: []string{"J. R. R. Tolkien"}},
+	{BookID: "biography", Title: "Tolkien: A Biography", Authors: []string{"Humphrey Carpenter"}},
+	{BookID: "dragons", Title: "Dragons", Authors: []string{"Someone Else"}, Description: "A tolkienesque world of hobbits and rings."},
+	{BookID: "ring", Title: "Ringworld", Authors: []string{"Larry Niven"}, Tags: []string{"Subject:Science fiction", "OpenLibrary:OL1W"}},
+}
+
+func TestSearch(t *testing.T) {
+	index, _ := testIndex(t, testBooks...)
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"title outranks author", "tolkien ", []string{"biography", "fellowship", "hobbit", "hobbit-annotated"}},
+		{"every word must match", "hobbit tolkien ", []string{"hobbit", "hobbit-annotated"}},
+		{"title outranks description", "hobbits ", []string{"hobbit", "hobbit-annotated", "dragons"}},
+		{"last word matches as prefix", "tolk", []string{"biography", "fellowship", "hobbit", "hobbit-annotated"}},
+		{"finished word does not", "tolk ", nil},
+		{"prefix ranks below the whole word", "ring", []string{"fellowship", "ring", "dragons"}},
+		{"tags are searched", "science fiction ", []string{"ring"}},
+		{"identifier tags are not", "ol1w ", nil},
+		{"stop words are ignored", "the hobbit ", []string{"hobbit", "hobbit-annotated", "dragons"}},
+		{"no match", "dune ", nil},
+		{"empty query", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := index.Search(ctx, tt.query, pagination.Request{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, book := range page.Books {
+				got = append(got, book.BookID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchPages(t *testing.T) {
+	index, books := testIndex(t, testBooks...)
+
+	first, err := index.Search(ctx, "tolkien ", pagination.Request{Limit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.Books) != 3 || first.Next != "3" {
+		t.Fatalf("first page has %d books and next %q, want 3 and \"3\"", len(first.Books), first.Next)
+	}
+
+	// A book deleted behind the index's back is skipped, not an error.
+	if err := books.Delete("hobbit-annotated"); err != nil {
+		t.Fatal(err)
+	}
+	second, err := index.Search(ctx, "tolkien ", pagination.Request{Limit: 3, After: first.Next})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(second.Books) != 0 || second.Next != "" {
+		t.Errorf("second page = %+v, want empty and last", second)
+	}
+
+	if _, err := index.Search(ctx, "tolkien", pagination.Request{After: "x"}); !errors.Is(err, pagination.ErrInvalidCursor) {
+		t.Errorf("bad cursor returned %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestSearchWorks(t *testing.T) {
+	index, _ := testIndex(t, testBooks...)
+
+	tests := []struct {
+		name     string
+		query    string
+		req      pagination.Request
+		want     []string
+		wantNext string
+	}{
+		{"editions are grouped", "hobbit ", pagination.Request{}, []string{"w-hobbit:hobbit,hobbit-annotated", "dragons:dragons"}, ""},
+		{"first page", "tolkien ", pagination.Request{Limit: 2}, []string{"biography:biography", "fellowship:fellowship"}, "2"},
+		{"last page", "tolkien ", pagination.Request{Limit: 2, After: "2"}, []string{"w-hobbit:hobbit,hobbit-annotated"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := index.SearchWorks(ctx, tt.query, tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, work := range page.Works {
+				var ids []string
+				for _, edition := range work.Editions {
+					ids = append(ids, edition.BookID)
+				}
+				got = append(got, work.WorkID+":"+strings.Join(ids, ","))
+			}
+			if !slices.Equal(got, tt.want) || page.Next != tt.wantNext {
+				t.Errorf("SearchWorks(%q) = %v next %q, want %v next %q", tt.query, got, page.Next, tt.want, tt.wantNext)
+			}
+		})
+	}
+}
+
+// countingBookStore counts the reads made through it.
+type countingBookStore struct {
+	store.BookStore
+	gets, batches int
+}
+
+func (s *countingBookStore) Get(bookID string) (*models.BookData, error) {
+	s.gets++
+	return s.BookStore.Get(bookID)
+}
+
+func (s *countingBookStore) GetMany(bookIDs []string) ([]models.BookData, error) {
+	s.batches++
+	return s.BookStore.GetMany(bookIDs)
+}
+
+func TestSearchLoadsBooksInBatches(t *testing.T) {
+	var books []models.BookData
+	for i := 0; i < 2*loadBatch+10; i++ {
+		books = append(books, models.BookData{BookID: fmt.Sprintf("b%03d", i), Title: "Dune"})
+	}
+	index, bookStore := testIndex(t, books...)
+	counting := &countingBookStore{BookStore: bookStore}
+	index.books = counting
+
+	page, err := index.Search(ctx, "dune ", pagination.Request{Limit: MaxLimit})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Books) != MaxLimit || counting.gets != 0 || counting.batches != 1 {
+		t.Errorf("Search loaded %d books with %d gets and %d batches, want %d in 1 batch", len(page.Books), counting.gets, counting.batches, MaxLimit)
+	}
+
+	counting.batches = 0
+	works, err := index.SearchWorks(ctx, "dune ", pagination.Request{Limit: loadBatch, After: strconv.Itoa(loadBatch)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(works.Works) != loadBatch || works.Next == "" || counting.gets != 0 || counting.batches != 3 {
+		t.Errorf("SearchWorks returned %d works with %d gets and %d batches, want %d works in 3 batches", len(works.Works), counting.gets, counting.batches, loadBatch)
+	}
+}
+
+func TestSearchGivesUpWhenContextIsDone(t *testing.T) {
+	index, _ := testIndex(t, testBooks...)
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	if _, err := index.Search(cancelled, "tolkien", pagination.Request{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Search with a cancelled context = %v, want context.Canceled", err)
+	}
+	if _, err := index.SearchWorks(cancelled, "tolkien", pagination.Request{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("SearchWorks with a cancelled context = %v, want context.Canceled", err)
+	}
+}
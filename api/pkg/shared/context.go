@@ -0,0 +1,88 @@
+package shared
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type contextKey int
+
+const userIDKey contextKey = iota
+
+// contextAuthorizerKey is where WithContext keeps the context in the
+// request's Authorizer map. Handlers only receive the proxy request, so this
+// is how middleware passes request-scoped values such as the user ID to
+// them: the context travels with the request, and a value decoded from an
+// incoming request can never be a context.Context, so callers cannot supply
+// one.
+const contextAuthorizerKey = "bookit:context"
+
+// WithContext returns a copy of the request carrying ctx, for the handlers
+// it is passed on to. The request itself is left untouched.
+func WithContext(request events.APIGatewayProxyRequest, ctx context.Context) events.APIGatewayProxyRequest {
+	authorizer := make(map[string]interface{}, len(request.RequestContext.Authorizer)+1)
+	for key, value := range request.RequestContext.Authorizer {
+		authorizer[key] = value
+	}
+	authorizer[contextAuthorizerKey] = ctx
+	request.RequestContext.Authorizer = authorizer
+	return request
+}
+
+// RequestContext returns the context the request carries, or
+// context.Background() if it carries none.
+func RequestContext(request events.APIGatewayProxyRequest) context.Context {
+	if ctx, ok := request.RequestContext.Authorizer[contextAuthorizerKey].(context.Context); ok {
+		return ctx
+	}
+	return context.Background()
+}
+
+// WithUserID returns a copy of ctx carrying the authenticated user's ID.
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDKey, userID)
+}
+
+// UserID returns the ID of the user making the request, as stored by the
+// authentication middleware. Outside the middleware chain it falls back to
+// the Cognito claims on the request, and returns "" if there are none.
+func UserID(request events.APIGatewayProxyRequest) string {
+	if userID, ok := RequestContext(request).Value(userIDKey).(string); ok {
+		return userID
+	}
+	claims, _ := request.RequestContext.Authorizer["claims"].(map[string]interface{})
+	userID, _ := claims["sub"].(string)
+	return userID
+}
+
+// Groups returns the Cognito groups of the user making the request. The
+// authorizer passes the cognito:groups claim as a string, either one group,
+// a comma-separated list or "[a b]", and some tools pass a JSON array.
+func Groups(request events.APIGatewayProxyRequest) []string {
+	claims, _ := request.RequestContext.Authorizer["claims"].(map[string]interface{})
+	switch value := claims["cognito:groups"].(type) {
+	case string:
+		return strings.FieldsFunc(strings.Trim(value, "[]"), func(r rune) bool { return r == ',' || r == ' ' })
+	case []interface{}:
+		var groups []string
+		for _, group := range value {
+			if name, ok := group.(string); ok {
+				groups = append(groups, name)
+			}
+		}
+		return groups
+	}
+	return nil
+}
+
+// InGroup reports whether the user making the request is in group.
+func InGroup(request events.APIGatewayProxyRequest, group string) bool {
+	for _, name := range Groups(request) {
+		if name == group {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package shared
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ErrorCode is a stable, machine-readable identifier for an API error. Clients
+// switch on the code; the accompanying message is for humans and may change.
+type ErrorCode string
+
+const (
+	// Malformed or incomplete requests.
+	CodeBadRequest       ErrorCode = "BAD_REQUEST"
+	CodeInvalidJSON      ErrorCode = "INVALID_JSON"
+	CodeMissingParameter ErrorCode = "MISSING_PARAMETER"
+	CodeInvalidParameter ErrorCode = "INVALID_PARAMETER"
+	CodeValidationFailed ErrorCode = "VALIDATION_FAILED"
+
+	// Authentication.
+	CodeUnauthorized            ErrorCode = "UNAUTHORIZED"
+	CodeForbidden               ErrorCode = "FORBIDDEN"
+	CodeAuthFailed              ErrorCode = "AUTH_FAILED"
+	CodeUserNotConfirmed        ErrorCode = "USER_NOT_CONFIRMED"
+	CodeUsernameTaken           ErrorCode = "USERNAME_TAKEN"
+	CodeInvalidPassword         ErrorCode = "INVALID_PASSWORD"
+	CodeInvalidConfirmationCode ErrorCode = "INVALID_CONFIRMATION_CODE"
+
+	// Routing.
+	CodeRouteNotFound    ErrorCode = "ROUTE_NOT_FOUND"
+	CodeMethodNotAllowed ErrorCode = "METHOD_NOT_ALLOWED"
+
+	// Missing resources.
+	CodeProfileNotFound         ErrorCode = "PROFILE_NOT_FOUND"
+	CodeBookNotFound            ErrorCode = "BOOK_NOT_FOUND"
+	CodeListNotFound            ErrorCode = "LIST_NOT_FOUND"
+	CodeListItemNotFound        ErrorCode = "LIST_ITEM_NOT_FOUND"
+	CodeChallengeNotFound       ErrorCode = "CHALLENGE_NOT_FOUND"
+	CodeReadingLogEntryNotFound ErrorCode = "READING_LOG_ENTRY_NOT_FOUND"
+	CodeWorkNotFound            ErrorCode = "WORK_NOT_FOUND"
+	CodeAuthorNotFound          ErrorCode = "AUTHOR_NOT_FOUND"
+	CodeSeriesNotFound          ErrorCode = "SERIES_NOT_FOUND"
+
+	// Conflicts with the current state.
+	CodeBookAlreadyInList      ErrorCode = "BOOK_ALREADY_IN_LIST"
+	CodeListAlreadyExists      ErrorCode = "LIST_ALREADY_EXISTS"
+	CodeConcurrentModification ErrorCode = "CONCURRENT_MODIFICATION"
+
+	// Failures on our side or upstream.
+	CodeExternalService    ErrorCode = "EXTERNAL_SERVICE_ERROR"
+	CodeServiceUnavailable ErrorCode = "SERVICE_UNAVAILABLE"
+	CodeInternal           ErrorCode = "INTERNAL_ERROR"
+)
+
+// errorStatus is the HTTP status returned with each error code.
+var errorStatus = map[ErrorCode]int{
+	CodeBadRequest:       400,
+	CodeInvalidJSON:      400,
+	CodeMissingParameter: 400,
+	CodeInvalidParameter: 400,
+	CodeValidationFailed: 422,
+
+	CodeUnauthorized:            401,
+	CodeForbidden:               403,
+	CodeAuthFailed:              401,
+	CodeUserNotConfirmed:        403,
+	CodeUsernameTaken:           409,
+	CodeInvalidPassword:         400,
+	CodeInvalidConfirmationCode: 400,
+
+	CodeRouteNotFound:    404,
+	CodeMethodNotAllowed: 405,
+
+	CodeProfileNotFound:         404,
+	CodeBookNotFound:            404,
+	CodeListNotFound:            404,
+	CodeListItemNotFound:        404,
+	CodeChallengeNotFound:       404,
+	CodeReadingLogEntryNotFound: 404,
+	CodeWorkNotFound:            404,
+	CodeAuthorNotFound:          404,
+	CodeSeriesNotFound:          404,
+
+	CodeBookAlreadyInList:      409,
+	CodeListAlreadyExists:      409,
+	CodeConcurrentModification: 409,
+
+	CodeExternalService:    502,
+	CodeServiceUnavailable: 503,
+	CodeInternal:           500,
+}
+
+// Status returns the HTTP status for the code, or 500 for unknown codes.
+func (c ErrorCode) Status() int {
+	if status, ok := errorStatus[c]; ok {
+		return status
+	}
+	return 500
+}
+
+// Codes returns every error code in the catalogue, sorted.
+func Codes() []ErrorCode {
+	codes := make([]ErrorCode, 0, len(errorStatus))
+	for code := range errorStatus {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	return codes
+}
+
+// APIError is an error that carries the code, message and optional details
+// to send to the client. Handlers and store mutators return it to abort with
+// a specific error.
+type APIError struct {
+	Code    ErrorCode   `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+// NewError returns an APIError with the given code and message.
+func NewError(code ErrorCode, message string) *APIError {
+	return &APIError{Code: code, Message: message}
+}
+
+// Errorf returns an APIError with a formatted message.
+func Errorf(code ErrorCode, format string, args ...interface{}) *APIError {
+	return NewError(code, fmt.Sprintf(format, args...))
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// WithDetails returns a copy of e carrying details, which are sent to the
+// client as-is.
+func (e *APIError) WithDetails(details interface{}) *APIError {
+	copy := *e
+	copy.Details = details
+	return &copy
+}
@@ -31,33 +31,61 @@ func DynamoDBClient() *dynamodb.DynamoDB {
 	return dynamodb.New(sess)
 }
 
-// ErrorResponse is a helper to generate an APIGatewayProxyResponse with a given status and message.
-func ErrorResponse(status int, message string) events.APIGatewayProxyResponse {
-	body, _ := json.Marshal(map[string]string{
-		"error": message,
-	})
-	return events.APIGatewayProxyResponse{
-		StatusCode: status,
-		Headers: map[string]string{
-			"Content-Type":                 "application/json",
-			"Access-Control-Allow-Origin":  "*",
-			"Access-Control-Allow-Methods": "OPTIONS,POST,GET,PUT,DELETE",
-			"Access-Control-Allow-Headers": "Content-Type,Authorization",
-		},
-		Body: string(body),
-	}
+// ErrorResponse builds the response for an API error. Every error body has
+// the shape {"error": {"code": ..., "message": ..., "details": ...}}.
+func ErrorResponse(apiErr *APIError) events.APIGatewayProxyResponse {
+	return jsonResponse(apiErr.Code.Status(), map[string]*APIError{"error": apiErr})
 }
 
-// SuccessResponse is a helper to generate an APIGatewayProxyResponse with a given status and body.
+// Error is shorthand for ErrorResponse(NewError(code, message)).
+func Error(code ErrorCode, message string) events.APIGatewayProxyResponse {
+	return ErrorResponse(NewError(code, message))
+}
+
+// SuccessResponse builds a JSON response. body must marshal to a JSON object;
+// use ListResponse for collections and MessageResponse for confirmations.
 func SuccessResponse(status int, body interface{}) events.APIGatewayProxyResponse {
-	jsonBody, _ := json.Marshal(body)
+	return jsonResponse(status, body)
+}
+
+// ListResponse returns items wrapped as {"items": [...]}, never null.
+func ListResponse[T any](status int, items []T) events.APIGatewayProxyResponse {
+	if items == nil {
+		items = []T{}
+	}
+	return jsonResponse(status, map[string][]T{"items": items})
+}
+
+// PageResponse returns one page of a collection as {"items": [...],
+// "nextCursor": "..."}. nextCursor is left out on the last page.
+func PageResponse[T any](status int, items []T, nextCursor string) events.APIGatewayProxyResponse {
+	if items == nil {
+		items = []T{}
+	}
+	return jsonResponse(status, page[T]{Items: items, NextCursor: nextCursor})
+}
+
+type page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// MessageResponse returns a confirmation as {"message": "..."}.
+func MessageResponse(status int, message string) events.APIGatewayProxyResponse {
+	return jsonResponse(status, map[string]string{"message": message})
+}
+
+func jsonResponse(status int, body interface{}) events.APIGatewayProxyResponse {
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		log.Printf("Error marshalling response: %v\n", err)
+		status = 500
+		jsonBody = []byte(`{"error":{"code":"INTERNAL_ERROR","message":"Error marshalling response"}}`)
+	}
 	return events.APIGatewayProxyResponse{
 		StatusCode: status,
 		Headers: map[string]string{
-			"Content-Type":                 "application/json",
-			"Access-Control-Allow-Origin":  "*",
-			"Access-Control-Allow-Methods": "OPTIONS,POST,GET,PUT,DELETE",
-			"Access-Control-Allow-Headers": "Content-Type,Authorization",
+			"Content-Type": "application/json",
 		},
 		Body: string(jsonBody),
 	}
@@ -0,0 +1,294 @@
+// Package smartshelf works out the books of smart shelves: shelves whose
+// books are not put on them but picked by rules, such as "read in 2025 and
+// rated at least 4" or "by an author I have read three books by".
+//
+// Rules are evaluated over the books on the user's lists, with facts from
+// the lists themselves, the saved books and the reading log. Books that are
+// on no list never appear on a smart shelf.
+package smartshelf
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+)
+
+// Data is what rules are evaluated over.
+type Data struct {
+	Profile *models.Profile
+
+	// Books are the saved books of the profile's lists, keyed by ID. Books
+	// that are not saved only have what the lists keep about them.
+	Books map[string]models.BookData
+
+	// ReadingLog is only needed by rules on the loggedYear field.
+	ReadingLog []models.ReadingLogItem
+}
+
+// BookIDs returns the IDs of the books on the profile's lists, which Data
+// needs the saved books of.
+func BookIDs(profile *models.Profile) []string {
+	var ids []string
+	for _, item := range candidates(profile) {
+		ids = append(ids, item.BookID)
+	}
+	return ids
+}
+
+// UsesReadingLog reports whether any of the profile's smart shelves needs
+// the reading log.
+func UsesReadingLog(profile *models.Profile) bool {
+	for _, shelf := range profile.Lists.Shelves {
+		if !shelf.Smart() {
+			continue
+		}
+		for _, rule := range shelf.Rules.Conditions {
+			if rule.Field == models.LoggedYearField {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Evaluate returns the books matching the rules, in the order they were
+// found on the lists: currently reading, to be read, read, then the static
+// shelves. Order is each book's index.
+func Evaluate(rules models.ShelfRules, data Data) []models.CustomListItem {
+	facts := gatherFacts(data)
+
+	items := []models.CustomListItem{}
+	for _, item := range candidates(data.Profile) {
+		if matches(rules, facts.of(item.BookID)) {
+			item.Order = len(items)
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// candidates returns each book on the profile's lists once, as a smart
+// shelf shows it, from the first list holding it.
+func candidates(profile *models.Profile) []models.CustomListItem {
+	var result []models.CustomListItem
+	seen := make(map[string]bool)
+	add := func(item models.CustomListItem) {
+		if item.BookID == "" || seen[item.BookID] {
+			return
+		}
+		seen[item.BookID] = true
+		item.Order = 0
+		result = append(result, item)
+	}
+
+	for _, item := range profile.CurrentlyReading {
+		add(models.CustomListItem{
+			BookID: item.Book.BookID, Thumbnail: item.Book.Thumbnail, AddedDate: item.StartedDate,
+			Title: item.Book.Title, Authors: item.Book.Authors,
+		})
+	}
+	for _, item := range profile.Lists.ToBeRead {
+		add(models.CustomListItem{
+			BookID: item.BookID, Thumbnail: item.Thumbnail, AddedDate: item.AddedDate,
+			Title: item.Title, Authors: item.Authors,
+		})
+	}
+	for _, item := range profile.Lists.Read {
+		add(models.CustomListItem{
+			BookID: item.BookID, Thumbnail: item.Thumbnail, AddedDate: item.CompletedDate,
+			Title: item.Title, Authors: item.Authors,
+		})
+	}
+	for _, shelf := range profile.Lists.Shelves {
+		if shelf.Smart() {
+			continue
+		}
+		for _, item := range shelf.Items {
+			add(item)
+		}
+	}
+	return result
+}
+
+// bookFacts are what rules can test about one book.
+type bookFacts struct {
+	lists           map[string]bool // lowercased list and shelf names and shelf IDs
+	tags            map[string]bool // lowercased
+	authors         []string        // lowercased
+	rating          int
+	readYears       []int
+	loggedYears     []int
+	pageCount       int
+	authorReadCount int
+}
+
+type factSet map[string]*bookFacts
+
+func (f factSet) of(bookID string) *bookFacts {
+	if f[bookID] == nil {
+		f[bookID] = &bookFacts{lists: map[string]bool{}, tags: map[string]bool{}}
+	}
+	return f[bookID]
+}
+
+// gatherFacts collects the facts of every book on the lists.
+func gatherFacts(data Data) factSet {
+	profile := data.Profile
+	facts := make(factSet)
+	setAuthors := func(bookID string, authors []string) {
+		f := facts.of(bookID)
+		if len(f.authors) > 0 {
+			return
+		}
+		if book, ok := data.Books[bookID]; ok && len(book.Authors) > 0 {
+			authors = book.Authors
+		}
+		for _, author := range authors {
+			f.authors = append(f.authors, strings.ToLower(strings.TrimSpace(author)))
+		}
+	}
+
+	for _, item := range profile.CurrentlyReading {
+		facts.of(item.Book.BookID).lists["currentlyreading"] = true
+		setAuthors(item.Book.BookID, item.Book.Authors)
+	}
+	for _, item := range profile.Lists.ToBeRead {
+		facts.of(item.BookID).lists["toberead"] = true
+		setAuthors(item.BookID, item.Authors)
+	}
+
+	// The latest rating given wins when a book was read more than once.
+	reads := append([]models.ReadItem(nil), profile.Lists.Read...)
+	sort.SliceStable(reads, func(i, j int) bool { return reads[i].CompletedDate < reads[j].CompletedDate })
+	readBy := make(map[string]map[string]bool) // author -> books read
+	for _, item := range reads {
+		f := facts.of(item.BookID)
+		f.lists["read"] = true
+		if item.Rating != 0 {
+			f.rating = item.Rating
+		}
+		if year, ok := yearOf(item.CompletedDate); ok {
+			f.readYears = append(f.readYears, year)
+		}
+		setAuthors(item.BookID, item.Authors)
+		for _, author := range f.authors {
+			if readBy[author] == nil {
+				readBy[author] = make(map[string]bool)
+			}
+			readBy[author][item.BookID] = true
+		}
+	}
+
+	for _, shelf := range profile.Lists.Shelves {
+		if shelf.Smart() {
+			continue
+		}
+		for _, item := range shelf.Items {
+			f := facts.of(item.BookID)
+			f.lists[strings.ToLower(shelf.Name)] = true
+			f.lists[strings.ToLower(shelf.ShelfID)] = true
+			setAuthors(item.BookID, item.Authors)
+		}
+	}
+
+	for _, entry := range append(append([]models.ReadingLogItem(nil), profile.ReadingLog...), data.ReadingLog...) {
+		if year, ok := yearOf(entry.Date); ok && facts[entry.BookID] != nil {
+			facts[entry.BookID].loggedYears = append(facts[entry.BookID].loggedYears, year)
+		}
+	}
+
+	for bookID, f := range facts {
+		if book, ok := data.Books[bookID]; ok {
+			f.pageCount = book.PageCount
+			for _, tag := range book.Tags {
+				f.tags[strings.ToLower(strings.TrimSpace(tag))] = true
+			}
+		}
+		for _, author := range f.authors {
+			f.authorReadCount = max(f.authorReadCount, len(readBy[author]))
+		}
+	}
+	return facts
+}
+
+// yearOf returns the year of an RFC 3339 date.
+func yearOf(date string) (int, bool) {
+	t, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return 0, false
+	}
+	return t.Year(), true
+}
+
+// matches reports whether a book with the given facts meets the rules.
+func matches(rules models.ShelfRules, f *bookFacts) bool {
+	if len(rules.Conditions) == 0 {
+		return false
+	}
+	for _, rule := range rules.Conditions {
+		ok := holds(rule, f)
+		if rules.Match == models.MatchAny && ok {
+			return true
+		}
+		if rules.Match != models.MatchAny && !ok {
+			return false
+		}
+	}
+	return rules.Match != models.MatchAny
+}
+
+// holds reports whether one condition holds for a book.
+func holds(rule models.ShelfRule, f *bookFacts) bool {
+	value := strings.ToLower(strings.TrimSpace(rule.Value))
+	switch rule.Field {
+	case models.ListField:
+		return f.lists[value] == (rule.Op == models.Equal)
+	case models.TagField:
+		return f.tags[value] == (rule.Op == models.Equal)
+	case models.AuthorField:
+		found := false
+		for _, author := range f.authors {
+			if author == value {
+				found = true
+				break
+			}
+		}
+		return found == (rule.Op == models.Equal)
+	case models.RatingField:
+		return compare(rule.Op, f.rating, rule.Number)
+	case models.PageCountField:
+		return compare(rule.Op, f.pageCount, rule.Number)
+	case models.AuthorReadCountField:
+		return compare(rule.Op, f.authorReadCount, rule.Number)
+	case models.ReadYearField:
+		return anyCompare(rule.Op, f.readYears, rule.Number)
+	case models.LoggedYearField:
+		return anyCompare(rule.Op, f.loggedYears, rule.Number)
+	}
+	return false
+}
+
+func compare(op models.RuleOp, actual, expected int) bool {
+	switch op {
+	case models.Equal:
+		return actual == expected
+	case models.NotEqual:
+		return actual != expected
+	case models.AtLeast:
+		return actual >= expected
+	case models.AtMost:
+		return actual <= expected
+	}
+	return false
+}
+
+func anyCompare(op models.RuleOp, actual []int, expected int) bool {
+	for _, value := range actual {
+		if compare(op, value, expected) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package smartshelf
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+)
+
+func TestEvaluate(t *testing.T) {
+	data := Data{
+		Profile: &models.Profile{
+			CurrentlyReading: []models.CurrentlyReadingItem{
+				{Book: models.Book{BookID: "cr1", Authors: []string{"Author A"}}, StartedDate: "2026-01-01T00:00:00Z"},
+			},
+			Lists: models.UserLists{
+				ToBeRead: []models.ToBeReadItem{
+					{BookID: "t1", Authors: []string{"Author B"}},
+					{BookID: "cr1", Authors: []string{"Author A"}},
+				},
+				Read: []models.ReadItem{
+					{BookID: "r1", Authors: []string{"Author A"}, Rating: 2, CompletedDate: "2025-02-01T00:00:00Z"},
+					{BookID: "r1", Authors: []string{"Author A"}, Rating: 5, CompletedDate: "2024-03-01T00:00:00Z"},
+					{BookID: "r2", Authors: []string{" author a "}, Rating: 3, CompletedDate: "2025-01-10T00:00:00Z"},
+					{BookID: "r3", Authors: []string{"Author C"}, Rating: 4, CompletedDate: "2025-06-01T00:00:00Z"},
+				},
+				Shelves: []models.Shelf{
+					{ShelfID: "h1", Name: "Holiday", Items: []models.CustomListItem{{BookID: "t1"}, {BookID: "s1"}}},
+					{ShelfID: "smart", Name: "Smart", Rules: &models.ShelfRules{}, Items: []models.CustomListItem{{BookID: "x1"}}},
+				},
+			},
+		},
+		Books: map[string]models.BookData{
+			"cr1": {BookID: "cr1", PageCount: 300},
+			"t1":  {BookID: "t1", PageCount: 500, Tags: []string{"fantasy"}},
+			"r3":  {BookID: "r3", PageCount: 200, Tags: []string{" Fantasy "}},
+		},
+		ReadingLog: []models.ReadingLogItem{
+			{BookID: "cr1", Date: "2026-01-05T00:00:00Z"},
+			{BookID: "unlisted", Date: "2026-01-05T00:00:00Z"},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		match models.RuleMatch
+		rules []models.ShelfRule
+		want  []string
+	}{
+		{"on a list", models.MatchAll, []models.ShelfRule{{Field: models.ListField, Op: models.Equal, Value: "read"}}, []string{"r1", "r2", "r3"}},
+		{"on a shelf by name", models.MatchAll, []models.ShelfRule{{Field: models.ListField, Op: models.Equal, Value: "HOLIDAY"}}, []string{"t1", "s1"}},
+		{"on a shelf by ID", models.MatchAll, []models.ShelfRule{{Field: models.ListField, Op: models.Equal, Value: "h1"}}, []string{"t1", "s1"}},
+		{"not on a list", models.MatchAll, []models.ShelfRule{{Field: models.ListField, Op: models.NotEqual, Value: "read"}}, []string{"cr1", "t1", "s1"}},
+		{"tag ignores case and spaces", models.MatchAll, []models.ShelfRule{{Field: models.TagField, Op: models.Equal, Value: "FANTASY"}}, []string{"t1", "r3"}},
+		{"author", models.MatchAll, []models.ShelfRule{{Field: models.AuthorField, Op: models.Equal, Value: "author a"}}, []string{"cr1", "r1", "r2"}},
+		{"latest rating", models.MatchAll, []models.ShelfRule{{Field: models.RatingField, Op: models.AtLeast, Number: 3}}, []string{"r2", "r3"}},
+		{"any read year", models.MatchAll, []models.ShelfRule{{Field: models.ReadYearField, Op: models.Equal, Number: 2024}}, []string{"r1"}},
+		{"logged year", models.MatchAll, []models.ShelfRule{{Field: models.LoggedYearField, Op: models.Equal, Number: 2026}}, []string{"cr1"}},
+		{"unknown page count is 0", models.MatchAll, []models.ShelfRule{{Field: models.PageCountField, Op: models.AtMost, Number: 300}}, []string{"cr1", "r1", "r2", "r3", "s1"}},
+		{"author read count", models.MatchAll, []models.ShelfRule{{Field: models.AuthorReadCountField, Op: models.AtLeast, Number: 2}}, []string{"cr1", "r1", "r2"}},
+		{"all conditions", models.MatchAll, []models.ShelfRule{
+			{Field: models.AuthorField, Op: models.Equal, Value: "Author A"},
+			{Field: models.ReadYearField, Op: models.Equal, Number: 2025},
+		}, []string{"r1", "r2"}},
+		{"any condition", models.MatchAny, []models.ShelfRule{
+			{Field: models.TagField, Op: models.Equal, Value: "fantasy"},
+			{Field: models.ListField, Op: models.Equal, Value: "currentlyReading"},
+		}, []string{"cr1", "t1", "r3"}},
+		{"no conditions", models.MatchAny, nil, nil},
+		{"unknown field", models.MatchAll, []models.ShelfRule{{Field: "colour", Op: models.Equal, Value: "red"}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := Evaluate(models.ShelfRules{Match: tt.match, Conditions: tt.rules}, data)
+			var ids []string
+			for i, item := range items {
+				ids = append(ids, item.BookID)
+				if item.Order != i {
+					t.Errorf("%s has order %d at index %d", item.BookID, item.Order, i)
+				}
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("Evaluate() = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestUsesReadingLog(t *testing.T) {
+	shelf := func(field models.RuleField) models.Shelf {
+		return models.Shelf{Rules: &models.ShelfRules{Conditions: []models.ShelfRule{{Field: field}}}}
+	}
+	tests := []struct {
+		name    string
+		shelves []models.Shelf
+		want    bool
+	}{
+		{"no shelves", nil, false},
+		{"static shelf", []models.Shelf{{Name: "Holiday"}}, false},
+		{"other fields", []models.Shelf{shelf(models.RatingField)}, false},
+		{"logged year", []models.Shelf{shelf(models.RatingField), shelf(models.LoggedYearField)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := &models.Profile{Lists: models.UserLists{Shelves: tt.shelves}}
+			if got := UsesReadingLog(profile); got != tt.want {
+				t.Errorf("UsesReadingLog() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
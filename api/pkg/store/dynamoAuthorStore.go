@@ -0,0 +1,77 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// DynamoAuthorStore is an AuthorStore backed by the Authors DynamoDB table.
+type DynamoAuthorStore struct {
+	svc   dynamodbiface.DynamoDBAPI
+	table string
+}
+
+// NewDynamoAuthorStore returns an AuthorStore that reads and writes table.
+func NewDynamoAuthorStore(svc dynamodbiface.DynamoDBAPI, table string) *DynamoAuthorStore {
+	return &DynamoAuthorStore{svc: svc, table: table}
+}
+
+func (s *DynamoAuthorStore) Get(authorID string) (*models.Author, error) {
+	result, err := s.svc.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(s.table),
+		Key:       map[string]*dynamodb.AttributeValue{"authorId": {S: aws.String(authorID)}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("DynamoDB GetItem error: %w", err)
+	}
+	if len(result.Item) == 0 {
+		return nil, ErrNotFound
+	}
+
+	var author models.Author
+	if err := dynamodbattribute.UnmarshalMap(result.Item, &author); err != nil {
+		return nil, fmt.Errorf("error unmarshalling author: %w", err)
+	}
+	return &author, nil
+}
+
+func (s *DynamoAuthorStore) Put(author *models.Author) error {
+	item, err := dynamodbattribute.MarshalMap(author)
+	if err != nil {
+		return fmt.Errorf("error marshalling author: %w", err)
+	}
+
+	_, err = s.svc.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(s.table),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("DynamoDB PutItem error: %w", err)
+	}
+	return nil
+}
+
+func (s *DynamoAuthorStore) List() ([]models.Author, error) {
+	var authors []models.Author
+	var unmarshalErr error
+	err := s.svc.ScanPages(&dynamodb.ScanInput{TableName: aws.String(s.table)}, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		var pageAuthors []models.Author
+		if unmarshalErr = dynamodbattribute.UnmarshalListOfMaps(page.Items, &pageAuthors); unmarshalErr != nil {
+			return false
+		}
+		authors = append(authors, pageAuthors...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("DynamoDB Scan error: %w", err)
+	}
+	if unmarshalErr != nil {
+		return nil, fmt.Errorf("error unmarshalling authors: %w", unmarshalErr)
+	}
+	return authors, nil
+}
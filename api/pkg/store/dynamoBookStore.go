@@ -0,0 +1,430 @@
+package store
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/FriedGlue/BookIt/api/pkg/pagination"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+// DynamoBookStore is a BookStore backed by the Books DynamoDB table and its
+// ISBN, Open Library and work secondary indexes.
+//
+// The table also holds an index from authors to their books, as a list
+// attribute like authorIds cannot key a secondary index: one item per
+// author, keyed by authorBooksPrefix and the author ID, with the IDs of
+// their books in a string set. Book IDs are UUIDs, so they never start with
+// the prefix.
+type DynamoBookStore struct {
+	svc              dynamodbiface.DynamoDBAPI
+	table            string
+	isbnIndex        string
+	openLibraryIndex string
+	workIndex        string
+}
+
+const authorBooksPrefix = "#author#"
+
+// authorBooks is an index item of the Books table.
+type authorBooks struct {
+	Key     string   `dynamodbav:"bookId"`
+	BookIDs []string `dynamodbav:"bookIds,stringset,omitempty"`
+}
+
+// NewDynamoBookStore returns a BookStore that reads and writes table, using
+// isbnIndex, openLibraryIndex and workIndex for the corresponding lookups.
+func NewDynamoBookStore(svc dynamodbiface.DynamoDBAPI, table, isbnIndex, openLibraryIndex, workIndex string) *DynamoBookStore {
+	return &DynamoBookStore{
+		svc:              svc,
+		table:            table,
+		isbnIndex:        isbnIndex,
+		openLibraryIndex: openLibraryIndex,
+		workIndex:        workIndex,
+	}
+}
+
+func (s *DynamoBookStore) Get(bookID string) (*models.BookData, error) {
+	if isAuthorBooksKey(bookID) {
+		return nil, ErrNotFound
+	}
+	result, err := s.svc.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(s.table),
+		Key: map[string]*dynamodb.AttributeValue{
+			"bookId": {S: aws.String(bookID)},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("DynamoDB GetItem error: %w", err)
+	}
+	if len(result.Item) == 0 {
+		return nil, ErrNotFound
+	}
+
+	var book models.BookData
+	if err := dynamodbattribute.UnmarshalMap(result.Item, &book); err != nil {
+		return nil, fmt.Errorf("error unmarshalling book: %w", err)
+	}
+	return &book, nil
+}
+
+func (s *DynamoBookStore) GetMany(bookIDs []string) ([]models.BookData, error) {
+	seen := make(map[string]bool, len(bookIDs))
+	var keys []map[string]*dynamodb.AttributeValue
+	for _, id := range bookIDs {
+		if id == "" || seen[id] || isAuthorBooksKey(id) {
+			continue // BatchGetItem rejects duplicate keys
+		}
+		seen[id] = true
+		keys = append(keys, map[string]*dynamodb.AttributeValue{"bookId": {S: aws.String(id)}})
+	}
+
+	var books []models.BookData
+	// BatchGetItem accepts at most 100 keys per call.
+	for start := 0; start < len(keys); start += 100 {
+		end := min(start+100, len(keys))
+		pending := map[string]*dynamodb.KeysAndAttributes{s.table: {Keys: keys[start:end]}}
+		for len(pending) > 0 {
+			out, err := s.svc.BatchGetItem(&dynamodb.BatchGetItemInput{RequestItems: pending})
+			if err != nil {
+				return nil, fmt.Errorf("DynamoDB BatchGetItem error: %w", err)
+			}
+			var pageBooks []models.BookData
+			if err := dynamodbattribute.UnmarshalListOfMaps(out.Responses[s.table], &pageBooks); err != nil {
+				return nil, fmt.Errorf("error unmarshalling books: %w", err)
+			}
+			books = append(books, pageBooks...)
+			pending = out.UnprocessedKeys
+		}
+	}
+	return books, nil
+}
+
+// Put writes the book and files it under its authors in the index. The
+// book is indexed under its authors before it is written and unindexed from
+// the authors it lost after, so a failed Put leaves at worst index items
+// naming a book without the author, which QueryByAuthorID skips.
+func (s *DynamoBookStore) Put(book *models.BookData) error {
+	book.TitleLowercase = strings.ToLower(book.Title)
+
+	item, err := dynamodbattribute.MarshalMap(book)
+	if err != nil {
+		return fmt.Errorf("error marshalling book: %w", err)
+	}
+
+	for _, authorID := range bookAuthorIDs(*book) {
+		if err := s.updateAuthorIndex(authorID, book.BookID, "ADD"); err != nil {
+			return err
+		}
+	}
+
+	out, err := s.svc.PutItem(&dynamodb.PutItemInput{
+		TableName:    aws.String(s.table),
+		Item:         item,
+		ReturnValues: aws.String(dynamodb.ReturnValueAllOld),
+	})
+	if err != nil {
+		return fmt.Errorf("DynamoDB PutItem error: %w", err)
+	}
+
+	lost, err := lostAuthors(out.Attributes, bookAuthorIDs(*book))
+	if err != nil {
+		return err
+	}
+	for _, authorID := range lost {
+		if err := s.updateAuthorIndex(authorID, book.BookID, "DELETE"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *DynamoBookStore) Update(bookID string, mutate func(*models.BookData) error) (*models.BookData, error) {
+	book, err := s.Get(bookID)
+	if err != nil {
+		return nil, err
+	}
+	if err := mutate(book); err != nil {
+		return nil, err
+	}
+	book.BookID = bookID
+	if err := s.Put(book); err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+func (s *DynamoBookStore) Delete(bookID string) error {
+	if isAuthorBooksKey(bookID) {
+		return nil
+	}
+	out, err := s.svc.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(s.table),
+		Key: map[string]*dynamodb.AttributeValue{
+			"bookId": {S: aws.String(bookID)},
+		},
+		ReturnValues: aws.String(dynamodb.ReturnValueAllOld),
+	})
+	if err != nil {
+		return fmt.Errorf("DynamoDB DeleteItem error: %w", err)
+	}
+
+	lost, err := lostAuthors(out.Attributes, nil)
+	if err != nil {
+		return err
+	}
+	for _, authorID := range lost {
+		if err := s.updateAuthorIndex(authorID, bookID, "DELETE"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *DynamoBookStore) List() ([]models.BookData, error) {
+	return s.scan(&dynamodb.ScanInput{
+		TableName:                 aws.String(s.table),
+		FilterExpression:          aws.String("NOT begins_with(bookId, :index)"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":index": {S: aws.String(authorBooksPrefix)}},
+	})
+}
+
+// ListPage scans the table in its own (hash) order, which is stable but not
+// by ID.
+func (s *DynamoBookStore) ListPage(page pagination.Request) ([]models.BookData, string, error) {
+	var startKey map[string]*dynamodb.AttributeValue
+	if page.After != "" {
+		startKey = map[string]*dynamodb.AttributeValue{"bookId": {S: aws.String(page.After)}}
+	}
+
+	// Ask for one book more than the page holds to learn whether there is a
+	// next page. A scan also stops at 1MB, so keep going until that book
+	// arrives or the table ends.
+	var books []models.BookData
+	for len(books) <= page.Limit {
+		out, err := s.svc.Scan(&dynamodb.ScanInput{
+			TableName:                 aws.String(s.table),
+			Limit:                     aws.Int64(int64(page.Limit + 1 - len(books))),
+			ExclusiveStartKey:         startKey,
+			FilterExpression:          aws.String("NOT begins_with(bookId, :index)"),
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":index": {S: aws.String(authorBooksPrefix)}},
+		})
+		if err != nil {
+			return nil, "", fmt.Errorf("DynamoDB Scan error: %w", err)
+		}
+		var pageBooks []models.BookData
+		if err := dynamodbattribute.UnmarshalListOfMaps(out.Items, &pageBooks); err != nil {
+			return nil, "", fmt.Errorf("error unmarshalling books: %w", err)
+		}
+		books = append(books, pageBooks...)
+		if len(out.LastEvaluatedKey) == 0 {
+			break
+		}
+		startKey = out.LastEvaluatedKey
+	}
+
+	if len(books) > page.Limit {
+		books = books[:page.Limit]
+		return books, books[len(books)-1].BookID, nil
+	}
+	return books, "", nil
+}
+
+func (s *DynamoBookStore) QueryByISBN(isbn string) ([]models.BookData, error) {
+	return s.query(s.isbnIndex, "isbn13", isbn)
+}
+
+// QueryByOpenLibraryID looks the work up in the Open Library index and falls
+// back to scanning for the "OpenLibrary:<id>" tag written by older versions.
+func (s *DynamoBookStore) QueryByOpenLibraryID(openLibraryID string) ([]models.BookData, error) {
+	books, err := s.query(s.openLibraryIndex, "openLibraryId", openLibraryID)
+	if err != nil {
+		return nil, err
+	}
+	if len(books) > 0 {
+		return books, nil
+	}
+
+	olTag := "OpenLibrary:" + openLibraryID
+	log.Printf("No book found with openLibraryId field, trying tag-based search for: %s", olTag)
+
+	expr, err := expression.NewBuilder().
+		WithFilter(expression.Contains(expression.Name("tags"), olTag)).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+	return s.scan(&dynamodb.ScanInput{
+		TableName:                 aws.String(s.table),
+		FilterExpression:          expr.Filter(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+}
+
+func (s *DynamoBookStore) QueryByWorkID(workID string) ([]models.BookData, error) {
+	return s.query(s.workIndex, "workId", workID)
+}
+
+// QueryByAuthorID looks the author up in the index and loads their books.
+func (s *DynamoBookStore) QueryByAuthorID(authorID string) ([]models.BookData, error) {
+	if authorID == "" {
+		return nil, nil
+	}
+	result, err := s.svc.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(s.table),
+		Key:       map[string]*dynamodb.AttributeValue{"bookId": {S: aws.String(authorBooksPrefix + authorID)}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("DynamoDB GetItem error: %w", err)
+	}
+	var index authorBooks
+	if err := dynamodbattribute.UnmarshalMap(result.Item, &index); err != nil {
+		return nil, fmt.Errorf("error unmarshalling author index: %w", err)
+	}
+
+	found, err := s.GetMany(index.BookIDs)
+	if err != nil {
+		return nil, err
+	}
+	// The index can be ahead of a book whose Put failed part way.
+	var books []models.BookData
+	for _, book := range found {
+		if slices.Contains(book.AuthorIDs, authorID) {
+			books = append(books, book)
+		}
+	}
+	return books, nil
+}
+
+// ReindexAuthors files every book under its authors, repairing the index
+// after a failed write and building it for books written before it existed.
+// It is safe to run more than once and returns how many books it indexed.
+func (s *DynamoBookStore) ReindexAuthors() (int, error) {
+	all, err := s.List()
+	if err != nil {
+		return 0, err
+	}
+	for _, book := range all {
+		for _, authorID := range bookAuthorIDs(book) {
+			if err := s.updateAuthorIndex(authorID, book.BookID, "ADD"); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(all), nil
+}
+
+// updateAuthorIndex adds bookID to the books of an author, or deletes it,
+// with action ADD or DELETE. DynamoDB removes the item's set once it is
+// empty.
+func (s *DynamoBookStore) updateAuthorIndex(authorID, bookID, action string) error {
+	_, err := s.svc.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:        aws.String(s.table),
+		Key:              map[string]*dynamodb.AttributeValue{"bookId": {S: aws.String(authorBooksPrefix + authorID)}},
+		UpdateExpression: aws.String(action + " bookIds :books"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":books": {SS: []*string{aws.String(bookID)}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("DynamoDB UpdateItem error: %w", err)
+	}
+	return nil
+}
+
+// lostAuthors returns the authors of the book stored in old that are not
+// among kept.
+func lostAuthors(old map[string]*dynamodb.AttributeValue, kept []string) ([]string, error) {
+	if len(old) == 0 {
+		return nil, nil
+	}
+	var previous models.BookData
+	if err := dynamodbattribute.UnmarshalMap(old, &previous); err != nil {
+		return nil, fmt.Errorf("error unmarshalling book: %w", err)
+	}
+	var lost []string
+	for _, authorID := range bookAuthorIDs(previous) {
+		if !slices.Contains(kept, authorID) {
+			lost = append(lost, authorID)
+		}
+	}
+	return lost, nil
+}
+
+// bookAuthorIDs returns the distinct, non-empty author IDs of a book.
+func bookAuthorIDs(book models.BookData) []string {
+	var authorIDs []string
+	for _, authorID := range book.AuthorIDs {
+		if authorID != "" && !slices.Contains(authorIDs, authorID) {
+			authorIDs = append(authorIDs, authorID)
+		}
+	}
+	return authorIDs
+}
+
+// isAuthorBooksKey reports whether a bookId is an index item's.
+func isAuthorBooksKey(bookID string) bool {
+	return strings.HasPrefix(bookID, authorBooksPrefix)
+}
+
+// query runs an equality query against a secondary index.
+func (s *DynamoBookStore) query(index, attribute, value string) ([]models.BookData, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		IndexName:              aws.String(index),
+		KeyConditionExpression: aws.String("#attr = :val"),
+		ExpressionAttributeNames: map[string]*string{
+			"#attr": aws.String(attribute),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":val": {S: aws.String(value)},
+		},
+	}
+
+	var books []models.BookData
+	var unmarshalErr error
+	err := s.svc.QueryPages(input, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		var pageBooks []models.BookData
+		if unmarshalErr = dynamodbattribute.UnmarshalListOfMaps(page.Items, &pageBooks); unmarshalErr != nil {
+			return false
+		}
+		books = append(books, pageBooks...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("DynamoDB Query error: %w", err)
+	}
+	if unmarshalErr != nil {
+		return nil, fmt.Errorf("error unmarshalling books: %w", unmarshalErr)
+	}
+	return books, nil
+}
+
+// scan runs a scan to completion, following LastEvaluatedKey.
+func (s *DynamoBookStore) scan(input *dynamodb.ScanInput) ([]models.BookData, error) {
+	var books []models.BookData
+	var unmarshalErr error
+	err := s.svc.ScanPages(input, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		var pageBooks []models.BookData
+		if unmarshalErr = dynamodbattribute.UnmarshalListOfMaps(page.Items, &pageBooks); unmarshalErr != nil {
+			return false
+		}
+		books = append(books, pageBooks...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("DynamoDB Scan error: %w", err)
+	}
+	if unmarshalErr != nil {
+		return nil, fmt.Errorf("error unmarshalling books: %w", unmarshalErr)
+	}
+	return books, nil
+}
@@ -0,0 +1,192 @@
+package store
+
+import (
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// fakeBookTable is a Books table keyed by bookId, supporting the calls
+// DynamoBookStore makes to keep its author index. Scans are not supported,
+// so any test that reaches one fails.
+type fakeBookTable struct {
+	dynamodbiface.DynamoDBAPI
+	items map[string]map[string]*dynamodb.AttributeValue
+}
+
+func newFakeBookTable() *fakeBookTable {
+	return &fakeBookTable{items: map[string]map[string]*dynamodb.AttributeValue{}}
+}
+
+func (f *fakeBookTable) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{Item: f.items[*input.Key["bookId"].S]}, nil
+}
+
+func (f *fakeBookTable) BatchGetItem(input *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+	out := &dynamodb.BatchGetItemOutput{Responses: map[string][]map[string]*dynamodb.AttributeValue{}}
+	for table, keys := range input.RequestItems {
+		for _, key := range keys.Keys {
+			if item, ok := f.items[*key["bookId"].S]; ok {
+				out.Responses[table] = append(out.Responses[table], item)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeBookTable) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	key := *input.Item["bookId"].S
+	out := &dynamodb.PutItemOutput{}
+	if aws.StringValue(input.ReturnValues) == dynamodb.ReturnValueAllOld {
+		out.Attributes = f.items[key]
+	}
+	f.items[key] = input.Item
+	return out, nil
+}
+
+func (f *fakeBookTable) DeleteItem(input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	key := *input.Key["bookId"].S
+	out := &dynamodb.DeleteItemOutput{}
+	if aws.StringValue(input.ReturnValues) == dynamodb.ReturnValueAllOld {
+		out.Attributes = f.items[key]
+	}
+	delete(f.items, key)
+	return out, nil
+}
+
+// UpdateItem applies "ADD bookIds :books" and "DELETE bookIds :books".
+func (f *fakeBookTable) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	key := *input.Key["bookId"].S
+	value := *input.ExpressionAttributeValues[":books"].SS[0]
+	item := f.items[key]
+	if item == nil {
+		item = map[string]*dynamodb.AttributeValue{"bookId": {S: aws.String(key)}}
+	}
+	var set []string
+	if item["bookIds"] != nil {
+		set = aws.StringValueSlice(item["bookIds"].SS)
+	}
+	switch *input.UpdateExpression {
+	case "ADD bookIds :books":
+		if !slices.Contains(set, value) {
+			set = append(set, value)
+		}
+	case "DELETE bookIds :books":
+		set = slices.DeleteFunc(set, func(id string) bool { return id == value })
+	}
+	if len(set) == 0 {
+		delete(item, "bookIds")
+	} else {
+		item["bookIds"] = &dynamodb.AttributeValue{SS: aws.StringSlice(set)}
+	}
+	f.items[key] = item
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+func TestDynamoBookStoreQueryByAuthorID(t *testing.T) {
+	table := newFakeBookTable()
+	s := NewDynamoBookStore(table, "Books", "", "", "")
+	for _, book := range []models.BookData{
+		{BookID: "a", Title: "A", AuthorIDs: []string{"OL1A"}},
+		{BookID: "b", Title: "B", AuthorIDs: []string{"OL1A", "OL2A", "OL1A"}},
+		{BookID: "c", Title: "C", AuthorIDs: []string{"OL2A"}},
+		{BookID: "d", Title: "D"},
+	} {
+		if err := s.Put(&book); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	query := func(authorID string) []string {
+		t.Helper()
+		books, err := s.QueryByAuthorID(authorID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, book := range books {
+			ids = append(ids, book.BookID)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	tests := []struct {
+		name   string
+		change func() error
+		author string
+		want   []string
+	}{
+		{"indexed on put", nil, "OL1A", []string{"a", "b"}},
+		{"co-author", nil, "OL2A", []string{"b", "c"}},
+		{"unknown author", nil, "OL9A", nil},
+		{"author removed", func() error {
+			_, err := s.Update("b", func(book *models.BookData) error {
+				book.AuthorIDs = []string{"OL2A"}
+				return nil
+			})
+			return err
+		}, "OL1A", []string{"a"}},
+		{"author added", func() error {
+			_, err := s.Update("d", func(book *models.BookData) error {
+				book.AuthorIDs = []string{"OL1A"}
+				return nil
+			})
+			return err
+		}, "OL1A", []string{"a", "d"}},
+		{"book deleted", func() error { return s.Delete("a") }, "OL1A", []string{"d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.change != nil {
+				if err := tt.change(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := query(tt.author); !slices.Equal(got, tt.want) {
+				t.Errorf("QueryByAuthorID(%s) = %v, want %v", tt.author, got, tt.want)
+			}
+		})
+	}
+
+	if _, ok := table.items[authorBooksPrefix+"OL1A"]["bookIds"]; !ok {
+		t.Error("index item of an author with books lost its set")
+	}
+	if index := table.items[authorBooksPrefix+"OL2A"]; len(aws.StringValueSlice(index["bookIds"].SS)) != 2 {
+		t.Errorf("index of OL2A = %v, want b and c", aws.StringValueSlice(index["bookIds"].SS))
+	}
+	if _, err := s.Get(authorBooksPrefix + "OL1A"); err != ErrNotFound {
+		t.Errorf("Get of an index item = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDynamoBookStoreQueryByAuthorIDSkipsStaleIndex(t *testing.T) {
+	table := newFakeBookTable()
+	s := NewDynamoBookStore(table, "Books", "", "", "")
+	if err := s.Put(&models.BookData{BookID: "a", AuthorIDs: []string{"OL1A"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(&models.BookData{BookID: "b", AuthorIDs: []string{"OL2A"}}); err != nil {
+		t.Fatal(err)
+	}
+	// Puts that failed after indexing leave the index ahead of the books:
+	// b was never written with OL1A, and "gone" not at all.
+	for _, bookID := range []string{"b", "gone"} {
+		if err := s.updateAuthorIndex("OL1A", bookID, "ADD"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	books, err := s.QueryByAuthorID("OL1A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(books) != 1 || books[0].BookID != "a" {
+		t.Errorf("QueryByAuthorID returned %v, want only the book that lists the author", books)
+	}
+}
@@ -0,0 +1,295 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// defaultUpdateAttempts is how many times Update tries to apply a mutation
+// before giving up with ErrConflict.
+const defaultUpdateAttempts = 5
+
+// DynamoProfileStore is a ProfileStore backed by the Profiles DynamoDB table.
+// Writes made through Update are conditional on the profile's version
+// attribute, so concurrent read-modify-write cycles cannot overwrite each other.
+type DynamoProfileStore struct {
+	svc   dynamodbiface.DynamoDBAPI
+	table string
+
+	// MaxAttempts bounds the number of reload-and-retry cycles in Update.
+	MaxAttempts int
+}
+
+// NewDynamoProfileStore returns a ProfileStore that reads and writes table.
+func NewDynamoProfileStore(svc dynamodbiface.DynamoDBAPI, table string) *DynamoProfileStore {
+	return &DynamoProfileStore{svc: svc, table: table, MaxAttempts: defaultUpdateAttempts}
+}
+
+func (s *DynamoProfileStore) Get(userID string) (*models.Profile, error) {
+	result, err := s.svc.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(s.table),
+		Key: map[string]*dynamodb.AttributeValue{
+			"_id": {S: aws.String(userID)},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("DynamoDB GetItem error: %w", err)
+	}
+	if result.Item == nil {
+		return nil, ErrNotFound
+	}
+
+	var profile models.Profile
+	if err := dynamodbattribute.UnmarshalMap(result.Item, &profile); err != nil {
+		return nil, fmt.Errorf("error unmarshalling profile: %w", err)
+	}
+	return &profile, nil
+}
+
+func (s *DynamoProfileStore) Put(profile *models.Profile) error {
+	profile.Version++
+	item, err := dynamodbattribute.MarshalMap(profile)
+	if err != nil {
+		return fmt.Errorf("error marshalling profile: %w", err)
+	}
+
+	_, err = s.svc.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(s.table),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("DynamoDB PutItem error: %w", err)
+	}
+	return nil
+}
+
+func (s *DynamoProfileStore) Create(profile *models.Profile) error {
+	profile.Version = 1
+	item, err := dynamodbattribute.MarshalMap(profile)
+	if err != nil {
+		profile.Version = 0
+		return fmt.Errorf("error marshalling profile: %w", err)
+	}
+
+	_, err = s.svc.PutItem(&dynamodb.PutItemInput{
+		TableName:                aws.String(s.table),
+		Item:                     item,
+		ConditionExpression:      aws.String("attribute_not_exists(#id)"),
+		ExpressionAttributeNames: map[string]*string{"#id": aws.String("_id")},
+	})
+	if err != nil {
+		profile.Version = 0
+		var aerr awserr.Error
+		if errors.As(err, &aerr) && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return ErrAlreadyExists
+		}
+		return fmt.Errorf("DynamoDB PutItem error: %w", err)
+	}
+	return nil
+}
+
+func (s *DynamoProfileStore) Update(userID string, mutate func(*models.Profile) error) (*models.Profile, error) {
+	profile, _, err := s.update(userID, nil, func(profile *models.Profile) ([]models.ReadingLogItem, error) {
+		return nil, mutate(profile)
+	})
+	return profile, err
+}
+
+// UpdateWithLog writes the profile and its reading log entries in one
+// transaction, which only succeeds if the profile's version is unchanged.
+func (s *DynamoProfileStore) UpdateWithLog(userID string, readingLog ReadingLogStore, mutate func(*models.Profile) ([]models.ReadingLogItem, error)) (*models.Profile, []models.ReadingLogItem, error) {
+	logStore, ok := readingLog.(*DynamoReadingLogStore)
+	if !ok {
+		return nil, nil, fmt.Errorf("reading log store %T cannot join a DynamoDB transaction", readingLog)
+	}
+	return s.update(userID, logStore, mutate)
+}
+
+// update runs the read-modify-write cycle of Update and UpdateWithLog.
+func (s *DynamoProfileStore) update(userID string, readingLog *DynamoReadingLogStore, mutate func(*models.Profile) ([]models.ReadingLogItem, error)) (*models.Profile, []models.ReadingLogItem, error) {
+	for attempt := 1; attempt <= s.MaxAttempts; attempt++ {
+		profile, err := s.Get(userID)
+		if err != nil {
+			return nil, nil, err
+		}
+		entries, err := mutate(profile)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(entries) > MaxLogEntriesPerUpdate {
+			return nil, nil, fmt.Errorf("%d reading log entries in one profile update, at most %d fit", len(entries), MaxLogEntriesPerUpdate)
+		}
+
+		err = s.putIfVersion(profile, readingLog, entries)
+		if err == nil {
+			return profile, entries, nil
+		}
+		if !errors.Is(err, errVersionMismatch) {
+			return nil, nil, err
+		}
+
+		if attempt == s.MaxAttempts {
+			break
+		}
+		log.Printf("Profile %s changed during update (attempt %d/%d), retrying", userID, attempt, s.MaxAttempts)
+		time.Sleep(retryBackoff(attempt))
+	}
+	log.Printf("Profile %s kept changing during update, giving up after %d attempts", userID, s.MaxAttempts)
+	return nil, nil, ErrConflict
+}
+
+// errVersionMismatch signals that the stored profile no longer has the
+// version the caller read.
+var errVersionMismatch = errors.New("profile version mismatch")
+
+// putIfVersion writes profile with its version incremented, provided the
+// stored item still carries the version profile was read with. Items written
+// before versioning was introduced have no version attribute and are treated
+// as version 0. Reading log entries, if any, are appended to readingLog in
+// the same transaction.
+func (s *DynamoProfileStore) putIfVersion(profile *models.Profile, readingLog *DynamoReadingLogStore, entries []models.ReadingLogItem) error {
+	expected := profile.Version
+	profile.Version = expected + 1
+
+	item, err := dynamodbattribute.MarshalMap(profile)
+	if err != nil {
+		profile.Version = expected
+		return fmt.Errorf("error marshalling profile: %w", err)
+	}
+
+	condition := "#version = :expected"
+	if expected == 0 {
+		condition = "attribute_not_exists(#version) OR #version = :expected"
+	}
+	put := &dynamodb.Put{
+		TableName:           aws.String(s.table),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_exists(#id) AND (" + condition + ")"),
+		ExpressionAttributeNames: map[string]*string{
+			"#id":      aws.String("_id"),
+			"#version": aws.String("version"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":expected": {N: aws.String(fmt.Sprintf("%d", expected))},
+		},
+	}
+
+	if len(entries) == 0 {
+		err = s.put(put)
+	} else {
+		err = s.putWithLog(put, profile.ID, readingLog, entries)
+	}
+	if err != nil {
+		profile.Version = expected
+	}
+	return err
+}
+
+// put writes a conditional profile put on its own.
+func (s *DynamoProfileStore) put(put *dynamodb.Put) error {
+	_, err := s.svc.PutItem(&dynamodb.PutItemInput{
+		TableName:                 put.TableName,
+		Item:                      put.Item,
+		ConditionExpression:       put.ConditionExpression,
+		ExpressionAttributeNames:  put.ExpressionAttributeNames,
+		ExpressionAttributeValues: put.ExpressionAttributeValues,
+	})
+	if isConditionalCheckFailed(err) {
+		return errVersionMismatch
+	}
+	if err != nil {
+		return fmt.Errorf("DynamoDB PutItem error: %w", err)
+	}
+	return nil
+}
+
+// putWithLog writes a conditional profile put and userID's new reading log
+// entries in one transaction.
+func (s *DynamoProfileStore) putWithLog(put *dynamodb.Put, userID string, readingLog *DynamoReadingLogStore, entries []models.ReadingLogItem) error {
+	items := []*dynamodb.TransactWriteItem{{Put: put}}
+	for i := range entries {
+		prepareReadingLogItem(userID, &entries[i])
+		av, err := dynamodbattribute.MarshalMap(entries[i])
+		if err != nil {
+			return fmt.Errorf("error marshalling reading log entry: %w", err)
+		}
+		items = append(items, &dynamodb.TransactWriteItem{Put: &dynamodb.Put{TableName: aws.String(readingLog.table), Item: av}})
+	}
+
+	_, err := s.svc.TransactWriteItems(&dynamodb.TransactWriteItemsInput{TransactItems: items})
+	if isProfileConflict(err) {
+		return errVersionMismatch
+	}
+	if err != nil {
+		return fmt.Errorf("DynamoDB TransactWriteItems error: %w", err)
+	}
+	return nil
+}
+
+// isProfileConflict reports whether a transaction was cancelled because the
+// profile's version changed or another transaction was writing it, both of
+// which a retry can get past.
+func isProfileConflict(err error) bool {
+	var cancelled *dynamodb.TransactionCanceledException
+	if !errors.As(err, &cancelled) {
+		return false
+	}
+	for _, reason := range cancelled.CancellationReasons {
+		switch aws.StringValue(reason.Code) {
+		case "ConditionalCheckFailed", "TransactionConflict":
+			return true
+		}
+	}
+	return false
+}
+
+// retryBackoff returns a short, jittered delay that grows with attempt so
+// competing writers spread out instead of colliding again.
+func retryBackoff(attempt int) time.Duration {
+	base := time.Duration(attempt) * 20 * time.Millisecond
+	return base + time.Duration(rand.Int63n(int64(base)))
+}
+
+func (s *DynamoProfileStore) Delete(userID string) error {
+	_, err := s.svc.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(s.table),
+		Key: map[string]*dynamodb.AttributeValue{
+			"_id": {S: aws.String(userID)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("DynamoDB DeleteItem error: %w", err)
+	}
+	return nil
+}
+
+// ListIDs scans the table for the _id attribute only.
+func (s *DynamoProfileStore) ListIDs() ([]string, error) {
+	var ids []string
+	err := s.svc.ScanPages(&dynamodb.ScanInput{
+		TableName:                aws.String(s.table),
+		ProjectionExpression:     aws.String("#id"),
+		ExpressionAttributeNames: map[string]*string{"#id": aws.String("_id")},
+	}, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			if id := item["_id"]; id != nil && id.S != nil {
+				ids = append(ids, *id.S)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("DynamoDB Scan error: %w", err)
+	}
+	return ids, nil
+}
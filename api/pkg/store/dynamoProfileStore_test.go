@@ -0,0 +1,238 @@
+package store
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// fakeProfileTable is a Profiles table holding at most one profile. Its
+// conditional puts and transactions fail as if another writer got there
+// first while conflicts is above 0.
+type fakeProfileTable struct {
+	dynamodbiface.DynamoDBAPI
+	item      map[string]*dynamodb.AttributeValue
+	conflicts int
+	putErr    error
+	gets      int
+	puts      int
+	logged    []map[string]*dynamodb.AttributeValue // reading log items
+}
+
+func (f *fakeProfileTable) GetItem(*dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	f.gets++
+	return &dynamodb.GetItemOutput{Item: f.item}, nil
+}
+
+func (f *fakeProfileTable) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	f.puts++
+	if f.putErr != nil {
+		return nil, f.putErr
+	}
+	if input.ConditionExpression != nil && f.conflicts > 0 {
+		f.conflicts--
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	}
+	f.item = input.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+// TransactWriteItems applies a transaction whose first item is the profile
+// put, keeping the other items in logged.
+func (f *fakeProfileTable) TransactWriteItems(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	f.puts++
+	if f.putErr != nil {
+		return nil, f.putErr
+	}
+	if f.conflicts > 0 {
+		f.conflicts--
+		reasons := make([]*dynamodb.CancellationReason, len(input.TransactItems))
+		for i := range reasons {
+			reasons[i] = &dynamodb.CancellationReason{Code: aws.String("None")}
+		}
+		reasons[0].Code = aws.String("ConditionalCheckFailed")
+		return nil, &dynamodb.TransactionCanceledException{Message_: aws.String("Transaction cancelled"), CancellationReasons: reasons}
+	}
+	f.item = input.TransactItems[0].Put.Item
+	for _, item := range input.TransactItems[1:] {
+		f.logged = append(f.logged, item.Put.Item)
+	}
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
+
+func (f *fakeProfileTable) stored(t *testing.T) models.Profile {
+	t.Helper()
+	var profile models.Profile
+	if err := dynamodbattribute.UnmarshalMap(f.item, &profile); err != nil {
+		t.Fatal(err)
+	}
+	return profile
+}
+
+func TestDynamoProfileStoreUpdate(t *testing.T) {
+	tests := []struct {
+		name        string
+		missing     bool
+		conflicts   int
+		putErr      error
+		want        error
+		wantPuts    int
+		wantVersion int64
+	}{
+		{name: "no conflict", wantPuts: 1, wantVersion: 4},
+		{name: "conflicts then succeeds", conflicts: 2, wantPuts: 3, wantVersion: 4},
+		{name: "conflicts on every attempt", conflicts: 3, want: ErrConflict, wantPuts: 3, wantVersion: 3},
+		{name: "other put error", putErr: errors.New("throttled"), wantPuts: 1, wantVersion: 3},
+		{name: "missing profile", missing: true, want: ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := &fakeProfileTable{conflicts: tt.conflicts, putErr: tt.putErr}
+			if !tt.missing {
+				item, err := dynamodbattribute.MarshalMap(models.Profile{ID: "user", Version: 3})
+				if err != nil {
+					t.Fatal(err)
+				}
+				table.item = item
+			}
+			s := NewDynamoProfileStore(table, "Profiles")
+			s.MaxAttempts = 3
+
+			mutations := 0
+			profile, err := s.Update("user", func(p *models.Profile) error {
+				mutations++
+				p.ProfileInformation.Username = "changed"
+				return nil
+			})
+			switch {
+			case tt.putErr != nil:
+				if !errors.Is(err, tt.putErr) || errors.Is(err, ErrConflict) {
+					t.Fatalf("Update error = %v, want %v", err, tt.putErr)
+				}
+			case !errors.Is(err, tt.want):
+				t.Fatalf("Update error = %v, want %v", err, tt.want)
+			}
+			// Every attempt reloads the profile and mutates it afresh
+			if table.puts != tt.wantPuts || mutations != tt.wantPuts || table.gets != max(tt.wantPuts, 1) {
+				t.Errorf("%d gets, %d mutations and %d puts, want %d of each", table.gets, mutations, table.puts, tt.wantPuts)
+			}
+			if tt.missing {
+				return
+			}
+
+			stored := table.stored(t)
+			if stored.Version != tt.wantVersion {
+				t.Errorf("stored version = %d, want %d", stored.Version, tt.wantVersion)
+			}
+			if err == nil && (profile.Version != tt.wantVersion || stored.ProfileInformation.Username != "changed") {
+				t.Errorf("returned version %d and stored %+v, want version %d with the change", profile.Version, stored, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestDynamoProfileStoreUpdateWithLog(t *testing.T) {
+	tests := []struct {
+		name       string
+		conflicts  int
+		entries    int
+		want       error
+		wantPuts   int
+		wantLogged int
+	}{
+		{name: "no entries", entries: 0, wantPuts: 1},
+		{name: "entries", entries: 2, wantPuts: 1, wantLogged: 2},
+		{name: "conflicts then succeeds", conflicts: 1, entries: 1, wantPuts: 2, wantLogged: 1},
+		{name: "conflicts on every attempt", conflicts: 3, entries: 1, want: ErrConflict, wantPuts: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item, err := dynamodbattribute.MarshalMap(models.Profile{ID: "user", Version: 3})
+			if err != nil {
+				t.Fatal(err)
+			}
+			table := &fakeProfileTable{item: item, conflicts: tt.conflicts}
+			s := NewDynamoProfileStore(table, "Profiles")
+			s.MaxAttempts = 3
+
+			_, entries, err := s.UpdateWithLog("user", NewDynamoReadingLogStore(table, "ReadingLog"), func(p *models.Profile) ([]models.ReadingLogItem, error) {
+				p.ProfileInformation.Username = "changed"
+				var entries []models.ReadingLogItem
+				for i := 0; i < tt.entries; i++ {
+					entries = append(entries, models.ReadingLogItem{Date: "2026-10-17T10:00:00Z", BookID: "book"})
+				}
+				return entries, nil
+			})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("UpdateWithLog error = %v, want %v", err, tt.want)
+			}
+			if table.puts != tt.wantPuts || len(table.logged) != tt.wantLogged {
+				t.Errorf("%d writes logging %d entries, want %d logging %d", table.puts, len(table.logged), tt.wantPuts, tt.wantLogged)
+			}
+			if err != nil {
+				return
+			}
+
+			if stored := table.stored(t); stored.Version != 4 || stored.ProfileInformation.Username != "changed" {
+				t.Errorf("stored %+v, want version 4 with the change", stored)
+			}
+			for i, av := range table.logged {
+				var logged models.ReadingLogItem
+				if err := dynamodbattribute.UnmarshalMap(av, &logged); err != nil {
+					t.Fatal(err)
+				}
+				if logged.UserID != "user" || logged.Id == "" || logged != entries[i] {
+					t.Errorf("logged %+v, returned %+v", logged, entries[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDynamoProfileStoreUpdateWithLogLimit(t *testing.T) {
+	item, err := dynamodbattribute.MarshalMap(models.Profile{ID: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := &fakeProfileTable{item: item}
+	_, _, err = NewDynamoProfileStore(table, "Profiles").UpdateWithLog("user", NewDynamoReadingLogStore(table, "ReadingLog"), func(*models.Profile) ([]models.ReadingLogItem, error) {
+		return make([]models.ReadingLogItem, MaxLogEntriesPerUpdate+1), nil
+	})
+	if err == nil || table.puts != 0 {
+		t.Errorf("UpdateWithLog returned %v after %d writes, want an error and no writes", err, table.puts)
+	}
+}
+
+func TestDynamoProfileStoreCreate(t *testing.T) {
+	tests := []struct {
+		name        string
+		exists      bool
+		want        error
+		wantVersion int64
+	}{
+		{name: "new profile", wantVersion: 1},
+		{name: "existing profile", exists: true, want: ErrAlreadyExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := &fakeProfileTable{}
+			if tt.exists {
+				table.conflicts = 1
+			}
+			profile := &models.Profile{ID: "user"}
+			err := NewDynamoProfileStore(table, "Profiles").Create(profile)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Create error = %v, want %v", err, tt.want)
+			}
+			if profile.Version != tt.wantVersion {
+				t.Errorf("version = %d, want %d", profile.Version, tt.wantVersion)
+			}
+		})
+	}
+}
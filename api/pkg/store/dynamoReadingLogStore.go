@@ -0,0 +1,235 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/FriedGlue/BookIt/api/pkg/pagination"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// DynamoReadingLogStore is a ReadingLogStore backed by the ReadingLog table,
+// whose partition key is userId and whose sort key is the entry's _id.
+type DynamoReadingLogStore struct {
+	svc   dynamodbiface.DynamoDBAPI
+	table string
+}
+
+// NewDynamoReadingLogStore returns a ReadingLogStore that reads and writes table.
+func NewDynamoReadingLogStore(svc dynamodbiface.DynamoDBAPI, table string) *DynamoReadingLogStore {
+	return &DynamoReadingLogStore{svc: svc, table: table}
+}
+
+func (s *DynamoReadingLogStore) Append(userID string, item *models.ReadingLogItem) error {
+	prepareReadingLogItem(userID, item)
+	return s.put(item, "")
+}
+
+func (s *DynamoReadingLogStore) Query(userID string, from, to time.Time) ([]models.ReadingLogItem, error) {
+	input := s.queryInput(userID, from, to)
+
+	var items []models.ReadingLogItem
+	var unmarshalErr error
+	err := s.svc.QueryPages(input, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		var pageItems []models.ReadingLogItem
+		if unmarshalErr = dynamodbattribute.UnmarshalListOfMaps(page.Items, &pageItems); unmarshalErr != nil {
+			return false
+		}
+		items = append(items, pageItems...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("DynamoDB Query error: %w", err)
+	}
+	if unmarshalErr != nil {
+		return nil, fmt.Errorf("error unmarshalling reading log: %w", unmarshalErr)
+	}
+	return items, nil
+}
+
+func (s *DynamoReadingLogStore) QueryPage(userID string, from, to time.Time, page pagination.Request) ([]models.ReadingLogItem, string, error) {
+	input := s.queryInput(userID, from, to)
+
+	// DynamoDB rejects a start key outside the queried range, which a
+	// cursor from a query with other dates could be.
+	lower, upper := readingLogRange(from, to)
+	switch {
+	case page.After == "" || page.After < lower:
+	case upper != "" && page.After >= upper:
+		return nil, "", nil
+	default:
+		input.ExclusiveStartKey = readingLogKey(userID, page.After)
+	}
+
+	// Ask for one entry more than the page holds to learn whether there is
+	// a next page, continuing past 1MB responses until it arrives.
+	var items []models.ReadingLogItem
+	for len(items) <= page.Limit {
+		input.Limit = aws.Int64(int64(page.Limit + 1 - len(items)))
+		out, err := s.svc.Query(input)
+		if err != nil {
+			return nil, "", fmt.Errorf("DynamoDB Query error: %w", err)
+		}
+		var pageItems []models.ReadingLogItem
+		if err := dynamodbattribute.UnmarshalListOfMaps(out.Items, &pageItems); err != nil {
+			return nil, "", fmt.Errorf("error unmarshalling reading log: %w", err)
+		}
+		items = append(items, pageItems...)
+		if len(out.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+
+	if len(items) > page.Limit {
+		items = items[:page.Limit]
+		return items, items[len(items)-1].Id, nil
+	}
+	return items, "", nil
+}
+
+// queryInput builds the query for userID's entries between from and to.
+func (s *DynamoReadingLogStore) queryInput(userID string, from, to time.Time) *dynamodb.QueryInput {
+	input := &dynamodb.QueryInput{
+		TableName: aws.String(s.table),
+		ExpressionAttributeNames: map[string]*string{
+			"#user": aws.String("userId"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":user": {S: aws.String(userID)},
+		},
+	}
+
+	lower, upper := readingLogRange(from, to)
+	keyCondition := "#user = :user"
+	if lower != "" || upper != "" {
+		input.ExpressionAttributeNames["#id"] = aws.String("_id")
+		switch {
+		case lower != "" && upper != "":
+			keyCondition += " AND #id BETWEEN :from AND :to"
+			input.ExpressionAttributeValues[":from"] = &dynamodb.AttributeValue{S: aws.String(lower)}
+			input.ExpressionAttributeValues[":to"] = &dynamodb.AttributeValue{S: aws.String(upper)}
+		case lower != "":
+			keyCondition += " AND #id >= :from"
+			input.ExpressionAttributeValues[":from"] = &dynamodb.AttributeValue{S: aws.String(lower)}
+		default:
+			keyCondition += " AND #id <= :to"
+			input.ExpressionAttributeValues[":to"] = &dynamodb.AttributeValue{S: aws.String(upper)}
+		}
+	}
+	input.KeyConditionExpression = aws.String(keyCondition)
+	return input
+}
+
+func (s *DynamoReadingLogStore) Update(userID, itemID string, mutate func(*models.ReadingLogItem) error) (*models.ReadingLogItem, error) {
+	result, err := s.svc.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(s.table),
+		Key:       readingLogKey(userID, itemID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("DynamoDB GetItem error: %w", err)
+	}
+	if result.Item == nil {
+		return nil, ErrNotFound
+	}
+
+	var item models.ReadingLogItem
+	if err := dynamodbattribute.UnmarshalMap(result.Item, &item); err != nil {
+		return nil, fmt.Errorf("error unmarshalling reading log entry: %w", err)
+	}
+	if err := mutate(&item); err != nil {
+		return nil, err
+	}
+	item.UserID, item.Id = userID, itemID
+
+	// Guard against resurrecting an entry deleted while we were mutating it.
+	if err := s.put(&item, "attribute_exists(userId)"); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (s *DynamoReadingLogStore) Delete(userID, itemID string) error {
+	_, err := s.svc.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName:           aws.String(s.table),
+		Key:                 readingLogKey(userID, itemID),
+		ConditionExpression: aws.String("attribute_exists(userId)"),
+	})
+	if isConditionalCheckFailed(err) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("DynamoDB DeleteItem error: %w", err)
+	}
+	return nil
+}
+
+func (s *DynamoReadingLogStore) DeleteAll(userID string) error {
+	items, err := s.Query(userID, time.Time{}, time.Time{})
+	if err != nil {
+		return err
+	}
+
+	// BatchWriteItem accepts at most 25 requests per call.
+	for start := 0; start < len(items); start += 25 {
+		end := min(start+25, len(items))
+		var requests []*dynamodb.WriteRequest
+		for _, item := range items[start:end] {
+			requests = append(requests, &dynamodb.WriteRequest{
+				DeleteRequest: &dynamodb.DeleteRequest{Key: readingLogKey(userID, item.Id)},
+			})
+		}
+
+		pending := map[string][]*dynamodb.WriteRequest{s.table: requests}
+		for len(pending) > 0 {
+			out, err := s.svc.BatchWriteItem(&dynamodb.BatchWriteItemInput{RequestItems: pending})
+			if err != nil {
+				return fmt.Errorf("DynamoDB BatchWriteItem error: %w", err)
+			}
+			pending = out.UnprocessedItems
+		}
+	}
+	return nil
+}
+
+func (s *DynamoReadingLogStore) put(item *models.ReadingLogItem, condition string) error {
+	av, err := dynamodbattribute.MarshalMap(item)
+	if err != nil {
+		return fmt.Errorf("error marshalling reading log entry: %w", err)
+	}
+
+	input := &dynamodb.PutItemInput{
+		TableName: aws.String(s.table),
+		Item:      av,
+	}
+	if condition != "" {
+		input.ConditionExpression = aws.String(condition)
+	}
+
+	_, err = s.svc.PutItem(input)
+	if isConditionalCheckFailed(err) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("DynamoDB PutItem error: %w", err)
+	}
+	return nil
+}
+
+func readingLogKey(userID, itemID string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"userId": {S: aws.String(userID)},
+		"_id":    {S: aws.String(itemID)},
+	}
+}
+
+func isConditionalCheckFailed(err error) bool {
+	var aerr awserr.Error
+	return errors.As(err, &aerr) && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException
+}
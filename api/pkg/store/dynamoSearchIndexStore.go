@@ -0,0 +1,243 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// DynamoSearchIndexStore is a SearchIndexStore backed by a DynamoDB table
+// keyed by term (partition) and bookId (sort), so the postings of a term are
+// a single Query.
+//
+// Two kinds of bookkeeping item share the table under terms the analyzer
+// never produces: one "#doc" item per book listing its terms, so they can be
+// removed when the book changes, and a "#stats" item counting the books.
+type DynamoSearchIndexStore struct {
+	svc   dynamodbiface.DynamoDBAPI
+	table string
+}
+
+const (
+	searchDocTerm   = "#doc"
+	searchStatsTerm = "#stats"
+	searchStatsKey  = "documents"
+)
+
+// searchPosting is one item of the search index table.
+type searchPosting struct {
+	Term   string   `dynamodbav:"term"`
+	BookID string   `dynamodbav:"bookId"`
+	Weight float64  `dynamodbav:"weight,omitempty"`
+	Terms  []string `dynamodbav:"terms,omitempty"`
+}
+
+// NewDynamoSearchIndexStore returns a SearchIndexStore that reads and writes
+// table.
+func NewDynamoSearchIndexStore(svc dynamodbiface.DynamoDBAPI, table string) *DynamoSearchIndexStore {
+	return &DynamoSearchIndexStore{svc: svc, table: table}
+}
+
+func (s *DynamoSearchIndexStore) Replace(bookID string, terms map[string]float64) error {
+	oldTerms, indexed, err := s.bookTerms(bookID)
+	if err != nil {
+		return err
+	}
+
+	var requests []*dynamodb.WriteRequest
+	newTerms := make([]string, 0, len(terms))
+	allTerms := append([]string(nil), oldTerms...)
+	for term, weight := range terms {
+		item, err := dynamodbattribute.MarshalMap(searchPosting{Term: term, BookID: bookID, Weight: weight})
+		if err != nil {
+			return fmt.Errorf("error marshalling search posting: %w", err)
+		}
+		requests = append(requests, &dynamodb.WriteRequest{PutRequest: &dynamodb.PutRequest{Item: item}})
+		newTerms = append(newTerms, term)
+		if !slices.Contains(oldTerms, term) {
+			allTerms = append(allTerms, term)
+		}
+	}
+	for _, term := range oldTerms {
+		if _, kept := terms[term]; !kept {
+			requests = append(requests, &dynamodb.WriteRequest{
+				DeleteRequest: &dynamodb.DeleteRequest{Key: searchKey(term, bookID)},
+			})
+		}
+	}
+
+	// Until the postings are written, the book's term list covers both its
+	// old and new terms, so that a Replace or Remove after a failed write
+	// still finds every posting that may exist.
+	if len(allTerms) > len(oldTerms) {
+		if err := s.putBookTerms(bookID, allTerms); err != nil {
+			return err
+		}
+	}
+	if err := s.batchWrite(requests); err != nil {
+		return err
+	}
+	if err := s.putBookTerms(bookID, newTerms); err != nil {
+		return err
+	}
+
+	if !indexed {
+		return s.countDocuments(1)
+	}
+	return nil
+}
+
+func (s *DynamoSearchIndexStore) Remove(bookID string) error {
+	terms, indexed, err := s.bookTerms(bookID)
+	if err != nil || !indexed {
+		return err
+	}
+
+	requests := make([]*dynamodb.WriteRequest, 0, len(terms)+1)
+	for _, term := range terms {
+		requests = append(requests, &dynamodb.WriteRequest{
+			DeleteRequest: &dynamodb.DeleteRequest{Key: searchKey(term, bookID)},
+		})
+	}
+	requests = append(requests, &dynamodb.WriteRequest{
+		DeleteRequest: &dynamodb.DeleteRequest{Key: searchKey(searchDocTerm, bookID)},
+	})
+	if err := s.batchWrite(requests); err != nil {
+		return err
+	}
+	return s.countDocuments(-1)
+}
+
+func (s *DynamoSearchIndexStore) Postings(ctx context.Context, term string) (map[string]float64, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		KeyConditionExpression: aws.String("#term = :term"),
+		ProjectionExpression:   aws.String("bookId, weight"),
+		ExpressionAttributeNames: map[string]*string{
+			"#term": aws.String("term"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":term": {S: aws.String(term)},
+		},
+	}
+
+	postings := make(map[string]float64)
+	var unmarshalErr error
+	err := s.svc.QueryPagesWithContext(ctx, input, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		var items []searchPosting
+		if unmarshalErr = dynamodbattribute.UnmarshalListOfMaps(page.Items, &items); unmarshalErr != nil {
+			return false
+		}
+		for _, item := range items {
+			postings[item.BookID] = item.Weight
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("DynamoDB Query error: %w", err)
+	}
+	if unmarshalErr != nil {
+		return nil, fmt.Errorf("error unmarshalling search postings: %w", unmarshalErr)
+	}
+	return postings, nil
+}
+
+func (s *DynamoSearchIndexStore) DocumentCount(ctx context.Context) (int, error) {
+	result, err := s.svc.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(s.table),
+		Key:       searchKey(searchStatsTerm, searchStatsKey),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("DynamoDB GetItem error: %w", err)
+	}
+	if result.Item["count"] == nil {
+		return 0, nil
+	}
+	count, err := strconv.Atoi(aws.StringValue(result.Item["count"].N))
+	if err != nil {
+		return 0, fmt.Errorf("error reading search document count: %w", err)
+	}
+	return max(count, 0), nil
+}
+
+// bookTerms returns the terms bookID is indexed under, and whether it is
+// indexed at all.
+func (s *DynamoSearchIndexStore) bookTerms(bookID string) ([]string, bool, error) {
+	result, err := s.svc.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(s.table),
+		Key:       searchKey(searchDocTerm, bookID),
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("DynamoDB GetItem error: %w", err)
+	}
+	if len(result.Item) == 0 {
+		return nil, false, nil
+	}
+
+	var doc searchPosting
+	if err := dynamodbattribute.UnmarshalMap(result.Item, &doc); err != nil {
+		return nil, false, fmt.Errorf("error unmarshalling search document: %w", err)
+	}
+	return doc.Terms, true, nil
+}
+
+func (s *DynamoSearchIndexStore) putBookTerms(bookID string, terms []string) error {
+	sort.Strings(terms)
+	item, err := dynamodbattribute.MarshalMap(searchPosting{Term: searchDocTerm, BookID: bookID, Terms: terms})
+	if err != nil {
+		return fmt.Errorf("error marshalling search document: %w", err)
+	}
+	if _, err := s.svc.PutItem(&dynamodb.PutItemInput{TableName: aws.String(s.table), Item: item}); err != nil {
+		return fmt.Errorf("DynamoDB PutItem error: %w", err)
+	}
+	return nil
+}
+
+// countDocuments adds delta to the number of indexed books.
+func (s *DynamoSearchIndexStore) countDocuments(delta int) error {
+	_, err := s.svc.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:        aws.String(s.table),
+		Key:              searchKey(searchStatsTerm, searchStatsKey),
+		UpdateExpression: aws.String("ADD #count :delta"),
+		ExpressionAttributeNames: map[string]*string{
+			"#count": aws.String("count"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":delta": {N: aws.String(strconv.Itoa(delta))},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("DynamoDB UpdateItem error: %w", err)
+	}
+	return nil
+}
+
+func (s *DynamoSearchIndexStore) batchWrite(requests []*dynamodb.WriteRequest) error {
+	// BatchWriteItem accepts at most 25 requests per call.
+	for start := 0; start < len(requests); start += 25 {
+		end := min(start+25, len(requests))
+		pending := map[string][]*dynamodb.WriteRequest{s.table: requests[start:end]}
+		for len(pending) > 0 {
+			out, err := s.svc.BatchWriteItem(&dynamodb.BatchWriteItemInput{RequestItems: pending})
+			if err != nil {
+				return fmt.Errorf("DynamoDB BatchWriteItem error: %w", err)
+			}
+			pending = out.UnprocessedItems
+		}
+	}
+	return nil
+}
+
+func searchKey(term, bookID string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"term":   {S: aws.String(term)},
+		"bookId": {S: aws.String(bookID)},
+	}
+}
@@ -0,0 +1,288 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// DynamoSeriesStore is a SeriesStore backed by the Series DynamoDB table.
+//
+// The table also holds an index from works to the series they are in: one
+// item per work, keyed by seriesWorkPrefix and the work ID, with the IDs of
+// its series in a string set. Series IDs are slugs and UUIDs, so they never
+// start with the prefix.
+type DynamoSeriesStore struct {
+	svc   dynamodbiface.DynamoDBAPI
+	table string
+}
+
+const seriesWorkPrefix = "#work#"
+
+// seriesWork is an index item of the Series table.
+type seriesWork struct {
+	Key       string   `dynamodbav:"seriesId"`
+	SeriesIDs []string `dynamodbav:"seriesIds,stringset,omitempty"`
+}
+
+// NewDynamoSeriesStore returns a SeriesStore that reads and writes table.
+func NewDynamoSeriesStore(svc dynamodbiface.DynamoDBAPI, table string) *DynamoSeriesStore {
+	return &DynamoSeriesStore{svc: svc, table: table}
+}
+
+func (s *DynamoSeriesStore) Get(seriesID string) (*models.Series, error) {
+	if isSeriesWorkKey(seriesID) {
+		return nil, ErrNotFound
+	}
+	result, err := s.svc.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(s.table),
+		Key:       map[string]*dynamodb.AttributeValue{"seriesId": {S: aws.String(seriesID)}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("DynamoDB GetItem error: %w", err)
+	}
+	if len(result.Item) == 0 {
+		return nil, ErrNotFound
+	}
+
+	var series models.Series
+	if err := dynamodbattribute.UnmarshalMap(result.Item, &series); err != nil {
+		return nil, fmt.Errorf("error unmarshalling series: %w", err)
+	}
+	return &series, nil
+}
+
+// Put writes the series and files it under its works in the index. The
+// works it gained are indexed before the series is written and the works it
+// lost are unindexed after, so a failed Put leaves at worst index items
+// naming a series without the work, which QueryByWorkIDs skips.
+func (s *DynamoSeriesStore) Put(series *models.Series) error {
+	old, err := s.Get(series.SeriesID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	var oldWorks []string
+	if old != nil {
+		oldWorks = seriesWorkIDs(*old)
+	}
+	newWorks := seriesWorkIDs(*series)
+
+	for _, workID := range newWorks {
+		if !slices.Contains(oldWorks, workID) {
+			if err := s.updateWorkIndex(workID, series.SeriesID, "ADD"); err != nil {
+				return err
+			}
+		}
+	}
+
+	item, err := dynamodbattribute.MarshalMap(series)
+	if err != nil {
+		return fmt.Errorf("error marshalling series: %w", err)
+	}
+	_, err = s.svc.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(s.table),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("DynamoDB PutItem error: %w", err)
+	}
+
+	for _, workID := range oldWorks {
+		if !slices.Contains(newWorks, workID) {
+			if err := s.updateWorkIndex(workID, series.SeriesID, "DELETE"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *DynamoSeriesStore) Delete(seriesID string) error {
+	old, err := s.Get(seriesID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	_, err = s.svc.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(s.table),
+		Key:       map[string]*dynamodb.AttributeValue{"seriesId": {S: aws.String(seriesID)}},
+	})
+	if err != nil {
+		return fmt.Errorf("DynamoDB DeleteItem error: %w", err)
+	}
+
+	if old == nil {
+		return nil
+	}
+	for _, workID := range seriesWorkIDs(*old) {
+		if err := s.updateWorkIndex(workID, seriesID, "DELETE"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *DynamoSeriesStore) List() ([]models.Series, error) {
+	var series []models.Series
+	var unmarshalErr error
+	input := &dynamodb.ScanInput{
+		TableName:                 aws.String(s.table),
+		FilterExpression:          aws.String("NOT begins_with(seriesId, :index)"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":index": {S: aws.String(seriesWorkPrefix)}},
+	}
+	err := s.svc.ScanPages(input, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		var pageSeries []models.Series
+		if unmarshalErr = dynamodbattribute.UnmarshalListOfMaps(page.Items, &pageSeries); unmarshalErr != nil {
+			return false
+		}
+		series = append(series, pageSeries...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("DynamoDB Scan error: %w", err)
+	}
+	if unmarshalErr != nil {
+		return nil, fmt.Errorf("error unmarshalling series: %w", unmarshalErr)
+	}
+	return series, nil
+}
+
+// QueryByWorkIDs looks the works up in the index and loads their series,
+// two batches of reads however many series the table holds.
+func (s *DynamoSeriesStore) QueryByWorkIDs(workIDs []string) ([]models.Series, error) {
+	keys := make([]string, 0, len(workIDs))
+	for _, workID := range workIDs {
+		if workID != "" {
+			keys = append(keys, seriesWorkPrefix+workID)
+		}
+	}
+	items, err := s.batchGet(keys)
+	if err != nil {
+		return nil, err
+	}
+	var works []seriesWork
+	if err := dynamodbattribute.UnmarshalListOfMaps(items, &works); err != nil {
+		return nil, fmt.Errorf("error unmarshalling series index: %w", err)
+	}
+
+	var seriesIDs []string
+	for _, work := range works {
+		seriesIDs = append(seriesIDs, work.SeriesIDs...)
+	}
+	items, err = s.batchGet(seriesIDs)
+	if err != nil {
+		return nil, err
+	}
+	var found []models.Series
+	if err := dynamodbattribute.UnmarshalListOfMaps(items, &found); err != nil {
+		return nil, fmt.Errorf("error unmarshalling series: %w", err)
+	}
+
+	// The index can be ahead of a series whose Put failed part way
+	var series []models.Series
+	for _, candidate := range found {
+		if seriesHasWork(candidate, workIDs) {
+			series = append(series, candidate)
+		}
+	}
+	return series, nil
+}
+
+// Reindex files every series under its works, repairing the index after a
+// failed write and building it for series written before it existed. It is
+// safe to run more than once and returns how many series it indexed.
+func (s *DynamoSeriesStore) Reindex() (int, error) {
+	all, err := s.List()
+	if err != nil {
+		return 0, err
+	}
+	for _, series := range all {
+		for _, workID := range seriesWorkIDs(series) {
+			if err := s.updateWorkIndex(workID, series.SeriesID, "ADD"); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(all), nil
+}
+
+// updateWorkIndex adds seriesID to the series of a work, or deletes it, with
+// action ADD or DELETE. DynamoDB removes the item's set once it is empty.
+func (s *DynamoSeriesStore) updateWorkIndex(workID, seriesID, action string) error {
+	_, err := s.svc.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:        aws.String(s.table),
+		Key:              map[string]*dynamodb.AttributeValue{"seriesId": {S: aws.String(seriesWorkPrefix + workID)}},
+		UpdateExpression: aws.String(action + " seriesIds :series"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":series": {SS: []*string{aws.String(seriesID)}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("DynamoDB UpdateItem error: %w", err)
+	}
+	return nil
+}
+
+// batchGet returns the items with the given seriesId keys, skipping missing
+// ones.
+func (s *DynamoSeriesStore) batchGet(ids []string) ([]map[string]*dynamodb.AttributeValue, error) {
+	seen := make(map[string]bool, len(ids))
+	var keys []map[string]*dynamodb.AttributeValue
+	for _, id := range ids {
+		if id == "" || seen[id] {
+			continue // BatchGetItem rejects duplicate keys
+		}
+		seen[id] = true
+		keys = append(keys, map[string]*dynamodb.AttributeValue{"seriesId": {S: aws.String(id)}})
+	}
+
+	var items []map[string]*dynamodb.AttributeValue
+	// BatchGetItem accepts at most 100 keys per call.
+	for start := 0; start < len(keys); start += 100 {
+		end := min(start+100, len(keys))
+		pending := map[string]*dynamodb.KeysAndAttributes{s.table: {Keys: keys[start:end]}}
+		for len(pending) > 0 {
+			out, err := s.svc.BatchGetItem(&dynamodb.BatchGetItemInput{RequestItems: pending})
+			if err != nil {
+				return nil, fmt.Errorf("DynamoDB BatchGetItem error: %w", err)
+			}
+			items = append(items, out.Responses[s.table]...)
+			pending = out.UnprocessedKeys
+		}
+	}
+	return items, nil
+}
+
+// seriesWorkIDs returns the works the series has entries for. Entries known
+// only by title have no work to index.
+func seriesWorkIDs(series models.Series) []string {
+	var workIDs []string
+	for _, entry := range series.Entries {
+		if entry.WorkID != "" && !slices.Contains(workIDs, entry.WorkID) {
+			workIDs = append(workIDs, entry.WorkID)
+		}
+	}
+	return workIDs
+}
+
+// seriesHasWork reports whether the series has an entry for any of the works.
+func seriesHasWork(series models.Series, workIDs []string) bool {
+	for _, entry := range series.Entries {
+		if entry.WorkID != "" && slices.Contains(workIDs, entry.WorkID) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSeriesWorkKey reports whether a seriesId is an index item's.
+func isSeriesWorkKey(seriesID string) bool {
+	return strings.HasPrefix(seriesID, seriesWorkPrefix)
+}
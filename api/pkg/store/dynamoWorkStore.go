@@ -0,0 +1,72 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// DynamoWorkStore is a WorkStore backed by the Works DynamoDB table.
+type DynamoWorkStore struct {
+	svc   dynamodbiface.DynamoDBAPI
+	table string
+}
+
+// NewDynamoWorkStore returns a WorkStore that reads and writes table.
+func NewDynamoWorkStore(svc dynamodbiface.DynamoDBAPI, table string) *DynamoWorkStore {
+	return &DynamoWorkStore{svc: svc, table: table}
+}
+
+func (s *DynamoWorkStore) Get(workID string) (*models.Work, error) {
+	result, err := s.svc.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(s.table),
+		Key:       workKey(workID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("DynamoDB GetItem error: %w", err)
+	}
+	if len(result.Item) == 0 {
+		return nil, ErrNotFound
+	}
+
+	var work models.Work
+	if err := dynamodbattribute.UnmarshalMap(result.Item, &work); err != nil {
+		return nil, fmt.Errorf("error unmarshalling work: %w", err)
+	}
+	return &work, nil
+}
+
+func (s *DynamoWorkStore) Put(work *models.Work) error {
+	item, err := dynamodbattribute.MarshalMap(work)
+	if err != nil {
+		return fmt.Errorf("error marshalling work: %w", err)
+	}
+
+	_, err = s.svc.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(s.table),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("DynamoDB PutItem error: %w", err)
+	}
+	return nil
+}
+
+func (s *DynamoWorkStore) Delete(workID string) error {
+	_, err := s.svc.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(s.table),
+		Key:       workKey(workID),
+	})
+	if err != nil {
+		return fmt.Errorf("DynamoDB DeleteItem error: %w", err)
+	}
+	return nil
+}
+
+func workKey(workID string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{"workId": {S: aws.String(workID)}}
+}
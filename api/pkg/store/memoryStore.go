@@ -0,0 +1,612 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/FriedGlue/BookIt/api/pkg/pagination"
+)
+
+// MemoryProfileStore is an in-memory ProfileStore for local development and
+// tests. Values are deep-copied on the way in and out so callers can never
+// mutate stored state without going through the store.
+type MemoryProfileStore struct {
+	mu       sync.RWMutex
+	profiles map[string]models.Profile
+}
+
+// NewMemoryProfileStore returns an empty in-memory ProfileStore.
+func NewMemoryProfileStore() *MemoryProfileStore {
+	return &MemoryProfileStore{profiles: make(map[string]models.Profile)}
+}
+
+func (s *MemoryProfileStore) Get(userID string) (*models.Profile, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	profile, ok := s.profiles[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return clone(&profile)
+}
+
+func (s *MemoryProfileStore) Put(profile *models.Profile) error {
+	stored, err := clone(profile)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored.Version = s.profiles[profile.ID].Version + 1
+	profile.Version = stored.Version
+	s.profiles[profile.ID] = *stored
+	return nil
+}
+
+func (s *MemoryProfileStore) Create(profile *models.Profile) error {
+	stored, err := clone(profile)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.profiles[profile.ID]; ok {
+		return ErrAlreadyExists
+	}
+	stored.Version = 1
+	profile.Version = stored.Version
+	s.profiles[profile.ID] = *stored
+	return nil
+}
+
+func (s *MemoryProfileStore) Update(userID string, mutate func(*models.Profile) error) (*models.Profile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.profiles[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	profile, err := clone(&current)
+	if err != nil {
+		return nil, err
+	}
+	if err := mutate(profile); err != nil {
+		return nil, err
+	}
+	// Updates are serialised by the mutex, so they can never conflict; the
+	// version is still bumped to mirror the DynamoDB store.
+	profile.Version = current.Version + 1
+	stored, err := clone(profile)
+	if err != nil {
+		return nil, err
+	}
+	s.profiles[userID] = *stored
+	return profile, nil
+}
+
+// UpdateWithLog appends the entries while holding the profile lock, so no
+// other update sees the profile change without them.
+func (s *MemoryProfileStore) UpdateWithLog(userID string, readingLog ReadingLogStore, mutate func(*models.Profile) ([]models.ReadingLogItem, error)) (*models.Profile, []models.ReadingLogItem, error) {
+	var entries []models.ReadingLogItem
+	profile, err := s.Update(userID, func(profile *models.Profile) error {
+		var err error
+		entries, err = mutate(profile)
+		if err != nil {
+			return err
+		}
+		if len(entries) > MaxLogEntriesPerUpdate {
+			return fmt.Errorf("%d reading log entries in one profile update, at most %d fit", len(entries), MaxLogEntriesPerUpdate)
+		}
+		for i := range entries {
+			if err := readingLog.Append(userID, &entries[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return profile, entries, nil
+}
+
+func (s *MemoryProfileStore) Delete(userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.profiles, userID)
+	return nil
+}
+
+func (s *MemoryProfileStore) ListIDs() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := make([]string, 0, len(s.profiles))
+	for id := range s.profiles {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// MemoryWorkStore is an in-memory WorkStore for local development and tests.
+type MemoryWorkStore struct {
+	mu    sync.RWMutex
+	works map[string]models.Work
+}
+
+// NewMemoryWorkStore returns an empty in-memory WorkStore.
+func NewMemoryWorkStore() *MemoryWorkStore {
+	return &MemoryWorkStore{works: make(map[string]models.Work)}
+}
+
+func (s *MemoryWorkStore) Get(workID string) (*models.Work, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	work, ok := s.works[workID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return clone(&work)
+}
+
+func (s *MemoryWorkStore) Put(work *models.Work) error {
+	stored, err := clone(work)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.works[work.WorkID] = *stored
+	return nil
+}
+
+func (s *MemoryWorkStore) Delete(workID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.works, workID)
+	return nil
+}
+
+// MemoryAuthorStore is an in-memory AuthorStore for local development and
+// tests.
+type MemoryAuthorStore struct {
+	mu      sync.RWMutex
+	authors map[string]models.Author
+}
+
+// NewMemoryAuthorStore returns an empty in-memory AuthorStore.
+func NewMemoryAuthorStore() *MemoryAuthorStore {
+	return &MemoryAuthorStore{authors: make(map[string]models.Author)}
+}
+
+func (s *MemoryAuthorStore) Get(authorID string) (*models.Author, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	author, ok := s.authors[authorID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return clone(&author)
+}
+
+func (s *MemoryAuthorStore) Put(author *models.Author) error {
+	stored, err := clone(author)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.authors[author.AuthorID] = *stored
+	return nil
+}
+
+// List returns the authors ordered by ID.
+func (s *MemoryAuthorStore) List() ([]models.Author, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	authors := make([]models.Author, 0, len(s.authors))
+	for _, author := range s.authors {
+		authors = append(authors, author)
+	}
+	sort.Slice(authors, func(i, j int) bool { return authors[i].AuthorID < authors[j].AuthorID })
+	return authors, nil
+}
+
+// MemorySeriesStore is an in-memory SeriesStore for local development and
+// tests.
+type MemorySeriesStore struct {
+	mu     sync.RWMutex
+	series map[string]models.Series
+}
+
+// NewMemorySeriesStore returns an empty in-memory SeriesStore.
+func NewMemorySeriesStore() *MemorySeriesStore {
+	return &MemorySeriesStore{series: make(map[string]models.Series)}
+}
+
+func (s *MemorySeriesStore) Get(seriesID string) (*models.Series, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	series, ok := s.series[seriesID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return clone(&series)
+}
+
+func (s *MemorySeriesStore) Put(series *models.Series) error {
+	stored, err := clone(series)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.series[series.SeriesID] = *stored
+	return nil
+}
+
+func (s *MemorySeriesStore) Delete(seriesID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.series, seriesID)
+	return nil
+}
+
+// List returns the series ordered by ID.
+func (s *MemorySeriesStore) List() ([]models.Series, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	all := make([]models.Series, 0, len(s.series))
+	for _, series := range s.series {
+		copied, err := clone(&series)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, *copied)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].SeriesID < all[j].SeriesID })
+	return all, nil
+}
+
+// QueryByWorkIDs returns the series with an entry for any of the works,
+// ordered by ID.
+func (s *MemorySeriesStore) QueryByWorkIDs(workIDs []string) ([]models.Series, error) {
+	all, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	var matching []models.Series
+	for _, series := range all {
+		if seriesHasWork(series, workIDs) {
+			matching = append(matching, series)
+		}
+	}
+	return matching, nil
+}
+
+// MemoryBookStore is an in-memory BookStore for local development and tests.
+type MemoryBookStore struct {
+	mu    sync.RWMutex
+	books map[string]models.BookData
+}
+
+// NewMemoryBookStore returns an empty in-memory BookStore.
+func NewMemoryBookStore() *MemoryBookStore {
+	return &MemoryBookStore{books: make(map[string]models.BookData)}
+}
+
+func (s *MemoryBookStore) Get(bookID string) (*models.BookData, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	book, ok := s.books[bookID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return clone(&book)
+}
+
+func (s *MemoryBookStore) GetMany(bookIDs []string) ([]models.BookData, error) {
+	wanted := make(map[string]bool, len(bookIDs))
+	for _, id := range bookIDs {
+		wanted[id] = true
+	}
+	return s.filter(func(b models.BookData) bool { return wanted[b.BookID] })
+}
+
+func (s *MemoryBookStore) Put(book *models.BookData) error {
+	book.TitleLowercase = strings.ToLower(book.Title)
+	stored, err := clone(book)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.books[book.BookID] = *stored
+	return nil
+}
+
+func (s *MemoryBookStore) Update(bookID string, mutate func(*models.BookData) error) (*models.BookData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.books[bookID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	book, err := clone(&current)
+	if err != nil {
+		return nil, err
+	}
+	if err := mutate(book); err != nil {
+		return nil, err
+	}
+	book.BookID = bookID
+	book.TitleLowercase = strings.ToLower(book.Title)
+	stored, err := clone(book)
+	if err != nil {
+		return nil, err
+	}
+	s.books[bookID] = *stored
+	return book, nil
+}
+
+func (s *MemoryBookStore) Delete(bookID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.books, bookID)
+	return nil
+}
+
+func (s *MemoryBookStore) List() ([]models.BookData, error) {
+	return s.filter(func(models.BookData) bool { return true })
+}
+
+func (s *MemoryBookStore) ListPage(page pagination.Request) ([]models.BookData, string, error) {
+	books, err := s.List()
+	if err != nil {
+		return nil, "", err
+	}
+	books, next := pagination.Slice(books, page, func(b models.BookData) string { return b.BookID })
+	return books, next, nil
+}
+
+func (s *MemoryBookStore) QueryByISBN(isbn string) ([]models.BookData, error) {
+	return s.filter(func(b models.BookData) bool { return b.ISBN13 == isbn })
+}
+
+func (s *MemoryBookStore) QueryByOpenLibraryID(openLibraryID string) ([]models.BookData, error) {
+	return s.filter(func(b models.BookData) bool { return b.OpenLibraryId == openLibraryID })
+}
+
+func (s *MemoryBookStore) QueryByWorkID(workID string) ([]models.BookData, error) {
+	return s.filter(func(b models.BookData) bool { return b.WorkID == workID })
+}
+
+func (s *MemoryBookStore) QueryByAuthorID(authorID string) ([]models.BookData, error) {
+	return s.filter(func(b models.BookData) bool { return slices.Contains(b.AuthorIDs, authorID) })
+}
+
+// filter returns copies of the books matching keep, ordered by bookId so
+// results are deterministic.
+func (s *MemoryBookStore) filter(keep func(models.BookData) bool) ([]models.BookData, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var books []models.BookData
+	for _, book := range s.books {
+		if !keep(book) {
+			continue
+		}
+		copied, err := clone(&book)
+		if err != nil {
+			return nil, err
+		}
+		books = append(books, *copied)
+	}
+	sort.Slice(books, func(i, j int) bool { return books[i].BookID < books[j].BookID })
+	return books, nil
+}
+
+// MemorySearchIndexStore is an in-memory SearchIndexStore for local
+// development and tests.
+type MemorySearchIndexStore struct {
+	mu       sync.RWMutex
+	postings map[string]map[string]float64 // term → bookId → weight
+	terms    map[string][]string           // bookId → its terms
+}
+
+// NewMemorySearchIndexStore returns an empty in-memory SearchIndexStore.
+func NewMemorySearchIndexStore() *MemorySearchIndexStore {
+	return &MemorySearchIndexStore{
+		postings: make(map[string]map[string]float64),
+		terms:    make(map[string][]string),
+	}
+}
+
+func (s *MemorySearchIndexStore) Replace(bookID string, terms map[string]float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(bookID)
+	bookTerms := make([]string, 0, len(terms))
+	for term, weight := range terms {
+		if s.postings[term] == nil {
+			s.postings[term] = make(map[string]float64)
+		}
+		s.postings[term][bookID] = weight
+		bookTerms = append(bookTerms, term)
+	}
+	s.terms[bookID] = bookTerms
+	return nil
+}
+
+func (s *MemorySearchIndexStore) Remove(bookID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remove(bookID)
+	return nil
+}
+
+func (s *MemorySearchIndexStore) remove(bookID string) {
+	for _, term := range s.terms[bookID] {
+		delete(s.postings[term], bookID)
+		if len(s.postings[term]) == 0 {
+			delete(s.postings, term)
+		}
+	}
+	delete(s.terms, bookID)
+}
+
+func (s *MemorySearchIndexStore) Postings(ctx context.Context, term string) (map[string]float64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	postings := make(map[string]float64, len(s.postings[term]))
+	for bookID, weight := range s.postings[term] {
+		postings[bookID] = weight
+	}
+	return postings, nil
+}
+
+func (s *MemorySearchIndexStore) DocumentCount(ctx context.Context) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.terms), nil
+}
+
+// clone deep-copies v through its JSON representation, which is also the
+// shape it is persisted in.
+func clone[T any](v *T) (*T, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out T
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// MemoryReadingLogStore is an in-memory ReadingLogStore for local development
+// and tests.
+type MemoryReadingLogStore struct {
+	mu      sync.RWMutex
+	entries map[string][]models.ReadingLogItem
+}
+
+// NewMemoryReadingLogStore returns an empty in-memory ReadingLogStore.
+func NewMemoryReadingLogStore() *MemoryReadingLogStore {
+	return &MemoryReadingLogStore{entries: make(map[string][]models.ReadingLogItem)}
+}
+
+func (s *MemoryReadingLogStore) Append(userID string, item *models.ReadingLogItem) error {
+	prepareReadingLogItem(userID, item)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := s.entries[userID]
+	i := sort.Search(len(entries), func(i int) bool { return entries[i].Id >= item.Id })
+	if i < len(entries) && entries[i].Id == item.Id {
+		entries[i] = *item
+		return nil
+	}
+	entries = append(entries, models.ReadingLogItem{})
+	copy(entries[i+1:], entries[i:])
+	entries[i] = *item
+	s.entries[userID] = entries
+	return nil
+}
+
+func (s *MemoryReadingLogStore) Query(userID string, from, to time.Time) ([]models.ReadingLogItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	lower, upper := readingLogRange(from, to)
+	var items []models.ReadingLogItem
+	for _, item := range s.entries[userID] {
+		if lower != "" && item.Id < lower {
+			continue
+		}
+		if upper != "" && item.Id > upper {
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (s *MemoryReadingLogStore) QueryPage(userID string, from, to time.Time, page pagination.Request) ([]models.ReadingLogItem, string, error) {
+	items, err := s.Query(userID, from, to)
+	if err != nil {
+		return nil, "", err
+	}
+	items, next := pagination.Slice(items, page, func(item models.ReadingLogItem) string { return item.Id })
+	return items, next, nil
+}
+
+func (s *MemoryReadingLogStore) Update(userID, itemID string, mutate func(*models.ReadingLogItem) error) (*models.ReadingLogItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, entry := range s.entries[userID] {
+		if entry.Id != itemID {
+			continue
+		}
+		item := entry
+		if err := mutate(&item); err != nil {
+			return nil, err
+		}
+		item.UserID, item.Id = userID, itemID
+		s.entries[userID][i] = item
+		return &item, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (s *MemoryReadingLogStore) Delete(userID, itemID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := s.entries[userID]
+	for i, entry := range entries {
+		if entry.Id == itemID {
+			s.entries[userID] = append(entries[:i], entries[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (s *MemoryReadingLogStore) DeleteAll(userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, userID)
+	return nil
+}
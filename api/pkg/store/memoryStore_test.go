@@ -0,0 +1,246 @@
+package store
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/FriedGlue/BookIt/api/pkg/pagination"
+)
+
+func TestMemoryProfileStore(t *testing.T) {
+	errMutate := errors.New("mutate failed")
+	tests := []struct {
+		name        string
+		op          func(s *MemoryProfileStore) error
+		want        error
+		wantVersion int64 // of "user" afterwards, 0 if missing
+		wantName    string
+	}{
+		{
+			name:        "create",
+			op:          func(s *MemoryProfileStore) error { return s.Create(&models.Profile{ID: "other"}) },
+			wantVersion: 1, wantName: "before",
+		},
+		{
+			name:        "create existing",
+			op:          func(s *MemoryProfileStore) error { return s.Create(&models.Profile{ID: "user"}) },
+			want:        ErrAlreadyExists,
+			wantVersion: 1, wantName: "before",
+		},
+		{
+			name: "update",
+			op: func(s *MemoryProfileStore) error {
+				_, err := s.Update("user", func(p *models.Profile) error {
+					p.ProfileInformation.Username = "after"
+					return nil
+				})
+				return err
+			},
+			wantVersion: 2, wantName: "after",
+		},
+		{
+			name: "failed update",
+			op: func(s *MemoryProfileStore) error {
+				_, err := s.Update("user", func(p *models.Profile) error {
+					p.ProfileInformation.Username = "after"
+					return errMutate
+				})
+				return err
+			},
+			want:        errMutate,
+			wantVersion: 1, wantName: "before",
+		},
+		{
+			name: "update missing",
+			op: func(s *MemoryProfileStore) error {
+				_, err := s.Update("missing", func(*models.Profile) error { return nil })
+				return err
+			},
+			want:        ErrNotFound,
+			wantVersion: 1, wantName: "before",
+		},
+		{
+			name:        "put",
+			op:          func(s *MemoryProfileStore) error { return s.Put(&models.Profile{ID: "user"}) },
+			wantVersion: 2,
+		},
+		{
+			name: "changing a loaded profile",
+			op: func(s *MemoryProfileStore) error {
+				p, err := s.Get("user")
+				if err == nil {
+					p.ProfileInformation.Username = "after"
+				}
+				return err
+			},
+			wantVersion: 1, wantName: "before",
+		},
+		{
+			name:        "delete",
+			op:          func(s *MemoryProfileStore) error { return s.Delete("user") },
+			wantVersion: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewMemoryProfileStore()
+			if err := s.Create(&models.Profile{ID: "user", ProfileInformation: models.ProfileInformation{Username: "before"}}); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tt.op(s); !errors.Is(err, tt.want) {
+				t.Fatalf("error = %v, want %v", err, tt.want)
+			}
+			got, err := s.Get("user")
+			if tt.wantVersion == 0 {
+				if !errors.Is(err, ErrNotFound) {
+					t.Errorf("Get after delete = %v, %v, want ErrNotFound", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Version != tt.wantVersion || got.ProfileInformation.Username != tt.wantName {
+				t.Errorf("stored version %d, username %q, want %d, %q",
+					got.Version, got.ProfileInformation.Username, tt.wantVersion, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestMemoryProfileStoreUpdateWithLog(t *testing.T) {
+	errMutate := errors.New("mutate failed")
+	for _, mutateErr := range []error{nil, errMutate} {
+		s := NewMemoryProfileStore()
+		if err := s.Create(&models.Profile{ID: "user"}); err != nil {
+			t.Fatal(err)
+		}
+		readingLog := NewMemoryReadingLogStore()
+
+		_, entries, err := s.UpdateWithLog("user", readingLog, func(p *models.Profile) ([]models.ReadingLogItem, error) {
+			p.ProfileInformation.Username = "after"
+			return []models.ReadingLogItem{{Date: "2026-10-17T10:00:00Z", BookID: "book"}}, mutateErr
+		})
+		if !errors.Is(err, mutateErr) {
+			t.Fatalf("UpdateWithLog error = %v, want %v", err, mutateErr)
+		}
+
+		profile, err := s.Get("user")
+		if err != nil {
+			t.Fatal(err)
+		}
+		logged, err := readingLog.Query("user", time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mutateErr != nil {
+			if profile.Version != 1 || len(logged) != 0 {
+				t.Errorf("failed update stored version %d and %d entries, want 1 and none", profile.Version, len(logged))
+			}
+			continue
+		}
+		if profile.Version != 2 || profile.ProfileInformation.Username != "after" {
+			t.Errorf("stored %+v, want version 2 with the change", profile)
+		}
+		if len(logged) != 1 || len(entries) != 1 || logged[0] != entries[0] || logged[0].Id == "" {
+			t.Errorf("logged %+v, returned %+v", logged, entries)
+		}
+	}
+}
+
+func TestMemoryBookStoreQueries(t *testing.T) {
+	s := NewMemoryBookStore()
+	for _, book := range []models.BookData{
+		{BookID: "b", ISBN13: "9780306406157", WorkID: "w1", AuthorIDs: []string{"a1"}, OpenLibraryId: "OL1M"},
+		{BookID: "a", ISBN13: "9780804429573", WorkID: "w1", AuthorIDs: []string{"a1", "a2"}},
+		{BookID: "c", WorkID: "w2", AuthorIDs: []string{"a2"}},
+	} {
+		if err := s.Put(&book); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query func() ([]models.BookData, error)
+		want  []string
+	}{
+		{"isbn", func() ([]models.BookData, error) { return s.QueryByISBN("9780306406157") }, []string{"b"}},
+		{"unknown isbn", func() ([]models.BookData, error) { return s.QueryByISBN("9791090636071") }, nil},
+		{"open library ID", func() ([]models.BookData, error) { return s.QueryByOpenLibraryID("OL1M") }, []string{"b"}},
+		{"work", func() ([]models.BookData, error) { return s.QueryByWorkID("w1") }, []string{"a", "b"}},
+		{"author", func() ([]models.BookData, error) { return s.QueryByAuthorID("a2") }, []string{"a", "c"}},
+		{"many", func() ([]models.BookData, error) { return s.GetMany([]string{"c", "missing", "a"}) }, []string{"a", "c"}},
+		{"list", s.List, []string{"a", "b", "c"}},
+		{"first page", func() ([]models.BookData, error) {
+			books, _, err := s.ListPage(pagination.Request{Limit: 2})
+			return books, err
+		}, []string{"a", "b"}},
+		{"second page", func() ([]models.BookData, error) {
+			books, _, err := s.ListPage(pagination.Request{Limit: 2, After: "b"})
+			return books, err
+		}, []string{"c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			books, err := tt.query()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, book := range books {
+				ids = append(ids, book.BookID)
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("got %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryReadingLogStoreQuery(t *testing.T) {
+	s := NewMemoryReadingLogStore()
+	for _, date := range []string{"2025-01-03T00:00:00Z", "2025-01-01T00:00:00Z", "2025-01-02T12:00:00Z"} {
+		if err := s.Append("user", &models.ReadingLogItem{BookID: "b", Date: date}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Append("other", &models.ReadingLogItem{BookID: "b", Date: "2025-01-02T00:00:00Z"}); err != nil {
+		t.Fatal(err)
+	}
+
+	day := func(d int) time.Time { return time.Date(2025, 1, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     []string
+	}{
+		{"everything", time.Time{}, time.Time{}, []string{"2025-01-01T00:00:00Z", "2025-01-02T12:00:00Z", "2025-01-03T00:00:00Z"}},
+		{"from", day(2), time.Time{}, []string{"2025-01-02T12:00:00Z", "2025-01-03T00:00:00Z"}},
+		{"to is inclusive", time.Time{}, day(3), []string{"2025-01-01T00:00:00Z", "2025-01-02T12:00:00Z", "2025-01-03T00:00:00Z"}},
+		{"between", day(2), day(2).Add(13 * time.Hour), []string{"2025-01-02T12:00:00Z"}},
+		{"empty range", day(4), day(5), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := s.Query("user", tt.from, tt.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var dates []string
+			for _, item := range items {
+				dates = append(dates, item.Date)
+				if item.UserID != "user" {
+					t.Errorf("entry %s belongs to %q", item.Id, item.UserID)
+				}
+			}
+			if !slices.Equal(dates, tt.want) {
+				t.Errorf("got %v, want %v", dates, tt.want)
+			}
+		})
+	}
+}
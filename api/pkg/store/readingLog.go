@@ -0,0 +1,34 @@
+package store
+
+import (
+	"time"
+
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+)
+
+// prepareReadingLogItem fills in the key attributes of a new entry.
+func prepareReadingLogItem(userID string, item *models.ReadingLogItem) {
+	item.UserID = userID
+	if item.Id != "" {
+		return
+	}
+	date, err := time.Parse(time.RFC3339, item.Date)
+	if err != nil {
+		date = time.Now()
+		item.Date = date.Format(time.RFC3339)
+	}
+	item.Id = models.NewReadingLogID(date)
+}
+
+// readingLogRange converts a time range into inclusive bounds on entry IDs.
+// An empty bound means that end of the range is open.
+func readingLogRange(from, to time.Time) (lower, upper string) {
+	if !from.IsZero() {
+		lower = models.ReadingLogIDPrefix(from)
+	}
+	if !to.IsZero() {
+		// Every ID made at `to` continues with "#", which sorts before "~".
+		upper = models.ReadingLogIDPrefix(to) + "~"
+	}
+	return lower, upper
+}
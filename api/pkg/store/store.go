@@ -0,0 +1,197 @@
+// Package store hides the persistence of profiles and books behind small
+// interfaces so handlers can run against DynamoDB in production and an
+// in-memory implementation locally and in tests.
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/FriedGlue/BookIt/api/pkg/models"
+	"github.com/FriedGlue/BookIt/api/pkg/pagination"
+)
+
+var (
+	// ErrNotFound is returned when the requested item does not exist.
+	ErrNotFound = errors.New("item not found")
+
+	// ErrConflict is returned by ProfileStore.Update when the profile kept
+	// changing underneath it and every retry lost the race.
+	ErrConflict = errors.New("profile was modified concurrently")
+
+	// ErrAlreadyExists is returned by ProfileStore.Create when the profile
+	// was created first by someone else.
+	ErrAlreadyExists = errors.New("profile already exists")
+)
+
+// MaxLogEntriesPerUpdate is how many reading log entries
+// ProfileStore.UpdateWithLog can write along with the profile: a DynamoDB
+// transaction takes at most 100 items.
+const MaxLogEntriesPerUpdate = 99
+
+// ProfileStore persists user profiles keyed by the Cognito "sub".
+type ProfileStore interface {
+	// Get returns the profile for userID or ErrNotFound.
+	Get(userID string) (*models.Profile, error)
+
+	// Put creates or replaces a profile unconditionally. Use Create for a
+	// new profile and Update for read-modify-write changes to an existing
+	// one.
+	Put(profile *models.Profile) error
+
+	// Create writes a new profile, or returns ErrAlreadyExists if one exists
+	// for its ID, so two first writes cannot overwrite each other.
+	Create(profile *models.Profile) error
+
+	// Update loads the profile for userID, applies mutate and writes the
+	// result back only if nobody else wrote the profile in the meantime. On
+	// a conflicting write the profile is reloaded and mutate is run again,
+	// so mutate must not have side effects outside the profile. If mutate
+	// returns an error nothing is written and the error is returned
+	// unchanged; if every attempt conflicts ErrConflict is returned.
+	Update(userID string, mutate func(*models.Profile) error) (*models.Profile, error)
+
+	// UpdateWithLog is Update for changes the reading log records: mutate
+	// also returns entries to append to readingLog, which are written
+	// together with the profile, so either both are stored or neither is.
+	// It returns the entries as stored. readingLog must be backed by the
+	// same database as the profiles, and at most MaxLogEntriesPerUpdate
+	// entries can be written at once.
+	UpdateWithLog(userID string, readingLog ReadingLogStore, mutate func(*models.Profile) ([]models.ReadingLogItem, error)) (*models.Profile, []models.ReadingLogItem, error)
+
+	// Delete removes the profile for userID.
+	Delete(userID string) error
+
+	// ListIDs returns the user ID of every profile, for maintenance jobs
+	// that have to visit them all.
+	ListIDs() ([]string, error)
+}
+
+// BookStore persists book metadata keyed by bookId.
+type BookStore interface {
+	// Get returns the book with the given ID or ErrNotFound.
+	Get(bookID string) (*models.BookData, error)
+
+	// GetMany returns the books with the given IDs, in no particular order.
+	// IDs of missing books are skipped.
+	GetMany(bookIDs []string) ([]models.BookData, error)
+
+	// Put creates or replaces a book.
+	Put(book *models.BookData) error
+
+	// Update loads the book, applies mutate and writes the result back.
+	Update(bookID string, mutate func(*models.BookData) error) (*models.BookData, error)
+
+	// Delete removes the book with the given ID.
+	Delete(bookID string) error
+
+	// List returns every stored book.
+	List() ([]models.BookData, error)
+
+	// ListPage returns up to page.Limit books following the book with ID
+	// page.After, and the ID to pass as After for the next page, empty
+	// after the last. The order is stable but need not be by ID.
+	ListPage(page pagination.Request) ([]models.BookData, string, error)
+
+	// QueryByISBN returns the books whose ISBN-13 matches isbn exactly.
+	QueryByISBN(isbn string) ([]models.BookData, error)
+
+	// QueryByOpenLibraryID returns the books saved from the given Open Library work.
+	QueryByOpenLibraryID(openLibraryID string) ([]models.BookData, error)
+
+	// QueryByWorkID returns the editions of a work.
+	QueryByWorkID(workID string) ([]models.BookData, error)
+
+	// QueryByAuthorID returns the books linked to an author.
+	QueryByAuthorID(authorID string) ([]models.BookData, error)
+}
+
+// AuthorStore persists authors (see models.Author) keyed by authorId.
+type AuthorStore interface {
+	// Get returns the author with the given ID or ErrNotFound.
+	Get(authorID string) (*models.Author, error)
+
+	// Put creates or replaces an author.
+	Put(author *models.Author) error
+
+	// List returns every author.
+	List() ([]models.Author, error)
+}
+
+// WorkStore persists works (see models.Work) keyed by workId.
+type WorkStore interface {
+	// Get returns the work with the given ID or ErrNotFound.
+	Get(workID string) (*models.Work, error)
+
+	// Put creates or replaces a work.
+	Put(work *models.Work) error
+
+	// Delete removes the work with the given ID.
+	Delete(workID string) error
+}
+
+// SeriesStore persists series (see models.Series) keyed by seriesId.
+type SeriesStore interface {
+	// Get returns the series with the given ID or ErrNotFound.
+	Get(seriesID string) (*models.Series, error)
+
+	// Put creates or replaces a series.
+	Put(series *models.Series) error
+
+	// Delete removes the series with the given ID.
+	Delete(seriesID string) error
+
+	// List returns every series.
+	List() ([]models.Series, error)
+
+	// QueryByWorkIDs returns the series with an entry for any of the works,
+	// without reading every series.
+	QueryByWorkIDs(workIDs []string) ([]models.Series, error)
+}
+
+// SearchIndexStore persists an inverted index over books: for each term, the
+// books containing it and a weight saying how strongly. Turning books into
+// terms and ranking matches is the search package's job.
+type SearchIndexStore interface {
+	// Replace sets the postings of bookID to terms (term to weight),
+	// removing any postings it had for other terms.
+	Replace(bookID string, terms map[string]float64) error
+
+	// Remove deletes every posting of bookID. Removing a book that is not
+	// indexed does nothing.
+	Remove(bookID string) error
+
+	// Postings returns the weight of term in each book containing it. It
+	// gives up when ctx is done, as searches run against a deadline.
+	Postings(ctx context.Context, term string) (map[string]float64, error)
+
+	// DocumentCount returns how many books are indexed.
+	DocumentCount(ctx context.Context) (int, error)
+}
+
+// ReadingLogStore persists reading log entries keyed by user ID and a
+// chronologically sortable entry ID (see models.NewReadingLogID).
+type ReadingLogStore interface {
+	// Append stores a new entry for userID. If item.Id is empty one is
+	// generated from item.Date.
+	Append(userID string, item *models.ReadingLogItem) error
+
+	// Query returns userID's entries made between from and to inclusive,
+	// oldest first. A zero from or to leaves that end of the range open.
+	Query(userID string, from, to time.Time) ([]models.ReadingLogItem, error)
+
+	// QueryPage is Query a page at a time: it returns up to page.Limit
+	// entries with IDs after page.After, and the ID to pass as After for the
+	// next page, empty after the last.
+	QueryPage(userID string, from, to time.Time, page pagination.Request) ([]models.ReadingLogItem, string, error)
+
+	// Update loads one entry, applies mutate and writes it back.
+	Update(userID, itemID string, mutate func(*models.ReadingLogItem) error) (*models.ReadingLogItem, error)
+
+	// Delete removes one entry. Deleting a missing entry returns ErrNotFound.
+	Delete(userID, itemID string) error
+
+	// DeleteAll removes every entry belonging to userID.
+	DeleteAll(userID string) error
+}
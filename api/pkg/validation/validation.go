@@ -0,0 +1,261 @@
+// Package validation decodes request bodies and checks them against the rules
+// declared on the request types, so every handler rejects bad input the same
+// way: one 422 response listing all the field errors.
+//
+// Rules are declared in a `validate` struct tag as a comma-separated list:
+//
+//	required    the field must not be its zero value
+//	min=N       numbers must be >= N; strings, slices and maps need at least N elements
+//	max=N       numbers must be <= N; strings, slices and maps may have at most N elements
+//	oneof=a b   the field must be one of the space-separated values
+//	isbn        the string must be a valid ISBN-10 or ISBN-13 (see package isbn)
+//
+// Optional fields are only checked against min, max and oneof when set.
+// Rules that span several fields go in a Validate method (see Validator).
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/FriedGlue/BookIt/api/pkg/isbn"
+	"github.com/FriedGlue/BookIt/api/pkg/shared"
+)
+
+// FieldError describes one field that failed validation. Field is the JSON
+// name of the field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Validator is implemented by request types with rules that cannot be
+// expressed in tags, e.g. an end date that must follow a start date. It runs
+// after the tag rules and its errors are reported alongside theirs.
+type Validator interface {
+	Validate() []FieldError
+}
+
+// Decode unmarshals body into v, which must be a pointer to a struct, and
+// validates the result. A body that is not a JSON object returns an
+// INVALID_JSON error; unknown fields, values of the wrong type and rule
+// violations are all collected into a single VALIDATION_FAILED error whose
+// details list every FieldError. An empty body is treated as {}.
+func Decode(body string, v interface{}) *shared.APIError {
+	if strings.TrimSpace(body) == "" {
+		body = "{}"
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(body), &fields); err != nil {
+		return shared.NewError(shared.CodeInvalidJSON, "Request body must be a JSON object")
+	}
+
+	errs := unknownFields(fields, reflect.TypeOf(v).Elem())
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(body)))
+	if len(errs) == 0 {
+		// Unknown top-level fields are already reported; this catches the
+		// ones in nested objects.
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(v); err != nil {
+		// The decoder carries on past a value of the wrong type, so the rest
+		// of v is still worth validating. Any other error leaves v half
+		// decoded.
+		var typeErr *json.UnmarshalTypeError
+		if !errors.As(err, &typeErr) {
+			return shared.NewError(shared.CodeInvalidJSON, "Invalid JSON: "+err.Error())
+		}
+		errs = append(errs, FieldError{Field: typeErr.Field, Message: "must be a " + jsonType(typeErr.Type)})
+	}
+
+	errs = firstPerField(append(errs, Struct(v)...))
+	if len(errs) > 0 {
+		return shared.NewError(shared.CodeValidationFailed, "Request failed validation").WithDetails(errs)
+	}
+	return nil
+}
+
+// Struct checks v, a struct or pointer to one, against its `validate` tags
+// and its Validate method if it has one.
+func Struct(v interface{}) []FieldError {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	var errs []FieldError
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		tag := field.Tag.Get("validate")
+		if tag == "" || !field.IsExported() {
+			continue
+		}
+		if err := checkField(jsonName(field), value.Field(i), tag); err != nil {
+			errs = append(errs, *err)
+		}
+	}
+
+	if validator, ok := v.(Validator); ok {
+		errs = append(errs, validator.Validate()...)
+	} else if validator, ok := value.Interface().(Validator); ok {
+		errs = append(errs, validator.Validate()...)
+	}
+	return errs
+}
+
+// checkField applies the rules in tag to one field and returns the first
+// rule it breaks.
+func checkField(name string, value reflect.Value, tag string) *FieldError {
+	rules := strings.Split(tag, ",")
+	for _, rule := range rules {
+		if rule == "required" && value.IsZero() {
+			return &FieldError{Field: name, Message: "is required"}
+		}
+	}
+	if value.IsZero() {
+		return nil
+	}
+	// Optional fields are pointers; the rules apply to what they point to.
+	set := reflect.Indirect(value)
+
+	for _, rule := range rules {
+		key, arg, _ := strings.Cut(rule, "=")
+		switch key {
+		case "required":
+		case "min", "max":
+			limit, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				panic(fmt.Sprintf("validation: bad %s rule %q on %s", key, rule, name))
+			}
+			if msg := checkLimit(key, set, limit); msg != "" {
+				return &FieldError{Field: name, Message: msg}
+			}
+		case "oneof":
+			allowed := strings.Fields(arg)
+			actual := fmt.Sprint(set.Interface())
+			found := false
+			for _, a := range allowed {
+				if a == actual {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return &FieldError{Field: name, Message: "must be one of " + strings.Join(allowed, ", ")}
+			}
+		case "isbn":
+			// An empty string clears an optional ISBN.
+			if set.Kind() != reflect.String {
+				panic(fmt.Sprintf("validation: isbn rule on unsupported kind %s", set.Kind()))
+			}
+			if set.String() == "" {
+				continue
+			}
+			if _, err := isbn.Parse(set.String()); err != nil {
+				return &FieldError{Field: name, Message: "must be a valid ISBN-10 or ISBN-13"}
+			}
+		default:
+			panic(fmt.Sprintf("validation: unknown rule %q on %s", rule, name))
+		}
+	}
+	return nil
+}
+
+// checkLimit compares a number, or the length of a string, slice or map,
+// against a min or max limit.
+func checkLimit(key string, value reflect.Value, limit float64) string {
+	var actual float64
+	var what string
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		actual = float64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		actual = float64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		actual = value.Float()
+	case reflect.String:
+		actual, what = float64(len([]rune(value.String()))), " characters"
+	case reflect.Slice, reflect.Map:
+		actual, what = float64(value.Len()), " items"
+	default:
+		panic(fmt.Sprintf("validation: %s rule on unsupported kind %s", key, value.Kind()))
+	}
+
+	limitText := strconv.FormatFloat(limit, 'f', -1, 64)
+	if key == "min" && actual < limit {
+		if what != "" {
+			return "must have at least " + limitText + what
+		}
+		return "must be at least " + limitText
+	}
+	if key == "max" && actual > limit {
+		if what != "" {
+			return "must have at most " + limitText + what
+		}
+		return "must be at most " + limitText
+	}
+	return ""
+}
+
+// unknownFields reports the keys in fields that t does not decode.
+func unknownFields(fields map[string]json.RawMessage, t reflect.Type) []FieldError {
+	known := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		known[strings.ToLower(jsonName(t.Field(i)))] = true
+	}
+
+	var errs []FieldError
+	for key := range fields {
+		// encoding/json matches field names case-insensitively.
+		if !known[strings.ToLower(key)] {
+			errs = append(errs, FieldError{Field: key, Message: "is not a known field"})
+		}
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+	return errs
+}
+
+// firstPerField drops all but the first error reported for each field, so a
+// value of the wrong type is not also reported as missing.
+func firstPerField(errs []FieldError) []FieldError {
+	seen := make(map[string]bool, len(errs))
+	out := errs[:0]
+	for _, err := range errs {
+		if !seen[err.Field] {
+			seen[err.Field] = true
+			out = append(out, err)
+		}
+	}
+	return out
+}
+
+// jsonName returns the name a struct field is encoded under.
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// jsonType describes a Go type in JSON terms for error messages.
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	default:
+		return "string"
+	}
+}
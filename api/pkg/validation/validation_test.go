@@ -0,0 +1,128 @@
+package validation
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/FriedGlue/BookIt/api/pkg/shared"
+)
+
+type testAuthor struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type testRequest struct {
+	Title    string      `json:"title" validate:"required,max=10"`
+	Pages    int         `json:"pages" validate:"min=1,max=5000"`
+	Status   string      `json:"status" validate:"oneof=reading finished"`
+	ISBN     *string     `json:"isbn" validate:"isbn"`
+	Tags     []string    `json:"tags" validate:"max=2"`
+	Rating   *int        `json:"rating" validate:"min=1,max=5"`
+	Author   *testAuthor `json:"author"`
+	Start    int         `json:"start"`
+	End      int         `json:"end"`
+	Internal string      `json:"-"`
+}
+
+func (r testRequest) Validate() []FieldError {
+	if r.End != 0 && r.End < r.Start {
+		return []FieldError{{Field: "end", Message: "must not be before start"}}
+	}
+	return nil
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantCode shared.ErrorCode
+		wantErrs []FieldError
+	}{
+		{name: "valid", body: `{"title":"Dune","pages":412,"status":"reading","isbn":"978-0-441-17271-9","tags":["sf"],"rating":5}`},
+		{name: "optional fields unset", body: `{"title":"Dune"}`},
+		{name: "empty isbn clears", body: `{"title":"Dune","isbn":""}`},
+		{name: "field names are case-insensitive", body: `{"Title":"Dune"}`},
+		{name: "not an object", body: `[1,2]`, wantCode: shared.CodeInvalidJSON},
+		{name: "truncated", body: `{"title":`, wantCode: shared.CodeInvalidJSON},
+		{
+			name:     "empty body",
+			body:     "",
+			wantCode: shared.CodeValidationFailed,
+			wantErrs: []FieldError{{"title", "is required"}},
+		},
+		{
+			name:     "every rule reported",
+			body:     `{"title":"A very long title","pages":0,"status":"lost","isbn":"123","tags":["a","b","c"],"start":5,"end":3}`,
+			wantCode: shared.CodeValidationFailed,
+			wantErrs: []FieldError{
+				{"title", "must have at most 10 characters"},
+				{"status", "must be one of reading, finished"},
+				{"isbn", "must be a valid ISBN-10 or ISBN-13"},
+				{"tags", "must have at most 2 items"},
+				{"end", "must not be before start"},
+			},
+		},
+		{
+			name:     "number out of range",
+			body:     `{"title":"Dune","pages":6000}`,
+			wantCode: shared.CodeValidationFailed,
+			wantErrs: []FieldError{{"pages", "must be at most 5000"}},
+		},
+		{
+			name:     "optional number out of range",
+			body:     `{"title":"Dune","rating":0}`,
+			wantCode: shared.CodeValidationFailed,
+			wantErrs: []FieldError{{"rating", "must be at least 1"}},
+		},
+		{
+			name:     "unknown fields",
+			body:     `{"title":"Dune","subtitle":"x","Internal":"y"}`,
+			wantCode: shared.CodeValidationFailed,
+			wantErrs: []FieldError{{"Internal", "is not a known field"}, {"subtitle", "is not a known field"}},
+		},
+		{
+			name:     "unknown nested field",
+			body:     `{"title":"Dune","author":{"name":"Herbert","born":1920}}`,
+			wantCode: shared.CodeInvalidJSON,
+		},
+		{
+			name:     "wrong type reported once",
+			body:     `{"title":42,"pages":"many"}`,
+			wantCode: shared.CodeValidationFailed,
+			wantErrs: []FieldError{{"title", "must be a string"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var request testRequest
+			apiErr := Decode(tt.body, &request)
+			if tt.wantCode == "" {
+				if apiErr != nil {
+					t.Fatalf("Decode(%s) = %v, want no error", tt.body, apiErr)
+				}
+				return
+			}
+			if apiErr == nil {
+				t.Fatalf("Decode(%s) succeeded, want %s", tt.body, tt.wantCode)
+			}
+			if apiErr.Code != tt.wantCode {
+				t.Errorf("code = %s, want %s", apiErr.Code, tt.wantCode)
+			}
+			if tt.wantErrs != nil && !reflect.DeepEqual(apiErr.Details, tt.wantErrs) {
+				t.Errorf("details = %v, want %v", apiErr.Details, tt.wantErrs)
+			}
+		})
+	}
+}
+
+func TestStructPanicsOnUnknownRule(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Struct did not panic on an unknown rule")
+		}
+	}()
+	Struct(struct {
+		Name string `validate:"email"`
+	}{Name: "x"})
+}
@@ -122,18 +122,16 @@ export class BookService {
 			);
 			
 			if (!response.ok) {
-				// If it's a 409 Conflict (already in list), provide a more helpful error
-				if (response.status === 409) {
+				const body = await response.json().catch(() => null);
+				// If the book is already in the list, provide a more helpful error
+				if (body?.error?.code === 'BOOK_ALREADY_IN_LIST') {
 					throw new Error('This book is already in your currently reading list');
 				}
 				
-				// For other errors, get the error message if possible
-				try {
-					const errorText = await response.text();
-					throw new Error(`Failed to add book to currently reading: ${errorText}`);
-				} catch (e) {
-					throw new Error('Failed to add book to currently reading');
-				}
+				// For other errors, use the error message if there is one
+				throw new Error(
+					`Failed to add book to currently reading: ${body?.error?.message ?? response.statusText}`
+				);
 			}
 		} catch (error) {
 			console.error('Error adding book to currently reading:', error);
@@ -159,7 +157,8 @@ export class BookService {
 		if (!response.ok) {
 			throw new Error('Failed to search books');
 		}
-		return await response.json();
+		const { items } = await response.json();
+		return items;
 	}
 
 	async addToList(bookId: string, listType: string): Promise<void> {
@@ -252,7 +251,7 @@ export class BookService {
 					);
 					
 					if (response.ok) {
-						const books = await response.json();
+						const { items: books } = await response.json();
 						console.log(`[DEBUG] Book search by OpenLibraryId response:`, books);
 						
 						// If we found books and have a valid bookId in the first result
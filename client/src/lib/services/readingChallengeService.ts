@@ -43,7 +43,8 @@ export class ReadingChallengeService {
 			throw new Error('Failed to fetch challenges');
 		}
 
-		return response.json();
+		const { items } = await response.json();
+		return items;
 	}
 
 	async updateChallenge(id: string, current: number): Promise<ReadingChallenge> {
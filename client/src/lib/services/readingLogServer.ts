@@ -20,9 +20,10 @@ export class ReadingLogService {
 		};
 	}
 
-	async getReadingList(): Promise<ReadingLogItem> {
+	async getReadingList(): Promise<ReadingLogItem[]> {
 		const response = await fetch(`${PUBLIC_API_BASE_URL}/reading-log`, this.getOptions('GET'));
-		return await response.json();
+		const { items } = await response.json();
+		return items;
 	}
 
 	async updateBookProgress(
@@ -130,3 +130,13 @@ export interface ReadingChallenge {
 	createdAt: string;
 	updatedAt: string;
 }
+
+// Error body returned by every API endpoint. Switch on `code`; `message` is
+// for display and may change.
+export interface ApiError {
+	error: {
+		code: string;
+		message: string;
+		details?: unknown;
+	};
+}
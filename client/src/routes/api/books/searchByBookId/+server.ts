@@ -35,8 +35,8 @@ export const GET: RequestHandler = async ({ url, cookies, fetch }) => {
 			return new Response('Error searching books', { status: response.status });
 		}
 
-		const result = await response.json();
-		return new Response(JSON.stringify(result), { status: 200 });
+		const { items } = await response.json();
+		return new Response(JSON.stringify(items), { status: 200 });
 	} catch (err) {
 		console.error('Search route error:', err);
 		return new Response('Internal Server Error', { status: 500 });
@@ -23,7 +23,7 @@ export const load: PageServerLoad = async ({ fetch, cookies }) => {
 		return { readingLog: [] };
 	}
 
-	const readingLog = await response.json();
+	const { items: readingLog } = await response.json();
 
 	return { readingLog: readingLog || [] };
 };